@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"ventura/internal/auth"
 	"ventura/internal/database"
 	"ventura/internal/di"
 	"ventura/internal/routes"
@@ -9,6 +10,11 @@ import (
 )
 
 func main() {
+	// Load JWT signing keys before anything can mint a token
+	if err := auth.LoadKeys(); err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+
 	// Connect to database and run migrations
 	db := database.Connect()
 
@@ -17,6 +23,9 @@ func main() {
 
 	// Start background workers
 	worker.StartNewsFetcher()
+	worker.StartSessionCleanup(container.SessionService)
+	worker.StartAuditRetention(container.AuditService)
+	worker.StartOrganizationLifecycle(container.OrganizationService, container.ExportService)
 
 	// Setup routes and start server
 	router := routes.Setup(container)
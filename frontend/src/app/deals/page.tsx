@@ -1,8 +1,8 @@
 "use client";
 
 import { useState, useCallback } from "react";
-import { fetchDeals, updateDealStage } from "@/lib/api";
-import { Deal, DealStage } from "@/lib/types";
+import { fetchDeals, fetchScorecard, updateDealStage } from "@/lib/api";
+import { Deal, DealStage, ScoringCriterion } from "@/lib/types";
 import { KanbanBoard } from "@/components/deals/kanban-board";
 import { AddDealModal } from "@/components/deals/add-deal-modal";
 import { ConvertToPortfolioModal } from "@/components/deals/convert-to-portfolio-modal";
@@ -24,6 +24,7 @@ import { Badge } from "@/components/ui/badge";
 export default function DealsPage() {
   const [deals, setDeals] = useState<Deal[]>([]);
   const [archivedDeals, setArchivedDeals] = useState<Deal[]>([]);
+  const [criteria, setCriteria] = useState<ScoringCriterion[]>([]);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
   const [isModalOpen, setIsModalOpen] = useState(false);
@@ -37,12 +38,14 @@ export default function DealsPage() {
   const loadDeals = useCallback(async () => {
     try {
       setLoading(true);
-      const [activeData, archivedData] = await Promise.all([
+      const [activeData, archivedData, scorecard] = await Promise.all([
         fetchDeals(false),
         fetchDeals(true),
+        fetchScorecard(),
       ]);
       setDeals(activeData);
       setArchivedDeals(archivedData);
+      setCriteria(scorecard);
       setError(null);
     } catch (err) {
       setError(err instanceof Error ? err.message : "Failed to load deals");
@@ -243,6 +246,7 @@ export default function DealsPage() {
           <>
             <KanbanBoard
               deals={deals}
+              criteria={criteria}
               onDealStageChange={handleDealStageChange}
               onDealUpdated={handleDealUpdated}
             />
@@ -367,6 +371,7 @@ export default function DealsPage() {
       {/* Modals */}
       <AddDealModal
         isOpen={isModalOpen}
+        criteria={criteria}
         onClose={() => setIsModalOpen(false)}
         onDealCreated={handleDealCreated}
       />
@@ -9,10 +9,11 @@ import {
   updateProfile,
   changePassword,
   fetchOrganization,
-  createInviteCode,
-  fetchInviteCodes,
+  createInvitation,
+  fetchInvitations,
+  acceptInvitation,
   Organization,
-  InviteCode,
+  Invitation,
 } from "@/lib/api";
 import { User, Building2, Key, Copy, Check, Plus, Clock } from "lucide-react";
 import { useAuthenticatedEffect } from "@/hooks/useAuthenticatedQuery";
@@ -25,7 +26,8 @@ export default function ProfilePage() {
   const [newPassword, setNewPassword] = useState("");
   const [confirmPassword, setConfirmPassword] = useState("");
   const [organization, setOrganization] = useState<Organization | null>(null);
-  const [inviteCodes, setInviteCodes] = useState<InviteCode[]>([]);
+  const [inviteCodes, setInviteCodes] = useState<Invitation[]>([]);
+  const [joinCode, setJoinCode] = useState("");
   const [copiedCode, setCopiedCode] = useState<string | null>(null);
 
   const [profileMessage, setProfileMessage] = useState("");
@@ -48,9 +50,10 @@ export default function ProfilePage() {
   useAuthenticatedEffect(() => {
     async function loadData() {
       try {
+        // Only members who manage users can list invitations
         const [org, codes] = await Promise.all([
           fetchOrganization(),
-          fetchInviteCodes(),
+          fetchInvitations().catch(() => []),
         ]);
         setOrganization(org);
         setInviteCodes(codes || []);
@@ -117,17 +120,8 @@ export default function ProfilePage() {
     setInviteLoading(true);
 
     try {
-      const newCode = await createInviteCode();
-      setInviteCodes([
-        {
-          ...newCode,
-          id: Date.now(),
-          organizationId: organization?.id || 0,
-          createdById: user?.id || 0,
-          createdAt: new Date().toISOString(),
-        } as InviteCode,
-        ...inviteCodes,
-      ]);
+      const invitation = await createInvitation();
+      setInviteCodes([invitation, ...inviteCodes]);
       setInviteMessage("Invite code created!");
     } catch (err) {
       setInviteError(
@@ -138,16 +132,31 @@ export default function ProfilePage() {
     }
   };
 
+  const handleJoin = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setInviteMessage("");
+    setInviteError("");
+    setInviteLoading(true);
+
+    try {
+      const joined = await acceptInvitation(joinCode.trim());
+      setJoinCode("");
+      setInviteMessage(`Joined ${joined.name}!`);
+    } catch (err) {
+      setInviteError(
+        err instanceof Error ? err.message : "Failed to accept invitation",
+      );
+    } finally {
+      setInviteLoading(false);
+    }
+  };
+
   const copyToClipboard = (code: string) => {
     navigator.clipboard.writeText(code);
     setCopiedCode(code);
     setTimeout(() => setCopiedCode(null), 2000);
   };
 
-  const isExpired = (expiresAt: string) => {
-    return new Date(expiresAt) < new Date();
-  };
-
   return (
     <AppLayout>
       {/* Header */}
@@ -398,6 +407,23 @@ export default function ProfilePage() {
             </div>
           )}
 
+          <form onSubmit={handleJoin} className="flex gap-2 mb-4">
+            <input
+              type="text"
+              value={joinCode}
+              onChange={(e) => setJoinCode(e.target.value)}
+              placeholder="Have a code? Join another organization"
+              className="flex-1 px-4 py-2 bg-slate-50 dark:bg-slate-800 border border-slate-300 dark:border-slate-700 rounded-lg text-slate-900 dark:text-white placeholder-slate-400 focus:outline-none focus:ring-2 focus:ring-purple-500"
+            />
+            <button
+              type="submit"
+              disabled={inviteLoading || !joinCode.trim()}
+              className="px-4 py-2 bg-slate-200 dark:bg-slate-700 hover:bg-slate-300 dark:hover:bg-slate-600 text-slate-900 dark:text-white font-medium rounded-lg transition-colors disabled:opacity-50"
+            >
+              Join
+            </button>
+          </form>
+
           {inviteCodes.length === 0 ? (
             <p className="text-slate-500 dark:text-slate-400 text-center py-8">
               No invite codes yet. Create one to invite team members.
@@ -408,7 +434,7 @@ export default function ProfilePage() {
                 <div
                   key={invite.code}
                   className={`flex items-center justify-between p-4 rounded-xl border ${
-                    isExpired(invite.expiresAt) || invite.usedById
+                    invite.status !== "pending"
                       ? "bg-slate-100 dark:bg-slate-800/30 border-slate-200 dark:border-slate-700 opacity-60"
                       : "bg-slate-50 dark:bg-slate-800/50 border-slate-200 dark:border-slate-700"
                   }`}
@@ -420,17 +446,19 @@ export default function ProfilePage() {
                     <div className="flex items-center gap-2 mt-1">
                       <Clock className="w-3 h-3 text-slate-400" />
                       <span className="text-xs text-slate-500">
-                        {isExpired(invite.expiresAt)
+                        {invite.status === "expired"
                           ? "Expired"
-                          : invite.usedById
+                          : invite.status === "used"
                             ? "Used"
-                            : `Expires ${new Date(
-                                invite.expiresAt,
-                              ).toLocaleDateString()}`}
+                            : invite.status === "revoked"
+                              ? "Revoked"
+                              : `Expires ${new Date(
+                                  invite.expiresAt,
+                                ).toLocaleDateString()}`}
                       </span>
                     </div>
                   </div>
-                  {!isExpired(invite.expiresAt) && !invite.usedById && (
+                  {invite.status === "pending" && (
                     <button
                       onClick={() => copyToClipboard(invite.code)}
                       className="p-2 hover:bg-slate-200 dark:hover:bg-slate-700 rounded-lg transition-colors"
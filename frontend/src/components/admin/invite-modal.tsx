@@ -1,7 +1,7 @@
 "use client";
 
 import { useState } from "react";
-import { inviteUser } from "@/lib/api";
+import { createInvitation } from "@/lib/api";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
 
@@ -12,11 +12,10 @@ interface InviteModalProps {
 
 export function InviteModal({ onClose, onSuccess }: InviteModalProps) {
   const [email, setEmail] = useState("");
-  const [name, setName] = useState("");
   const [role, setRole] = useState<"admin" | "viewer">("viewer");
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
-  const [success, setSuccess] = useState<{ tempPassword: string } | null>(null);
+  const [success, setSuccess] = useState<{ code: string } | null>(null);
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
@@ -24,8 +23,8 @@ export function InviteModal({ onClose, onSuccess }: InviteModalProps) {
     setError(null);
 
     try {
-      const result = await inviteUser({ email, name, role });
-      setSuccess({ tempPassword: result.tempPassword });
+      const invitation = await createInvitation({ email, role });
+      setSuccess({ code: invitation.code });
     } catch (err) {
       setError(err instanceof Error ? err.message : "Failed to invite user");
     } finally {
@@ -65,10 +64,10 @@ export function InviteModal({ onClose, onSuccess }: InviteModalProps) {
           <div className="space-y-4">
             <div className="bg-green-50 dark:bg-green-900/20 border border-green-200 dark:border-green-800 rounded-lg p-4 transition-colors">
               <p className="text-green-700 dark:text-green-400 font-medium">
-                User invited successfully!
+                Invitation sent!
               </p>
               <p className="text-slate-600 dark:text-slate-400 text-sm mt-2">
-                Share these credentials with the new user:
+                We emailed a link to join. They can also use this code:
               </p>
               <div className="mt-3 p-3 bg-slate-100 dark:bg-slate-800 rounded-lg font-mono text-sm transition-colors">
                 <div className="text-slate-600 dark:text-slate-400">
@@ -78,9 +77,9 @@ export function InviteModal({ onClose, onSuccess }: InviteModalProps) {
                   </span>
                 </div>
                 <div className="text-slate-600 dark:text-slate-400">
-                  Temp Password:{" "}
+                  Code:{" "}
                   <span className="text-slate-900 dark:text-white">
-                    {success.tempPassword}
+                    {success.code}
                   </span>
                 </div>
               </div>
@@ -110,19 +109,6 @@ export function InviteModal({ onClose, onSuccess }: InviteModalProps) {
               />
             </div>
 
-            <div>
-              <label className="block text-sm font-medium text-slate-600 dark:text-slate-400 mb-1">
-                Name
-              </label>
-              <Input
-                type="text"
-                value={name}
-                onChange={(e) => setName(e.target.value)}
-                required
-                placeholder="John Doe"
-              />
-            </div>
-
             <div>
               <label className="block text-sm font-medium text-slate-600 dark:text-slate-400 mb-2">
                 Role
@@ -197,7 +183,7 @@ export function InviteModal({ onClose, onSuccess }: InviteModalProps) {
                 className="flex-1 gap-2"
                 isLoading={loading}
               >
-                {!loading && "Invite User"}
+                {!loading && "Send Invitation"}
               </Button>
             </div>
           </form>
@@ -9,7 +9,6 @@ import {
   SelectValue,
 } from "@/components/ui/select";
 import { Button } from "@/components/ui/button";
-import { Input } from "@/components/ui/input";
 import { Spinner } from "@/components/ui/spinner";
 
 export function UserList() {
@@ -17,7 +16,6 @@ export function UserList() {
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
   const [editingId, setEditingId] = useState<number | null>(null);
-  const [editName, setEditName] = useState("");
   const [editRole, setEditRole] = useState<"admin" | "viewer">("viewer");
 
   useEffect(() => {
@@ -39,13 +37,12 @@ export function UserList() {
 
   const handleEdit = (user: UserWithDetails) => {
     setEditingId(user.id);
-    setEditName(user.name);
     setEditRole(user.role);
   };
 
   const handleSave = async (id: number) => {
     try {
-      await updateUser(id, { name: editName, role: editRole });
+      await updateUser(id, { role: editRole });
       setEditingId(null);
       loadUsers();
     } catch (err) {
@@ -98,17 +95,9 @@ export function UserList() {
               className="hover:bg-slate-100/50 dark:hover:bg-slate-800/30 transition-colors"
             >
               <td className="px-6 py-4">
-                {editingId === user.id ? (
-                  <Input
-                    type="text"
-                    value={editName}
-                    onChange={(e) => setEditName(e.target.value)}
-                  />
-                ) : (
-                  <span className="text-slate-900 dark:text-white font-medium">
-                    {user.name}
-                  </span>
-                )}
+                <span className="text-slate-900 dark:text-white font-medium">
+                  {user.name}
+                </span>
               </td>
               <td className="px-6 py-4 text-slate-600 dark:text-slate-400">
                 {user.email}
@@ -1,19 +1,22 @@
 "use client";
 
 import { useState } from "react";
-import { Deal, DealStage } from "@/lib/types";
+import { Deal, ScoringCriterion } from "@/lib/types";
+import { createDeal, setDealScores } from "@/lib/api";
 import { X } from "lucide-react";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
 
 interface AddDealModalProps {
   isOpen: boolean;
+  criteria: ScoringCriterion[];
   onClose: () => void;
   onDealCreated: (deal: Deal) => void;
 }
 
 export function AddDealModal({
   isOpen,
+  criteria,
   onClose,
   onDealCreated,
 }: AddDealModalProps) {
@@ -26,15 +29,14 @@ export function AddDealModal({
     requestedAmount: "",
     valuation: "",
     roundStage: "Seed",
-    teamScore: 5,
-    productScore: 5,
-    marketScore: 5,
-    tractionScore: 5,
     founderName: "",
     founderEmail: "",
     notes: "",
   });
 
+  // The creator's own scores on the scorecard, by criterion key; unset ones start at 5
+  const [scores, setScores] = useState<Record<string, number>>({});
+
   const [errors, setErrors] = useState<Record<string, string>>({});
 
   if (!isOpen) return null;
@@ -82,57 +84,36 @@ export function AddDealModal({
     setIsSubmitting(true);
 
     try {
-      const response = await fetch("http://localhost:8080/api/deals", {
-        method: "POST",
-        headers: {
-          "Content-Type": "application/json",
-        },
-        credentials: "include",
-        body: JSON.stringify({
-          CompanyName: formData.companyName,
-          Sector: formData.sector,
-          Stage: "incoming" as DealStage,
-          RequestedAmount: parseFloat(formData.requestedAmount),
-          Valuation: parseFloat(formData.valuation),
-          RoundStage: formData.roundStage,
-          TeamScore: parseInt(String(formData.teamScore)),
-          ProductScore: parseInt(String(formData.productScore)),
-          MarketScore: parseInt(String(formData.marketScore)),
-          TractionScore: parseInt(String(formData.tractionScore)),
-          FounderName: formData.founderName,
-          FounderEmail: formData.founderEmail,
-          Notes: formData.notes,
-        }),
+      const newDeal = await createDeal({
+        companyName: formData.companyName,
+        sector: formData.sector,
+        requestedAmount: parseFloat(formData.requestedAmount),
+        valuation: parseFloat(formData.valuation),
+        roundStage: formData.roundStage,
+        founderName: formData.founderName,
+        founderEmail: formData.founderEmail,
+        notes: formData.notes,
       });
 
-      if (!response.ok) {
-        throw new Error("Failed to create deal");
+      // Record the creator's scores as the first reviewer's
+      const scorecard = await setDealScores(
+        newDeal.id,
+        Object.fromEntries(
+          criteria.map((criterion) => [criterion.key, scores[criterion.key] ?? 5]),
+        ),
+      );
+      const averages: Record<string, number> = {};
+      for (const criterion of scorecard.criteria) {
+        if (criterion.average !== null) {
+          averages[criterion.key] = criterion.average;
+        }
       }
 
-      const backendDeal = await response.json();
-
-      // Transform backend response to frontend format
-      const newDeal: Deal = {
-        id: backendDeal.ID,
-        companyName: backendDeal.CompanyName,
-        sector: backendDeal.Sector,
-        stage: backendDeal.Stage,
-        requestedAmount: backendDeal.RequestedAmount,
-        valuation: backendDeal.Valuation,
-        roundStage: backendDeal.RoundStage,
-        teamScore: backendDeal.TeamScore,
-        productScore: backendDeal.ProductScore,
-        marketScore: backendDeal.MarketScore,
-        tractionScore: backendDeal.TractionScore,
-        totalScore: backendDeal.TotalScore,
-        founderName: backendDeal.FounderName,
-        founderEmail: backendDeal.FounderEmail,
-        notes: backendDeal.Notes,
-        createdAt: backendDeal.CreatedAt,
-        updatedAt: backendDeal.UpdatedAt,
-      };
-
-      onDealCreated(newDeal);
+      onDealCreated({
+        ...newDeal,
+        scores: averages,
+        totalScore: scorecard.totalScore,
+      });
 
       // Reset form
       setFormData({
@@ -141,14 +122,11 @@ export function AddDealModal({
         requestedAmount: "",
         valuation: "",
         roundStage: "Seed",
-        teamScore: 5,
-        productScore: 5,
-        marketScore: 5,
-        tractionScore: 5,
         founderName: "",
         founderEmail: "",
         notes: "",
       });
+      setScores({});
       setErrors({});
       onClose();
     } catch (err) {
@@ -303,21 +281,24 @@ export function AddDealModal({
             </h3>
 
             <div className="grid grid-cols-2 gap-4">
-              {[
-                { name: "teamScore", label: "Team Score" },
-                { name: "productScore", label: "Product Score" },
-                { name: "marketScore", label: "Market Score" },
-                { name: "tractionScore", label: "Traction Score" },
-              ].map((score) => (
-                <div key={score.name}>
-                  <label className="block text-sm font-medium text-slate-700 dark:text-slate-300 mb-2">
-                    {score.label}
+              {criteria.map((criterion) => (
+                <div key={criterion.key}>
+                  <label
+                    className="block text-sm font-medium text-slate-700 dark:text-slate-300 mb-2"
+                    title={criterion.description}
+                  >
+                    {criterion.name} ({criterion.weight}%)
                   </label>
                   <input
                     type="range"
-                    name={score.name}
-                    value={formData[score.name as keyof typeof formData]}
-                    onChange={handleChange}
+                    name={criterion.key}
+                    value={scores[criterion.key] ?? 5}
+                    onChange={(e) =>
+                      setScores((prev) => ({
+                        ...prev,
+                        [criterion.key]: parseInt(e.target.value),
+                      }))
+                    }
                     min="1"
                     max="10"
                     className="w-full h-2 bg-slate-200 rounded-lg appearance-none cursor-pointer dark:bg-slate-700 accent-indigo-600"
@@ -325,7 +306,7 @@ export function AddDealModal({
                   <div className="flex justify-between text-xs text-slate-500 dark:text-slate-400 mt-1">
                     <span>1</span>
                     <span className="font-semibold text-slate-900 dark:text-white">
-                      {formData[score.name as keyof typeof formData]}
+                      {scores[criterion.key] ?? 5}
                     </span>
                     <span>10</span>
                   </div>
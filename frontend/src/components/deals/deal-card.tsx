@@ -1,18 +1,19 @@
 "use client";
 
-import { Deal } from "@/lib/types";
+import { Deal, ScoringCriterion } from "@/lib/types";
 import { Badge } from "@/components/ui/badge";
 import { useSortable } from "@dnd-kit/sortable";
 import { CSS } from "@dnd-kit/utilities";
-import { Building2, Star, Sparkles } from "lucide-react";
+import { Building2, Star } from "lucide-react";
 import { useCurrency } from "@/contexts/CurrencyContext";
 
 interface DealCardProps {
   deal: Deal;
+  criteria: ScoringCriterion[];
   onDealUpdated?: (updatedDeal: Deal) => void;
 }
 
-export function DealCard({ deal }: DealCardProps) {
+export function DealCard({ deal, criteria }: DealCardProps) {
   const {
     attributes,
     listeners,
@@ -75,48 +76,37 @@ export function DealCard({ deal }: DealCardProps) {
       {/* Total Score */}
       <div className="flex items-center justify-between mb-2">
         <div className="flex items-center gap-1 text-xs text-slate-600 dark:text-slate-400">
-          {deal.totalScore > 0 ? (
-            <>
-              <Sparkles className="h-3 w-3 text-violet-500" />
-              <span className="text-violet-600 dark:text-violet-400 font-medium">AI Score</span>
-            </>
-          ) : (
-            <>
-              <Star className="h-3 w-3" />
-              <span>Score</span>
-            </>
-          )}
+          <Star className="h-3 w-3" />
+          <span>Score</span>
         </div>
         <div className="flex items-center gap-2">
           <span className="text-sm font-bold text-slate-900 dark:text-white">
-            {deal.totalScore}/40
+            {deal.totalScore > 0 ? `${deal.totalScore}/${maxScore}` : "Not scored"}
           </span>
         </div>
       </div>
 
       {/* Score Breakdown */}
       <div className="space-y-1.5">
-        {[
-          { label: "Team", score: deal.teamScore },
-          { label: "Product", score: deal.productScore },
-          { label: "Market", score: deal.marketScore },
-          { label: "Traction", score: deal.tractionScore },
-        ].map((item) => (
-          <div key={item.label} className="flex items-center gap-2">
-            <span className="text-xs text-slate-600 dark:text-slate-400 w-16">
-              {item.label}
-            </span>
-            <div className="flex-1 h-1.5 bg-slate-200 dark:bg-slate-700 rounded-full overflow-hidden">
-              <div
-                className={`h-full ${getScoreColor(item.score)} transition-all`}
-                style={{ width: `${(item.score / maxScore) * 100}%` }}
-              />
+        {criteria.map((criterion) => {
+          const score = deal.scores[criterion.key] ?? 0;
+          return (
+            <div key={criterion.key} className="flex items-center gap-2">
+              <span className="text-xs text-slate-600 dark:text-slate-400 w-16 truncate">
+                {criterion.name}
+              </span>
+              <div className="flex-1 h-1.5 bg-slate-200 dark:bg-slate-700 rounded-full overflow-hidden">
+                <div
+                  className={`h-full ${getScoreColor(score)} transition-all`}
+                  style={{ width: `${(score / maxScore) * 100}%` }}
+                />
+              </div>
+              <span className="text-xs font-medium text-slate-700 dark:text-slate-300 w-6">
+                {score > 0 ? score : "-"}
+              </span>
             </div>
-            <span className="text-xs font-medium text-slate-700 dark:text-slate-300 w-4">
-              {item.score}
-            </span>
-          </div>
-        ))}
+          );
+        })}
       </div>
     </div>
   );
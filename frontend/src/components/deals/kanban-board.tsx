@@ -1,7 +1,7 @@
 "use client";
 
 import { useState, useEffect } from "react";
-import { Deal, DealStage } from "@/lib/types";
+import { Deal, DealStage, ScoringCriterion } from "@/lib/types";
 import { KanbanColumn } from "./kanban-column";
 import {
   DndContext,
@@ -17,6 +17,7 @@ import { DealCard } from "./deal-card";
 
 interface KanbanBoardProps {
   deals: Deal[];
+  criteria: ScoringCriterion[];
   onDealStageChange: (dealId: number, newStage: DealStage) => Promise<void>;
   onDealUpdated?: (updatedDeal: Deal) => void;
 }
@@ -30,7 +31,7 @@ const STAGES: { id: DealStage; title: string }[] = [
   { id: "lost", title: "Lost" },
 ];
 
-export function KanbanBoard({ deals, onDealStageChange, onDealUpdated }: KanbanBoardProps) {
+export function KanbanBoard({ deals, criteria, onDealStageChange, onDealUpdated }: KanbanBoardProps) {
   const [activeDeal, setActiveDeal] = useState<Deal | null>(null);
   const [localDeals, setLocalDeals] = useState(deals);
 
@@ -131,6 +132,7 @@ export function KanbanBoard({ deals, onDealStageChange, onDealUpdated }: KanbanB
               title={stage.title}
               stage={stage.id}
               deals={stageDeals}
+              criteria={criteria}
               count={stageDeals.length}
               onDealUpdated={onDealUpdated}
             />
@@ -141,7 +143,7 @@ export function KanbanBoard({ deals, onDealStageChange, onDealUpdated }: KanbanB
       <DragOverlay>
         {activeDeal ? (
           <div className="rotate-3 scale-105">
-            <DealCard deal={activeDeal} onDealUpdated={onDealUpdated} />
+            <DealCard deal={activeDeal} criteria={criteria} onDealUpdated={onDealUpdated} />
           </div>
         ) : null}
       </DragOverlay>
@@ -1,6 +1,6 @@
 "use client";
 
-import { Deal, DealStage } from "@/lib/types";
+import { Deal, DealStage, ScoringCriterion } from "@/lib/types";
 import { DealCard } from "./deal-card";
 import { useDroppable } from "@dnd-kit/core";
 import {
@@ -13,6 +13,7 @@ interface KanbanColumnProps {
   title: string;
   stage: DealStage;
   deals: Deal[];
+  criteria: ScoringCriterion[];
   count: number;
   onDealUpdated?: (updatedDeal: Deal) => void;
 }
@@ -21,6 +22,7 @@ export function KanbanColumn({
   title,
   stage,
   deals,
+  criteria,
   count,
   onDealUpdated,
 }: KanbanColumnProps) {
@@ -54,7 +56,7 @@ export function KanbanColumn({
               No deals in this stage
             </div>
           ) : (
-            deals.map((deal) => <DealCard key={deal.id} deal={deal} onDealUpdated={onDealUpdated} />)
+            deals.map((deal) => <DealCard key={deal.id} deal={deal} criteria={criteria} onDealUpdated={onDealUpdated} />)
           )}
         </SortableContext>
       </div>
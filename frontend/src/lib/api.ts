@@ -2,6 +2,9 @@ import {
   PortfolioCompany,
   Deal,
   DealStage,
+  DealAnalysis,
+  DealScorecard,
+  ScoringCriterion,
   CreateCompanyData,
   UpdateCompanyData,
   Founder,
@@ -11,11 +14,11 @@ import {
   MissingUpdateInfo,
   UserWithDetails,
   AuditLogResponse,
-  InviteUserData,
   UpdateUserData,
   TeamMember,
   AddTeamMemberData,
 } from "./types";
+import { apiFetch } from "./auth";
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
 
@@ -45,7 +48,7 @@ export interface DashboardData {
 }
 
 export async function fetchDashboardData(): Promise<DashboardData> {
-  const response = await fetch(`${API_BASE_URL}/api/dashboard`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/dashboard`, {
     credentials: "include", // Include cookies for authentication
   });
 
@@ -95,7 +98,7 @@ export interface DashboardHistoryData {
 }
 
 export async function fetchDashboardHistory(): Promise<DashboardHistoryData> {
-  const response = await fetch(`${API_BASE_URL}/api/dashboard/history`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/dashboard/history`, {
     credentials: "include",
   });
 
@@ -116,7 +119,7 @@ export async function fetchDashboardHistory(): Promise<DashboardHistoryData> {
 }
 
 export async function fetchAIInsight(): Promise<{ insight: string }> {
-  const response = await fetch(`${API_BASE_URL}/api/dashboard/ai-insight`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/dashboard/ai-insight`, {
     credentials: "include",
   });
 
@@ -137,7 +140,7 @@ export async function fetchAIInsight(): Promise<{ insight: string }> {
 }
 
 export async function fetchPortfolioCompanies(): Promise<PortfolioCompany[]> {
-  const response = await fetch(`${API_BASE_URL}/api/portfolio/companies`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/portfolio/companies`, {
     credentials: "include",
   });
 
@@ -158,7 +161,7 @@ export async function fetchPortfolioCompanies(): Promise<PortfolioCompany[]> {
 }
 
 export async function fetchCompanyById(id: string): Promise<PortfolioCompany> {
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/portfolio/companies/${id}`,
     {
       credentials: "include",
@@ -186,7 +189,7 @@ export async function fetchCompanyById(id: string): Promise<PortfolioCompany> {
 export async function createCompany(
   data: CreateCompanyData,
 ): Promise<PortfolioCompany> {
-  const response = await fetch(`${API_BASE_URL}/api/portfolio/companies`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/portfolio/companies`, {
     method: "POST",
     headers: {
       "Content-Type": "application/json",
@@ -214,7 +217,7 @@ export async function updateCompany(
   id: number,
   data: UpdateCompanyData,
 ): Promise<PortfolioCompany> {
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/portfolio/companies/${id}`,
     {
       method: "PUT",
@@ -246,7 +249,7 @@ export async function updateCompany(
 }
 
 export async function deleteCompany(id: number): Promise<void> {
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/portfolio/companies/${id}`,
     {
       method: "DELETE",
@@ -280,10 +283,7 @@ interface BackendDeal {
   RequestedAmount: string;
   Valuation: string;
   RoundStage: string;
-  TeamScore: number;
-  ProductScore: number;
-  MarketScore: number;
-  TractionScore: number;
+  Scores: Record<string, number> | null;
   TotalScore: number;
   FounderName: string;
   FounderEmail: string;
@@ -305,10 +305,7 @@ function transformDeal(backendDeal: BackendDeal): Deal {
     requestedAmount: backendDeal.RequestedAmount,
     valuation: backendDeal.Valuation,
     roundStage: backendDeal.RoundStage,
-    teamScore: backendDeal.TeamScore,
-    productScore: backendDeal.ProductScore,
-    marketScore: backendDeal.MarketScore,
-    tractionScore: backendDeal.TractionScore,
+    scores: backendDeal.Scores ?? {},
     totalScore: backendDeal.TotalScore,
     founderName: backendDeal.FounderName,
     founderEmail: backendDeal.FounderEmail,
@@ -328,7 +325,7 @@ export async function fetchDeals(archived?: boolean): Promise<Deal[]> {
   }
   const url = `${API_BASE_URL}/api/deals${params.toString() ? "?" + params.toString() : ""}`;
 
-  const response = await fetch(url, {
+  const response = await apiFetch(url, {
     credentials: "include",
   });
 
@@ -347,11 +344,56 @@ export async function fetchDeals(archived?: boolean): Promise<Deal[]> {
   return data.map(transformDeal);
 }
 
+export interface CreateDealData {
+  companyName: string;
+  sector: string;
+  requestedAmount: number;
+  valuation: number;
+  roundStage: string;
+  founderName: string;
+  founderEmail: string;
+  notes: string;
+}
+
+export async function createDeal(data: CreateDealData): Promise<Deal> {
+  const response = await apiFetch(`${API_BASE_URL}/api/deals`, {
+    method: "POST",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+    body: JSON.stringify({
+      CompanyName: data.companyName,
+      Sector: data.sector,
+      Stage: "incoming" as DealStage,
+      RequestedAmount: data.requestedAmount,
+      Valuation: data.valuation,
+      RoundStage: data.roundStage,
+      FounderName: data.founderName,
+      FounderEmail: data.founderEmail,
+      Notes: data.notes,
+    }),
+  });
+
+  if (response.status === 401) {
+    if (typeof window !== "undefined") {
+      window.location.href = "/login";
+    }
+    throw new Error("Unauthorized");
+  }
+
+  if (!response.ok) {
+    throw new Error("Failed to create deal");
+  }
+
+  return transformDeal(await response.json());
+}
+
 export async function updateDealStage(
   id: number,
   stage: DealStage,
 ): Promise<void> {
-  const response = await fetch(`${API_BASE_URL}/api/deals/${id}/stage`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/deals/${id}/stage`, {
     method: "PATCH",
     headers: {
       "Content-Type": "application/json",
@@ -372,8 +414,9 @@ export async function updateDealStage(
   }
 }
 
-export async function aiScoreDeal(id: number): Promise<Deal> {
-  const response = await fetch(`${API_BASE_URL}/api/deals/${id}/ai-score`, {
+// aiScoreDeal has the AI analyze a deal. Its scores are only suggested until accepted.
+export async function aiScoreDeal(id: number): Promise<DealAnalysis> {
+  const response = await apiFetch(`${API_BASE_URL}/api/deals/${id}/ai-score`, {
     method: "POST",
     headers: {
       "Content-Type": "application/json",
@@ -393,8 +436,74 @@ export async function aiScoreDeal(id: number): Promise<Deal> {
     throw new Error(errObj?.error || `Failed to score deal: ${response.statusText}`);
   }
 
-  const data = await response.json();
-  return transformDeal(data);
+  return response.json();
+}
+
+export async function fetchScorecard(): Promise<ScoringCriterion[]> {
+  const response = await apiFetch(`${API_BASE_URL}/api/deals/scorecard`, {
+    credentials: "include",
+  });
+
+  if (response.status === 401) {
+    if (typeof window !== "undefined") {
+      window.location.href = "/login";
+    }
+    throw new Error("Unauthorized");
+  }
+
+  if (!response.ok) {
+    throw new Error(`Failed to fetch scorecard: ${response.statusText}`);
+  }
+
+  return response.json();
+}
+
+export async function fetchDealScores(id: number): Promise<DealScorecard> {
+  const response = await apiFetch(`${API_BASE_URL}/api/deals/${id}/scores`, {
+    credentials: "include",
+  });
+
+  if (response.status === 401) {
+    if (typeof window !== "undefined") {
+      window.location.href = "/login";
+    }
+    throw new Error("Unauthorized");
+  }
+
+  if (!response.ok) {
+    throw new Error(`Failed to fetch deal scores: ${response.statusText}`);
+  }
+
+  return response.json();
+}
+
+// setDealScores replaces the current user's scores of a deal, by criterion key
+export async function setDealScores(
+  id: number,
+  scores: Record<string, number>,
+): Promise<DealScorecard> {
+  const response = await apiFetch(`${API_BASE_URL}/api/deals/${id}/scores`, {
+    method: "PUT",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+    body: JSON.stringify({ scores }),
+  });
+
+  if (response.status === 401) {
+    if (typeof window !== "undefined") {
+      window.location.href = "/login";
+    }
+    throw new Error("Unauthorized");
+  }
+
+  if (!response.ok) {
+    const errObj = await response.json().catch(() => null);
+    throw new Error(errObj?.error || `Failed to save scores: ${response.statusText}`);
+  }
+
+  return response.json();
 }
 
 export interface CloseDealData {
@@ -409,7 +518,7 @@ export async function closeDeal(
   id: number,
   data: CloseDealData,
 ): Promise<{ message: string; companyId?: number }> {
-  const response = await fetch(`${API_BASE_URL}/api/deals/${id}/close`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/deals/${id}/close`, {
     method: "PATCH",
     headers: {
       "Content-Type": "application/json",
@@ -439,7 +548,7 @@ export async function loseDeal(
   id: number,
   reason: LossReason,
 ): Promise<{ message: string }> {
-  const response = await fetch(`${API_BASE_URL}/api/deals/${id}/lose`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/deals/${id}/lose`, {
     method: "PATCH",
     headers: {
       "Content-Type": "application/json",
@@ -468,7 +577,7 @@ export async function loseDeal(
 export async function fetchFoundersByCompany(
   companyId: number,
 ): Promise<Founder[]> {
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/companies/${companyId}/founders`,
     {
       credentials: "include",
@@ -493,7 +602,7 @@ export async function createFounder(
   companyId: number,
   data: CreateFounderData,
 ): Promise<Founder> {
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/companies/${companyId}/founders`,
     {
       method: "POST",
@@ -524,7 +633,7 @@ export async function updateFounder(
   id: number,
   data: CreateFounderData,
 ): Promise<Founder> {
-  const response = await fetch(`${API_BASE_URL}/api/founders/${id}`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/founders/${id}`, {
     method: "PUT",
     headers: {
       "Content-Type": "application/json",
@@ -549,7 +658,7 @@ export async function updateFounder(
 }
 
 export async function deleteFounder(id: number): Promise<void> {
-  const response = await fetch(`${API_BASE_URL}/api/founders/${id}`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/founders/${id}`, {
     method: "DELETE",
     credentials: "include",
   });
@@ -572,7 +681,7 @@ export async function deleteFounder(id: number): Promise<void> {
 export async function fetchMonthlyUpdatesByCompany(
   companyId: number,
 ): Promise<MonthlyUpdate[]> {
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/companies/${companyId}/updates`,
     {
       credentials: "include",
@@ -597,7 +706,7 @@ export async function createMonthlyUpdate(
   companyId: number,
   data: CreateMonthlyUpdateData,
 ): Promise<MonthlyUpdate> {
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/companies/${companyId}/updates`,
     {
       method: "POST",
@@ -628,7 +737,7 @@ export async function updateMonthlyUpdate(
   id: number,
   data: CreateMonthlyUpdateData,
 ): Promise<MonthlyUpdate> {
-  const response = await fetch(`${API_BASE_URL}/api/monthly-updates/${id}`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/monthly-updates/${id}`, {
     method: "PUT",
     headers: {
       "Content-Type": "application/json",
@@ -653,7 +762,7 @@ export async function updateMonthlyUpdate(
 }
 
 export async function deleteMonthlyUpdate(id: number): Promise<void> {
-  const response = await fetch(`${API_BASE_URL}/api/monthly-updates/${id}`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/monthly-updates/${id}`, {
     method: "DELETE",
     credentials: "include",
   });
@@ -674,7 +783,7 @@ export async function deleteMonthlyUpdate(id: number): Promise<void> {
 // Missing Updates / Notifications
 
 export async function fetchMissingUpdates(): Promise<MissingUpdateInfo[]> {
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/dashboard/missing-updates`,
     {
       credentials: "include",
@@ -700,7 +809,7 @@ export async function toggleCompanyNotifications(
   companyId: number,
   enabled: boolean,
 ): Promise<{ updatesNotificationsEnabled: boolean }> {
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/portfolio/companies/${companyId}/notifications`,
     {
       method: "PATCH",
@@ -730,7 +839,7 @@ export async function toggleCompanyNotifications(
 // ============================================
 
 export async function fetchUsers(): Promise<UserWithDetails[]> {
-  const response = await fetch(`${API_BASE_URL}/api/admin/users`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/admin/users`, {
     credentials: "include",
   });
 
@@ -756,7 +865,7 @@ export async function updateUser(
   id: number,
   data: UpdateUserData,
 ): Promise<UserWithDetails> {
-  const response = await fetch(`${API_BASE_URL}/api/admin/users/${id}`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/admin/users/${id}`, {
     method: "PUT",
     headers: { "Content-Type": "application/json" },
     credentials: "include",
@@ -783,7 +892,7 @@ export async function updateUser(
 }
 
 export async function deleteUser(id: number): Promise<void> {
-  const response = await fetch(`${API_BASE_URL}/api/admin/users/${id}`, {
+  const response = await apiFetch(`${API_BASE_URL}/api/admin/users/${id}`, {
     method: "DELETE",
     credentials: "include",
   });
@@ -805,39 +914,6 @@ export async function deleteUser(id: number): Promise<void> {
   }
 }
 
-export async function inviteUser(
-  data: InviteUserData,
-): Promise<{ user: UserWithDetails; tempPassword: string }> {
-  const response = await fetch(`${API_BASE_URL}/api/admin/users/invite`, {
-    method: "POST",
-    headers: { "Content-Type": "application/json" },
-    credentials: "include",
-    body: JSON.stringify(data),
-  });
-
-  if (response.status === 401) {
-    if (typeof window !== "undefined") {
-      window.location.href = "/login";
-    }
-    throw new Error("Unauthorized");
-  }
-
-  if (response.status === 403) {
-    throw new Error("Forbidden: Admin access required");
-  }
-
-  if (response.status === 409) {
-    throw new Error("User with this email already exists");
-  }
-
-  if (!response.ok) {
-    const error = await response.json();
-    throw new Error(error.error || "Failed to invite user");
-  }
-
-  return response.json();
-}
-
 export async function fetchAuditLogs(
   page = 1,
   limit = 50,
@@ -852,7 +928,7 @@ export async function fetchAuditLogs(
   if (filters?.entity) params.append("entity", filters.entity);
   if (filters?.action) params.append("action", filters.action);
 
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/admin/audit-logs?${params}`,
     {
       credentials: "include",
@@ -884,7 +960,7 @@ export async function fetchAuditLogs(
 export async function fetchCompanyTeam(
   companyId: number,
 ): Promise<TeamMember[]> {
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/companies/${companyId}/team`,
     {
       credentials: "include",
@@ -909,7 +985,7 @@ export async function addTeamMember(
   companyId: number,
   data: AddTeamMemberData,
 ): Promise<TeamMember> {
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/companies/${companyId}/team`,
     {
       method: "POST",
@@ -942,7 +1018,7 @@ export async function removeTeamMember(
   companyId: number,
   userId: number,
 ): Promise<void> {
-  const response = await fetch(
+  const response = await apiFetch(
     `${API_BASE_URL}/api/companies/${companyId}/team/${userId}`,
     {
       method: "DELETE",
@@ -986,15 +1062,33 @@ export interface Organization {
   createdAt: string;
 }
 
-export interface InviteCode {
+export interface Invitation {
   id: number;
   code: string;
-  organizationId: number;
-  createdById: number;
-  usedById?: number;
+  email?: string; // Only this address may accept
+  role: string;
+  status: "pending" | "used" | "expired" | "revoked";
+  maxUses: number;
+  useCount: number;
+  createdBy?: string;
   expiresAt: string;
+  lastSentAt?: string;
   createdAt: string;
-  createdBy?: { name: string; email: string };
+}
+
+export interface CreateInvitationData {
+  email?: string;
+  role?: string;
+  expiresInDays?: number;
+  maxUses?: number;
+}
+
+export interface JoinedOrganization {
+  organizationId: number;
+  name: string;
+  slug: string;
+  role: string;
+  joinedAt: string;
 }
 
 export async function updateProfile(data: UpdateProfileData): Promise<{
@@ -1005,7 +1099,7 @@ export async function updateProfile(data: UpdateProfileData): Promise<{
   organizationId: number;
   organizationName?: string;
 }> {
-  const response = await fetch(`${AUTH_BASE_URL}/auth/profile`, {
+  const response = await apiFetch(`${AUTH_BASE_URL}/auth/profile`, {
     method: "PUT",
     headers: { "Content-Type": "application/json" },
     credentials: "include",
@@ -1028,7 +1122,7 @@ export async function updateProfile(data: UpdateProfileData): Promise<{
 }
 
 export async function changePassword(data: ChangePasswordData): Promise<void> {
-  const response = await fetch(`${AUTH_BASE_URL}/auth/password`, {
+  const response = await apiFetch(`${AUTH_BASE_URL}/auth/password`, {
     method: "PUT",
     headers: { "Content-Type": "application/json" },
     credentials: "include",
@@ -1049,7 +1143,7 @@ export async function changePassword(data: ChangePasswordData): Promise<void> {
 }
 
 export async function fetchOrganization(): Promise<Organization> {
-  const response = await fetch(`${AUTH_BASE_URL}/auth/organization`, {
+  const response = await apiFetch(`${AUTH_BASE_URL}/auth/organization`, {
     credentials: "include",
   });
 
@@ -1068,13 +1162,14 @@ export async function fetchOrganization(): Promise<Organization> {
   return response.json();
 }
 
-export async function createInviteCode(): Promise<{
-  code: string;
-  expiresAt: string;
-}> {
-  const response = await fetch(`${AUTH_BASE_URL}/auth/invites`, {
+export async function createInvitation(
+  data: CreateInvitationData = {},
+): Promise<Invitation> {
+  const response = await apiFetch(`${API_BASE_URL}/api/admin/invitations`, {
     method: "POST",
+    headers: { "Content-Type": "application/json" },
     credentials: "include",
+    body: JSON.stringify(data),
   });
 
   if (response.status === 401) {
@@ -1086,15 +1181,39 @@ export async function createInviteCode(): Promise<{
 
   if (!response.ok) {
     const error = await response.json();
-    throw new Error(error.error || "Failed to create invite code");
+    throw new Error(error.error || "Failed to create invitation");
   }
 
   return response.json();
 }
 
-export async function fetchInviteCodes(): Promise<InviteCode[]> {
-  const response = await fetch(`${AUTH_BASE_URL}/auth/invites`, {
+export async function fetchInvitations(): Promise<Invitation[]> {
+  const response = await apiFetch(`${API_BASE_URL}/api/admin/invitations`, {
+    credentials: "include",
+  });
+
+  if (response.status === 401) {
+    if (typeof window !== "undefined") {
+      window.location.href = "/login";
+    }
+    throw new Error("Unauthorized");
+  }
+
+  if (!response.ok) {
+    const error = await response.json();
+    throw new Error(error.error || "Failed to fetch invitations");
+  }
+
+  return response.json();
+}
+
+// acceptInvitation adds the signed-in user to the organization of an invitation code
+export async function acceptInvitation(code: string): Promise<JoinedOrganization> {
+  const response = await apiFetch(`${AUTH_BASE_URL}/auth/invitations/accept`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
     credentials: "include",
+    body: JSON.stringify({ code }),
   });
 
   if (response.status === 401) {
@@ -1106,7 +1225,7 @@ export async function fetchInviteCodes(): Promise<InviteCode[]> {
 
   if (!response.ok) {
     const error = await response.json();
-    throw new Error(error.error || "Failed to fetch invite codes");
+    throw new Error(error.error || "Failed to accept invitation");
   }
 
   return response.json();
@@ -15,9 +15,55 @@ export interface RegisterRequest {
   inviteCode?: string; // Join existing org
 }
 
+const CSRF_HEADER = "X-CSRF-Token";
+const CSRF_COOKIE = "csrf_token";
+const SAFE_METHODS = ["GET", "HEAD", "OPTIONS"];
+
+// The CSRF token of the current session, from the last login, refresh or me response
+let csrfToken: string | null = null;
+
+function setCSRFToken(token: string | null | undefined) {
+  if (token) {
+    csrfToken = token;
+  }
+}
+
+// Read the CSRF token from its cookie, which is only readable on the API's own site
+function readCSRFCookie(): string | null {
+  if (typeof document === "undefined") {
+    return null;
+  }
+  const cookie = document.cookie
+    .split("; ")
+    .find((part) => part.startsWith(`${CSRF_COOKIE}=`));
+  return cookie ? decodeURIComponent(cookie.split("=")[1]) : null;
+}
+
+// Fetch with the session cookies. Writes carry the CSRF token the API requires of
+// cookie-authenticated requests, and a rotated token in the response replaces it.
+export async function apiFetch(
+  input: string,
+  init: RequestInit = {},
+): Promise<Response> {
+  const headers = new Headers(init.headers);
+  const method = (init.method || "GET").toUpperCase();
+  const token = csrfToken || readCSRFCookie();
+  if (!SAFE_METHODS.includes(method) && token) {
+    headers.set(CSRF_HEADER, token);
+  }
+
+  const response = await fetch(input, {
+    credentials: "include",
+    ...init,
+    headers,
+  });
+  setCSRFToken(response.headers.get(CSRF_HEADER));
+  return response;
+}
+
 // Register a new user
 export async function register(data: RegisterRequest): Promise<AuthResponse> {
-  const response = await fetch(`${API_BASE_URL}/auth/register`, {
+  const response = await apiFetch(`${API_BASE_URL}/auth/register`, {
     method: "POST",
     headers: {
       "Content-Type": "application/json",
@@ -31,12 +77,14 @@ export async function register(data: RegisterRequest): Promise<AuthResponse> {
     throw new Error(error.error || "Registration failed");
   }
 
-  return response.json();
+  const result: AuthResponse = await response.json();
+  setCSRFToken(result.user.csrfToken);
+  return result;
 }
 
 // Login user
 export async function login(data: LoginRequest): Promise<AuthResponse> {
-  const response = await fetch(`${API_BASE_URL}/auth/login`, {
+  const response = await apiFetch(`${API_BASE_URL}/auth/login`, {
     method: "POST",
     headers: {
       "Content-Type": "application/json",
@@ -50,12 +98,14 @@ export async function login(data: LoginRequest): Promise<AuthResponse> {
     throw new Error(error.error || "Login failed");
   }
 
-  return response.json();
+  const result: AuthResponse = await response.json();
+  setCSRFToken(result.user.csrfToken);
+  return result;
 }
 
 // Logout user
 export async function logout(): Promise<void> {
-  const response = await fetch(`${API_BASE_URL}/auth/logout`, {
+  const response = await apiFetch(`${API_BASE_URL}/auth/logout`, {
     method: "POST",
     credentials: "include",
   });
@@ -63,11 +113,12 @@ export async function logout(): Promise<void> {
   if (!response.ok) {
     throw new Error("Logout failed");
   }
+  csrfToken = null;
 }
 
 // Get current user
 export async function getCurrentUser(): Promise<User> {
-  const response = await fetch(`${API_BASE_URL}/auth/me`, {
+  const response = await apiFetch(`${API_BASE_URL}/auth/me`, {
     credentials: "include",
   });
 
@@ -75,12 +126,14 @@ export async function getCurrentUser(): Promise<User> {
     throw new Error("Not authenticated");
   }
 
-  return response.json();
+  const user: User = await response.json();
+  setCSRFToken(user.csrfToken);
+  return user;
 }
 
 // Refresh access token
 export async function refreshToken(): Promise<void> {
-  const response = await fetch(`${API_BASE_URL}/auth/refresh`, {
+  const response = await apiFetch(`${API_BASE_URL}/auth/refresh`, {
     method: "POST",
     credentials: "include",
   });
@@ -88,4 +141,7 @@ export async function refreshToken(): Promise<void> {
   if (!response.ok) {
     throw new Error("Token refresh failed");
   }
+
+  const result: { csrfToken?: string } = await response.json();
+  setCSRFToken(result.csrfToken);
 }
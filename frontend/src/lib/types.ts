@@ -96,6 +96,7 @@ export interface User {
   role: "admin" | "viewer";
   organizationId: number;
   organizationName?: string;
+  csrfToken?: string; // Sent back on writes; see apiFetch
 }
 
 export interface AuthResponse {
@@ -126,11 +127,8 @@ export interface Deal {
   requestedAmount: string;
   valuation: string;
   roundStage: string; // "Seed", "Series A", etc.
-  teamScore: number; // 1-10
-  productScore: number; // 1-10
-  marketScore: number; // 1-10
-  tractionScore: number; // 1-10
-  totalScore: number; // Calculated sum
+  scores: Record<string, number>; // Average reviewer score (1-10) by criterion key
+  totalScore: number; // Weighted average on the scorecard, 1-10; 0 until scored
   founderName: string;
   founderEmail: string;
   notes: string;
@@ -142,6 +140,59 @@ export interface Deal {
   updatedAt: string;
 }
 
+// Scorecard types
+export interface ScaleLevel {
+  score: number;
+  description: string;
+}
+
+export interface ScoringCriterion {
+  key: string;
+  name: string;
+  description?: string;
+  weight: number; // Percent of the total score
+  scale?: ScaleLevel[];
+}
+
+export interface DealScore {
+  dealId: number;
+  criterion: string;
+  source: "reviewer" | "ai" | "legacy";
+  reviewerId?: number;
+  reviewerName: string;
+  analysisId?: number;
+  score: number;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface CriterionScores extends ScoringCriterion {
+  average: number | null;
+  scores: DealScore[];
+}
+
+export interface DealScorecard {
+  dealId: number;
+  criteria: CriterionScores[];
+  totalScore: number;
+}
+
+export interface DealAnalysis {
+  id: number;
+  dealId: number;
+  model: string;
+  promptVersion: string;
+  scores: Record<string, number>;
+  summary: string;
+  keyStrengths: string;
+  keyRisks: string;
+  status: "pending" | "accepted" | "rejected";
+  requestedByName: string;
+  reviewedByName?: string;
+  reviewedAt?: string;
+  createdAt: string;
+}
+
 export interface MonthlyUpdate {
   id: number;
   companyId: number;
@@ -203,15 +254,8 @@ export interface AuditLogResponse {
   limit: number;
 }
 
-export interface InviteUserData {
-  email: string;
-  name: string;
-  role?: "admin" | "viewer";
-}
-
 export interface UpdateUserData {
-  name?: string;
-  role?: "admin" | "viewer";
+  role: "admin" | "viewer";
 }
 
 // Team assignment types
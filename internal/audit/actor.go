@@ -0,0 +1,29 @@
+package audit
+
+import "context"
+
+// Actor is who database changes are attributed to
+type Actor struct {
+	UserID         uint
+	Email          string
+	OrganizationID uint
+	IPAddress      string
+	UserAgent      string
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose database changes are attributed to actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor carried by ctx. Changes made without one, e.g. by
+// background workers, are attributed to the system.
+func ActorFrom(ctx context.Context) (Actor, bool) {
+	if ctx == nil {
+		return Actor{}, false
+	}
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
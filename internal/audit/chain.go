@@ -0,0 +1,190 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/tenancy"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var auditLogType = reflect.TypeOf(models.AuditLog{})
+
+// Hash returns the hash of an audit entry, chained onto its PrevHash. It covers every
+// column but the ID, which the database assigns.
+func Hash(entry *models.AuditLog) (string, error) {
+	changes, err := canonicalJSON(entry.Changes)
+	if err != nil {
+		return "", err
+	}
+	content, err := json.Marshal([]interface{}{
+		entry.OrganizationID,
+		entry.Sequence,
+		entry.UserID,
+		entry.UserEmail,
+		entry.UserName,
+		entry.Action,
+		entry.Entity,
+		entry.EntityID,
+		entry.Details,
+		changes,
+		entry.IPAddress,
+		entry.UserAgent,
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(append([]byte(entry.PrevHash+"\n"), content...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJSON re-encodes a JSON value with sorted keys and no whitespace, so the hash
+// doesn't depend on how the database stored it
+func canonicalJSON(value json.RawMessage) (json.RawMessage, error) {
+	if isNull(value) {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return json.Marshal(decoded)
+}
+
+// chain gives new audit entries their place in their organization's chain. The chain head
+// stays locked until the transaction ends, so concurrent entries are chained one at a time.
+func chain(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || db.Statement.Schema == nil || db.Statement.Schema.ModelType != auditLogType {
+		return
+	}
+
+	byOrganization := make(map[uint][]*models.AuditLog)
+	collect := func(value reflect.Value) {
+		if value.Kind() != reflect.Ptr {
+			value = value.Addr()
+		}
+		if entry, ok := value.Interface().(*models.AuditLog); ok {
+			byOrganization[entry.OrganizationID] = append(byOrganization[entry.OrganizationID], entry)
+		}
+	}
+	switch value := db.Statement.ReflectValue; value.Kind() {
+	case reflect.Struct:
+		collect(value)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			collect(value.Index(i))
+		}
+	}
+
+	// Lock heads in a fixed order so transactions writing to several chains can't deadlock
+	orgIDs := make([]uint, 0, len(byOrganization))
+	for orgID := range byOrganization {
+		orgIDs = append(orgIDs, orgID)
+	}
+	sort.Slice(orgIDs, func(i, j int) bool { return orgIDs[i] < orgIDs[j] })
+
+	// Row-level security admits entries to the organization they are recorded for, whatever
+	// the statement's tenant; entries of several organizations are written one organization
+	// at a time, as record does
+	if len(orgIDs) == 1 {
+		tenancy.Apply(db, orgIDs[0])
+	}
+
+	now := time.Now().Truncate(time.Microsecond)
+	tx := db.Session(&gorm.Session{NewDB: true})
+	for _, orgID := range orgIDs {
+		head, err := lockHead(tx, orgID)
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		for _, entry := range byOrganization[orgID] {
+			if entry.CreatedAt.IsZero() {
+				entry.CreatedAt = now
+			}
+			if err := link(entry, head); err != nil {
+				db.AddError(err)
+				return
+			}
+		}
+		err = tx.Model(&models.AuditChainHead{}).Where("organization_id = ?", orgID).
+			Updates(map[string]interface{}{"sequence": head.Sequence, "hash": head.Hash}).Error
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+	}
+}
+
+// lockHead loads an organization's chain head for update, starting the chain if needed
+func lockHead(tx *gorm.DB, orgID uint) (*models.AuditChainHead, error) {
+	head := &models.AuditChainHead{OrganizationID: orgID}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(head).Error; err != nil {
+		return nil, err
+	}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("organization_id = ?", orgID).First(head).Error
+	return head, err
+}
+
+// link appends entry to the chain ending at head and moves head onto it
+func link(entry *models.AuditLog, head *models.AuditChainHead) error {
+	entry.Sequence = head.Sequence + 1
+	entry.PrevHash = head.Hash
+	hash, err := Hash(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+	head.Sequence, head.Hash = entry.Sequence, entry.Hash
+	return nil
+}
+
+// ChainExisting chains entries written before the audit log was hash-chained, in the order
+// they were written. It runs as a migration, before the plugin is registered.
+func ChainExisting(db *gorm.DB) error {
+	heads := make(map[uint]*models.AuditChainHead)
+	var batch []models.AuditLog
+	err := db.Where("hash = ''").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			entry := &batch[i]
+			head, ok := heads[entry.OrganizationID]
+			if !ok {
+				head = &models.AuditChainHead{OrganizationID: entry.OrganizationID}
+				heads[entry.OrganizationID] = head
+			}
+			if err := link(entry, head); err != nil {
+				return err
+			}
+			err := db.Model(&models.AuditLog{}).Where("id = ?", entry.ID).UpdateColumns(map[string]interface{}{
+				"sequence":  entry.Sequence,
+				"prev_hash": entry.PrevHash,
+				"hash":      entry.Hash,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	for _, head := range heads {
+		if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(head).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+var schemaCache sync.Map
+
+// FieldNames maps an audited model's columns to the names of its JSON fields
+func FieldNames(model interface{}) (map[string]string, error) {
+	s, err := schema.Parse(model, &schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(s.Fields))
+	for _, field := range s.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+		names[field.DBName] = name
+	}
+	return names, nil
+}
+
+// Detach clears the relations loaded with model, a pointer to a record, since they aren't
+// versioned with it
+func Detach(model interface{}) error {
+	s, err := schema.Parse(model, &schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	record := reflect.Indirect(reflect.ValueOf(model))
+	for _, relationship := range s.Relationships.Relations {
+		field := relationship.Field.ReflectValueOf(context.Background(), record)
+		field.Set(reflect.Zero(field.Type()))
+	}
+	return nil
+}
+
+// Revert undoes a logged change to model, a pointer to the changed record, by setting each
+// changed column back to its old value
+func Revert(model interface{}, changes json.RawMessage) error {
+	s, err := schema.Parse(model, &schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	record := reflect.Indirect(reflect.ValueOf(model))
+
+	if isNull(changes) {
+		return nil
+	}
+	var columns map[string]Change
+	if err := json.Unmarshal(changes, &columns); err != nil {
+		return err
+	}
+	for column, change := range columns {
+		field, ok := s.FieldsByDBName[column]
+		if !ok || secretColumns[column] {
+			continue
+		}
+		value := reflect.New(field.FieldType)
+		if !isNull(change.Old) {
+			if err := json.Unmarshal(change.Old, value.Interface()); err != nil {
+				return err
+			}
+		}
+		field.ReflectValueOf(context.Background(), record).Set(value.Elem())
+	}
+	return nil
+}
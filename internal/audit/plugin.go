@@ -0,0 +1,457 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"ventura/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxSnapshotRows bounds how many rows a single bulk update or delete records
+const maxSnapshotRows = 1000
+
+const snapshotKey = "audit:snapshot"
+
+// entities maps the audited models to the entity recorded in the log. Sessions, emailed
+// tokens and recovery codes are left out; their use is logged as login, logout and MFA events.
+var entities = map[reflect.Type]string{
+	reflect.TypeOf(models.Organization{}):         models.EntityOrganization,
+	reflect.TypeOf(models.User{}):                 models.EntityUser,
+	reflect.TypeOf(models.Membership{}):           models.EntityMembership,
+	reflect.TypeOf(models.Invitation{}):           models.EntityInvitation,
+	reflect.TypeOf(models.CustomRole{}):           models.EntityRole,
+	reflect.TypeOf(models.TeamAssignment{}):       models.EntityTeam,
+	reflect.TypeOf(models.APIToken{}):             models.EntityAPIToken,
+	reflect.TypeOf(models.OIDCProvider{}):         models.EntitySSOProvider,
+	reflect.TypeOf(models.PortfolioCompany{}):     models.EntityCompany,
+	reflect.TypeOf(models.Deal{}):                 models.EntityDeal,
+	reflect.TypeOf(models.DealStageEvent{}):       models.EntityStageEvent,
+	reflect.TypeOf(models.DealStageTransition{}):  models.EntityStageTransition,
+	reflect.TypeOf(models.DealAnalysis{}):         models.EntityAnalysis,
+	reflect.TypeOf(models.DealScore{}):            models.EntityScore,
+	reflect.TypeOf(models.ScoringCriterion{}):     models.EntityCriterion,
+	reflect.TypeOf(models.DealActivity{}):         models.EntityActivity,
+	reflect.TypeOf(models.DealActivityMention{}):  models.EntityMention,
+	reflect.TypeOf(models.DealActivityRevision{}): models.EntityRevision,
+	reflect.TypeOf(models.Notification{}):         models.EntityNotification,
+	reflect.TypeOf(models.Founder{}):              models.EntityFounder,
+	reflect.TypeOf(models.MonthlyUpdate{}):        models.EntityMonthlyUpdate,
+	reflect.TypeOf(models.Document{}):             models.EntityDocument,
+	reflect.TypeOf(models.Investment{}):           models.EntityInvestment,
+}
+
+// secretColumns hold credentials; the log only shows that they changed
+var secretColumns = map[string]bool{
+	"password":            true,
+	"totp_secret":         true,
+	"totp_pending_secret": true,
+	"token_hash":          true,
+	"client_secret":       true,
+	"code":                true,
+}
+
+// noisyColumns change as a side effect of normal use and are left out of diffs
+var noisyColumns = map[string]bool{
+	"id":                    true,
+	"created_at":            true,
+	"updated_at":            true,
+	"failed_login_attempts": true,
+	"locked_until":          true,
+	"totp_last_used_step":   true,
+	"last_used_at":          true,
+	"last_used_ip":          true,
+}
+
+// mirroredUserColumns repeat the user's default membership, whose changes are logged there
+var mirroredUserColumns = map[string]bool{
+	"organization_id": true,
+	"role":            true,
+}
+
+// redactedValue replaces the values of secret columns
+var redactedValue = json.RawMessage(`"[redacted]"`)
+
+// Change is a column's value before and after a change. Old is null when the row
+// was created and New is null when it was deleted.
+type Change struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// row is a record's column values, JSON encoded
+type row map[string]json.RawMessage
+
+// Plugin writes an audit log entry with a JSON diff for every create, update and delete
+// of an audited model, in the same transaction as the change. Entries are attributed to
+// the Actor of the statement's context, so repositories take the request's context with
+// their WithContext method; changes made without one are attributed to the system. Raw SQL
+// is not captured. Every audit entry, however it is written, is hash-chained to its
+// organization's previous entry.
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "audit"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	create := db.Callback().Create()
+	if err := create.After("gorm:begin_transaction").Before("gorm:create").Register("audit:chain", chain); err != nil {
+		return err
+	}
+	if err := create.After("gorm:create").Before("gorm:commit_or_rollback_transaction").Register("audit:after_create", afterCreate); err != nil {
+		return err
+	}
+
+	update := db.Callback().Update()
+	if err := update.After("gorm:begin_transaction").Before("gorm:update").Register("audit:before_update", takeSnapshot); err != nil {
+		return err
+	}
+	if err := update.After("gorm:update").Before("gorm:commit_or_rollback_transaction").Register("audit:after_update", afterUpdate); err != nil {
+		return err
+	}
+
+	remove := db.Callback().Delete()
+	if err := remove.After("gorm:begin_transaction").Before("gorm:delete").Register("audit:before_delete", takeSnapshot); err != nil {
+		return err
+	}
+	return remove.After("gorm:delete").Before("gorm:commit_or_rollback_transaction").Register("audit:after_delete", afterDelete)
+}
+
+// auditedEntity returns the entity a statement changes, if it is audited
+func auditedEntity(db *gorm.DB) (string, bool) {
+	if db.Error != nil || db.DryRun || db.Statement.Schema == nil || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return "", false
+	}
+	entity, ok := entities[db.Statement.Schema.ModelType]
+	return entity, ok
+}
+
+func afterCreate(db *gorm.DB) {
+	entity, ok := auditedEntity(db)
+	if !ok || db.Statement.RowsAffected == 0 {
+		return
+	}
+	ids := primaryKeys(db)
+	if len(ids) == 0 {
+		return
+	}
+
+	created, err := load(rowQuery(db).Where(clause.IN{Column: clause.PrimaryColumn, Values: ids}), db)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	record(db, models.ActionCreate, entity, nil, created)
+}
+
+// takeSnapshot loads the rows an update or delete is about to change: the model's own
+// row, or else every row matching the statement's conditions
+func takeSnapshot(db *gorm.DB) {
+	if _, ok := auditedEntity(db); !ok {
+		return
+	}
+
+	query := rowQuery(db)
+	if db.Statement.Unscoped {
+		query = query.Unscoped()
+	}
+	if ids := primaryKeys(db); len(ids) > 0 {
+		query = query.Where(clause.IN{Column: clause.PrimaryColumn, Values: ids})
+	} else if where, ok := db.Statement.Clauses["WHERE"]; ok {
+		query = query.Clauses(where.Expression)
+	} else {
+		return
+	}
+
+	before, err := load(query.Limit(maxSnapshotRows), db)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(snapshotKey, before)
+}
+
+func afterUpdate(db *gorm.DB) {
+	entity, ok := auditedEntity(db)
+	before := snapshot(db)
+	if !ok || len(before) == 0 || db.Statement.RowsAffected == 0 {
+		return
+	}
+
+	ids := make([]interface{}, 0, len(before))
+	for id := range before {
+		ids = append(ids, id)
+	}
+	after, err := load(rowQuery(db).Unscoped().Where(clause.IN{Column: clause.PrimaryColumn, Values: ids}), db)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	record(db, models.ActionUpdate, entity, before, after)
+}
+
+func afterDelete(db *gorm.DB) {
+	entity, ok := auditedEntity(db)
+	before := snapshot(db)
+	if !ok || len(before) == 0 || db.Statement.RowsAffected == 0 {
+		return
+	}
+	record(db, models.ActionDelete, entity, before, nil)
+}
+
+func snapshot(db *gorm.DB) map[uint]row {
+	value, ok := db.InstanceGet(snapshotKey)
+	if !ok {
+		return nil
+	}
+	before, _ := value.(map[uint]row)
+	return before
+}
+
+// rowQuery starts a query over the statement's model in the same transaction
+func rowQuery(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(reflect.New(db.Statement.Schema.ModelType).Interface())
+}
+
+// primaryKeys returns the primary keys of the records a statement was given
+func primaryKeys(db *gorm.DB) []interface{} {
+	stmt := db.Statement
+	field := stmt.Schema.PrioritizedPrimaryField
+	var ids []interface{}
+	collect := func(value reflect.Value) {
+		value = reflect.Indirect(value)
+		if value.Kind() != reflect.Struct || value.Type() != stmt.Schema.ModelType {
+			return
+		}
+		if id, zero := field.ValueOf(stmt.Context, value); !zero {
+			ids = append(ids, id)
+		}
+	}
+
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Struct:
+		collect(stmt.ReflectValue)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			collect(stmt.ReflectValue.Index(i))
+		}
+	}
+	return ids
+}
+
+// load runs query and returns the rows keyed by primary key
+func load(query *gorm.DB, db *gorm.DB) (map[uint]row, error) {
+	stmt := db.Statement
+	records := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	if err := query.Find(records.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	rows := make(map[uint]row)
+	for i := 0; i < records.Elem().Len(); i++ {
+		record := records.Elem().Index(i)
+		id, _ := stmt.Schema.PrioritizedPrimaryField.ValueOf(stmt.Context, record)
+		values := make(row)
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" || !field.Readable {
+				continue
+			}
+			value, _ := field.ValueOf(stmt.Context, record)
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			values[field.DBName] = encoded
+		}
+		rows[toUint(id)] = values
+	}
+	return rows, nil
+}
+
+// record writes an entry for each row whose audited columns changed
+func record(db *gorm.DB, action, entity string, before, after map[uint]row) {
+	actor, ok := ActorFrom(db.Statement.Context)
+	email, name := "system", "System"
+	switch {
+	case ok && actor.UserID != 0:
+		email, name = actor.Email, actorName(db, actor.UserID)
+	case ok:
+		email, name = "anonymous", "Anonymous"
+	}
+
+	ids := make([]uint, 0, len(before)+len(after))
+	for id := range before {
+		ids = append(ids, id)
+	}
+	for id := range after {
+		if _, seen := before[id]; !seen {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	companies := make(map[uint]uint)
+	var entries []models.AuditLog
+	for _, id := range ids {
+		changes := diff(entity, before[id], after[id])
+		if len(changes) == 0 {
+			continue
+		}
+		encoded, err := json.Marshal(changes)
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+
+		current := after[id]
+		if current == nil {
+			current = before[id]
+		}
+		entries = append(entries, models.AuditLog{
+			OrganizationID: organizationOf(db, entity, id, current, actor, companies),
+			UserID:         actor.UserID,
+			UserEmail:      email,
+			UserName:       name,
+			Action:         action,
+			Entity:         entity,
+			EntityID:       id,
+			Details:        summary(action, entity, id, changes),
+			Changes:        encoded,
+			IPAddress:      actor.IPAddress,
+			UserAgent:      actor.UserAgent,
+		})
+	}
+
+	// Entries are written one organization at a time so each insert passes row-level security
+	byOrganization := make(map[uint][]models.AuditLog)
+	var orgIDs []uint
+	for _, entry := range entries {
+		if _, seen := byOrganization[entry.OrganizationID]; !seen {
+			orgIDs = append(orgIDs, entry.OrganizationID)
+		}
+		byOrganization[entry.OrganizationID] = append(byOrganization[entry.OrganizationID], entry)
+	}
+	for _, orgID := range orgIDs {
+		batch := byOrganization[orgID]
+		if err := db.Session(&gorm.Session{NewDB: true}).Create(&batch).Error; err != nil {
+			db.AddError(fmt.Errorf("audit log: %w", err))
+			return
+		}
+	}
+}
+
+// diff returns the audited columns that differ between two versions of a row
+func diff(entity string, before, after row) map[string]Change {
+	changes := make(map[string]Change)
+	for _, values := range []row{before, after} {
+		for column := range values {
+			if _, done := changes[column]; done || noisyColumns[column] || (entity == models.EntityUser && mirroredUserColumns[column]) {
+				continue
+			}
+			old, updated := before[column], after[column]
+			if isNull(old) && isNull(updated) || bytes.Equal(old, updated) {
+				continue
+			}
+			if secretColumns[column] {
+				if old, updated = redact(old), redact(updated); old == nil && updated == nil {
+					continue
+				}
+			}
+			changes[column] = Change{Old: old, New: updated}
+		}
+	}
+	return changes
+}
+
+// organizationOf returns the organization a row belongs to. Rows owned by a company
+// belong to the company's organization.
+func organizationOf(db *gorm.DB, entity string, id uint, values row, actor Actor, companies map[uint]uint) uint {
+	switch entity {
+	case models.EntityOrganization:
+		return id
+	case models.EntityUser:
+		if actor.OrganizationID != 0 {
+			return actor.OrganizationID
+		}
+	}
+
+	if orgID := decodeUint(values["organization_id"]); orgID != 0 {
+		return orgID
+	}
+	if companyID := decodeUint(values["company_id"]); companyID != 0 {
+		if orgID, ok := companies[companyID]; ok {
+			return orgID
+		}
+		var orgIDs []uint
+		db.Session(&gorm.Session{NewDB: true}).Model(&models.PortfolioCompany{}).Unscoped().
+			Where("id = ?", companyID).Pluck("organization_id", &orgIDs)
+		if len(orgIDs) > 0 {
+			companies[companyID] = orgIDs[0]
+			return orgIDs[0]
+		}
+	}
+	return actor.OrganizationID
+}
+
+func actorName(db *gorm.DB, userID uint) string {
+	var names []string
+	db.Session(&gorm.Session{NewDB: true}).Model(&models.User{}).Where("id = ?", userID).Pluck("name", &names)
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+func summary(action, entity string, id uint, changes map[string]Change) string {
+	label := strings.ReplaceAll(entity, "_", " ")
+	switch action {
+	case models.ActionCreate:
+		return fmt.Sprintf("Created %s %d", label, id)
+	case models.ActionDelete:
+		return fmt.Sprintf("Deleted %s %d", label, id)
+	}
+
+	columns := make([]string, 0, len(changes))
+	for column := range changes {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return fmt.Sprintf("Updated %s %d: %s", label, id, strings.Join(columns, ", "))
+}
+
+func isNull(value json.RawMessage) bool {
+	return len(value) == 0 || string(value) == "null"
+}
+
+// redact hides a secret's value, leaving unset secrets null
+func redact(value json.RawMessage) json.RawMessage {
+	if isNull(value) || string(value) == `""` {
+		return nil
+	}
+	return redactedValue
+}
+
+func decodeUint(value json.RawMessage) uint {
+	var id uint
+	if isNull(value) || json.Unmarshal(value, &id) != nil {
+		return 0
+	}
+	return id
+}
+
+func toUint(value interface{}) uint {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return uint(v.Uint())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint(v.Int())
+	}
+	return 0
+}
@@ -0,0 +1,10 @@
+package auth
+
+// Every session has a CSRF token. Browsers send the auth cookies on cross-site requests,
+// so a write authenticated by cookie, refresh and logout included, must also echo the token
+// in CSRFHeader, which another site can neither read nor forge. It is issued in CSRFHeader on login and refresh, and in
+// CSRFCookie for frontends served from the API's site.
+const (
+	CSRFHeader = "X-CSRF-Token"
+	CSRFCookie = "csrf_token"
+)
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 	"ventura/internal/models"
@@ -8,73 +10,176 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var (
-	// Use environment variable in production
-	jwtSecret = []byte("your-secret-key-change-in-production")
+// Issuer is the iss claim on every token minted by the API
+const Issuer = "ventura-api"
 
+var (
 	// Token expiration times
 	AccessTokenExpiry  = 1 * time.Hour  // 1 hour
 	RefreshTokenExpiry = 24 * time.Hour // 24 hours
+
+	// ChallengeTokenExpiry bounds the time between the password step and the next login step
+	ChallengeTokenExpiry = 5 * time.Minute
+
+	// Emailed link lifetimes
+	PasswordResetExpiry     = 1 * time.Hour
+	EmailVerificationExpiry = 48 * time.Hour
 )
 
+// Token types carried in the typ claim so one kind can't be used as another
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+
+	// Login challenge tokens prove the password step succeeded; they grant no API access
+	TokenTypeMFAVerify      = "mfa_verify"      // User must enter a TOTP or recovery code
+	TokenTypeMFASetup       = "mfa_setup"       // User must enroll because the org policy requires MFA
+	TokenTypePasswordChange = "password_change" // Invited user must replace their temporary password
+
+	// Emailed action tokens; their jti is persisted so each can be used once
+	TokenTypePasswordReset       = "password_reset"
+	TokenTypeEmailVerification   = "email_verification"
+	TokenTypeOrganizationRestore = "organization_restore"
+)
+
+// ErrWrongTokenType is returned when a valid token is presented for the wrong purpose
+var ErrWrongTokenType = errors.New("wrong token type")
+
 // Claims represents the JWT claims
 type Claims struct {
 	UserID         uint            `json:"user_id"`
 	OrganizationID uint            `json:"organization_id"`
 	Email          string          `json:"email"`
 	Role           models.UserRole `json:"role"`
+	TokenType      string          `json:"typ"`
+	SessionID      uint            `json:"sid,omitempty"`
+	CSRFToken      string          `json:"csrf,omitempty"`         // Access tokens only: the session's CSRF token
+	LoginMethod    string          `json:"login_method,omitempty"` // Login challenges only: how the first step signed in
 	jwt.RegisteredClaims
 }
 
-// GenerateAccessToken creates a new access token for a user
-func GenerateAccessToken(user *models.User) (string, error) {
+// NewTokenID returns a random identifier for the jti claim
+func NewTokenID() string {
+	randomBytes := make([]byte, 16)
+	rand.Read(randomBytes)
+	return hex.EncodeToString(randomBytes)
+}
+
+// GenerateAccessToken creates a new access token for a user bound to a session. csrfToken
+// is the session's CSRF token, which cookie-authenticated writes must echo back.
+func GenerateAccessToken(user *models.User, sessionID uint, csrfToken string) (string, error) {
 	claims := Claims{
 		UserID:         user.ID,
 		OrganizationID: user.OrganizationID,
 		Email:          user.Email,
 		Role:           user.Role,
+		TokenType:      TokenTypeAccess,
+		SessionID:      sessionID,
+		CSRFToken:      csrfToken,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "ventura-api",
+			Issuer:    Issuer,
 			Subject:   user.Email,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signClaims(claims)
 }
 
-// GenerateRefreshToken creates a new refresh token for a user
-func GenerateRefreshToken(user *models.User) (string, error) {
+// GenerateRefreshToken creates a refresh token for a session.
+// tokenID must match the session's current token ID for the token to be accepted.
+func GenerateRefreshToken(user *models.User, sessionID uint, tokenID string) (string, error) {
 	claims := Claims{
 		UserID:         user.ID,
 		OrganizationID: user.OrganizationID,
 		Email:          user.Email,
-		Role:           user.Role,
+		TokenType:      TokenTypeRefresh,
+		SessionID:      sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "ventura-api",
+			Issuer:    Issuer,
 			Subject:   user.Email,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signClaims(claims)
+}
+
+// GenerateChallengeToken creates a short-lived token for the next login step.
+// challengeType is one of TokenTypeMFAVerify, TokenTypeMFASetup or TokenTypePasswordChange;
+// loginMethod is how the user got this far (models.SessionAuthPassword or SessionAuthSSO).
+func GenerateChallengeToken(user *models.User, challengeType, loginMethod string) (string, error) {
+	claims := actionClaims(user, challengeType, NewTokenID(), ChallengeTokenExpiry)
+	claims.LoginMethod = loginMethod
+	return signClaims(claims)
+}
+
+// GenerateActionToken creates a single-purpose token, e.g. for a password reset link
+func GenerateActionToken(user *models.User, tokenType, tokenID string, expiry time.Duration) (string, error) {
+	return signClaims(actionClaims(user, tokenType, tokenID, expiry))
+}
+
+// actionClaims returns the claims of a single-purpose token
+func actionClaims(user *models.User, tokenType, tokenID string, expiry time.Duration) Claims {
+	return Claims{
+		UserID:         user.ID,
+		OrganizationID: user.OrganizationID,
+		Email:          user.Email,
+		TokenType:      tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    Issuer,
+			Subject:   user.Email,
+		},
+	}
+}
+
+// signClaims signs claims with the active key and stamps its kid in the header
+func signClaims(claims jwt.Claims) (string, error) {
+	if keyring == nil {
+		return "", errors.New("signing keys not loaded")
+	}
+
+	key := keyring.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
+}
+
+// keyFunc resolves the verification key from the token's kid header
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	if keyring == nil {
+		return nil, errors.New("signing keys not loaded")
+	}
+
+	// Tokens issued before kid headers were introduced fall back to the active key
+	kid, _ := token.Header["kid"].(string)
+	key := keyring.Active()
+	if kid != "" {
+		var ok bool
+		if key, ok = keyring.Lookup(kid); !ok {
+			return nil, errors.New("unknown signing key")
+		}
+	}
+
+	// Verify signing method matches the key, never trust the alg header alone
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.verifyKey, nil
 }
 
 // ValidateToken validates a JWT token and returns the claims
 func ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return jwtSecret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyFunc, jwt.WithIssuer(Issuer))
 
 	if err != nil {
 		return nil, err
@@ -87,20 +192,28 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
-// RefreshAccessToken generates a new access token from a valid refresh token
-func RefreshAccessToken(refreshTokenString string) (string, error) {
-	claims, err := ValidateToken(refreshTokenString)
+// ValidateAccessToken validates a token and checks it is an access token
+func ValidateAccessToken(tokenString string) (*Claims, error) {
+	return validateTokenType(tokenString, TokenTypeAccess)
+}
+
+// ValidateRefreshToken validates a token and checks it is a refresh token
+func ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return validateTokenType(tokenString, TokenTypeRefresh)
+}
+
+// ValidateTypedToken validates a challenge or action token of the given type
+func ValidateTypedToken(tokenString, tokenType string) (*Claims, error) {
+	return validateTokenType(tokenString, tokenType)
+}
+
+func validateTokenType(tokenString, tokenType string) (*Claims, error) {
+	claims, err := ValidateToken(tokenString)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	// Create a new access token with the same user info
-	user := &models.User{
-		ID:             claims.UserID,
-		OrganizationID: claims.OrganizationID,
-		Email:          claims.Email,
-		Role:           claims.Role,
+	if claims.TokenType != tokenType {
+		return nil, ErrWrongTokenType
 	}
-
-	return GenerateAccessToken(user)
+	return claims, nil
 }
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// devSecret is only used outside release mode when no keys are configured
+const devSecret = "your-secret-key-change-in-production"
+
+// SigningKey is a single key in the keyring
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	signKey   interface{} // nil for verify-only keys
+	verifyKey interface{}
+}
+
+// CanSign reports whether the key holds private material
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// Keyring holds every key that may verify a token and the key used to sign new ones
+type Keyring struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	order  []string
+}
+
+// NewKeyring builds a keyring and selects the active signing key.
+// If activeID is empty the first key that can sign is used.
+func NewKeyring(keys []*SigningKey, activeID string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("keyring must contain at least one key")
+	}
+
+	kr := &Keyring{keys: make(map[string]*SigningKey, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("every signing key needs a kid")
+		}
+		if _, exists := kr.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate kid %q", key.ID)
+		}
+		kr.keys[key.ID] = key
+		kr.order = append(kr.order, key.ID)
+
+		if kr.active == nil && activeID == "" && key.CanSign() {
+			kr.active = key
+		}
+	}
+
+	if activeID != "" {
+		key, ok := kr.keys[activeID]
+		if !ok {
+			return nil, fmt.Errorf("active kid %q is not in the keyring", activeID)
+		}
+		kr.active = key
+	}
+
+	if kr.active == nil || !kr.active.CanSign() {
+		return nil, errors.New("keyring has no key that can sign tokens")
+	}
+
+	return kr, nil
+}
+
+// Active returns the key used to sign new tokens
+func (k *Keyring) Active() *SigningKey {
+	return k.active
+}
+
+// Lookup returns the key for a kid
+func (k *Keyring) Lookup(kid string) (*SigningKey, bool) {
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// keyring is the process-wide keyring, set by LoadKeys or SetKeyring
+var keyring *Keyring
+
+// SetKeyring replaces the process-wide keyring
+func SetKeyring(kr *Keyring) {
+	keyring = kr
+}
+
+// LoadKeys builds the keyring from the environment.
+//
+//	JWT_KEYS        kid:ALG:value entries, one per line. For HS256 the value is the
+//	                shared secret; for RS256 and EdDSA it is the path to a PEM file
+//	                (a private key can sign, a public key is verify-only). A value
+//	                prefixed with "base64:" is the secret or PEM itself, base64-encoded.
+//	JWT_KEYS_FILE   file holding the JWT_KEYS entries, instead of JWT_KEYS
+//	JWT_ACTIVE_KID  kid used to sign new tokens (defaults to the first signing key)
+//	JWT_SECRET      shorthand for a single HS256 key with kid "default"
+//
+// Keys that are no longer active stay in JWT_KEYS until every token they
+// signed has expired.
+func LoadKeys() error {
+	var keys []*SigningKey
+
+	raw := os.Getenv("JWT_KEYS")
+	if path := os.Getenv("JWT_KEYS_FILE"); path != "" && raw == "" {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading JWT_KEYS_FILE: %w", err)
+		}
+		raw = string(contents)
+	}
+
+	if raw != "" {
+		var err error
+		if keys, err = parseKeys(raw); err != nil {
+			return err
+		}
+	} else if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		keys = append(keys, &SigningKey{ID: "default", Method: jwt.SigningMethodHS256, signKey: []byte(secret), verifyKey: []byte(secret)})
+	} else {
+		if os.Getenv("GIN_MODE") == "release" {
+			return errors.New("JWT_KEYS, JWT_KEYS_FILE or JWT_SECRET must be set in release mode")
+		}
+		log.Println("WARNING: no JWT keys configured, using the insecure development secret")
+		keys = append(keys, &SigningKey{ID: "default", Method: jwt.SigningMethodHS256, signKey: []byte(devSecret), verifyKey: []byte(devSecret)})
+	}
+
+	kr, err := NewKeyring(keys, os.Getenv("JWT_ACTIVE_KID"))
+	if err != nil {
+		return err
+	}
+
+	SetKeyring(kr)
+	log.Printf("JWT keyring loaded: %d key(s), signing with kid %q (%s)", len(keys), kr.active.ID, kr.active.Method.Alg())
+	return nil
+}
+
+// legacyKeyList matches a second entry of the comma-separated list JWT_KEYS used to take,
+// which would otherwise be read as part of the first entry's secret
+var legacyKeyList = regexp.MustCompile(`(?i),\s*[^,:\s]+:(HS256|RS256|EdDSA):`)
+
+// parseKeys parses the kid:ALG:value entries of JWT_KEYS, one per line. Blank lines and
+// lines starting with # are skipped.
+func parseKeys(raw string) ([]*SigningKey, error) {
+	var keys []*SigningKey
+	for i, line := range strings.Split(raw, "\n") {
+		entry := strings.TrimSpace(line)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if legacyKeyList.MatchString(entry) {
+			return nil, fmt.Errorf("JWT_KEYS line %d: one kid:ALG:value entry per line, not a comma-separated list", i+1)
+		}
+		key, err := parseKeyEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_KEYS line %d: %w", i+1, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// parseKeyEntry parses a single kid:ALG:value entry from JWT_KEYS. Errors leave the value
+// out, since it may be a secret.
+func parseKeyEntry(entry string) (*SigningKey, error) {
+	parts := strings.SplitN(entry, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return nil, errors.New("invalid entry, expected kid:ALG:value")
+	}
+	kid, alg, value := parts[0], strings.ToUpper(parts[1]), parts[2]
+
+	material, inline := []byte(value), false
+	if encoded, ok := strings.CutPrefix(value, "base64:"); ok {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64: %w", kid, err)
+		}
+		material, inline = decoded, true
+	}
+
+	switch alg {
+	case "HS256":
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodHS256, signKey: material, verifyKey: material}, nil
+	case "RS256", "EDDSA":
+		pemBytes := material
+		if !inline {
+			var err error
+			if pemBytes, err = os.ReadFile(value); err != nil {
+				return nil, fmt.Errorf("reading key %q: %w", kid, err)
+			}
+		}
+		if alg == "RS256" {
+			return parseRSAKey(kid, pemBytes)
+		}
+		return parseEdDSAKey(kid, pemBytes)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q for key %q", parts[1], kid)
+	}
+}
+
+// parseRSAKey accepts either a private or a public RSA key in PEM form
+func parseRSAKey(kid string, pemBytes []byte) (*SigningKey, error) {
+	if private, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes); err == nil {
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, signKey: private, verifyKey: &private.PublicKey}, nil
+	}
+	public, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("key %q is not a valid RSA PEM key: %w", kid, err)
+	}
+	return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, verifyKey: public}, nil
+}
+
+// parseEdDSAKey accepts either a private or a public Ed25519 key in PEM form
+func parseEdDSAKey(kid string, pemBytes []byte) (*SigningKey, error) {
+	if private, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes); err == nil {
+		edPrivate := private.(ed25519.PrivateKey)
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, signKey: edPrivate, verifyKey: edPrivate.Public()}, nil
+	}
+	public, err := jwt.ParseEdPublicKeyFromPEM(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("key %q is not a valid Ed25519 PEM key: %w", kid, err)
+	}
+	return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, verifyKey: public}, nil
+}
+
+// isAsymmetric reports whether the key's public half can be published
+func (k *SigningKey) isAsymmetric() bool {
+	switch k.verifyKey.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestParseKeys(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	publicPath := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(publicPath, publicPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type want struct {
+		id      string
+		alg     string
+		canSign bool
+		secret  string // HS256 only
+	}
+	tests := []struct {
+		name string
+		raw  string
+		want []want
+	}{
+		{
+			name: "secret with commas and colons",
+			raw:  "2025-01:HS256:s3cr,et:with,commas",
+			want: []want{{"2025-01", "HS256", true, "s3cr,et:with,commas"}},
+		},
+		{
+			name: "one entry per line",
+			raw:  "\n  # retired after 2025-07\n2025-01:hs256:old-secret\r\n\n2025-06:EdDSA:" + publicPath + "\n",
+			want: []want{{"2025-01", "HS256", true, "old-secret"}, {"2025-06", "EdDSA", false, ""}},
+		},
+		{
+			name: "base64 secret",
+			raw:  "k1:HS256:base64:" + base64.StdEncoding.EncodeToString([]byte("line\nbreak")),
+			want: []want{{"k1", "HS256", true, "line\nbreak"}},
+		},
+		{
+			name: "base64 PEM",
+			raw:  "k1:EdDSA:base64:" + base64.StdEncoding.EncodeToString(privatePEM),
+			want: []want{{"k1", "EdDSA", true, ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := parseKeys(tt.raw)
+			if err != nil {
+				t.Fatalf("parseKeys: %v", err)
+			}
+			if len(keys) != len(tt.want) {
+				t.Fatalf("got %d keys, want %d", len(keys), len(tt.want))
+			}
+			for i, w := range tt.want {
+				key := keys[i]
+				if key.ID != w.id || key.Method.Alg() != w.alg || key.CanSign() != w.canSign {
+					t.Errorf("key %d = %s %s canSign=%v, want %s %s canSign=%v", i, key.ID, key.Method.Alg(), key.CanSign(), w.id, w.alg, w.canSign)
+				}
+				if w.secret != "" && string(key.signKey.([]byte)) != w.secret {
+					t.Errorf("key %d secret = %q, want %q", i, key.signKey, w.secret)
+				}
+			}
+		})
+	}
+}
+
+func TestParseKeysRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"comma-separated list", "2025-01:HS256:old-secret,2025-06:EdDSA:/etc/ventura/ed25519.pem"},
+		{"missing value", "k1:HS256:"},
+		{"missing algorithm", "k1-secret"},
+		{"unsupported algorithm", "k1:ES256:secret"},
+		{"missing PEM file", "k1:RS256:/nonexistent/key.pem"},
+		{"invalid base64", "k1:HS256:base64:!!!"},
+		{"invalid PEM", "k1:EdDSA:base64:" + base64.StdEncoding.EncodeToString([]byte("not a key"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if keys, err := parseKeys(tt.raw); err == nil {
+				t.Fatalf("parseKeys(%q) = %d keys, want an error", tt.raw, len(keys))
+			}
+		})
+	}
+}
+
+func TestNewKeyringActiveKey(t *testing.T) {
+	keys, err := parseKeys("old:HS256:old-secret\nnew:HS256:new-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kr, err := NewKeyring(keys, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kr.Active().ID != "old" {
+		t.Errorf("active kid = %q, want the first signing key", kr.Active().ID)
+	}
+
+	kr, err = NewKeyring(keys, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kr.Active().ID != "new" {
+		t.Errorf("active kid = %q, want %q", kr.Active().ID, "new")
+	}
+	if _, ok := kr.Lookup("old"); !ok {
+		t.Error("retired key is no longer in the keyring")
+	}
+
+	if _, err := NewKeyring(keys, "missing"); err == nil {
+		t.Error("NewKeyring accepted an active kid that isn't in the keyring")
+	}
+	if _, err := NewKeyring(append(keys, keys[0]), ""); err == nil {
+		t.Error("NewKeyring accepted a duplicate kid")
+	}
+	verifyOnly := &SigningKey{ID: "public", Method: jwt.SigningMethodEdDSA, verifyKey: ed25519.PublicKey(make([]byte, ed25519.PublicKeySize))}
+	if _, err := NewKeyring([]*SigningKey{verifyOnly}, ""); err == nil {
+		t.Error("NewKeyring accepted a keyring that can't sign")
+	}
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SSOStateExpiry bounds the round trip to the identity provider
+const SSOStateExpiry = 10 * time.Minute
+
+const tokenTypeSSOState = "sso_state"
+
+// SSOState is what the login callback needs to finish an OIDC authorization-code flow.
+// It travels in a signed, httpOnly cookie so no server-side storage is needed.
+type SSOState struct {
+	State          string `json:"state"`
+	Nonce          string `json:"nonce"`
+	Verifier       string `json:"verifier"` // PKCE code verifier
+	OrganizationID uint   `json:"organization_id"`
+	Redirect       string `json:"redirect,omitempty"`
+}
+
+type ssoStateClaims struct {
+	SSOState
+	TokenType string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// GenerateSSOStateToken signs the state of an SSO login in progress
+func GenerateSSOStateToken(state SSOState) (string, error) {
+	claims := ssoStateClaims{
+		SSOState:  state,
+		TokenType: tokenTypeSSOState,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(SSOStateExpiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    Issuer,
+		},
+	}
+
+	return signClaims(claims)
+}
+
+// ValidateSSOStateToken verifies a state token and returns its contents
+func ValidateSSOStateToken(tokenString string) (*SSOState, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ssoStateClaims{}, keyFunc, jwt.WithIssuer(Issuer))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*ssoStateClaims)
+	if !ok || !token.Valid || claims.TokenType != tokenTypeSSOState {
+		return nil, errors.New("invalid SSO state")
+	}
+	return &claims.SSOState, nil
+}
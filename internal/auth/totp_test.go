@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of the RFC 6238 test vectors, "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC 6238 appendix B vectors for SHA-1 are 8 digits; ours are their last 6
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestHOTPMatchesRFC6238(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range rfc6238Vectors {
+		if got := hotp(key, v.unix/30); got != v.code {
+			t.Errorf("hotp at %d = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		at := time.Unix(v.unix, 0)
+		step, ok := ValidateTOTP(rfc6238Secret, v.code, at)
+		if !ok || step != v.unix/30 {
+			t.Errorf("ValidateTOTP(%s at %d) = %d, %v; want step %d", v.code, v.unix, step, ok, v.unix/30)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	// 1111111111 is step 37037037; its code is accepted one step either side and no further
+	const code, step = "050471", 37037037
+	tests := []struct {
+		offset time.Duration
+		ok     bool
+	}{
+		{-60 * time.Second, false},
+		{-30 * time.Second, true},
+		{0, true},
+		{30 * time.Second, true},
+		{60 * time.Second, false},
+	}
+	for _, tt := range tests {
+		at := time.Unix(step*30, 0).Add(tt.offset)
+		matched, ok := ValidateTOTP(rfc6238Secret, code, at)
+		if ok != tt.ok {
+			t.Errorf("code at offset %v: ok = %v, want %v", tt.offset, ok, tt.ok)
+		}
+		if ok && matched != step {
+			t.Errorf("code at offset %v matched step %d, want %d", tt.offset, matched, step)
+		}
+	}
+}
+
+func TestValidateTOTPRejects(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	tests := []struct {
+		name, secret, code string
+	}{
+		{"wrong code", rfc6238Secret, "050472"},
+		{"too short", rfc6238Secret, "05047"},
+		{"eight digits", rfc6238Secret, "14050471"},
+		{"empty", rfc6238Secret, ""},
+		{"invalid secret", "not base32!", "050471"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, at); ok {
+				t.Errorf("ValidateTOTP accepted %q", tt.code)
+			}
+		})
+	}
+
+	// Spaces authenticator apps display, and a lowercase secret, are tolerated
+	if _, ok := ValidateTOTP("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", " 050 471 ", at); !ok {
+		t.Error("ValidateTOTP rejected a spaced code with a lowercase secret")
+	}
+}
@@ -3,7 +3,9 @@ package database
 import (
 	"log"
 	"os"
+	"ventura/internal/audit"
 	"ventura/internal/models"
+	"ventura/internal/tenancy"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -26,17 +28,114 @@ func Connect() *gorm.DB {
 
 	log.Println("Database connected successfully")
 
-	// Auto Migrate all models
-	runMigrations(db)
+	// Migrations run as the tables' owner when DATABASE_MIGRATION_URL names one, so the
+	// API's own role needs no more than read and write access to the rows
+	if migrationDSN := os.Getenv("DATABASE_MIGRATION_URL"); migrationDSN != "" {
+		owner, err := gorm.Open(postgres.Open(migrationDSN), &gorm.Config{})
+		if err != nil {
+			log.Fatal("Failed to connect to database for migrations:", err)
+		}
+		migrate(owner)
+		grantAccess(owner, db)
+		if sqlDB, err := owner.DB(); err == nil {
+			sqlDB.Close()
+		}
+	} else {
+		migrate(db)
+	}
+
+	checkRowLevelSecurity(db)
+
+	// Confine the statements of each tenant to its organization's rows
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		log.Fatal("Failed to register tenant isolation:", err)
+	}
+
+	// Record every change from here on in the audit log
+	if err := db.Use(audit.Plugin{}); err != nil {
+		log.Fatal("Failed to register audit logging:", err)
+	}
 
 	return db
 }
 
+// migrate runs the migrations and installs the row-level security policies in one
+// transaction that sees every organization's rows, as the tables' owner
+func migrate(db *gorm.DB) {
+	err := tenancy.Migrate(db, func(tx *gorm.DB) error {
+		runMigrations(tx)
+		return nil
+	})
+	if err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+}
+
+// grantAccess lets the API's role read and write the rows of the tables owner migrated
+func grantAccess(owner, db *gorm.DB) {
+	var role string
+	if err := db.Raw("SELECT current_user").Scan(&role).Error; err != nil {
+		log.Fatal("Failed to look up database role:", err)
+	}
+	var ownerRole string
+	if err := owner.Raw("SELECT current_user").Scan(&ownerRole).Error; err != nil {
+		log.Fatal("Failed to look up database role:", err)
+	}
+	if role == ownerRole {
+		return
+	}
+
+	quoted := owner.Statement.Quote(role)
+	for _, statement := range []string{
+		"GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO " + quoted,
+		"GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO " + quoted,
+	} {
+		if err := owner.Exec(statement).Error; err != nil {
+			log.Fatal("Failed to grant database access:", err)
+		}
+	}
+}
+
+// checkRowLevelSecurity refuses, in release mode, a role that row-level security doesn't apply to.
+// Elsewhere it only warns, and tenant isolation is then up to the repositories' scoping alone.
+func checkRowLevelSecurity(db *gorm.DB) {
+	bypass, err := tenancy.CanBypass(db)
+	if err != nil {
+		log.Fatal("Failed to check database role:", err)
+	}
+	if !bypass {
+		return
+	}
+	if os.Getenv("GIN_MODE") == "release" {
+		log.Fatal("The database role bypasses row-level security; connect as a role without SUPERUSER or BYPASSRLS")
+	}
+	log.Println("WARNING: the database role bypasses row-level security, so tenant isolation relies on the application alone")
+}
+
 // runMigrations runs all database migrations
 func runMigrations(db *gorm.DB) {
+	// Accounts created before email verification existed are treated as verified
+	backfillEmailVerified := !db.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt")
+
+	// Users created before memberships existed belong to their one organization
+	backfillMemberships := !db.Migrator().HasTable(&models.Membership{})
+
+	// Open single-use invite codes carry over as viewer invitations
+	migrateInviteCodes := !db.Migrator().HasTable(&models.Invitation{}) && db.Migrator().HasTable("invite_codes")
+
+	// Sessions started before CSRF protection get a token on their next refresh
+	backfillCSRFTokens := db.Migrator().HasTable(&models.Session{}) && !db.Migrator().HasColumn(&models.Session{}, "CSRFToken")
+
+	// Deal scores set before scorecards existed carry over as imported scores on the default one
+	importDealScores := !db.Migrator().HasTable(&models.DealScore{}) && db.Migrator().HasColumn(&models.Deal{}, "team_score")
+
+	// Entries written before the audit log was hash-chained are chained in the order they were written
+	if db.Migrator().HasTable(&models.AuditLog{}) && !db.Migrator().HasColumn(&models.AuditLog{}, "Hash") {
+		chainAuditLogs(db)
+	}
+
 	db.AutoMigrate(
 		&models.Organization{},
-		&models.InviteCode{},
 		&models.User{},
 		&models.Investment{},
 		&models.Metrics{},
@@ -46,6 +145,71 @@ func runMigrations(db *gorm.DB) {
 		&models.MonthlyUpdate{},
 		&models.Document{},
 		&models.AuditLog{},
+		&models.AuditChainHead{},
+		&models.AuditArchive{},
 		&models.TeamAssignment{},
+		&models.Session{},
+		&models.APIToken{},
+		&models.RecoveryCode{},
+		&models.UserToken{},
+		&models.OIDCProvider{},
+		&models.UserIdentity{},
+		&models.CustomRole{},
+		&models.Membership{},
+		&models.Invitation{},
+		&models.InvitationTeamAssignment{},
+		&models.OrganizationExport{},
+		&models.DealStageTransition{},
+		&models.DealStageEvent{},
+		&models.DealActivity{},
+		&models.DealActivityMention{},
+		&models.DealActivityRevision{},
+		&models.DealAnalysis{},
+		&models.ScoringCriterion{},
+		&models.DealScore{},
+		&models.Notification{},
 	)
+
+	if backfillEmailVerified {
+		db.Model(&models.User{}).Where("email_verified_at IS NULL").Update("email_verified_at", gorm.Expr("created_at"))
+	}
+
+	if backfillMemberships {
+		db.Exec("INSERT INTO memberships (user_id, organization_id, role, created_at, updated_at) SELECT id, organization_id, COALESCE(role, 'viewer'), created_at, NOW() FROM users")
+	}
+
+	if backfillCSRFTokens {
+		db.Exec("UPDATE sessions SET csrf_token = md5(random()::text || id::text) WHERE csrf_token = ''")
+	}
+
+	if importDealScores {
+		db.Exec(`INSERT INTO deal_scores (organization_id, deal_id, criterion, source, reviewer_id, reviewer_name, score, created_at, updated_at)
+			SELECT deals.organization_id, deals.id, legacy.criterion, 'legacy', 0, 'Imported', legacy.score, NOW(), NOW()
+			FROM deals CROSS JOIN LATERAL (VALUES ('team', team_score), ('product', product_score), ('market', market_score), ('traction', traction_score)) AS legacy(criterion, score)
+			WHERE legacy.score BETWEEN 1 AND 10`)
+	}
+
+	if migrateInviteCodes {
+		db.Exec(`INSERT INTO invitations (organization_id, code, role, max_uses, use_count, expires_at, created_by_id, created_at, updated_at)
+			SELECT organization_id, code, 'viewer', 1, CASE WHEN used_by_id IS NULL THEN 0 ELSE 1 END, expires_at, created_by_id, created_at, NOW()
+			FROM invite_codes WHERE deleted_at IS NULL`)
+	}
+}
+
+// chainAuditLogs adds the hash chain columns to an existing audit log and chains its entries,
+// before the unique index on each organization's sequence is created
+func chainAuditLogs(db *gorm.DB) {
+	for _, field := range []string{"OrganizationID", "Changes", "UserAgent", "Sequence", "PrevHash", "Hash"} {
+		if !db.Migrator().HasColumn(&models.AuditLog{}, field) {
+			db.Migrator().AddColumn(&models.AuditLog{}, field)
+		}
+	}
+	db.AutoMigrate(&models.AuditChainHead{})
+
+	// Entries from before organizations were recorded belong to the user's organization
+	db.Exec("UPDATE audit_logs SET organization_id = COALESCE((SELECT organization_id FROM users WHERE users.id = audit_logs.user_id), 0) WHERE organization_id IS NULL")
+
+	if err := audit.ChainExisting(db); err != nil {
+		log.Fatal("Failed to chain existing audit log entries:", err)
+	}
 }
@@ -2,6 +2,7 @@ package di
 
 import (
 	"ventura/internal/handler"
+	"ventura/internal/mailer"
 	"ventura/internal/repository"
 	"ventura/internal/service"
 
@@ -14,6 +15,11 @@ type Container struct {
 	AuthHandler          *handler.AuthHandler
 	InvestmentHandler    *handler.InvestmentHandler
 	DashboardHandler     *handler.DashboardHandler
+	AnalyticsHandler     *handler.AnalyticsHandler
+	DealActivityHandler  *handler.DealActivityHandler
+	DealAnalysisHandler  *handler.DealAnalysisHandler
+	ScorecardHandler     *handler.ScorecardHandler
+	NotificationHandler  *handler.NotificationHandler
 	DealHandler          *handler.DealHandler
 	PortfolioHandler     *handler.PortfolioHandler
 	FounderHandler       *handler.FounderHandler
@@ -22,6 +28,24 @@ type Container struct {
 	AuditHandler         *handler.AuditHandler
 	TeamHandler          *handler.TeamHandler
 	SearchHandler        *handler.SearchHandler
+	APITokenHandler      *handler.APITokenHandler
+	MFAHandler           *handler.MFAHandler
+	SSOHandler           *handler.SSOHandler
+	RoleHandler          *handler.RoleHandler
+	MembershipHandler    *handler.MembershipHandler
+	InvitationHandler    *handler.InvitationHandler
+	HistoryHandler       *handler.HistoryHandler
+	OrganizationHandler  *handler.OrganizationHandler
+
+	// Services used by middleware
+	SessionService    *service.SessionService
+	APITokenService   *service.APITokenService
+	PermissionService *service.PermissionService
+
+	// Services used by background workers
+	AuditService        *service.AuditService
+	OrganizationService *service.OrganizationService
+	ExportService       *service.OrganizationExportService
 }
 
 // NewContainer creates and wires up all dependencies
@@ -36,25 +60,80 @@ func NewContainer(db *gorm.DB) *Container {
 	monthlyUpdateRepo := repository.NewMonthlyUpdateRepository(db)
 	auditLogRepo := repository.NewAuditLogRepository(db)
 	teamAssignmentRepo := repository.NewTeamAssignmentRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	apiTokenRepo := repository.NewAPITokenRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	userTokenRepo := repository.NewUserTokenRepository(db)
+	ssoRepo := repository.NewSSORepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	membershipRepo := repository.NewMembershipRepository(db)
+	invitationRepo := repository.NewInvitationRepository(db)
+	documentRepo := repository.NewDocumentRepository(db)
+	exportRepo := repository.NewOrganizationExportRepository(db)
+	dealStageTransitionRepo := repository.NewDealStageTransitionRepository(db)
+	dealActivityRepo := repository.NewDealActivityRepository(db)
+	dealAnalysisRepo := repository.NewDealAnalysisRepository(db)
+	scoringCriterionRepo := repository.NewScoringCriterionRepository(db)
+	dealScoreRepo := repository.NewDealScoreRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
 
 	// Services
 	investmentService := service.NewInvestmentService(investmentRepo)
 	analyticsService := service.NewAnalyticsService()
 	aiDealScorerService := service.NewAIDealScorerService()
+	dealStageService := service.NewDealStageService(dealRepo, dealStageTransitionRepo, userRepo)
+	scorecardService := service.NewScorecardService(scoringCriterionRepo, dealScoreRepo, userRepo)
+	dealAnalysisService := service.NewDealAnalysisService(dealAnalysisRepo, aiDealScorerService, scorecardService, userRepo)
 	aiPortfolioInsightService := service.NewAIPortfolioInsightService()
+	sessionService := service.NewSessionService(sessionRepo, userRepo)
+	apiTokenService := service.NewAPITokenService(apiTokenRepo, userRepo, auditLogRepo)
+	mfaService := service.NewMFAService(userRepo, orgRepo, recoveryCodeRepo, sessionRepo)
+	mail := mailer.FromEnv()
+	accountService := service.NewAccountService(userRepo, userTokenRepo, sessionRepo, mail)
+	notificationService := service.NewNotificationService(notificationRepo, userRepo, mail)
+	dealActivityService := service.NewDealActivityService(dealActivityRepo, dealRepo, dealAnalysisRepo, userRepo, notificationService)
+	loginGuard := service.NewLoginGuard(userRepo, auditLogRepo)
+	ssoService := service.NewSSOService(ssoRepo, orgRepo, userRepo)
+	permissionService := service.NewPermissionService(roleRepo, userRepo)
+	companyAccessService := service.NewCompanyAccessService(portfolioRepo, teamAssignmentRepo)
+	membershipService := service.NewMembershipService(membershipRepo, userRepo, sessionRepo, teamAssignmentRepo, dealRepo)
+	auditService := service.NewAuditService(auditLogRepo, orgRepo)
+	historyService := service.NewHistoryService(auditLogRepo)
+	organizationService := service.NewOrganizationService(orgRepo, userRepo, sessionRepo, auditLogRepo, accountService)
+	exportService := service.NewOrganizationExportService(exportRepo, orgRepo, portfolioRepo, dealRepo, dealActivityRepo, dealAnalysisRepo, dealScoreRepo, founderRepo, monthlyUpdateRepo, documentRepo, teamAssignmentRepo, auditService)
+	invitationService := service.NewInvitationService(invitationRepo, userRepo, membershipRepo, teamAssignmentRepo, portfolioRepo, accountService)
 
 	// Handlers
 	return &Container{
-		AuthHandler:          handler.NewAuthHandler(userRepo, orgRepo),
+		AuthHandler:          handler.NewAuthHandler(userRepo, orgRepo, auditLogRepo, sessionService, mfaService, accountService, loginGuard, ssoService, invitationService),
 		InvestmentHandler:    handler.NewInvestmentHandler(investmentService),
-		DashboardHandler:     handler.NewDashboardHandler(portfolioRepo, analyticsService, aiPortfolioInsightService, monthlyUpdateRepo),
-		DealHandler:          handler.NewDealHandler(dealRepo, portfolioRepo, aiDealScorerService),
-		PortfolioHandler:     handler.NewPortfolioHandler(portfolioRepo),
-		FounderHandler:       handler.NewFounderHandler(founderRepo, portfolioRepo),
-		MonthlyUpdateHandler: handler.NewMonthlyUpdateHandler(monthlyUpdateRepo, portfolioRepo),
-		UserHandler:          handler.NewUserHandler(userRepo, auditLogRepo),
-		AuditHandler:         handler.NewAuditHandler(auditLogRepo),
-		TeamHandler:          handler.NewTeamHandler(teamAssignmentRepo, userRepo, portfolioRepo, auditLogRepo),
+		DashboardHandler:     handler.NewDashboardHandler(portfolioRepo, analyticsService, aiPortfolioInsightService, monthlyUpdateRepo, organizationService),
+		AnalyticsHandler:     handler.NewAnalyticsHandler(dealRepo, analyticsService, organizationService),
+		DealActivityHandler:  handler.NewDealActivityHandler(dealRepo, dealActivityService),
+		DealAnalysisHandler:  handler.NewDealAnalysisHandler(dealRepo, dealAnalysisService),
+		ScorecardHandler:     handler.NewScorecardHandler(dealRepo, scorecardService),
+		NotificationHandler:  handler.NewNotificationHandler(notificationService),
+		DealHandler:          handler.NewDealHandler(dealRepo, portfolioRepo, dealStageService, scorecardService, userRepo),
+		PortfolioHandler:     handler.NewPortfolioHandler(portfolioRepo, companyAccessService, organizationService),
+		FounderHandler:       handler.NewFounderHandler(founderRepo, companyAccessService),
+		MonthlyUpdateHandler: handler.NewMonthlyUpdateHandler(monthlyUpdateRepo, portfolioRepo, companyAccessService, organizationService),
+		UserHandler:          handler.NewUserHandler(userRepo, auditLogRepo, loginGuard, permissionService, membershipService),
+		AuditHandler:         handler.NewAuditHandler(auditService, auditLogRepo, userRepo),
+		TeamHandler:          handler.NewTeamHandler(teamAssignmentRepo, userRepo, companyAccessService),
 		SearchHandler:        handler.NewSearchHandler(portfolioRepo, dealRepo, userRepo),
+		APITokenHandler:      handler.NewAPITokenHandler(apiTokenService, userRepo, auditLogRepo, permissionService),
+		MFAHandler:           handler.NewMFAHandler(mfaService, sessionService, loginGuard, permissionService, membershipService, userRepo, auditLogRepo),
+		SSOHandler:           handler.NewSSOHandler(ssoService, sessionService, mfaService, loginGuard, userRepo, permissionService),
+		RoleHandler:          handler.NewRoleHandler(permissionService),
+		MembershipHandler:    handler.NewMembershipHandler(membershipService, sessionService, mfaService, ssoService, loginGuard, userRepo, auditLogRepo),
+		InvitationHandler:    handler.NewInvitationHandler(invitationService, permissionService, companyAccessService, userRepo, auditLogRepo),
+		HistoryHandler:       handler.NewHistoryHandler(historyService, portfolioRepo, dealRepo, founderRepo, monthlyUpdateRepo, companyAccessService, organizationService),
+		OrganizationHandler:  handler.NewOrganizationHandler(organizationService, exportService, userRepo, auditLogRepo),
+		SessionService:       sessionService,
+		APITokenService:      apiTokenService,
+		PermissionService:    permissionService,
+		AuditService:         auditService,
+		OrganizationService:  organizationService,
+		ExportService:        exportService,
 	}
 }
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+	"ventura/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnalyticsHandler serves reports on the deal pipeline
+type AnalyticsHandler struct {
+	dealRepo      *repository.DealRepository
+	analytics     *service.AnalyticsService
+	organizations *service.OrganizationService
+}
+
+func NewAnalyticsHandler(dealRepo *repository.DealRepository, analytics *service.AnalyticsService, organizations *service.OrganizationService) *AnalyticsHandler {
+	return &AnalyticsHandler{dealRepo: dealRepo, analytics: analytics, organizations: organizations}
+}
+
+// GetPipeline returns the pipeline's stage conversion, time in stage, monthly throughput and
+// loss reasons. from and to (YYYY-MM-DD, inclusive, in the organization's timezone) limit it
+// to the deals created and the moves made in that window; ownerId to one member's deals.
+func (h *AnalyticsHandler) GetPipeline(c *gin.Context) {
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
+		return
+	}
+
+	var window service.PipelineWindow
+	location := settings.Location()
+	if from := c.Query("from"); from != "" {
+		date, err := time.ParseInLocation("2006-01-02", from, location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, use YYYY-MM-DD"})
+			return
+		}
+		window.From = date
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := time.ParseInLocation("2006-01-02", to, location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, use YYYY-MM-DD"})
+			return
+		}
+		window.To = date.AddDate(0, 0, 1)
+	}
+	if !window.From.IsZero() && !window.To.IsZero() && !window.From.Before(window.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+
+	deals := h.dealRepo.Within(companyScope(c))
+	var pipeline []models.Deal
+	var err error
+	if owner := c.Query("ownerId"); owner != "" {
+		ownerID, parseErr := strconv.ParseUint(owner, 10, 32)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner ID"})
+			return
+		}
+		pipeline, err = deals.GetByOwner(uint(ownerID))
+	} else {
+		pipeline, err = deals.GetAll()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deals"})
+		return
+	}
+
+	ids := make([]uint, len(pipeline))
+	for i, deal := range pipeline {
+		ids[i] = deal.ID
+	}
+	events, err := deals.GetStageEventsByDeals(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stage history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.analytics.GetPipelineAnalytics(pipeline, events, window, settings))
+}
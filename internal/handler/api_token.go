@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+	"ventura/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APITokenHandler struct {
+	tokens       *service.APITokenService
+	userRepo     *repository.UserRepository
+	auditLogRepo *repository.AuditLogRepository
+	permissions  *service.PermissionService
+}
+
+func NewAPITokenHandler(tokens *service.APITokenService, userRepo *repository.UserRepository, auditLogRepo *repository.AuditLogRepository, permissions *service.PermissionService) *APITokenHandler {
+	return &APITokenHandler{
+		tokens:       tokens,
+		userRepo:     userRepo,
+		auditLogRepo: auditLogRepo,
+		permissions:  permissions,
+	}
+}
+
+// APITokenResponse represents an API token in responses (never includes the secret)
+type APITokenResponse struct {
+	ID         uint                `json:"id"`
+	Name       string              `json:"name"`
+	Kind       models.APITokenKind `json:"kind"`
+	Prefix     string              `json:"prefix"`
+	Scopes     []string            `json:"scopes"`
+	UserID     uint                `json:"userId"`
+	UserName   string              `json:"userName"`
+	ExpiresAt  *time.Time          `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time          `json:"lastUsedAt,omitempty"`
+	LastUsedIP string              `json:"lastUsedIp,omitempty"`
+	RevokedAt  *time.Time          `json:"revokedAt,omitempty"`
+	CreatedAt  string              `json:"createdAt"`
+}
+
+// CreateAPITokenRequest represents the create API token request body
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	UserID        uint     `json:"userId"` // Service account to own the token; defaults to the caller
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"omitempty,min=1,max=365"`
+}
+
+// CreateServiceAccountRequest represents the create service account request body
+type CreateServiceAccountRequest struct {
+	Name string          `json:"name" binding:"required"`
+	Role models.UserRole `json:"role"` // Built-in or custom role key
+}
+
+func toAPITokenResponse(token *models.APIToken) APITokenResponse {
+	response := APITokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Kind:       token.Kind,
+		Prefix:     token.Prefix,
+		Scopes:     token.ScopeList(),
+		UserID:     token.UserID,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		LastUsedIP: token.LastUsedIP,
+		RevokedAt:  token.RevokedAt,
+		CreatedAt:  token.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if token.User != nil {
+		response.UserName = token.User.Name
+	}
+	return response
+}
+
+// GetAPITokens returns all API tokens of the organization
+func (h *APITokenHandler) GetAPITokens(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	tokens, err := h.tokens.List(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API tokens"})
+		return
+	}
+
+	response := make([]APITokenResponse, len(tokens))
+	for i := range tokens {
+		response[i] = toAPITokenResponse(&tokens[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateAPIToken creates a personal or service-account token and returns its secret once
+func (h *APITokenHandler) CreateAPIToken(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+	currentUserID, _ := c.Get("user_id")
+
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ownerID := req.UserID
+	if ownerID == 0 {
+		ownerID = currentUserID.(uint)
+	} else if ownerID != currentUserID.(uint) {
+		// Callers may only mint tokens for themselves or for service accounts
+		owner, err := h.userRepo.FindMember(ownerID, orgID)
+		if err != nil || !owner.IsServiceAccount {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "userId must be a service account in this organization"})
+			return
+		}
+
+		// The token acts with the account's role, which must not exceed the caller's
+		if err := h.permissions.CanAssignRole(orgID, owner.Role, currentPermissions(c)); err != nil {
+			respondRoleError(c, err)
+			return
+		}
+	}
+
+	expiresInDays := req.ExpiresInDays
+	if expiresInDays == 0 {
+		expiresInDays = 90
+	}
+
+	token, plaintext, err := h.tokens.Create(c.Request.Context(), service.CreateAPITokenInput{
+		OrganizationID: orgID,
+		UserID:         ownerID,
+		CreatedByID:    currentUserID.(uint),
+		Name:           req.Name,
+		Scopes:         req.Scopes,
+		ExpiresAt:      time.Now().AddDate(0, 0, expiresInDays),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":  toAPITokenResponse(token),
+		"secret": plaintext, // Shown only once
+	})
+}
+
+// RevokeAPIToken revokes a token of the organization
+func (h *APITokenHandler) RevokeAPIToken(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	token, err := h.tokens.Revoke(c.Request.Context(), uint(id), orgID)
+	if err != nil {
+		if err == service.ErrAPITokenNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API token"})
+		return
+	}
+
+	// Log the action
+	h.logAction(c, models.ActionRevoke, models.EntityAPIToken, token.ID, "Revoked API token "+token.Prefix+" ("+token.Name+")")
+
+	c.JSON(http.StatusOK, gin.H{"message": "API token revoked successfully"})
+}
+
+// GetServiceAccounts returns all service accounts of the organization
+func (h *APITokenHandler) GetServiceAccounts(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	accounts, err := h.userRepo.GetServiceAccountsByOrganization(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service accounts"})
+		return
+	}
+
+	response := make([]UserListResponse, len(accounts))
+	for i, account := range accounts {
+		response[i] = UserListResponse{
+			ID:        account.ID,
+			Email:     account.Email,
+			Name:      account.Name,
+			Role:      account.Role,
+			CreatedAt: account.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateServiceAccount creates a non-login user that acts through API tokens
+func (h *APITokenHandler) CreateServiceAccount(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	var req CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.RoleViewer
+	}
+	if err := h.permissions.CanAssignRole(orgID, role, currentPermissions(c)); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	// Service accounts need a unique email and a password nobody knows
+	randomBytes := make([]byte, 24)
+	rand.Read(randomBytes)
+	account := &models.User{
+		OrganizationID:   orgID,
+		Email:            "svc-" + generateSlug(req.Name) + "@service-accounts.ventura.invalid",
+		Password:         hex.EncodeToString(randomBytes),
+		Name:             req.Name,
+		Role:             role,
+		IsServiceAccount: true,
+	}
+
+	if err := h.userRepo.WithContext(c.Request.Context()).Create(account); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service account"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, UserListResponse{
+		ID:        account.ID,
+		Email:     account.Email,
+		Name:      account.Name,
+		Role:      account.Role,
+		CreatedAt: account.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	})
+}
+
+// Helper function to log audit actions
+func (h *APITokenHandler) logAction(c *gin.Context, action, entity string, entityID uint, details string) {
+	userID, _ := c.Get("user_id")
+	userEmail, _ := c.Get("user_email")
+	userName := ""
+	if user, err := h.userRepo.FindByID(userID.(uint)); err == nil {
+		userName = user.Name
+	}
+
+	log := &models.AuditLog{
+		OrganizationID: c.GetUint("organization_id"),
+		UserID:         userID.(uint),
+		UserEmail:      userEmail.(string),
+		UserName:       userName,
+		Action:         action,
+		Entity:         entity,
+		EntityID:       entityID,
+		Details:        details,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+	h.auditLogRepo.Create(log)
+}
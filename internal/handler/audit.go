@@ -1,20 +1,27 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 	"ventura/internal/models"
 	"ventura/internal/repository"
+	"ventura/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type AuditHandler struct {
+	audits       *service.AuditService
 	auditLogRepo *repository.AuditLogRepository
+	userRepo     *repository.UserRepository
 }
 
-func NewAuditHandler(auditLogRepo *repository.AuditLogRepository) *AuditHandler {
-	return &AuditHandler{auditLogRepo: auditLogRepo}
+func NewAuditHandler(audits *service.AuditService, auditLogRepo *repository.AuditLogRepository, userRepo *repository.UserRepository) *AuditHandler {
+	return &AuditHandler{audits: audits, auditLogRepo: auditLogRepo, userRepo: userRepo}
 }
 
 // AuditLogResponse represents the paginated audit log response
@@ -25,7 +32,12 @@ type AuditLogResponse struct {
 	Limit int               `json:"limit"`
 }
 
-// GetAuditLogs returns paginated audit logs with optional filters
+// UpdateAuditRetentionRequest sets how long audit entries are kept before they are archived
+type UpdateAuditRetentionRequest struct {
+	RetentionDays *int `json:"retentionDays" binding:"required"`
+}
+
+// GetAuditLogs returns the organization's paginated audit logs with optional filters
 func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
 	// Parse pagination
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -38,21 +50,13 @@ func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
 	}
 	offset := (page - 1) * limit
 
-	// Parse filters
-	userIDStr := c.Query("userId")
-	entity := c.Query("entity")
-	action := c.Query("action")
-
-	var userID uint
-	if userIDStr != "" {
-		id, err := strconv.ParseUint(userIDStr, 10, 32)
-		if err == nil {
-			userID = uint(id)
-		}
+	filter, ok := auditLogFilter(c)
+	if !ok {
+		return
 	}
 
 	// Get filtered logs
-	logs, total, err := h.auditLogRepo.GetFiltered(userID, entity, action, limit, offset)
+	logs, total, err := h.auditLogRepo.GetFiltered(filter, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit logs"})
 		return
@@ -65,3 +69,213 @@ func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
 		Limit: limit,
 	})
 }
+
+// ExportAuditLogs streams the organization's audit logs as CSV or JSON Lines, oldest first,
+// with the same filters as GetAuditLogs
+func (h *AuditHandler) ExportAuditLogs(c *gin.Context) {
+	filter, ok := auditLogFilter(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", service.AuditExportCSV)
+	contentType := "text/csv"
+	switch format {
+	case service.AuditExportCSV:
+	case service.AuditExportJSONL:
+		contentType = "application/x-ndjson"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrInvalidExportFormat.Error()})
+		return
+	}
+
+	h.logAction(c, models.ActionExport, models.EntityAuditLog, 0, "Exported the audit log as "+format)
+
+	filename := fmt.Sprintf("audit-log-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+	if err := h.audits.Export(filter, format, c.Writer); err != nil {
+		// Headers are already sent; a truncated file is all that can be signalled
+		c.Error(err)
+	}
+}
+
+// VerifyAuditLogs checks that the organization's audit chain is intact
+func (h *AuditHandler) VerifyAuditLogs(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	result, err := h.audits.Verify(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify audit logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetAuditRetention returns how long the organization keeps audit entries
+func (h *AuditHandler) GetAuditRetention(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	archives, err := h.audits.Archives(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit archives"})
+		return
+	}
+	days, err := h.audits.RetentionDays(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit retention"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"retentionDays": days, "archives": archives})
+}
+
+// UpdateAuditRetention sets how long the organization keeps audit entries before archiving them
+func (h *AuditHandler) UpdateAuditRetention(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	var req UpdateAuditRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, err := h.audits.SetRetention(c.Request.Context(), orgID, *req.RetentionDays)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAuditRetention) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update audit retention"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"retentionDays": org.AuditRetentionDays})
+}
+
+// DownloadAuditArchive sends an archive of the organization's audit entries as gzipped JSON Lines
+func (h *AuditHandler) DownloadAuditArchive(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid archive ID"})
+		return
+	}
+
+	archive, err := h.audits.Archive(uint(id), orgID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Archive not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch archive"})
+		return
+	}
+
+	h.logAction(c, models.ActionExport, models.EntityAuditLog, archive.ID,
+		fmt.Sprintf("Downloaded audit archive %d (%d to %d)", archive.ID, archive.FirstSequence, archive.LastSequence))
+
+	c.Header("X-Checksum-SHA256", archive.SHA256)
+	c.FileAttachment(archive.Path, fmt.Sprintf("audit-log-%d-%d.jsonl.gz", archive.FirstSequence, archive.LastSequence))
+}
+
+// auditLogFilter reads the audit log filters from the query string, answering 400 if one
+// is malformed. Dates are inclusive and accept YYYY-MM-DD or RFC 3339.
+func auditLogFilter(c *gin.Context) (repository.AuditLogFilter, bool) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return repository.AuditLogFilter{}, false
+	}
+
+	filter := repository.AuditLogFilter{
+		OrganizationID: orgID,
+		Entity:         c.Query("entity"),
+		Action:         c.Query("action"),
+	}
+
+	for name, target := range map[string]*uint{"userId": &filter.UserID, "entityId": &filter.EntityID} {
+		if value := c.Query(name); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+				return filter, false
+			}
+			*target = uint(id)
+		}
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, _, err := parseAuditDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+			return filter, false
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, dateOnly, err := parseAuditDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+			return filter, false
+		}
+		if dateOnly {
+			// A date covers the whole day
+			to = to.AddDate(0, 0, 1)
+		} else {
+			to = to.Add(time.Microsecond)
+		}
+		filter.To = &to
+	}
+	return filter, true
+}
+
+// parseAuditDate parses a YYYY-MM-DD date (UTC) or an RFC 3339 timestamp
+func parseAuditDate(value string) (time.Time, bool, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+func (h *AuditHandler) logAction(c *gin.Context, action, entity string, entityID uint, details string) {
+	userID := c.GetUint("user_id")
+	userName := ""
+	if user, err := h.userRepo.FindByID(userID); err == nil {
+		userName = user.Name
+	}
+
+	log := &models.AuditLog{
+		OrganizationID: c.GetUint("organization_id"),
+		UserID:         userID,
+		UserEmail:      c.GetString("user_email"),
+		UserName:       userName,
+		Action:         action,
+		Entity:         entity,
+		EntityID:       entityID,
+		Details:        details,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+	h.auditLogRepo.Create(log)
+}
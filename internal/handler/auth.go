@@ -3,26 +3,46 @@ package handler
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
+	"math"
 	"net/http"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 	"ventura/internal/auth"
 	"ventura/internal/models"
 	"ventura/internal/repository"
+	"ventura/internal/service"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandler struct {
-	userRepo *repository.UserRepository
-	orgRepo  *repository.OrganizationRepository
+	userRepo     *repository.UserRepository
+	orgRepo      *repository.OrganizationRepository
+	auditLogRepo *repository.AuditLogRepository
+	sessions     *service.SessionService
+	mfa          *service.MFAService
+	accounts     *service.AccountService
+	loginGuard   *service.LoginGuard
+	sso          *service.SSOService
+	invitations  *service.InvitationService
 }
 
-func NewAuthHandler(userRepo *repository.UserRepository, orgRepo *repository.OrganizationRepository) *AuthHandler {
-	return &AuthHandler{userRepo: userRepo, orgRepo: orgRepo}
+func NewAuthHandler(userRepo *repository.UserRepository, orgRepo *repository.OrganizationRepository, auditLogRepo *repository.AuditLogRepository, sessions *service.SessionService, mfa *service.MFAService, accounts *service.AccountService, loginGuard *service.LoginGuard, sso *service.SSOService, invitations *service.InvitationService) *AuthHandler {
+	return &AuthHandler{
+		userRepo:     userRepo,
+		orgRepo:      orgRepo,
+		auditLogRepo: auditLogRepo,
+		sessions:     sessions,
+		mfa:          mfa,
+		accounts:     accounts,
+		loginGuard:   loginGuard,
+		sso:          sso,
+		invitations:  invitations,
+	}
 }
 
 // RegisterRequest represents the registration request body
@@ -31,7 +51,7 @@ type RegisterRequest struct {
 	Password         string `json:"password" binding:"required,min=8"`
 	Name             string `json:"name" binding:"required"`
 	OrganizationName string `json:"organizationName"` // Optional: create new org
-	InviteCode       string `json:"inviteCode"`       // Optional: join existing org
+	InviteCode       string `json:"inviteCode"`       // Optional: join existing org with an invitation
 }
 
 // LoginRequest represents the login request body
@@ -42,19 +62,52 @@ type LoginRequest struct {
 
 // AuthResponse represents the authentication response
 type AuthResponse struct {
-	User         UserResponse `json:"user"`
-	AccessToken  string       `json:"access_token,omitempty"`  // Only for debugging
-	RefreshToken string       `json:"refresh_token,omitempty"` // Only for debugging
+	User                      UserResponse `json:"user"`
+	EmailVerificationRequired bool         `json:"emailVerificationRequired,omitempty"`
+	AccessToken               string       `json:"access_token,omitempty"`  // Only for debugging
+	RefreshToken              string       `json:"refresh_token,omitempty"` // Only for debugging
+}
+
+// LoginChallengeResponse is returned by Login instead of tokens when another step is needed
+type LoginChallengeResponse struct {
+	PasswordChangeRequired bool   `json:"passwordChangeRequired,omitempty"` // Set a password with POST /auth/password/initial
+	MFARequired            bool   `json:"mfaRequired,omitempty"`            // Verify with POST /auth/mfa/verify
+	MFASetupRequired       bool   `json:"mfaSetupRequired,omitempty"`       // Enroll with POST /auth/mfa/setup
+	ChallengeToken         string `json:"challengeToken"`
+}
+
+// InitialPasswordRequest replaces an invited user's temporary password during login
+type InitialPasswordRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	NewPassword    string `json:"newPassword" binding:"required,min=8"`
+}
+
+// ForgotPasswordRequest represents the password reset request body
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents the password reset confirmation body
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,min=8"`
+}
+
+// VerifyEmailRequest represents the email verification body
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
 }
 
 // UserResponse represents a user in responses (without password)
 type UserResponse struct {
-	ID               uint            `json:"id"`
-	Email            string          `json:"email"`
-	Name             string          `json:"name"`
-	Role             models.UserRole `json:"role"`
-	OrganizationID   uint            `json:"organizationId"`
-	OrganizationName string          `json:"organizationName,omitempty"`
+	ID               uint                `json:"id"`
+	Email            string              `json:"email"`
+	Name             string              `json:"name"`
+	Role             models.UserRole     `json:"role"`
+	OrganizationID   uint                `json:"organizationId"`
+	OrganizationName string              `json:"organizationName,omitempty"`
+	Permissions      []models.Permission `json:"permissions,omitempty"` // Only returned by /auth/me
+	CSRFToken        string              `json:"csrfToken,omitempty"`   // Send as X-CSRF-Token on cookie-authenticated writes
 }
 
 // generateSlug creates a URL-friendly slug from a name
@@ -77,13 +130,6 @@ func generateSlug(name string) string {
 	return slug + "-" + hex.EncodeToString(randomBytes)
 }
 
-// generateInviteCode creates a random invite code
-func generateInviteCode() string {
-	randomBytes := make([]byte, 16)
-	rand.Read(randomBytes)
-	return hex.EncodeToString(randomBytes)
-}
-
 // Register creates a new user account
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
@@ -93,8 +139,12 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// Check if user already exists
-	existingUser, _ := h.userRepo.FindByEmail(req.Email)
+	existingUser, _ := h.userRepo.FindByEmail(strings.ToLower(req.Email))
 	if existingUser != nil {
+		if req.InviteCode != "" {
+			c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists, sign in and accept the invite from your account"})
+			return
+		}
 		c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
 		return
 	}
@@ -105,31 +155,25 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	var organizationID uint
+	// Create user (password will be hashed by BeforeCreate hook)
+	user := &models.User{
+		Email:    strings.ToLower(req.Email),
+		Password: req.Password,
+		Name:     req.Name,
+	}
 	var organizationName string
 
 	if req.InviteCode != "" {
-		// Join existing organization via invite code
-		invite, err := h.orgRepo.GetInviteCodeByCode(req.InviteCode)
+		// Join an existing organization with the invitation's role
+		invitation, err := h.invitations.Register(c.Request.Context(), req.InviteCode, user)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite code"})
-			return
-		}
-
-		// Check if invite is expired
-		if time.Now().After(invite.ExpiresAt) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invite code has expired"})
-			return
-		}
-
-		// Check if invite is already used
-		if invite.UsedByID != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invite code has already been used"})
+			respondInvitationError(c, err)
 			return
 		}
+		organizationName = invitation.Organization.Name
 
-		organizationID = invite.OrganizationID
-		organizationName = invite.Organization.Name
+		h.logAction(c, user, models.ActionJoin, models.EntityOrganization, invitation.OrganizationID,
+			fmt.Sprintf("Registered with invitation %d as %s", invitation.ID, invitation.Role))
 	} else {
 		// Create new organization
 		org := &models.Organization{
@@ -137,67 +181,37 @@ func (h *AuthHandler) Register(c *gin.Context) {
 			Slug: generateSlug(req.OrganizationName),
 		}
 
-		if err := h.orgRepo.Create(org); err != nil {
+		if err := h.orgRepo.WithContext(c.Request.Context()).Create(org); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
 			return
 		}
 
-		organizationID = org.ID
-		organizationName = org.Name
-	}
-
-	// Create user (password will be hashed by BeforeCreate hook)
-	user := &models.User{
-		OrganizationID: organizationID,
-		Email:          strings.ToLower(req.Email),
-		Password:       req.Password,
-		Name:           req.Name,
-		Role:           models.RoleAdmin, // First user in org is admin
-	}
-
-	// If joining via invite, make them a viewer by default
-	if req.InviteCode != "" {
-		user.Role = models.RoleViewer
-	}
-
-	if err := h.userRepo.Create(user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
-		return
-	}
-
-	// Mark invite code as used if applicable
-	if req.InviteCode != "" {
-		invite, _ := h.orgRepo.GetInviteCodeByCode(req.InviteCode)
-		if invite != nil {
-			h.orgRepo.MarkInviteCodeUsed(invite.ID, user.ID)
+		// First user in org is admin
+		user.OrganizationID = org.ID
+		user.Role = models.RoleAdmin
+		if err := h.userRepo.WithContext(c.Request.Context()).Create(user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+			return
 		}
+		organizationName = org.Name
 	}
 
-	// Generate tokens
-	accessToken, err := auth.GenerateAccessToken(user)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
-		return
-	}
-
-	refreshToken, err := auth.GenerateRefreshToken(user)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+	// The account can sign in once the email address is confirmed
+	if err := h.accounts.SendEmailVerification(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
 		return
 	}
 
-	// Set httpOnly cookies
-	setAuthCookies(c, accessToken, refreshToken)
-
 	c.JSON(http.StatusCreated, AuthResponse{
 		User: UserResponse{
 			ID:               user.ID,
 			Email:            user.Email,
 			Name:             user.Name,
 			Role:             user.Role,
-			OrganizationID:   organizationID,
+			OrganizationID:   user.OrganizationID,
 			OrganizationName: organizationName,
 		},
+		EmailVerificationRequired: true,
 	})
 }
 
@@ -209,53 +223,218 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Find user by email with organization preloaded
-	user, err := h.userRepo.FindByEmailWithOrganization(strings.ToLower(req.Email))
+	attempt := loginAttempt(c, strings.ToLower(req.Email))
+
+	// Find user by email as a member of the organization they last worked in
+	user, err := h.userRepo.FindMemberByEmail(attempt.Email)
 	if err != nil {
+		h.loginGuard.RecordFailure(nil, attempt, service.LoginFailureUnknownEmail)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+
+	// Refuse without checking the password while the account is locked
+	if retryAfter := h.loginGuard.RetryAfter(user); retryAfter > 0 {
+		h.loginGuard.RecordFailure(user, attempt, service.LoginFailureLocked)
+		respondLocked(c, retryAfter)
+		return
+	}
+
+	// Service accounts can only authenticate with API tokens
+	if user.IsServiceAccount {
+		h.loginGuard.RecordFailure(user, attempt, service.LoginFailureServiceAccount)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
+	// Organizations can require their members to sign in through SSO
+	if h.sso.PasswordLoginDisabled(user) {
+		h.loginGuard.RecordFailure(user, attempt, service.LoginFailureSSORequired)
+		response := gin.H{"error": "Your organization signs in with single sign-on", "ssoRequired": true}
+		if user.Organization != nil {
+			response["ssoPath"] = "/auth/sso/" + user.Organization.Slug + "/start"
+		}
+		c.JSON(http.StatusForbidden, response)
+		return
+	}
+
 	// Check password
 	if !user.CheckPassword(req.Password) {
+		h.loginGuard.RecordFailure(user, attempt, service.LoginFailureInvalidPassword)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	// Generate tokens
-	accessToken, err := auth.GenerateAccessToken(user)
+	// Invited users must replace their temporary password before anything else
+	if user.MustChangePassword {
+		h.sendChallenge(c, user, auth.TokenTypePasswordChange)
+		return
+	}
+
+	if !user.IsEmailVerified() {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":                     "Please confirm your email address before signing in",
+			"emailVerificationRequired": true,
+		})
+		return
+	}
+
+	h.completeLogin(c, user)
+}
+
+// completeLogin runs the steps after the password: a second factor challenge if one
+// applies, otherwise a new session
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User) {
+	if challengeType := secondFactorStep(h.mfa, user); challengeType != "" {
+		h.sendChallenge(c, user, challengeType)
+		return
+	}
+
+	response, ok := startSession(c, h.sessions, h.loginGuard, user, service.SessionAuth{Method: models.SessionAuthPassword})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{User: *response})
+}
+
+// secondFactorStep returns the challenge a user who passed the first login step must
+// answer before getting a session: TokenTypeMFAVerify if they use MFA, TokenTypeMFASetup
+// if their organization requires it and they haven't enrolled, otherwise ""
+func secondFactorStep(mfa *service.MFAService, user *models.User) string {
+	if user.MFAEnabled {
+		return auth.TokenTypeMFAVerify
+	}
+	if mfa.IsRequired(user) {
+		return auth.TokenTypeMFASetup
+	}
+	return ""
+}
+
+// sendChallenge hands out a short-lived challenge token instead of a session
+func (h *AuthHandler) sendChallenge(c *gin.Context, user *models.User, challengeType string) {
+	challenge, err := auth.GenerateChallengeToken(user, challengeType, models.SessionAuthPassword)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create login challenge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginChallengeResponse{
+		PasswordChangeRequired: challengeType == auth.TokenTypePasswordChange,
+		MFARequired:            challengeType == auth.TokenTypeMFAVerify,
+		MFASetupRequired:       challengeType == auth.TokenTypeMFASetup,
+		ChallengeToken:         challenge,
+	})
+}
+
+// SetInitialPassword replaces an invited user's temporary password and continues the login
+func (h *AuthHandler) SetInitialPassword(c *gin.Context) {
+	var req InitialPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	refreshToken, err := auth.GenerateRefreshToken(user)
+	claims, err := auth.ValidateTypedToken(req.ChallengeToken, auth.TokenTypePasswordChange)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge, please sign in again"})
 		return
 	}
 
-	// Set httpOnly cookies
-	setAuthCookies(c, accessToken, refreshToken)
+	user, err := h.userRepo.FindMember(claims.UserID, claims.OrganizationID)
+	if err != nil || !user.MustChangePassword {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge, please sign in again"})
+		return
+	}
 
-	orgName := ""
-	if user.Organization != nil {
-		orgName = user.Organization.Name
+	if err := h.accounts.SetInitialPassword(c.Request.Context(), user, req.NewPassword); err != nil {
+		if err == service.ErrPasswordUnchanged {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
 	}
 
-	c.JSON(http.StatusOK, AuthResponse{
-		User: UserResponse{
-			ID:               user.ID,
-			Email:            user.Email,
-			Name:             user.Name,
-			Role:             user.Role,
-			OrganizationID:   user.OrganizationID,
-			OrganizationName: orgName,
-		},
-	})
+	h.completeLogin(c, user)
+}
+
+// ForgotPassword emails a password reset link. The response is the same whether or
+// not the account exists.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.accounts.RequestPasswordReset(req.Email)
+
+	c.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password using an emailed reset token and signs out every session
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.accounts.ResetPassword(c.Request.Context(), req.Token, req.NewPassword)
+	if err != nil {
+		if err == service.ErrInvalidActionToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	h.logAction(c, user, models.ActionPasswordReset, models.EntityUser, user.ID, "Reset password via emailed link")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully, please sign in"})
+}
+
+// VerifyEmail confirms an email address using an emailed token
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.accounts.VerifyEmail(c.Request.Context(), req.Token)
+	if err != nil {
+		if err == service.ErrInvalidActionToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	h.logAction(c, user, models.ActionEmailVerify, models.EntityUser, user.ID, "Verified email address")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified, you can now sign in"})
+}
+
+// ResendVerification sends a new verification link. The response is the same whether
+// or not the account exists.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.accounts.ResendEmailVerification(req.Email)
+
+	c.JSON(http.StatusOK, gin.H{"message": "If this account still needs verification, a new link has been sent"})
 }
 
-// Refresh generates a new access token from a refresh token
+// Refresh rotates the refresh token and issues a new token pair.
+// Presenting an already-rotated refresh token revokes the whole session.
 func (h *AuthHandler) Refresh(c *gin.Context) {
 	refreshToken, err := c.Cookie("refresh_token")
 	if err != nil {
@@ -263,33 +442,21 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	// Generate new access token
-	accessToken, err := auth.RefreshAccessToken(refreshToken)
+	tokens, _, err := h.sessions.Refresh(refreshToken, c.GetHeader(auth.CSRFHeader), c.Request.UserAgent(), c.ClientIP())
+	if err == service.ErrInvalidCSRFToken {
+		// Not the session's client: leave its cookies alone
+		c.JSON(http.StatusForbidden, gin.H{"error": "Missing or invalid CSRF token", "csrfRequired": true})
+		return
+	}
 	if err != nil {
+		clearAuthCookies(c)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
 		return
 	}
 
-	// Set new access token cookie with production-aware settings
-	isProduction := os.Getenv("GIN_MODE") == "release"
-	secure := isProduction
-	sameSite := http.SameSiteLaxMode
-	if isProduction {
-		sameSite = http.SameSiteNoneMode
-	}
-
-	c.SetSameSite(sameSite)
-	c.SetCookie(
-		"access_token",
-		accessToken,
-		int(auth.AccessTokenExpiry.Seconds()),
-		"/",
-		"",
-		secure,
-		true, // httpOnly
-	)
+	setAuthCookies(c, tokens)
 
-	c.JSON(http.StatusOK, gin.H{"message": "Token refreshed successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Token refreshed successfully", "csrfToken": tokens.CSRFToken})
 }
 
 // Me returns the current authenticated user's information
@@ -300,7 +467,8 @@ func (h *AuthHandler) Me(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userRepo.FindByIDWithOrganization(userID.(uint))
+	orgID, _ := getOrganizationID(c)
+	user, err := h.userRepo.FindMember(userID.(uint), orgID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -318,75 +486,113 @@ func (h *AuthHandler) Me(c *gin.Context) {
 		Role:             user.Role,
 		OrganizationID:   user.OrganizationID,
 		OrganizationName: orgName,
+		Permissions:      currentPermissions(c),
+		CSRFToken:        c.GetString("csrf_token"),
 	})
 }
 
-// Logout clears the authentication cookies
+// Logout revokes the current session and clears the authentication cookies
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// Clear cookies by setting them to expired with production-aware settings
-	isProduction := os.Getenv("GIN_MODE") == "release"
-	secure := isProduction
-	sameSite := http.SameSiteLaxMode
-	if isProduction {
-		sameSite = http.SameSiteNoneMode
+	// Either token identifies the session; the access token may already have expired
+	if sessionID := sessionIDFromCookies(c); sessionID != 0 {
+		user, err := h.sessions.Logout(sessionID, c.GetHeader(auth.CSRFHeader))
+		if err == service.ErrInvalidCSRFToken {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Missing or invalid CSRF token", "csrfRequired": true})
+			return
+		}
+		if err == nil {
+			h.logAction(c, user, models.ActionLogout, models.EntityUser, user.ID, "Logged out")
+		}
 	}
 
-	c.SetSameSite(sameSite)
-	c.SetCookie("access_token", "", -1, "/", "", secure, true)
-	c.SetSameSite(sameSite)
-	c.SetCookie("refresh_token", "", -1, "/", "", secure, true)
+	clearAuthCookies(c)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
 
-// CreateInviteCode creates a new invite code for the current user's organization
-func (h *AuthHandler) CreateInviteCode(c *gin.Context) {
+// SessionResponse represents an active session/device in responses
+type SessionResponse struct {
+	ID         uint   `json:"id"`
+	UserAgent  string `json:"userAgent"`
+	IPAddress  string `json:"ipAddress"`
+	CreatedAt  string `json:"createdAt"`
+	LastUsedAt string `json:"lastUsedAt"`
+	ExpiresAt  string `json:"expiresAt"`
+	Current    bool   `json:"current"`
+}
+
+// GetSessions lists the current user's active sessions and devices
+func (h *AuthHandler) GetSessions(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
+	currentSessionID, _ := c.Get("session_id")
 
-	orgID, exists := c.Get("organization_id")
+	sessions, err := h.sessions.ListActive(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	response := make([]SessionResponse, len(sessions))
+	for i, session := range sessions {
+		response[i] = SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			LastUsedAt: session.LastUsedAt.Format("2006-01-02T15:04:05Z07:00"),
+			ExpiresAt:  session.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+			Current:    session.ID == currentSessionID,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeSession signs out one of the current user's sessions
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	// Create invite code that expires in 7 days
-	invite := &models.InviteCode{
-		OrganizationID: orgID.(uint),
-		Code:           generateInviteCode(),
-		CreatedByID:    userID.(uint),
-		ExpiresAt:      time.Now().AddDate(0, 0, 7),
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
 	}
 
-	if err := h.orgRepo.CreateInviteCode(invite); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invite code"})
+	if err := h.sessions.RevokeForUser(userID.(uint), uint(id)); err != nil {
+		if err == service.ErrSessionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"code":      invite.Code,
-		"expiresAt": invite.ExpiresAt,
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
 }
 
-// GetInviteCodes returns all invite codes for the current user's organization
-func (h *AuthHandler) GetInviteCodes(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
+// RevokeOtherSessions signs out every session of the current user except this one
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
+	currentSessionID, _ := c.Get("session_id")
 
-	invites, err := h.orgRepo.GetInviteCodesByOrganization(orgID.(uint))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invite codes"})
+	if err := h.sessions.RevokeAllForUser(userID.(uint), currentSessionID.(uint), models.SessionRevokedByUser); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
 		return
 	}
 
-	c.JSON(http.StatusOK, invites)
+	c.JSON(http.StatusOK, gin.H{"message": "Other sessions revoked successfully"})
 }
 
 // UpdateProfileRequest represents the profile update request body
@@ -414,7 +620,8 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userRepo.FindByIDWithOrganization(userID.(uint))
+	orgID, _ := getOrganizationID(c)
+	user, err := h.userRepo.FindMember(userID.(uint), orgID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -423,7 +630,7 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	// Update name
 	user.Name = req.Name
 
-	if err := h.userRepo.Update(user); err != nil {
+	if err := h.userRepo.WithContext(c.Request.Context()).Update(user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
 		return
 	}
@@ -470,18 +677,20 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	}
 
 	// Hash and set new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
-	if err != nil {
+	if err := user.SetPassword(req.NewPassword); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
 		return
 	}
-	user.Password = string(hashedPassword)
 
-	if err := h.userRepo.Update(user); err != nil {
+	if err := h.userRepo.WithContext(c.Request.Context()).Update(user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
 	}
 
+	// Sign out every other device; the current session stays logged in
+	currentSessionID, _ := c.Get("session_id")
+	h.sessions.RevokeAllForUser(user.ID, currentSessionID.(uint), models.SessionRevokedPasswordChange)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
 }
 
@@ -503,13 +712,81 @@ func (h *AuthHandler) GetOrganization(c *gin.Context) {
 		"id":        org.ID,
 		"name":      org.Name,
 		"slug":      org.Slug,
+		"mfaPolicy": org.MFAPolicy,
+		"settings":  org.Settings,
 		"createdAt": org.CreatedAt,
 	})
 }
 
-// Helper function to set auth cookies
+// logAction records an account event; the actor is passed in because these flows run unauthenticated
+func (h *AuthHandler) logAction(c *gin.Context, actor *models.User, action, entity string, entityID uint, details string) {
+	log := &models.AuditLog{
+		OrganizationID: actor.OrganizationID,
+		UserID:         actor.ID,
+		UserEmail:      actor.Email,
+		UserName:       actor.Name,
+		Action:         action,
+		Entity:         entity,
+		EntityID:       entityID,
+		Details:        details,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+	h.auditLogRepo.Create(log)
+}
+
+// startSession starts a session for a fully authenticated user, sets the auth cookies
+// and records the successful login. It writes the error response itself and returns
+// false on failure.
+func startSession(c *gin.Context, sessions *service.SessionService, loginGuard *service.LoginGuard, user *models.User, authn service.SessionAuth) (*UserResponse, bool) {
+	tokens, err := sessions.Start(user, authn, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return nil, false
+	}
+	loginGuard.RecordSuccess(user, loginAttempt(c, user.Email))
+
+	// Set httpOnly cookies
+	setAuthCookies(c, tokens)
+
+	orgName := ""
+	if user.Organization != nil {
+		orgName = user.Organization.Name
+	}
+
+	return &UserResponse{
+		ID:               user.ID,
+		Email:            user.Email,
+		Name:             user.Name,
+		Role:             user.Role,
+		OrganizationID:   user.OrganizationID,
+		OrganizationName: orgName,
+		CSRFToken:        tokens.CSRFToken,
+	}, true
+}
+
+// loginAttempt describes the current request for login auditing
+func loginAttempt(c *gin.Context, email string) service.LoginAttempt {
+	return service.LoginAttempt{
+		Email:     email,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// respondLocked tells the client how long to wait before the next login attempt
+func respondLocked(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":      "Too many failed login attempts, please try again later",
+		"retryAfter": seconds,
+	})
+}
+
+// Helper function to set auth cookies and hand out the session's CSRF token
 // In production (GIN_MODE=release), sets Secure=true and SameSite=None for cross-origin cookies
-func setAuthCookies(c *gin.Context, accessToken, refreshToken string) {
+func setAuthCookies(c *gin.Context, tokens *service.TokenPair) {
 	isProduction := os.Getenv("GIN_MODE") == "release"
 
 	// Cookie settings based on environment
@@ -523,7 +800,7 @@ func setAuthCookies(c *gin.Context, accessToken, refreshToken string) {
 	c.SetSameSite(sameSite)
 	c.SetCookie(
 		"access_token",
-		accessToken,
+		tokens.AccessToken,
 		int(auth.AccessTokenExpiry.Seconds()),
 		"/",
 		"",
@@ -535,11 +812,48 @@ func setAuthCookies(c *gin.Context, accessToken, refreshToken string) {
 	c.SetSameSite(sameSite)
 	c.SetCookie(
 		"refresh_token",
-		refreshToken,
+		tokens.RefreshToken,
 		int(auth.RefreshTokenExpiry.Seconds()),
 		"/",
 		"",
 		secure,
 		true, // httpOnly
 	)
+
+	// The CSRF token is meant to be read by the frontend
+	c.SetSameSite(sameSite)
+	c.SetCookie(auth.CSRFCookie, tokens.CSRFToken, int(auth.RefreshTokenExpiry.Seconds()), "/", "", secure, false)
+	c.Header(auth.CSRFHeader, tokens.CSRFToken)
+}
+
+// clearAuthCookies expires the auth and CSRF cookies with the same settings they were set with
+func clearAuthCookies(c *gin.Context) {
+	isProduction := os.Getenv("GIN_MODE") == "release"
+	secure := isProduction
+	sameSite := http.SameSiteLaxMode
+	if isProduction {
+		sameSite = http.SameSiteNoneMode
+	}
+
+	c.SetSameSite(sameSite)
+	c.SetCookie("access_token", "", -1, "/", "", secure, true)
+	c.SetSameSite(sameSite)
+	c.SetCookie("refresh_token", "", -1, "/", "", secure, true)
+	c.SetSameSite(sameSite)
+	c.SetCookie(auth.CSRFCookie, "", -1, "/", "", secure, false)
+}
+
+// sessionIDFromCookies reads the session ID from whichever auth cookie still verifies
+func sessionIDFromCookies(c *gin.Context) uint {
+	if refreshToken, err := c.Cookie("refresh_token"); err == nil {
+		if claims, err := auth.ValidateRefreshToken(refreshToken); err == nil {
+			return claims.SessionID
+		}
+	}
+	if accessToken, err := c.Cookie("access_token"); err == nil {
+		if claims, err := auth.ValidateAccessToken(accessToken); err == nil {
+			return claims.SessionID
+		}
+	}
+	return 0
 }
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"time"
 	"ventura/internal/repository"
 	"ventura/internal/service"
 
@@ -9,10 +10,11 @@ import (
 )
 
 type DashboardHandler struct {
-	portfolioRepo    *repository.PortfolioRepository
-	analytics        *service.AnalyticsService
-	aiInsight        *service.AIPortfolioInsightService
+	portfolioRepo     *repository.PortfolioRepository
+	analytics         *service.AnalyticsService
+	aiInsight         *service.AIPortfolioInsightService
 	monthlyUpdateRepo *repository.MonthlyUpdateRepository
+	organizations     *service.OrganizationService
 }
 
 func NewDashboardHandler(
@@ -20,32 +22,40 @@ func NewDashboardHandler(
 	analytics *service.AnalyticsService,
 	aiInsight *service.AIPortfolioInsightService,
 	monthlyUpdateRepo *repository.MonthlyUpdateRepository,
+	organizations *service.OrganizationService,
 ) *DashboardHandler {
 	return &DashboardHandler{
 		portfolioRepo:     portfolioRepo,
 		analytics:         analytics,
 		aiInsight:         aiInsight,
 		monthlyUpdateRepo: monthlyUpdateRepo,
+		organizations:     organizations,
 	}
 }
 
 // GetDashboard returns all dashboard metrics in one call
 func (h *DashboardHandler) GetDashboard(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
+	_, exists := c.Get("organization_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
 	}
 
-	companies, err := h.portfolioRepo.GetAllByOrganization(orgID.(uint))
+	companies, err := h.portfolioRepo.Within(companyScope(c)).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	metrics := h.analytics.GetDashboardMetrics(companies)
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
+		return
+	}
+
+	metrics := h.analytics.GetDashboardMetrics(companies, settings)
 
 	c.JSON(http.StatusOK, gin.H{
+		"baseCurrency": settings.BaseCurrency,
 		"aum": gin.H{
 			"totalDeployed":    metrics.TotalDeployed,
 			"currentValuation": metrics.CurrentValuation,
@@ -69,21 +79,27 @@ func (h *DashboardHandler) GetDashboard(c *gin.Context) {
 
 // GetAUM returns Assets Under Management metrics
 func (h *DashboardHandler) GetAUM(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
+	_, exists := c.Get("organization_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
 	}
 
-	companies, err := h.portfolioRepo.GetAllByOrganization(orgID.(uint))
+	companies, err := h.portfolioRepo.Within(companyScope(c)).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	metrics := h.analytics.GetDashboardMetrics(companies)
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
+		return
+	}
+
+	metrics := h.analytics.GetDashboardMetrics(companies, settings)
 
 	c.JSON(http.StatusOK, gin.H{
+		"baseCurrency":     settings.BaseCurrency,
 		"totalDeployed":    metrics.TotalDeployed,
 		"currentValuation": metrics.CurrentValuation,
 		"unrealizedGains":  metrics.UnrealizedGains,
@@ -92,21 +108,27 @@ func (h *DashboardHandler) GetAUM(c *gin.Context) {
 
 // GetPerformance returns performance metrics (IRR, MOIC)
 func (h *DashboardHandler) GetPerformance(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
+	_, exists := c.Get("organization_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
 	}
 
-	companies, err := h.portfolioRepo.GetAllByOrganization(orgID.(uint))
+	companies, err := h.portfolioRepo.Within(companyScope(c)).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	metrics := h.analytics.GetDashboardMetrics(companies)
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
+		return
+	}
+
+	metrics := h.analytics.GetDashboardMetrics(companies, settings)
 
 	c.JSON(http.StatusOK, gin.H{
+		"baseCurrency":  settings.BaseCurrency,
 		"irr":           metrics.IRR,
 		"moic":          metrics.MOIC,
 		"totalDeployed": metrics.TotalDeployed,
@@ -117,13 +139,13 @@ func (h *DashboardHandler) GetPerformance(c *gin.Context) {
 
 // GetSectors returns sector allocation
 func (h *DashboardHandler) GetSectors(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
+	_, exists := c.Get("organization_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
 	}
 
-	companies, err := h.portfolioRepo.GetAllByOrganization(orgID.(uint))
+	companies, err := h.portfolioRepo.Within(companyScope(c)).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -136,19 +158,24 @@ func (h *DashboardHandler) GetSectors(c *gin.Context) {
 
 // GetHealth returns portfolio health breakdown
 func (h *DashboardHandler) GetHealth(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
+	_, exists := c.Get("organization_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
 	}
 
-	companies, err := h.portfolioRepo.GetAllByOrganization(orgID.(uint))
+	companies, err := h.portfolioRepo.Within(companyScope(c)).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	health := h.analytics.GetPortfolioHealth(companies)
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
+		return
+	}
+
+	health := h.analytics.GetPortfolioHealth(companies, settings)
 
 	c.JSON(http.StatusOK, gin.H{
 		"green":  health.Green,
@@ -159,20 +186,25 @@ func (h *DashboardHandler) GetHealth(c *gin.Context) {
 
 // GetDashboardHistory returns historical metrics for charts
 func (h *DashboardHandler) GetDashboardHistory(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
+	_, exists := c.Get("organization_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
 	}
 
-	companies, err := h.portfolioRepo.GetAllByOrganization(orgID.(uint))
+	companies, err := h.portfolioRepo.Within(companyScope(c)).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
+		return
+	}
+
 	// Generate portfolio history based on investment dates
-	portfolioHistory := h.analytics.GetPortfolioHistory(companies)
+	portfolioHistory := h.analytics.GetPortfolioHistory(companies, settings)
 
 	// Generate investment timeline
 	investmentTimeline := h.analytics.GetInvestmentTimeline(companies)
@@ -189,13 +221,19 @@ func (h *DashboardHandler) GetDashboardHistory(c *gin.Context) {
 
 // GetMissingUpdates returns companies that haven't submitted updates for last month
 func (h *DashboardHandler) GetMissingUpdates(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
+	_, exists := c.Get("organization_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
 	}
 
-	missingUpdates, err := h.portfolioRepo.GetCompaniesWithMissingUpdatesByOrganization(orgID.(uint))
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
+		return
+	}
+
+	from, to := settings.LastReportingPeriod(time.Now())
+	missingUpdates, err := h.portfolioRepo.Within(companyScope(c)).GetCompaniesWithMissingUpdates(from, to)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -206,19 +244,19 @@ func (h *DashboardHandler) GetMissingUpdates(c *gin.Context) {
 
 // GetAIInsight returns an AI-generated summary of the portfolio
 func (h *DashboardHandler) GetAIInsight(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
+	_, exists := c.Get("organization_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
 	}
 
-	companies, err := h.portfolioRepo.GetAllByOrganization(orgID.(uint))
+	companies, err := h.portfolioRepo.Within(companyScope(c)).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	updates, err := h.monthlyUpdateRepo.GetAll()
+	updates, err := h.monthlyUpdateRepo.Within(companyScope(c)).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -15,16 +16,29 @@ import (
 type DealHandler struct {
 	dealRepo      *repository.DealRepository
 	portfolioRepo *repository.PortfolioRepository
-	aiDealScorer  *service.AIDealScorerService
+	stages        *service.DealStageService
+	scorecards    *service.ScorecardService
+	userRepo      *repository.UserRepository
 }
 
-func NewDealHandler(dealRepo *repository.DealRepository, portfolioRepo *repository.PortfolioRepository, aiDealScorer *service.AIDealScorerService) *DealHandler {
-	return &DealHandler{dealRepo: dealRepo, portfolioRepo: portfolioRepo, aiDealScorer: aiDealScorer}
+func NewDealHandler(dealRepo *repository.DealRepository, portfolioRepo *repository.PortfolioRepository, stages *service.DealStageService, scorecards *service.ScorecardService, userRepo *repository.UserRepository) *DealHandler {
+	return &DealHandler{
+		dealRepo:      dealRepo,
+		portfolioRepo: portfolioRepo,
+		stages:        stages,
+		scorecards:    scorecards,
+		userRepo:      userRepo,
+	}
+}
+
+// SetStageTransitionsRequest replaces the stage transitions the organization allows
+type SetStageTransitionsRequest struct {
+	Transitions []models.DealStageTransition `json:"transitions"`
 }
 
 // GetDeals returns all deals for the user's organization, optionally filtered by stage or archived status
 func (h *DealHandler) GetDeals(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
+	_, exists := c.Get("organization_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
@@ -38,14 +52,14 @@ func (h *DealHandler) GetDeals(c *gin.Context) {
 
 	switch {
 	case archived == "true":
-		deals, err = h.dealRepo.GetArchivedByOrganization(orgID.(uint))
+		deals, err = h.deals(c).GetArchived()
 	case archived == "false":
-		deals, err = h.dealRepo.GetActiveByOrganization(orgID.(uint))
+		deals, err = h.deals(c).GetActive()
 	case stage != "":
-		deals, err = h.dealRepo.GetByStageAndOrganization(models.DealStage(stage), orgID.(uint))
+		deals, err = h.deals(c).GetByStage(models.DealStage(stage))
 	default:
 		// By default, return only active deals
-		deals, err = h.dealRepo.GetActiveByOrganization(orgID.(uint))
+		deals, err = h.deals(c).GetActive()
 	}
 
 	if err != nil {
@@ -53,9 +67,10 @@ func (h *DealHandler) GetDeals(c *gin.Context) {
 		return
 	}
 
-	// Calculate total scores
-	for i := range deals {
-		deals[i].CalculateTotalScore()
+	// Average the reviewers' scores on the scorecard
+	if err := h.scorecards.Summarize(companyScope(c), deals); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusOK, deals)
@@ -78,18 +93,35 @@ func (h *DealHandler) CreateDeal(c *gin.Context) {
 	// Set organization ID from context
 	deal.OrganizationID = orgID.(uint)
 
-	if err := h.dealRepo.Create(&deal); err != nil {
+	// Deals enter the pipeline in an open stage; closing and losing go through their routes
+	if deal.Stage == "" {
+		deal.Stage = models.StageIncoming
+	}
+	if !deal.Stage.IsValid() || deal.Stage.IsTerminal() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New deals must start in an open stage"})
+		return
+	}
+
+	// The deal belongs to its creator unless another active member is named its owner
+	if deal.OwnerID == nil {
+		ownerID := c.GetUint("user_id")
+		deal.OwnerID = &ownerID
+	} else if _, err := h.userRepo.FindMember(*deal.OwnerID, deal.OrganizationID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Owner must be an active member of the organization"})
+		return
+	}
+
+	if _, err := h.stages.Create(c.Request.Context(), companyScope(c), &deal, c.GetUint("user_id")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	deal.CalculateTotalScore()
 	c.JSON(http.StatusCreated, deal)
 }
 
-// UpdateDealStage updates a deal's pipeline stage
+// UpdateDealStage moves a deal to another open stage, if the organization allows it
 func (h *DealHandler) UpdateDealStage(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
+	_, exists := c.Get("organization_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
@@ -103,7 +135,8 @@ func (h *DealHandler) UpdateDealStage(c *gin.Context) {
 	}
 
 	var input struct {
-		Stage models.DealStage `json:"stage" binding:"required"`
+		Stage   models.DealStage `json:"stage" binding:"required"`
+		Comment string           `json:"comment"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -111,19 +144,29 @@ func (h *DealHandler) UpdateDealStage(c *gin.Context) {
 		return
 	}
 
+	if input.Stage.IsTerminal() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use the close or lose route to finish a deal"})
+		return
+	}
+
 	// Verify deal belongs to organization
-	_, err = h.dealRepo.GetByIDAndOrganization(uint(id), orgID.(uint))
+	deal, err := h.deals(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
 		return
 	}
 
-	if err := h.dealRepo.UpdateStageByOrganization(uint(id), input.Stage, orgID.(uint)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	event, err := h.stages.Transition(c.Request.Context(), companyScope(c), deal, service.StageChange{
+		To:      input.Stage,
+		ActorID: c.GetUint("user_id"),
+		Comment: input.Comment,
+	})
+	if err != nil {
+		respondStageError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Stage updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Stage updated successfully", "event": event})
 }
 
 // CloseDeal closes a deal, optionally converting it to a portfolio company
@@ -142,14 +185,15 @@ func (h *DealHandler) CloseDeal(c *gin.Context) {
 	}
 
 	// Get the deal first
-	deal, err := h.dealRepo.GetByIDAndOrganization(uint(id), orgID.(uint))
+	deal, err := h.deals(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
 		return
 	}
 
 	var input struct {
-		ConvertToPortfolio bool `json:"convertToPortfolio"`
+		ConvertToPortfolio bool   `json:"convertToPortfolio"`
+		Comment            string `json:"comment"`
 		// Company data for conversion
 		AmountInvested  float64 `json:"amountInvested"`
 		CashRemaining   float64 `json:"cashRemaining"`
@@ -162,6 +206,19 @@ func (h *DealHandler) CloseDeal(c *gin.Context) {
 		return
 	}
 
+	// Check the move before creating anything for it
+	if err := h.stages.CanTransition(deal.OrganizationID, deal.Stage, models.StageClosed); err != nil {
+		respondStageError(c, err)
+		return
+	}
+
+	change := service.StageChange{
+		To:      models.StageClosed,
+		ActorID: c.GetUint("user_id"),
+		Comment: input.Comment,
+		Fields:  map[string]interface{}{"archived_at": time.Now()},
+	}
+
 	if input.ConvertToPortfolio {
 		// Create portfolio company from deal data
 		company := models.PortfolioCompany{
@@ -177,14 +234,17 @@ func (h *DealHandler) CloseDeal(c *gin.Context) {
 			InvestedAt:       time.Now(),
 		}
 
-		if err := h.portfolioRepo.Create(&company); err != nil {
+		companies := h.portfolioRepo.Within(companyScope(c)).WithContext(c.Request.Context())
+		if err := companies.Create(&company); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create company: " + err.Error()})
 			return
 		}
 
 		// Close deal and link to company
-		if err := h.dealRepo.CloseAndConvert(uint(id), company.ID, orgID.(uint)); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		change.Fields["converted_company_id"] = company.ID
+		if _, err := h.stages.Transition(c.Request.Context(), companyScope(c), deal, change); err != nil {
+			companies.Delete(company.ID)
+			respondStageError(c, err)
 			return
 		}
 
@@ -196,8 +256,8 @@ func (h *DealHandler) CloseDeal(c *gin.Context) {
 	}
 
 	// Just close the deal without conversion
-	if err := h.dealRepo.CloseDeal(uint(id), orgID.(uint)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if _, err := h.stages.Transition(c.Request.Context(), companyScope(c), deal, change); err != nil {
+		respondStageError(c, err)
 		return
 	}
 
@@ -206,7 +266,7 @@ func (h *DealHandler) CloseDeal(c *gin.Context) {
 
 // LoseDeal marks a deal as lost with a reason and archives it
 func (h *DealHandler) LoseDeal(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
+	_, exists := c.Get("organization_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
@@ -220,14 +280,15 @@ func (h *DealHandler) LoseDeal(c *gin.Context) {
 	}
 
 	// Verify deal exists
-	_, err = h.dealRepo.GetByIDAndOrganization(uint(id), orgID.(uint))
+	deal, err := h.deals(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
 		return
 	}
 
 	var input struct {
-		Reason string `json:"reason" binding:"required"`
+		Reason  string `json:"reason" binding:"required"`
+		Comment string `json:"comment"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -250,53 +311,103 @@ func (h *DealHandler) LoseDeal(c *gin.Context) {
 		return
 	}
 
-	if err := h.dealRepo.SetLossReasonAndArchive(uint(id), input.Reason, orgID.(uint)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	_, err = h.stages.Transition(c.Request.Context(), companyScope(c), deal, service.StageChange{
+		To:      models.StageLost,
+		ActorID: c.GetUint("user_id"),
+		Comment: input.Comment,
+		Fields: map[string]interface{}{
+			"loss_reason": input.Reason,
+			"archived_at": time.Now(),
+		},
+	})
+	if err != nil {
+		respondStageError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Deal marked as lost and archived"})
 }
 
-// AIScoreDeal analyzes a deal using AI
-func (h *DealHandler) AIScoreDeal(c *gin.Context) {
-	orgID, exists := c.Get("organization_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+// GetStageEvents returns a deal's stage history, oldest first
+func (h *DealHandler) GetStageEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
 
-	idParam := c.Param("id")
-	id, err := strconv.ParseUint(idParam, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+	if _, err := h.deals(c).GetByID(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
 		return
 	}
 
-	deal, err := h.dealRepo.GetByIDAndOrganization(uint(id), orgID.(uint))
+	events, err := h.stages.History(companyScope(c), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stage history"})
 		return
 	}
 
-	// Calculate and get AI score logic
-	result, err := h.aiDealScorer.ScoreDeal(c.Request.Context(), deal)
+	c.JSON(http.StatusOK, events)
+}
+
+// GetStageTransitions returns the stage transitions the organization allows
+func (h *DealHandler) GetStageTransitions(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	transitions, err := h.stages.Transitions(orgID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stage transitions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, transitions)
+}
+
+// SetStageTransitions replaces the stage transitions the organization allows; an empty
+// list restores the defaults
+func (h *DealHandler) SetStageTransitions(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
 	}
 
-	// Save the scores and AI insight to the deal
-	deal.TeamScore = result.TeamScore
-	deal.ProductScore = result.ProductScore
-	deal.MarketScore = result.MarketScore
-	deal.TractionScore = result.TractionScore
-	deal.Notes = deal.Notes + "\n\n--- AI Analysis ---\nSummary: " + result.Summary + "\nStrengths: " + result.KeyStrengths + "\nRisks: " + result.KeyRisks
-	
-	if err := h.dealRepo.Update(deal); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save AI scores: " + err.Error()})
+	var req SetStageTransitionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, deal)
+	transitions, err := h.stages.SetTransitions(c.Request.Context(), orgID, req.Transitions)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidStage) || errors.Is(err, service.ErrInvalidTransitions) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save stage transitions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, transitions)
+}
+
+// respondStageError writes the response for a deal that couldn't move to another stage
+func respondStageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidStage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTransitionNotAllowed), errors.Is(err, repository.ErrStageChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// deals returns the deal repository confined to the caller's organization and bound to the request
+func (h *DealHandler) deals(c *gin.Context) *repository.DealRepository {
+	return h.dealRepo.Within(companyScope(c)).WithContext(c.Request.Context())
 }
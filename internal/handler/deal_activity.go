@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+	"ventura/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DealActivityHandler serves the activities logged on deals and their timeline
+type DealActivityHandler struct {
+	dealRepo   *repository.DealRepository
+	activities *service.DealActivityService
+}
+
+func NewDealActivityHandler(dealRepo *repository.DealRepository, activities *service.DealActivityService) *DealActivityHandler {
+	return &DealActivityHandler{dealRepo: dealRepo, activities: activities}
+}
+
+// CreateActivityRequest logs a comment, call, meeting or email on a deal. The body may
+// @mention members as @[Name](userId).
+type CreateActivityRequest struct {
+	Type       models.ActivityType `json:"type" binding:"required"`
+	Subject    string              `json:"subject"`
+	Body       string              `json:"body" binding:"required"`
+	OccurredAt *time.Time          `json:"occurredAt"` // Defaults to now
+}
+
+// UpdateActivityRequest edits an activity's subject, body or date
+type UpdateActivityRequest struct {
+	Subject    string     `json:"subject"`
+	Body       string     `json:"body" binding:"required"`
+	OccurredAt *time.Time `json:"occurredAt"`
+}
+
+// GetTimeline returns a deal's activities, stage changes and AI analyses in chronological order
+func (h *DealActivityHandler) GetTimeline(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+
+	timeline, err := h.activities.Timeline(companyScope(c), deal.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeline"})
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+// CreateActivity logs an activity on a deal and notifies the members it mentions
+func (h *DealActivityHandler) CreateActivity(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+
+	var req CreateActivityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	activity, err := h.activities.Log(c.Request.Context(), companyScope(c), deal, c.GetUint("user_id"), service.ActivityInput{
+		Type:       req.Type,
+		Subject:    req.Subject,
+		Body:       req.Body,
+		OccurredAt: req.OccurredAt,
+	})
+	if err != nil {
+		respondActivityError(c, err, "Failed to log activity")
+		return
+	}
+
+	c.JSON(http.StatusCreated, activity)
+}
+
+// UpdateActivity edits an activity the caller wrote, keeping its previous version
+func (h *DealActivityHandler) UpdateActivity(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+	activityID, ok := activityIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateActivityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	activity, err := h.activities.Edit(c.Request.Context(), companyScope(c), deal, activityID, c.GetUint("user_id"), service.ActivityInput{
+		Subject:    req.Subject,
+		Body:       req.Body,
+		OccurredAt: req.OccurredAt,
+	})
+	if err != nil {
+		respondActivityError(c, err, "Failed to update activity")
+		return
+	}
+
+	c.JSON(http.StatusOK, activity)
+}
+
+// DeleteActivity deletes an activity the caller wrote
+func (h *DealActivityHandler) DeleteActivity(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+	activityID, ok := activityIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.activities.Delete(c.Request.Context(), companyScope(c), deal, activityID, c.GetUint("user_id")); err != nil {
+		respondActivityError(c, err, "Failed to delete activity")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Activity deleted successfully"})
+}
+
+// GetActivityRevisions returns the earlier versions of an activity, oldest first
+func (h *DealActivityHandler) GetActivityRevisions(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+	activityID, ok := activityIDParam(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.activities.Revisions(companyScope(c), deal, activityID)
+	if err != nil {
+		respondActivityError(c, err, "Failed to fetch activity history")
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// findDeal loads the deal of the :id parameter from the caller's organization. It writes
+// the error response and returns false if there is none.
+func (h *DealActivityHandler) findDeal(c *gin.Context) (*models.Deal, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	deal, err := h.dealRepo.Within(companyScope(c)).GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		return nil, false
+	}
+	return deal, true
+}
+
+func activityIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("activityId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid activity ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondActivityError writes the response for an error of the activity service
+func respondActivityError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Activity not found"})
+	case errors.Is(err, service.ErrInvalidActivityType), errors.Is(err, service.ErrEmptyActivity):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotActivityAuthor), errors.Is(err, service.ErrNotMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+	"ventura/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DealAnalysisHandler serves the AI analyses of deals and their review
+type DealAnalysisHandler struct {
+	dealRepo *repository.DealRepository
+	analyses *service.DealAnalysisService
+}
+
+func NewDealAnalysisHandler(dealRepo *repository.DealRepository, analyses *service.DealAnalysisService) *DealAnalysisHandler {
+	return &DealAnalysisHandler{dealRepo: dealRepo, analyses: analyses}
+}
+
+// AnalyzeDeal has the AI analyze a deal and returns the analysis. Its scores are only
+// suggested: they reach the deal when an analyst accepts them.
+func (h *DealAnalysisHandler) AnalyzeDeal(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+
+	analysis, err := h.analyses.Analyze(c.Request.Context(), companyScope(c), deal, c.GetUint("user_id"))
+	if err != nil {
+		respondAnalysisError(c, err, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, analysis)
+}
+
+// GetAnalyses returns a deal's AI analyses, newest first
+func (h *DealAnalysisHandler) GetAnalyses(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+
+	analyses, err := h.analyses.History(companyScope(c), deal.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch analyses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, analyses)
+}
+
+// GetAnalysis returns one of a deal's AI analyses
+func (h *DealAnalysisHandler) GetAnalysis(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+	analysisID, ok := analysisIDParam(c)
+	if !ok {
+		return
+	}
+
+	analysis, err := h.analyses.Get(companyScope(c), deal, analysisID)
+	if err != nil {
+		respondAnalysisError(c, err, "Failed to fetch analysis")
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+// AcceptAnalysis makes a pending analysis's scores the deal's AI scores
+func (h *DealAnalysisHandler) AcceptAnalysis(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+	analysisID, ok := analysisIDParam(c)
+	if !ok {
+		return
+	}
+
+	analysis, err := h.analyses.Accept(c.Request.Context(), companyScope(c), deal, analysisID, c.GetUint("user_id"))
+	if err != nil {
+		respondAnalysisError(c, err, "Failed to accept analysis")
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+// RejectAnalysis dismisses a pending analysis's scores
+func (h *DealAnalysisHandler) RejectAnalysis(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+	analysisID, ok := analysisIDParam(c)
+	if !ok {
+		return
+	}
+
+	analysis, err := h.analyses.Reject(c.Request.Context(), companyScope(c), deal, analysisID, c.GetUint("user_id"))
+	if err != nil {
+		respondAnalysisError(c, err, "Failed to reject analysis")
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+// findDeal loads the deal of the :id parameter from the caller's organization. It writes
+// the error response and returns false if there is none.
+func (h *DealAnalysisHandler) findDeal(c *gin.Context) (*models.Deal, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	deal, err := h.dealRepo.Within(companyScope(c)).GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		return nil, false
+	}
+	return deal, true
+}
+
+func analysisIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("analysisId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid analysis ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondAnalysisError writes the response for an error of the analysis service
+func respondAnalysisError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+	case errors.Is(err, repository.ErrAnalysisReviewed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -5,25 +5,26 @@ import (
 	"strconv"
 	"ventura/internal/models"
 	"ventura/internal/repository"
+	"ventura/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 type FounderHandler struct {
-	founderRepo   *repository.FounderRepository
-	portfolioRepo *repository.PortfolioRepository
+	founderRepo *repository.FounderRepository
+	access      *service.CompanyAccessService
 }
 
-func NewFounderHandler(founderRepo *repository.FounderRepository, portfolioRepo *repository.PortfolioRepository) *FounderHandler {
+func NewFounderHandler(founderRepo *repository.FounderRepository, access *service.CompanyAccessService) *FounderHandler {
 	return &FounderHandler{
-		founderRepo:   founderRepo,
-		portfolioRepo: portfolioRepo,
+		founderRepo: founderRepo,
+		access:      access,
 	}
 }
 
-// GetFounders returns all founders
+// GetFounders returns the founders of every company the caller can see
 func (h *FounderHandler) GetFounders(c *gin.Context) {
-	founders, err := h.founderRepo.GetAll()
+	founders, err := h.founders(c).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -40,7 +41,7 @@ func (h *FounderHandler) GetFounder(c *gin.Context) {
 		return
 	}
 
-	founder, err := h.founderRepo.GetByID(uint(id))
+	founder, err := h.founders(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Founder not found"})
 		return
@@ -58,14 +59,13 @@ func (h *FounderHandler) GetFoundersByCompany(c *gin.Context) {
 		return
 	}
 
-	// Verify company exists
-	_, err = h.portfolioRepo.GetByID(uint(companyID))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+	// Verify company exists and is visible
+	if _, err := h.access.View(companyScope(c), uint(companyID)); err != nil {
+		respondCompanyError(c, err)
 		return
 	}
 
-	founders, err := h.founderRepo.GetByCompanyID(uint(companyID))
+	founders, err := h.founders(c).GetByCompanyID(uint(companyID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -83,10 +83,9 @@ func (h *FounderHandler) CreateFounder(c *gin.Context) {
 		return
 	}
 
-	// Verify company exists
-	_, err = h.portfolioRepo.GetByID(uint(companyID))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+	// Verify company exists and may be edited
+	if _, err := h.access.Edit(companyScope(c), uint(companyID)); err != nil {
+		respondCompanyError(c, err)
 		return
 	}
 
@@ -98,7 +97,7 @@ func (h *FounderHandler) CreateFounder(c *gin.Context) {
 
 	founder.CompanyID = uint(companyID)
 
-	if err := h.founderRepo.Create(&founder); err != nil {
+	if err := h.founders(c).Create(&founder); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -116,12 +115,16 @@ func (h *FounderHandler) UpdateFounder(c *gin.Context) {
 	}
 
 	// Get existing founder
-	existing, err := h.founderRepo.GetByID(uint(id))
+	existing, err := h.founders(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Founder not found"})
 		return
 	}
 
+	if !h.canEdit(c, existing) {
+		return
+	}
+
 	// Bind updated fields
 	var updates models.Founder
 	if err := c.ShouldBindJSON(&updates); err != nil {
@@ -135,7 +138,7 @@ func (h *FounderHandler) UpdateFounder(c *gin.Context) {
 	existing.Role = updates.Role
 	existing.LinkedInURL = updates.LinkedInURL
 
-	if err := h.founderRepo.Update(existing); err != nil {
+	if err := h.founders(c).Update(existing); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -153,16 +156,39 @@ func (h *FounderHandler) DeleteFounder(c *gin.Context) {
 	}
 
 	// Verify founder exists
-	_, err = h.founderRepo.GetByID(uint(id))
+	founder, err := h.founders(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Founder not found"})
 		return
 	}
 
-	if err := h.founderRepo.Delete(uint(id)); err != nil {
+	if !h.canEdit(c, founder) {
+		return
+	}
+
+	if err := h.founders(c).Delete(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Founder deleted successfully"})
 }
+
+// founders returns the founder repository confined to the caller's companies and bound to the request
+func (h *FounderHandler) founders(c *gin.Context) *repository.FounderRepository {
+	return h.founderRepo.Within(companyScope(c)).WithContext(c.Request.Context())
+}
+
+// canEdit checks the caller may edit the founder's company, writing the error response if not
+func (h *FounderHandler) canEdit(c *gin.Context, founder *models.Founder) bool {
+	_, err := h.access.Edit(companyScope(c), founder.CompanyID)
+	switch err {
+	case nil:
+		return true
+	case service.ErrCompanyNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Founder not found"})
+	default:
+		respondCompanyError(c, err)
+	}
+	return false
+}
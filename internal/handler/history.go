@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+	"ventura/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HistoryHandler serves the change history of companies, deals, founders and monthly updates
+type HistoryHandler struct {
+	history       *service.HistoryService
+	portfolioRepo *repository.PortfolioRepository
+	dealRepo      *repository.DealRepository
+	founderRepo   *repository.FounderRepository
+	updateRepo    *repository.MonthlyUpdateRepository
+	access        *service.CompanyAccessService
+	organizations *service.OrganizationService
+}
+
+func NewHistoryHandler(history *service.HistoryService, portfolioRepo *repository.PortfolioRepository, dealRepo *repository.DealRepository, founderRepo *repository.FounderRepository, updateRepo *repository.MonthlyUpdateRepository, access *service.CompanyAccessService, organizations *service.OrganizationService) *HistoryHandler {
+	return &HistoryHandler{
+		history:       history,
+		portfolioRepo: portfolioRepo,
+		dealRepo:      dealRepo,
+		founderRepo:   founderRepo,
+		updateRepo:    updateRepo,
+		access:        access,
+		organizations: organizations,
+	}
+}
+
+// GetCompanyHistory returns a company's changes, or the company as it was at ?asOf=
+func (h *HistoryHandler) GetCompanyHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+
+	company, err := h.access.View(companyScope(c), uint(id))
+	if err != nil {
+		respondCompanyError(c, err)
+		return
+	}
+
+	if h.respond(c, models.EntityCompany, company.ID, company) {
+		settings, ok := organizationSettings(c, h.organizations)
+		if !ok {
+			return
+		}
+		company.CalculateHealthStatus(settings)
+		c.JSON(http.StatusOK, gin.H{"asOf": c.Query("asOf"), "company": company})
+	}
+}
+
+// GetDealHistory returns a deal's changes, or the deal as it was at ?asOf=
+func (h *HistoryHandler) GetDealHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deal ID"})
+		return
+	}
+
+	deal, err := h.dealRepo.Within(companyScope(c)).GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		return
+	}
+
+	if h.respond(c, models.EntityDeal, deal.ID, deal) {
+		c.JSON(http.StatusOK, gin.H{"asOf": c.Query("asOf"), "deal": deal})
+	}
+}
+
+// GetFounderHistory returns a founder's changes, or the founder as they were at ?asOf=
+func (h *HistoryHandler) GetFounderHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid founder ID"})
+		return
+	}
+
+	founder, err := h.founderRepo.Within(companyScope(c)).GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Founder not found"})
+		return
+	}
+
+	if h.respond(c, models.EntityFounder, founder.ID, founder) {
+		c.JSON(http.StatusOK, gin.H{"asOf": c.Query("asOf"), "founder": founder})
+	}
+}
+
+// GetMonthlyUpdateHistory returns a monthly update's changes, or the update as it was at ?asOf=
+func (h *HistoryHandler) GetMonthlyUpdateHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid update ID"})
+		return
+	}
+
+	update, err := h.updateRepo.Within(companyScope(c)).GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Monthly update not found"})
+		return
+	}
+
+	if h.respond(c, models.EntityMonthlyUpdate, update.ID, update) {
+		c.JSON(http.StatusOK, gin.H{"asOf": c.Query("asOf"), "monthlyUpdate": update})
+	}
+}
+
+// respond answers with the record's history, unless ?asOf= asks for a past version: then
+// record is rewound to it and true is returned for the caller to send it
+func (h *HistoryHandler) respond(c *gin.Context, entity string, id uint, record interface{}) bool {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return false
+	}
+
+	value := c.Query("asOf")
+	if value == "" {
+		history, err := h.history.History(orgID, entity, id, record)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
+			return false
+		}
+		c.JSON(http.StatusOK, gin.H{"entity": entity, "entityId": id, "history": history})
+		return false
+	}
+
+	asOf, dateOnly, err := parseAuditDate(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid asOf date"})
+		return false
+	}
+	if dateOnly {
+		// A date means the end of that day (UTC), e.g. a quarter end
+		asOf = asOf.AddDate(0, 0, 1).Add(-time.Microsecond)
+	}
+
+	if err := h.history.AsOf(orgID, entity, id, record, asOf); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotYetCreated):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Did not exist on " + value})
+		case errors.Is(err, service.ErrHistoryArchived):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconstruct history"})
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,342 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+	"ventura/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvitationHandler struct {
+	invitations  *service.InvitationService
+	permissions  *service.PermissionService
+	access       *service.CompanyAccessService
+	userRepo     *repository.UserRepository
+	auditLogRepo *repository.AuditLogRepository
+}
+
+func NewInvitationHandler(invitations *service.InvitationService, permissions *service.PermissionService, access *service.CompanyAccessService, userRepo *repository.UserRepository, auditLogRepo *repository.AuditLogRepository) *InvitationHandler {
+	return &InvitationHandler{
+		invitations:  invitations,
+		permissions:  permissions,
+		access:       access,
+		userRepo:     userRepo,
+		auditLogRepo: auditLogRepo,
+	}
+}
+
+// CreateInvitationRequest represents the create invitation request body
+type CreateInvitationRequest struct {
+	Email           string                            `json:"email" binding:"omitempty,email"` // Optional: only this address may accept, and it is emailed the link
+	Role            models.UserRole                   `json:"role"`                            // Built-in or custom role key, defaults to viewer
+	ExpiresInDays   int                               `json:"expiresInDays" binding:"min=0,max=30"`
+	MaxUses         int                               `json:"maxUses" binding:"min=0,max=500"` // Defaults to 1
+	TeamAssignments []models.InvitationTeamAssignment `json:"teamAssignments"`
+}
+
+// ResendInvitationRequest represents the resend invitation request body
+type ResendInvitationRequest struct {
+	ExpiresInDays int `json:"expiresInDays" binding:"min=0,max=30"`
+}
+
+// AcceptInvitationRequest represents the accept invitation request body
+type AcceptInvitationRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// InvitationResponse represents an invitation in admin responses
+type InvitationResponse struct {
+	ID              uint                              `json:"id"`
+	Code            string                            `json:"code"`
+	Email           string                            `json:"email,omitempty"`
+	Role            models.UserRole                   `json:"role"`
+	Status          string                            `json:"status"` // pending, used, expired or revoked
+	MaxUses         int                               `json:"maxUses"`
+	UseCount        int                               `json:"useCount"`
+	TeamAssignments []models.InvitationTeamAssignment `json:"teamAssignments"`
+	CreatedBy       string                            `json:"createdBy,omitempty"`
+	ExpiresAt       string                            `json:"expiresAt"`
+	LastSentAt      *time.Time                        `json:"lastSentAt,omitempty"`
+	CreatedAt       string                            `json:"createdAt"`
+}
+
+func toInvitationResponse(invitation *models.Invitation) InvitationResponse {
+	response := InvitationResponse{
+		ID:              invitation.ID,
+		Code:            invitation.Code,
+		Email:           invitation.Email,
+		Role:            invitation.Role,
+		Status:          invitation.Status(),
+		MaxUses:         invitation.MaxUses,
+		UseCount:        invitation.UseCount,
+		TeamAssignments: invitation.TeamAssignments,
+		ExpiresAt:       invitation.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+		LastSentAt:      invitation.LastSentAt,
+		CreatedAt:       invitation.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if response.TeamAssignments == nil {
+		response.TeamAssignments = []models.InvitationTeamAssignment{}
+	}
+	if invitation.CreatedBy != nil {
+		response.CreatedBy = invitation.CreatedBy.Name
+	}
+	return response
+}
+
+// GetInvitations returns the organization's invitations
+func (h *InvitationHandler) GetInvitations(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	invitations, err := h.invitations.List(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	response := make([]InvitationResponse, len(invitations))
+	for i := range invitations {
+		response[i] = toInvitationResponse(&invitations[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateInvitation creates an invitation and emails it when an address is given
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+	userID, _ := c.Get("user_id")
+
+	var req CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.RoleViewer
+	}
+	if err := h.permissions.CanAssignRole(orgID, role, currentPermissions(c)); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	// Team assignments follow the same rules as adding a team member directly
+	if len(req.TeamAssignments) > 0 && !models.HasPermission(currentPermissions(c), models.PermTeamManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions: team.manage is required"})
+		return
+	}
+	for _, assignment := range req.TeamAssignments {
+		if _, err := h.access.Edit(companyScope(c), assignment.CompanyID); err != nil {
+			respondCompanyError(c, err)
+			return
+		}
+	}
+
+	invitation, err := h.invitations.Create(c.Request.Context(), service.InvitationInput{
+		OrganizationID:  orgID,
+		CreatedByID:     userID.(uint),
+		Email:           req.Email,
+		Role:            role,
+		ExpiresIn:       time.Duration(req.ExpiresInDays) * 24 * time.Hour,
+		MaxUses:         req.MaxUses,
+		TeamAssignments: req.TeamAssignments,
+	}, h.actorName(c))
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	// Log the action
+	details := fmt.Sprintf("Created invitation %d as %s for up to %d members", invitation.ID, invitation.Role, invitation.MaxUses)
+	if invitation.Email != "" {
+		details = fmt.Sprintf("Invited %s as %s", invitation.Email, invitation.Role)
+	}
+	h.logAction(c, models.ActionInvite, models.EntityInvitation, invitation.ID, details)
+
+	c.JSON(http.StatusCreated, toInvitationResponse(invitation))
+}
+
+// RevokeInvitation stops an invitation from being accepted
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	invitation, err := h.invitations.Revoke(c.Request.Context(), orgID, uint(id))
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	// Log the action
+	h.logAction(c, models.ActionRevoke, models.EntityInvitation, invitation.ID, fmt.Sprintf("Revoked invitation %d", invitation.ID))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// ResendInvitation emails an invitation again with a fresh expiry
+func (h *InvitationHandler) ResendInvitation(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	// The body is optional
+	var req ResendInvitationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	invitation, err := h.invitations.Resend(c.Request.Context(), orgID, uint(id), time.Duration(req.ExpiresInDays)*24*time.Hour, h.actorName(c))
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	// Log the action
+	h.logAction(c, models.ActionInvite, models.EntityInvitation, invitation.ID, "Resent invitation to "+invitation.Email)
+
+	c.JSON(http.StatusOK, toInvitationResponse(invitation))
+}
+
+// GetInvitation shows what an invite code is for, so the invitee can decide whether
+// to register or sign in and accept
+func (h *InvitationHandler) GetInvitation(c *gin.Context) {
+	invitation, err := h.invitations.Lookup(c.Param("code"))
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organizationName": invitation.Organization.Name,
+		"email":            invitation.Email,
+		"role":             invitation.Role,
+		"expiresAt":        invitation.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+	})
+}
+
+// AcceptInvitation adds the signed-in user to the organization of an invitation.
+// People without an account use the code when registering instead.
+func (h *InvitationHandler) AcceptInvitation(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userRepo.FindByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	invitation, err := h.invitations.Accept(c.Request.Context(), req.Code, user)
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	// Log the action
+	h.logAction(c, models.ActionJoin, models.EntityOrganization, invitation.OrganizationID,
+		fmt.Sprintf("Accepted invitation %d as %s", invitation.ID, invitation.Role))
+
+	c.JSON(http.StatusCreated, MembershipResponse{
+		OrganizationID: invitation.OrganizationID,
+		Name:           invitation.Organization.Name,
+		Slug:           invitation.Organization.Slug,
+		Role:           invitation.Role,
+		JoinedAt:       time.Now().Format("2006-01-02T15:04:05Z07:00"),
+	})
+}
+
+// respondInvitationError maps invitation errors to HTTP responses
+func respondInvitationError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrInvitationNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case service.ErrInvitationExpired, service.ErrInvitationRevoked, service.ErrInvitationUsed:
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case service.ErrInvitationEmailMismatch, service.ErrNotMember:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case service.ErrAlreadyMember:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case service.ErrInvalidInvitation, service.ErrInvalidTeamRole, service.ErrInvitationNoEmail:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process invitation"})
+	}
+}
+
+// actorName is how invitation emails refer to the current user
+func (h *InvitationHandler) actorName(c *gin.Context) string {
+	userID, _ := c.Get("user_id")
+	if user, err := h.userRepo.FindByID(userID.(uint)); err == nil {
+		return user.Name
+	}
+	return "A colleague"
+}
+
+// Helper function to log audit actions
+func (h *InvitationHandler) logAction(c *gin.Context, action, entity string, entityID uint, details string) {
+	userID, _ := c.Get("user_id")
+	userEmail, _ := c.Get("user_email")
+	userName := ""
+	if user, err := h.userRepo.FindByID(userID.(uint)); err == nil {
+		userName = user.Name
+	}
+
+	// Joining or entering an organization is recorded in that organization's log
+	orgID := c.GetUint("organization_id")
+	if entity == models.EntityOrganization {
+		orgID = entityID
+	}
+
+	log := &models.AuditLog{
+		OrganizationID: orgID,
+		UserID:         userID.(uint),
+		UserEmail:      userEmail.(string),
+		UserName:       userName,
+		Action:         action,
+		Entity:         entity,
+		EntityID:       entityID,
+		Details:        details,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+	h.auditLogRepo.Create(log)
+}
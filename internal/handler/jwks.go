@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"net/http"
+	"ventura/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS publishes the public signing keys so other services can verify tokens
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.PublicJWKS())
+}
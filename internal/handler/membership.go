@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"net/http"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+	"ventura/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MembershipHandler struct {
+	memberships  *service.MembershipService
+	sessions     *service.SessionService
+	mfa          *service.MFAService
+	sso          *service.SSOService
+	loginGuard   *service.LoginGuard
+	userRepo     *repository.UserRepository
+	auditLogRepo *repository.AuditLogRepository
+}
+
+func NewMembershipHandler(memberships *service.MembershipService, sessions *service.SessionService, mfa *service.MFAService, sso *service.SSOService, loginGuard *service.LoginGuard, userRepo *repository.UserRepository, auditLogRepo *repository.AuditLogRepository) *MembershipHandler {
+	return &MembershipHandler{
+		memberships:  memberships,
+		sessions:     sessions,
+		mfa:          mfa,
+		sso:          sso,
+		loginGuard:   loginGuard,
+		userRepo:     userRepo,
+		auditLogRepo: auditLogRepo,
+	}
+}
+
+// MembershipResponse represents one of the caller's organizations
+type MembershipResponse struct {
+	OrganizationID uint            `json:"organizationId"`
+	Name           string          `json:"name"`
+	Slug           string          `json:"slug"`
+	Role           models.UserRole `json:"role"`
+	Current        bool            `json:"current"` // The organization of this session
+	Default        bool            `json:"default"` // Where the next login lands
+	JoinedAt       string          `json:"joinedAt"`
+}
+
+// SwitchOrganizationRequest represents the switch organization request body. Entering an
+// organization is a sign-in to it, so the password, and the second factor of users who
+// have one, are asked again.
+type SwitchOrganizationRequest struct {
+	OrganizationID uint   `json:"organizationId" binding:"required"`
+	Password       string `json:"password"`
+	Code           string `json:"code"` // TOTP or recovery code
+}
+
+// GetOrganizations lists the organizations the current user belongs to
+func (h *MembershipHandler) GetOrganizations(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentOrgID, _ := getOrganizationID(c)
+
+	user, err := h.userRepo.FindByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	memberships, err := h.memberships.List(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizations"})
+		return
+	}
+
+	response := make([]MembershipResponse, len(memberships))
+	for i, membership := range memberships {
+		response[i] = MembershipResponse{
+			OrganizationID: membership.OrganizationID,
+			Role:           membership.Role,
+			Current:        membership.OrganizationID == currentOrgID,
+			Default:        membership.OrganizationID == user.OrganizationID,
+			JoinedAt:       membership.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+		if membership.Organization != nil {
+			response[i].Name = membership.Organization.Name
+			response[i].Slug = membership.Organization.Slug
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SwitchOrganization moves the caller into another of their organizations. The current
+// session is replaced by one whose tokens carry the new organization and role, and the
+// organization becomes the default for the next login. The session the caller has was
+// authenticated for its own organization only: a login through one organization's identity
+// provider must not open another, so the caller signs in to the new one with their password
+// and second factor, or through its own single sign-on if it doesn't allow passwords.
+func (h *MembershipHandler) SwitchOrganization(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+
+	var req SwitchOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.memberships.Member(userID.(uint), req.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	if h.sso.PasswordLoginDisabled(member) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":       "This organization signs in with single sign-on",
+			"ssoRequired": true,
+			"ssoPath":     "/auth/sso/" + member.Organization.Slug + "/start",
+		})
+		return
+	}
+
+	// Each organization's MFA policy applies when entering it, not only at login
+	if !member.MFAEnabled && h.mfa.IsRequired(member) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":            "This organization requires two-factor authentication, enable it before switching",
+			"mfaSetupRequired": true,
+		})
+		return
+	}
+
+	attempt := loginAttempt(c, member.Email)
+	if retryAfter := h.loginGuard.RetryAfter(member); retryAfter > 0 {
+		h.loginGuard.RecordFailure(member, attempt, service.LoginFailureLocked)
+		respondLocked(c, retryAfter)
+		return
+	}
+	if req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Enter your password to switch organizations", "passwordRequired": true})
+		return
+	}
+	if !member.CheckPassword(req.Password) {
+		h.loginGuard.RecordFailure(member, attempt, service.LoginFailureInvalidPassword)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+		return
+	}
+	if member.MFAEnabled {
+		if req.Code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Enter your authentication code to switch organizations", "mfaRequired": true})
+			return
+		}
+		usedRecoveryCode, err := h.mfa.Verify(member, req.Code)
+		if err != nil {
+			h.loginGuard.RecordFailure(member, attempt, service.LoginFailureInvalidMFACode)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+			return
+		}
+		if usedRecoveryCode {
+			h.logAction(c, models.ActionMFARecoveryCodeUse, models.EntityUser, member.ID, "Switched organization with a recovery code")
+		}
+	}
+
+	authn := service.SessionAuth{Method: models.SessionAuthPassword, MFA: member.MFAEnabled}
+	tokens, err := h.sessions.SwitchOrganization(sessionID.(uint), member, authn, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+		return
+	}
+	h.loginGuard.RecordSuccess(member, attempt)
+	h.userRepo.SetDefaultOrganization(member.ID, member.OrganizationID)
+
+	setAuthCookies(c, tokens)
+
+	// Log the action
+	h.logAction(c, models.ActionOrgSwitch, models.EntityOrganization, member.OrganizationID, "Switched to organization: "+member.Organization.Name)
+
+	c.JSON(http.StatusOK, UserResponse{
+		ID:               member.ID,
+		Email:            member.Email,
+		Name:             member.Name,
+		Role:             member.Role,
+		OrganizationID:   member.OrganizationID,
+		OrganizationName: member.Organization.Name,
+		CSRFToken:        tokens.CSRFToken,
+	})
+}
+
+// Helper function to log audit actions
+func (h *MembershipHandler) logAction(c *gin.Context, action, entity string, entityID uint, details string) {
+	userID, _ := c.Get("user_id")
+	userEmail, _ := c.Get("user_email")
+	userName := ""
+	if user, err := h.userRepo.FindByID(userID.(uint)); err == nil {
+		userName = user.Name
+	}
+
+	// Joining or entering an organization is recorded in that organization's log
+	orgID := c.GetUint("organization_id")
+	if entity == models.EntityOrganization {
+		orgID = entityID
+	}
+
+	log := &models.AuditLog{
+		OrganizationID: orgID,
+		UserID:         userID.(uint),
+		UserEmail:      userEmail.(string),
+		UserName:       userName,
+		Action:         action,
+		Entity:         entity,
+		EntityID:       entityID,
+		Details:        details,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+	h.auditLogRepo.Create(log)
+}
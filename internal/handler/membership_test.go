@@ -0,0 +1,146 @@
+package handler_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"ventura/internal/auth"
+	"ventura/internal/di"
+	"ventura/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// totpCode returns the code an authenticator app shows for secret at the current time
+func totpCode(t *testing.T, secret string) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+// loadTestKeys lets handlers that start sessions sign tokens
+func loadTestKeys(t *testing.T) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret")
+	if err := auth.LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newSwitchRouter serves the organization switch for user, signed in to session
+func newSwitchRouter(db *gorm.DB, user models.User, session models.Session) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	c := di.NewContainer(db)
+
+	r := gin.New()
+	r.Use(func(ctx *gin.Context) {
+		ctx.Set("user_id", user.ID)
+		ctx.Set("organization_id", session.OrganizationID)
+		ctx.Set("user_email", user.Email)
+		ctx.Set("session_id", session.ID)
+	})
+	r.POST("/auth/organizations/switch", c.MembershipHandler.SwitchOrganization)
+	return r
+}
+
+func TestSwitchOrganizationSignsInAgain(t *testing.T) {
+	loadTestKeys(t)
+	db := newTestDB(t)
+	acme := newTenant(t, db, "Acme")
+	globex := newTenant(t, db, "Globex")
+
+	// The member signed in to Globex through its identity provider, which Globex's admins
+	// control; that mustn't open their Acme membership
+	user := models.User{OrganizationID: globex.org.ID, Email: "member@example.com", Password: "correct horse", Name: "Member", Role: models.RoleViewer, MFAEnabled: true, TOTPSecret: testTOTPSecret}
+	mustCreate(t, db, &user)
+	mustCreate(t, db, &models.Membership{UserID: user.ID, OrganizationID: globex.org.ID, Role: models.RoleViewer})
+	mustCreate(t, db, &models.Membership{UserID: user.ID, OrganizationID: acme.org.ID, Role: models.RoleEditor})
+	session := models.Session{UserID: user.ID, OrganizationID: globex.org.ID, AuthMethod: models.SessionAuthSSO, TokenID: "t", CSRFToken: "c", ExpiresAt: time.Now().Add(time.Hour)}
+	mustCreate(t, db, &session)
+
+	r := newSwitchRouter(db, user, session)
+	switchTo := func(body string) int {
+		return serve(r, http.MethodPost, "/auth/organizations/switch", body).Code
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"without a password", fmt.Sprintf(`{"organizationId":%d}`, acme.org.ID), http.StatusBadRequest},
+		{"with a wrong password", fmt.Sprintf(`{"organizationId":%d,"password":"wrong","code":"%s"}`, acme.org.ID, totpCode(t, testTOTPSecret)), http.StatusUnauthorized},
+		{"without the second factor", fmt.Sprintf(`{"organizationId":%d,"password":"correct horse"}`, acme.org.ID), http.StatusBadRequest},
+		{"with a wrong code", fmt.Sprintf(`{"organizationId":%d,"password":"correct horse","code":"000000"}`, acme.org.ID), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if code := switchTo(tt.body); code != tt.want {
+			t.Errorf("switch %s: status = %d, want %d", tt.name, code, tt.want)
+		}
+	}
+	var active int64
+	db.Model(&models.Session{}).Where("user_id = ? AND organization_id = ?", user.ID, acme.org.ID).Count(&active)
+	if active != 0 {
+		t.Fatalf("%d sessions in Acme after failed switches, want none", active)
+	}
+
+	body := fmt.Sprintf(`{"organizationId":%d,"password":"correct horse","code":"%s"}`, acme.org.ID, totpCode(t, testTOTPSecret))
+	if w := serve(r, http.MethodPost, "/auth/organizations/switch", body); w.Code != http.StatusOK {
+		t.Fatalf("switch with password and code: status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var switched models.Session
+	if err := db.Where("user_id = ? AND organization_id = ?", user.ID, acme.org.ID).First(&switched).Error; err != nil {
+		t.Fatal(err)
+	}
+	if switched.AuthMethod != models.SessionAuthPassword || !switched.MFAVerified {
+		t.Errorf("new session AuthMethod = %q, MFAVerified = %v; want a password and second factor sign-in", switched.AuthMethod, switched.MFAVerified)
+	}
+	var old models.Session
+	if err := db.First(&old, session.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if old.RevokedAt == nil {
+		t.Error("the Globex session is still active after the switch")
+	}
+}
+
+func TestSwitchOrganizationRequiresItsSSO(t *testing.T) {
+	db := newTestDB(t)
+	acme := newTenant(t, db, "Acme")
+	globex := newTenant(t, db, "Globex")
+	mustCreate(t, db, &models.OIDCProvider{OrganizationID: acme.org.ID, Issuer: "https://idp.acme.example", ClientID: "ventura", ClientSecret: "secret", Enabled: true, DisablePasswordLogin: true})
+
+	user := models.User{OrganizationID: globex.org.ID, Email: "member@example.com", Password: "correct horse", Name: "Member", Role: models.RoleViewer}
+	mustCreate(t, db, &user)
+	mustCreate(t, db, &models.Membership{UserID: user.ID, OrganizationID: globex.org.ID, Role: models.RoleViewer})
+	mustCreate(t, db, &models.Membership{UserID: user.ID, OrganizationID: acme.org.ID, Role: models.RoleViewer})
+	session := models.Session{UserID: user.ID, OrganizationID: globex.org.ID, AuthMethod: models.SessionAuthPassword, TokenID: "t", CSRFToken: "c", ExpiresAt: time.Now().Add(time.Hour)}
+	mustCreate(t, db, &session)
+
+	r := newSwitchRouter(db, user, session)
+	w := serve(r, http.MethodPost, "/auth/organizations/switch", fmt.Sprintf(`{"organizationId":%d,"password":"correct horse"}`, acme.org.ID))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("password switch into an SSO-only organization: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if want := `"ssoPath":"/auth/sso/acme/start"`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("response %s doesn't contain %s", w.Body, want)
+	}
+}
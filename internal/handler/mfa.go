@@ -0,0 +1,423 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"ventura/internal/auth"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+	"ventura/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	mfa          *service.MFAService
+	sessions     *service.SessionService
+	loginGuard   *service.LoginGuard
+	permissions  *service.PermissionService
+	memberships  *service.MembershipService
+	userRepo     *repository.UserRepository
+	auditLogRepo *repository.AuditLogRepository
+}
+
+func NewMFAHandler(mfa *service.MFAService, sessions *service.SessionService, loginGuard *service.LoginGuard, permissions *service.PermissionService, memberships *service.MembershipService, userRepo *repository.UserRepository, auditLogRepo *repository.AuditLogRepository) *MFAHandler {
+	return &MFAHandler{
+		mfa:          mfa,
+		sessions:     sessions,
+		loginGuard:   loginGuard,
+		permissions:  permissions,
+		memberships:  memberships,
+		userRepo:     userRepo,
+		auditLogRepo: auditLogRepo,
+	}
+}
+
+// MFAChallengeRequest carries the challenge token returned by Login
+type MFAChallengeRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+}
+
+// MFAVerifyRequest completes a login with a TOTP or recovery code
+type MFAVerifyRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// MFACodeRequest carries a TOTP code for an authenticated user
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableMFARequest represents the disable MFA request body
+type DisableMFARequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// UpdateMFAPolicyRequest represents the MFA policy update request body
+type UpdateMFAPolicyRequest struct {
+	Policy models.MFAPolicy `json:"policy" binding:"required,oneof=optional admins all"`
+}
+
+// VerifyMFA completes a login by checking the second factor
+func (h *MFAHandler) VerifyMFA(c *gin.Context) {
+	var req MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, loginMethod, ok := h.userFromChallenge(c, req.ChallengeToken, auth.TokenTypeMFAVerify)
+	if !ok {
+		return
+	}
+
+	// Codes count towards the same lockout as passwords
+	attempt := loginAttempt(c, user.Email)
+	if retryAfter := h.loginGuard.RetryAfter(user); retryAfter > 0 {
+		h.loginGuard.RecordFailure(user, attempt, service.LoginFailureLocked)
+		respondLocked(c, retryAfter)
+		return
+	}
+
+	usedRecoveryCode, err := h.mfa.Verify(user, req.Code)
+	if err != nil {
+		h.loginGuard.RecordFailure(user, attempt, service.LoginFailureInvalidMFACode)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	if usedRecoveryCode {
+		h.logAction(c, user, models.ActionMFARecoveryCodeUse, models.EntityUser, user.ID, "Signed in with a recovery code")
+	}
+
+	response, ok := startSession(c, h.sessions, h.loginGuard, user, service.SessionAuth{Method: loginMethod, MFA: true})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{User: *response})
+}
+
+// SetupMFA starts enrollment for a user whose organization requires MFA before they can sign in
+func (h *MFAHandler) SetupMFA(c *gin.Context) {
+	var req MFAChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, _, ok := h.userFromChallenge(c, req.ChallengeToken, auth.TokenTypeMFASetup)
+	if !ok {
+		return
+	}
+
+	h.beginEnrollment(c, user)
+}
+
+// ConfirmSetupMFA activates MFA for a user in the setup challenge and signs them in
+func (h *MFAHandler) ConfirmSetupMFA(c *gin.Context) {
+	var req MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, loginMethod, ok := h.userFromChallenge(c, req.ChallengeToken, auth.TokenTypeMFASetup)
+	if !ok {
+		return
+	}
+
+	codes, ok := h.confirmEnrollment(c, user, req.Code)
+	if !ok {
+		return
+	}
+
+	response, ok := startSession(c, h.sessions, h.loginGuard, user, service.SessionAuth{Method: loginMethod, MFA: true})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":          response,
+		"recoveryCodes": codes, // Shown only once
+	})
+}
+
+// GetMFAStatus returns the current user's two-factor setup
+func (h *MFAHandler) GetMFAStatus(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.mfa.Status(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch MFA status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollMFA starts enrollment for the signed-in user
+func (h *MFAHandler) EnrollMFA(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	h.beginEnrollment(c, user)
+}
+
+// ConfirmEnrollMFA activates MFA for the signed-in user
+func (h *MFAHandler) ConfirmEnrollMFA(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	codes, ok := h.confirmEnrollment(c, user, req.Code)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes}) // Shown only once
+}
+
+// RegenerateRecoveryCodes replaces the signed-in user's recovery codes
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.mfa.RegenerateRecoveryCodes(user, req.Code)
+	if err != nil {
+		h.respondMFAError(c, err)
+		return
+	}
+
+	h.logAction(c, user, models.ActionMFARecoveryCodes, models.EntityUser, user.ID, "Regenerated MFA recovery codes")
+
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes}) // Shown only once
+}
+
+// DisableMFA removes the signed-in user's authenticator, unless the org policy requires it
+func (h *MFAHandler) DisableMFA(c *gin.Context) {
+	var req DisableMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.CheckPassword(req.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+		return
+	}
+
+	if h.mfa.IsRequired(user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": service.ErrMFARequiredByPolicy.Error()})
+		return
+	}
+
+	if _, err := h.mfa.Verify(user, req.Code); err != nil {
+		h.respondMFAError(c, err)
+		return
+	}
+
+	if err := h.mfa.Disable(c.Request.Context(), user); err != nil {
+		h.respondMFAError(c, err)
+		return
+	}
+
+	h.logAction(c, user, models.ActionMFARemove, models.EntityUser, user.ID, "Disabled two-factor authentication")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// ResetUserMFA lets an admin remove a member's authenticator (e.g. a lost phone), as long
+// as the member belongs to no other organization. The member is signed out and must
+// enroll again if the org policy requires MFA.
+func (h *MFAHandler) ResetUserMFA(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.userRepo.FindMember(uint(id), orgID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := h.permissions.CanManage(orgID, user.Role, currentPermissions(c)); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	// The authenticator protects the account in every organization it belongs to
+	if err := h.memberships.CheckSoleMembership(user.ID); err != nil {
+		h.respondMFAError(c, err)
+		return
+	}
+
+	actor, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.mfa.Disable(c.Request.Context(), user); err != nil {
+		h.respondMFAError(c, err)
+		return
+	}
+	h.sessions.RevokeAllInOrganization(user.ID, orgID, models.SessionRevokedMFAReset)
+
+	h.logAction(c, actor, models.ActionMFARemove, models.EntityUser, user.ID, "Reset two-factor authentication for "+user.Email)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+// UpdateMFAPolicy sets which members of the organization must use MFA
+func (h *MFAHandler) UpdateMFAPolicy(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	var req UpdateMFAPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	org, err := h.mfa.SetPolicy(c.Request.Context(), orgID, req.Policy, actor)
+	if err != nil {
+		h.respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mfaPolicy": org.MFAPolicy})
+}
+
+func (h *MFAHandler) beginEnrollment(c *gin.Context, user *models.User) {
+	enrollment, err := h.mfa.BeginEnrollment(c.Request.Context(), user)
+	if err != nil {
+		h.respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *MFAHandler) confirmEnrollment(c *gin.Context, user *models.User, code string) ([]string, bool) {
+	codes, err := h.mfa.ConfirmEnrollment(c.Request.Context(), user, code)
+	if err != nil {
+		h.respondMFAError(c, err)
+		return nil, false
+	}
+
+	h.logAction(c, user, models.ActionMFAEnroll, models.EntityUser, user.ID, "Enabled two-factor authentication")
+	return codes, true
+}
+
+// userFromChallenge resolves the user behind a login challenge token and how they signed
+// in before it
+func (h *MFAHandler) userFromChallenge(c *gin.Context, challengeToken, challengeType string) (*models.User, string, bool) {
+	claims, err := auth.ValidateTypedToken(challengeToken, challengeType)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge, please sign in again"})
+		return nil, "", false
+	}
+
+	user, err := h.userRepo.FindMember(claims.UserID, claims.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge, please sign in again"})
+		return nil, "", false
+	}
+
+	loginMethod := claims.LoginMethod
+	if loginMethod == "" {
+		loginMethod = models.SessionAuthPassword
+	}
+	return user, loginMethod, true
+}
+
+func (h *MFAHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	orgID, _ := getOrganizationID(c)
+	user, err := h.userRepo.FindMember(userID.(uint), orgID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+
+	return user, true
+}
+
+func (h *MFAHandler) respondMFAError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrInvalidMFACode:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+	case service.ErrMFAAlreadyEnabled, service.ErrMFANotEnabled, service.ErrMFANoPendingSecret:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case service.ErrMemberElsewhere:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case service.ErrInvalidMFAPolicy, service.ErrMFAEnrollBeforePolicy:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update two-factor authentication"})
+	}
+}
+
+// logAction records an MFA event; the actor is passed in because login steps run unauthenticated
+func (h *MFAHandler) logAction(c *gin.Context, actor *models.User, action, entity string, entityID uint, details string) {
+	log := &models.AuditLog{
+		OrganizationID: actor.OrganizationID,
+		UserID:         actor.ID,
+		UserEmail:      actor.Email,
+		UserName:       actor.Name,
+		Action:         action,
+		Entity:         entity,
+		EntityID:       entityID,
+		Details:        details,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+	h.auditLogRepo.Create(log)
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 	"ventura/internal/models"
 	"ventura/internal/repository"
+	"ventura/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,18 +14,22 @@ import (
 type MonthlyUpdateHandler struct {
 	updateRepo    *repository.MonthlyUpdateRepository
 	portfolioRepo *repository.PortfolioRepository
+	access        *service.CompanyAccessService
+	organizations *service.OrganizationService
 }
 
-func NewMonthlyUpdateHandler(updateRepo *repository.MonthlyUpdateRepository, portfolioRepo *repository.PortfolioRepository) *MonthlyUpdateHandler {
+func NewMonthlyUpdateHandler(updateRepo *repository.MonthlyUpdateRepository, portfolioRepo *repository.PortfolioRepository, access *service.CompanyAccessService, organizations *service.OrganizationService) *MonthlyUpdateHandler {
 	return &MonthlyUpdateHandler{
 		updateRepo:    updateRepo,
 		portfolioRepo: portfolioRepo,
+		access:        access,
+		organizations: organizations,
 	}
 }
 
-// GetMonthlyUpdates returns all monthly updates
+// GetMonthlyUpdates returns the monthly updates of every company the caller can see
 func (h *MonthlyUpdateHandler) GetMonthlyUpdates(c *gin.Context) {
-	updates, err := h.updateRepo.GetAll()
+	updates, err := h.updates(c).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -41,7 +46,7 @@ func (h *MonthlyUpdateHandler) GetMonthlyUpdate(c *gin.Context) {
 		return
 	}
 
-	update, err := h.updateRepo.GetByID(uint(id))
+	update, err := h.updates(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Monthly update not found"})
 		return
@@ -59,14 +64,13 @@ func (h *MonthlyUpdateHandler) GetMonthlyUpdatesByCompany(c *gin.Context) {
 		return
 	}
 
-	// Verify company exists
-	_, err = h.portfolioRepo.GetByID(uint(companyID))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+	// Verify company exists and is visible
+	if _, err := h.access.View(companyScope(c), uint(companyID)); err != nil {
+		respondCompanyError(c, err)
 		return
 	}
 
-	updates, err := h.updateRepo.GetByCompanyID(uint(companyID))
+	updates, err := h.updates(c).GetByCompanyID(uint(companyID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -86,9 +90,9 @@ func (h *MonthlyUpdateHandler) CreateMonthlyUpdate(c *gin.Context) {
 	}
 
 	// Get company (we'll need to update it)
-	company, err := h.portfolioRepo.GetByID(uint(companyID))
+	company, err := h.access.Edit(companyScope(c), uint(companyID))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		respondCompanyError(c, err)
 		return
 	}
 
@@ -98,10 +102,14 @@ func (h *MonthlyUpdateHandler) CreateMonthlyUpdate(c *gin.Context) {
 		return
 	}
 
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
+		return
+	}
+
 	// Validate that the report month has ended (cannot submit for current or future months)
-	now := time.Now()
-	// Get the first day of current month
-	firstOfCurrentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	// Get the first day of current month in the organization's timezone
+	firstOfCurrentMonth := settings.CurrentMonth(time.Now())
 	// Get the first day of the report month
 	reportMonthStart := time.Date(update.ReportMonth.Year(), update.ReportMonth.Month(), 1, 0, 0, 0, 0, time.UTC)
 
@@ -114,7 +122,7 @@ func (h *MonthlyUpdateHandler) CreateMonthlyUpdate(c *gin.Context) {
 
 	update.CompanyID = uint(companyID)
 
-	if err := h.updateRepo.Create(&update); err != nil {
+	if err := h.updates(c).Create(&update); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -124,7 +132,7 @@ func (h *MonthlyUpdateHandler) CreateMonthlyUpdate(c *gin.Context) {
 	company.MonthlyBurnRate = update.BurnRate
 	company.MonthlyRevenue = update.MRR // Using MRR as monthly revenue
 
-	if err := h.portfolioRepo.Update(company); err != nil {
+	if err := h.portfolioRepo.Within(companyScope(c)).WithContext(c.Request.Context()).Update(company); err != nil {
 		// Log the error but don't fail the request since the update was created
 		c.JSON(http.StatusCreated, gin.H{
 			"update":  update,
@@ -146,12 +154,16 @@ func (h *MonthlyUpdateHandler) UpdateMonthlyUpdate(c *gin.Context) {
 	}
 
 	// Get existing update
-	existing, err := h.updateRepo.GetByID(uint(id))
+	existing, err := h.updates(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Monthly update not found"})
 		return
 	}
 
+	if !h.canEdit(c, existing) {
+		return
+	}
+
 	// Bind updated fields
 	var updates models.MonthlyUpdate
 	if err := c.ShouldBindJSON(&updates); err != nil {
@@ -169,7 +181,7 @@ func (h *MonthlyUpdateHandler) UpdateMonthlyUpdate(c *gin.Context) {
 	existing.ReportMonth = updates.ReportMonth
 	existing.Notes = updates.Notes
 
-	if err := h.updateRepo.Update(existing); err != nil {
+	if err := h.updates(c).Update(existing); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -187,16 +199,39 @@ func (h *MonthlyUpdateHandler) DeleteMonthlyUpdate(c *gin.Context) {
 	}
 
 	// Verify update exists
-	_, err = h.updateRepo.GetByID(uint(id))
+	update, err := h.updates(c).GetByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Monthly update not found"})
 		return
 	}
 
-	if err := h.updateRepo.Delete(uint(id)); err != nil {
+	if !h.canEdit(c, update) {
+		return
+	}
+
+	if err := h.updates(c).Delete(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Monthly update deleted successfully"})
 }
+
+// updates returns the update repository confined to the caller's companies and bound to the request
+func (h *MonthlyUpdateHandler) updates(c *gin.Context) *repository.MonthlyUpdateRepository {
+	return h.updateRepo.Within(companyScope(c)).WithContext(c.Request.Context())
+}
+
+// canEdit checks the caller may edit the update's company, writing the error response if not
+func (h *MonthlyUpdateHandler) canEdit(c *gin.Context, update *models.MonthlyUpdate) bool {
+	_, err := h.access.Edit(companyScope(c), update.CompanyID)
+	switch err {
+	case nil:
+		return true
+	case service.ErrCompanyNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Monthly update not found"})
+	default:
+		respondCompanyError(c, err)
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+	"ventura/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OrganizationHandler serves the organization's settings, data exports and deletion
+type OrganizationHandler struct {
+	organizations *service.OrganizationService
+	exports       *service.OrganizationExportService
+	userRepo      *repository.UserRepository
+	auditLogRepo  *repository.AuditLogRepository
+}
+
+func NewOrganizationHandler(organizations *service.OrganizationService, exports *service.OrganizationExportService, userRepo *repository.UserRepository, auditLogRepo *repository.AuditLogRepository) *OrganizationHandler {
+	return &OrganizationHandler{organizations: organizations, exports: exports, userRepo: userRepo, auditLogRepo: auditLogRepo}
+}
+
+// UpdateSettingsRequest changes some of the organization's settings; omitted fields keep their value
+type UpdateSettingsRequest struct {
+	BaseCurrency             *string               `json:"baseCurrency"`
+	FiscalYearStartMonth     *int                  `json:"fiscalYearStartMonth"`
+	Timezone                 *string               `json:"timezone"`
+	UpdateCadence            *models.UpdateCadence `json:"updateCadence"`
+	HealthGreenRunwayMonths  *int                  `json:"healthGreenRunwayMonths"`
+	HealthYellowRunwayMonths *int                  `json:"healthYellowRunwayMonths"`
+}
+
+// DeleteOrganizationRequest confirms the deletion of the organization by repeating its slug
+type DeleteOrganizationRequest struct {
+	Confirmation string `json:"confirmation" binding:"required"`
+}
+
+// RestoreOrganizationRequest restores a deleted organization with an emailed link's token
+type RestoreOrganizationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// GetSettings returns the organization's settings
+func (h *OrganizationHandler) GetSettings(c *gin.Context) {
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings changes the organization's settings
+func (h *OrganizationHandler) UpdateSettings(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	var req UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
+		return
+	}
+	if req.BaseCurrency != nil {
+		settings.BaseCurrency = *req.BaseCurrency
+	}
+	if req.FiscalYearStartMonth != nil {
+		settings.FiscalYearStartMonth = *req.FiscalYearStartMonth
+	}
+	if req.Timezone != nil {
+		settings.Timezone = *req.Timezone
+	}
+	if req.UpdateCadence != nil {
+		settings.UpdateCadence = *req.UpdateCadence
+	}
+	if req.HealthGreenRunwayMonths != nil {
+		settings.HealthGreenRunwayMonths = *req.HealthGreenRunwayMonths
+	}
+	if req.HealthYellowRunwayMonths != nil {
+		settings.HealthYellowRunwayMonths = *req.HealthYellowRunwayMonths
+	}
+
+	org, err := h.organizations.UpdateSettings(c.Request.Context(), orgID, settings)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnsupportedCurrency), errors.Is(err, service.ErrInvalidFiscalYearStart),
+			errors.Is(err, service.ErrInvalidTimezone), errors.Is(err, service.ErrInvalidUpdateCadence),
+			errors.Is(err, service.ErrInvalidHealthThresholds):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, org.Settings)
+}
+
+// RequestExport starts building a zip of all of the organization's data
+func (h *OrganizationHandler) RequestExport(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	export, err := h.exports.Request(orgID, c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrExportInProgress) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start export"})
+		return
+	}
+
+	h.logAction(c, models.ActionExport, models.EntityOrganization, orgID, fmt.Sprintf("Requested organization export %d", export.ID))
+
+	c.JSON(http.StatusAccepted, export)
+}
+
+// GetExports returns the organization's exports, most recent first
+func (h *OrganizationHandler) GetExports(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	exports, err := h.exports.List(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exports)
+}
+
+// GetExport returns one of the organization's exports, to follow its progress
+func (h *OrganizationHandler) GetExport(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	export, err := h.exports.Get(uint(id), orgID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch export"})
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// DownloadExport sends a finished export's zip
+func (h *OrganizationHandler) DownloadExport(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	export, err := h.exports.Download(uint(id), orgID)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		case errors.Is(err, service.ErrExportNotReady):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrExportExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch export"})
+		}
+		return
+	}
+
+	h.logAction(c, models.ActionExport, models.EntityOrganization, orgID, fmt.Sprintf("Downloaded organization export %d", export.ID))
+
+	c.Header("X-Checksum-SHA256", export.SHA256)
+	c.FileAttachment(export.Path, fmt.Sprintf("organization-export-%s.zip", export.CreatedAt.UTC().Format("20060102-150405")))
+}
+
+// DeleteOrganization deletes the organization. Members lose access at once; the data is
+// purged at the end of the grace period unless the emailed link restores it first.
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	var req DeleteOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, err := h.organizations.Delete(c.Request.Context(), c.GetUint("user_id"), orgID, req.Confirmation)
+	if err != nil {
+		if errors.Is(err, service.ErrDeletionNotConfirmed) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Organization deleted. A link to restore it has been emailed to you.",
+		"purgeAt": org.PurgeAt,
+	})
+}
+
+// RestoreOrganization undeletes an organization with the link emailed when it was deleted
+func (h *OrganizationHandler) RestoreOrganization(c *gin.Context) {
+	var req RestoreOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, user, err := h.organizations.Restore(c.Request.Context(), req.Token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidActionToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore organization"})
+		return
+	}
+
+	h.auditLogRepo.Create(&models.AuditLog{
+		OrganizationID: org.ID,
+		UserID:         user.ID,
+		UserEmail:      user.Email,
+		UserName:       user.Name,
+		Action:         models.ActionRestore,
+		Entity:         models.EntityOrganization,
+		EntityID:       org.ID,
+		Details:        "Restored the organization via emailed link",
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Organization restored, please sign in"})
+}
+
+// organizationSettings returns the settings of the caller's organization, or responds with an error
+func organizationSettings(c *gin.Context, organizations *service.OrganizationService) (models.OrganizationSettings, bool) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return models.OrganizationSettings{}, false
+	}
+	settings, err := organizations.Settings(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load organization settings"})
+		return models.OrganizationSettings{}, false
+	}
+	return settings, true
+}
+
+// logAction creates an audit log entry for the current user
+func (h *OrganizationHandler) logAction(c *gin.Context, action, entity string, entityID uint, details string) {
+	userID := c.GetUint("user_id")
+	userName := ""
+	if user, err := h.userRepo.FindByID(userID); err == nil {
+		userName = user.Name
+	}
+
+	h.auditLogRepo.Create(&models.AuditLog{
+		OrganizationID: c.GetUint("organization_id"),
+		UserID:         userID,
+		UserEmail:      c.GetString("user_email"),
+		UserName:       userName,
+		Action:         action,
+		Entity:         entity,
+		EntityID:       entityID,
+		Details:        details,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	})
+}
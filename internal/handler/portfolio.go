@@ -5,16 +5,19 @@ import (
 	"strconv"
 	"ventura/internal/models"
 	"ventura/internal/repository"
+	"ventura/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 type PortfolioHandler struct {
 	portfolioRepo *repository.PortfolioRepository
+	access        *service.CompanyAccessService
+	organizations *service.OrganizationService
 }
 
-func NewPortfolioHandler(portfolioRepo *repository.PortfolioRepository) *PortfolioHandler {
-	return &PortfolioHandler{portfolioRepo: portfolioRepo}
+func NewPortfolioHandler(portfolioRepo *repository.PortfolioRepository, access *service.CompanyAccessService, organizations *service.OrganizationService) *PortfolioHandler {
+	return &PortfolioHandler{portfolioRepo: portfolioRepo, access: access, organizations: organizations}
 }
 
 // getOrganizationID extracts organization ID from context
@@ -26,23 +29,56 @@ func getOrganizationID(c *gin.Context) (uint, bool) {
 	return orgID.(uint), true
 }
 
+// companyScope describes which companies the caller may see
+func companyScope(c *gin.Context) repository.CompanyScope {
+	orgID, _ := getOrganizationID(c)
+	userID, _ := c.Get("user_id")
+	return repository.CompanyScope{
+		OrganizationID: orgID,
+		UserID:         userID.(uint),
+		AllCompanies:   models.HasPermission(currentPermissions(c), models.PermCompanyAll),
+	}
+}
+
+// companies returns the company repository confined to what the caller can see and bound to the request
+func (h *PortfolioHandler) companies(c *gin.Context) *repository.PortfolioRepository {
+	return h.portfolioRepo.Within(companyScope(c)).WithContext(c.Request.Context())
+}
+
+// respondCompanyError maps company access errors to HTTP responses
+func respondCompanyError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrCompanyNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+	case service.ErrCompanyReadOnly, service.ErrCompanyNotLead:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 // GetCompanies returns all portfolio companies for the user's organization
 func (h *PortfolioHandler) GetCompanies(c *gin.Context) {
-	orgID, ok := getOrganizationID(c)
+	_, ok := getOrganizationID(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
 	}
 
-	companies, err := h.portfolioRepo.GetAllByOrganization(orgID)
+	companies, err := h.companies(c).GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
+		return
+	}
+
 	// Calculate health status for each company
 	for i := range companies {
-		companies[i].CalculateHealthStatus()
+		companies[i].CalculateHealthStatus(settings)
 	}
 
 	c.JSON(http.StatusOK, companies)
@@ -65,7 +101,13 @@ func (h *PortfolioHandler) CreateCompany(c *gin.Context) {
 	// Set organization ID from context
 	company.OrganizationID = orgID
 
-	if err := h.portfolioRepo.Create(&company); err != nil {
+	if err := h.companies(c).Create(&company); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Keep a restricted company visible to whoever created it
+	if err := h.access.AssignCreator(c.Request.Context(), companyScope(c), &company); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -75,7 +117,7 @@ func (h *PortfolioHandler) CreateCompany(c *gin.Context) {
 
 // GetCompany returns a single portfolio company by ID
 func (h *PortfolioHandler) GetCompany(c *gin.Context) {
-	orgID, ok := getOrganizationID(c)
+	_, ok := getOrganizationID(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
@@ -88,21 +130,26 @@ func (h *PortfolioHandler) GetCompany(c *gin.Context) {
 		return
 	}
 
-	company, err := h.portfolioRepo.GetByIDAndOrganization(uint(id), orgID)
+	company, err := h.access.View(companyScope(c), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		respondCompanyError(c, err)
+		return
+	}
+
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
 		return
 	}
 
 	// Calculate health status
-	company.CalculateHealthStatus()
+	company.CalculateHealthStatus(settings)
 
 	c.JSON(http.StatusOK, company)
 }
 
 // UpdateCompany updates an existing portfolio company
 func (h *PortfolioHandler) UpdateCompany(c *gin.Context) {
-	orgID, ok := getOrganizationID(c)
+	_, ok := getOrganizationID(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
@@ -115,10 +162,10 @@ func (h *PortfolioHandler) UpdateCompany(c *gin.Context) {
 		return
 	}
 
-	// Get existing company (verifies ownership)
-	existing, err := h.portfolioRepo.GetByIDAndOrganization(uint(id), orgID)
+	// Get existing company (verifies ownership and team access)
+	existing, err := h.access.Edit(companyScope(c), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		respondCompanyError(c, err)
 		return
 	}
 
@@ -140,18 +187,22 @@ func (h *PortfolioHandler) UpdateCompany(c *gin.Context) {
 	existing.MonthlyBurnRate = updates.MonthlyBurnRate
 	existing.MonthlyRevenue = updates.MonthlyRevenue
 
-	if err := h.portfolioRepo.Update(existing); err != nil {
+	if err := h.companies(c).Update(existing); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	existing.CalculateHealthStatus()
+	settings, ok := organizationSettings(c, h.organizations)
+	if !ok {
+		return
+	}
+	existing.CalculateHealthStatus(settings)
 	c.JSON(http.StatusOK, existing)
 }
 
 // DeleteCompany soft deletes a portfolio company
 func (h *PortfolioHandler) DeleteCompany(c *gin.Context) {
-	orgID, ok := getOrganizationID(c)
+	_, ok := getOrganizationID(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
@@ -164,14 +215,13 @@ func (h *PortfolioHandler) DeleteCompany(c *gin.Context) {
 		return
 	}
 
-	// Verify company exists and belongs to organization
-	_, err = h.portfolioRepo.GetByIDAndOrganization(uint(id), orgID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+	// Verify company exists, belongs to organization and may be edited
+	if _, err := h.access.Edit(companyScope(c), uint(id)); err != nil {
+		respondCompanyError(c, err)
 		return
 	}
 
-	if err := h.portfolioRepo.DeleteByIDAndOrganization(uint(id), orgID); err != nil {
+	if err := h.companies(c).Delete(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -181,7 +231,7 @@ func (h *PortfolioHandler) DeleteCompany(c *gin.Context) {
 
 // ToggleNotifications toggles the updates notifications for a company
 func (h *PortfolioHandler) ToggleNotifications(c *gin.Context) {
-	orgID, ok := getOrganizationID(c)
+	_, ok := getOrganizationID(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
 		return
@@ -194,10 +244,10 @@ func (h *PortfolioHandler) ToggleNotifications(c *gin.Context) {
 		return
 	}
 
-	// Get existing company (verifies ownership)
-	company, err := h.portfolioRepo.GetByIDAndOrganization(uint(id), orgID)
+	// Get existing company (verifies ownership and team access)
+	company, err := h.access.Edit(companyScope(c), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		respondCompanyError(c, err)
 		return
 	}
 
@@ -213,7 +263,7 @@ func (h *PortfolioHandler) ToggleNotifications(c *gin.Context) {
 	// Update notification setting
 	company.UpdatesNotificationsEnabled = request.Enabled
 
-	if err := h.portfolioRepo.Update(company); err != nil {
+	if err := h.companies(c).Update(company); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -223,3 +273,31 @@ func (h *PortfolioHandler) ToggleNotifications(c *gin.Context) {
 		"updatesNotificationsEnabled": company.UpdatesNotificationsEnabled,
 	})
 }
+
+// SetRestriction restricts a company to its team, or opens it to the whole organization
+func (h *PortfolioHandler) SetRestriction(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+
+	var request struct {
+		Restricted bool `json:"restricted"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	company, err := h.access.SetRestricted(c.Request.Context(), companyScope(c), uint(id), request.Restricted)
+	if err != nil {
+		respondCompanyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Company access updated successfully",
+		"restricted": company.Restricted,
+	})
+}
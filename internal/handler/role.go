@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"ventura/internal/models"
+	"ventura/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleHandler struct {
+	permissions *service.PermissionService
+}
+
+func NewRoleHandler(permissions *service.PermissionService) *RoleHandler {
+	return &RoleHandler{permissions: permissions}
+}
+
+// RoleRequest represents the create/update custom role request body
+type RoleRequest struct {
+	Key         models.UserRole     `json:"key"` // Required on create, ignored on update
+	Name        string              `json:"name" binding:"required"`
+	Description string              `json:"description"`
+	Permissions []models.Permission `json:"permissions" binding:"required"`
+}
+
+// GetPermissions returns the permission catalogue
+func (h *RoleHandler) GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, models.PermissionCatalogue)
+}
+
+// GetRoles returns the built-in and custom roles of the organization
+func (h *RoleHandler) GetRoles(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	roles, err := h.permissions.ListRoles(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// CreateRole creates a custom role
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.permissions.CreateRole(c.Request.Context(), orgID, service.RoleInput{
+		Key:         req.Key,
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}, currentPermissions(c))
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateRole changes a custom role's name, description and permissions
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.permissions.UpdateRole(c.Request.Context(), orgID, uint(id), service.RoleInput{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}, currentPermissions(c))
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole removes a custom role that no user holds
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	if err := h.permissions.DeleteRole(c.Request.Context(), orgID, uint(id)); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// respondRoleError maps role and permission errors to HTTP responses
+func respondRoleError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrUnknownRole:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+	case service.ErrRoleKeyTaken, service.ErrRoleInUse:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case service.ErrPermissionEscalate:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case service.ErrInvalidRoleKey, service.ErrUnknownPermission:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save role"})
+	}
+}
+
+// currentPermissions returns the permissions AuthMiddleware resolved for the caller
+func currentPermissions(c *gin.Context) []models.Permission {
+	granted, _ := c.Get("permissions")
+	permissions, _ := granted.([]models.Permission)
+	return permissions
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+	"ventura/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScorecardHandler serves the organization's deal scorecard and the scores reviewers give deals on it
+type ScorecardHandler struct {
+	dealRepo   *repository.DealRepository
+	scorecards *service.ScorecardService
+}
+
+func NewScorecardHandler(dealRepo *repository.DealRepository, scorecards *service.ScorecardService) *ScorecardHandler {
+	return &ScorecardHandler{dealRepo: dealRepo, scorecards: scorecards}
+}
+
+// SetScorecardRequest replaces the criteria deals are scored on
+type SetScorecardRequest struct {
+	Criteria []models.ScoringCriterion `json:"criteria"`
+}
+
+// SetDealScoresRequest replaces the caller's scores of a deal, by criterion key
+type SetDealScoresRequest struct {
+	Scores map[string]int `json:"scores"`
+}
+
+// GetScorecard returns the criteria the organization scores deals on
+func (h *ScorecardHandler) GetScorecard(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	criteria, err := h.scorecards.Criteria(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scorecard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, criteria)
+}
+
+// SetScorecard replaces the criteria the organization scores deals on; an empty list
+// restores the default
+func (h *ScorecardHandler) SetScorecard(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	var req SetScorecardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	criteria, err := h.scorecards.SetCriteria(c.Request.Context(), orgID, req.Criteria)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidScorecard) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save scorecard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, criteria)
+}
+
+// GetDealScores returns a deal's scores on each criterion, by reviewer and averaged, with its total
+func (h *ScorecardHandler) GetDealScores(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+
+	scorecard, err := h.scorecards.Scorecard(companyScope(c), deal)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scores"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scorecard)
+}
+
+// SetDealScores replaces the caller's scores of a deal
+func (h *ScorecardHandler) SetDealScores(c *gin.Context) {
+	deal, ok := h.findDeal(c)
+	if !ok {
+		return
+	}
+
+	var req SetDealScoresRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scorecard, err := h.scorecards.Score(c.Request.Context(), companyScope(c), deal, c.GetUint("user_id"), req.Scores)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidScore):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNotMember):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save scores"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, scorecard)
+}
+
+// findDeal loads the deal of the :id parameter from the caller's organization. It writes
+// the error response and returns false if there is none.
+func (h *ScorecardHandler) findDeal(c *gin.Context) (*models.Deal, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	deal, err := h.dealRepo.Within(companyScope(c)).GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deal not found"})
+		return nil, false
+	}
+	return deal, true
+}
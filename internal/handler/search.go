@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"ventura/internal/models"
 	"ventura/internal/repository"
 
 	"github.com/gin-gonic/gin"
@@ -69,8 +70,8 @@ func (h *SearchHandler) GlobalSearch(c *gin.Context) {
 	}
 
 	// Search companies within organization
-	companies, err := h.portfolioRepo.GetAllByOrganization(orgID.(uint))
-	if err == nil {
+	companies, err := h.portfolioRepo.Within(companyScope(c)).GetAll()
+	if err == nil && tokenAllows(c, models.ScopeResourcePortfolio+":read") {
 		for _, company := range companies {
 			if strings.Contains(strings.ToLower(company.Name), queryLower) ||
 				strings.Contains(strings.ToLower(company.Sector), queryLower) {
@@ -86,8 +87,8 @@ func (h *SearchHandler) GlobalSearch(c *gin.Context) {
 	}
 
 	// Search deals within organization
-	deals, err := h.dealRepo.GetAllByOrganization(orgID.(uint))
-	if err == nil {
+	deals, err := h.dealRepo.Within(companyScope(c)).GetAll()
+	if err == nil && tokenAllows(c, models.ScopeResourceDeals+":read") {
 		for _, deal := range deals {
 			if strings.Contains(strings.ToLower(deal.CompanyName), queryLower) ||
 				strings.Contains(strings.ToLower(deal.Sector), queryLower) {
@@ -102,9 +103,8 @@ func (h *SearchHandler) GlobalSearch(c *gin.Context) {
 		}
 	}
 
-	// Search users within organization (admin only - check role from context)
-	userRole, roleExists := c.Get("user_role")
-	if roleExists && userRole == "admin" {
+	// Search users within organization (only for callers who manage users)
+	if models.HasPermission(currentPermissions(c), models.PermUserManage) && tokenAllows(c, models.ScopeResourceAdmin+":read") {
 		users, err := h.userRepo.GetAllByOrganization(orgID.(uint))
 		if err == nil {
 			for _, user := range users {
@@ -165,3 +165,13 @@ func getStaticPages(query string) []SearchResult {
 func uintToString(n uint) string {
 	return strconv.FormatUint(uint64(n), 10)
 }
+
+// tokenAllows reports whether an API-token request was granted scope.
+// Requests authenticated with a session are never scope-limited.
+func tokenAllows(c *gin.Context, scope string) bool {
+	scopes, isToken := c.Get("token_scopes")
+	if !isToken {
+		return true
+	}
+	return models.ScopesAllow(scopes.([]string), scope)
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"ventura/internal/auth"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+	"ventura/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ssoStateCookie carries the signed SSO state between the start and callback requests
+const ssoStateCookie = "sso_state"
+
+type SSOHandler struct {
+	sso         *service.SSOService
+	sessions    *service.SessionService
+	mfa         *service.MFAService
+	loginGuard  *service.LoginGuard
+	userRepo    *repository.UserRepository
+	permissions *service.PermissionService
+}
+
+func NewSSOHandler(sso *service.SSOService, sessions *service.SessionService, mfa *service.MFAService, loginGuard *service.LoginGuard, userRepo *repository.UserRepository, permissions *service.PermissionService) *SSOHandler {
+	return &SSOHandler{
+		sso:         sso,
+		sessions:    sessions,
+		mfa:         mfa,
+		loginGuard:  loginGuard,
+		userRepo:    userRepo,
+		permissions: permissions,
+	}
+}
+
+// SSOConfigRequest represents the SSO configuration request body
+type SSOConfigRequest struct {
+	Issuer               string          `json:"issuer" binding:"required,url"`
+	ClientID             string          `json:"clientId" binding:"required"`
+	ClientSecret         string          `json:"clientSecret"` // Omit to keep the stored secret
+	AllowedDomains       []string        `json:"allowedDomains"`
+	DefaultRole          models.UserRole `json:"defaultRole"` // Role for new members, defaults to viewer
+	Enabled              bool            `json:"enabled"`
+	DisablePasswordLogin bool            `json:"disablePasswordLogin"`
+}
+
+// SSOConfigResponse represents the SSO configuration in responses (never includes the secret)
+type SSOConfigResponse struct {
+	Issuer               string          `json:"issuer"`
+	ClientID             string          `json:"clientId"`
+	HasClientSecret      bool            `json:"hasClientSecret"`
+	AllowedDomains       []string        `json:"allowedDomains"`
+	DefaultRole          models.UserRole `json:"defaultRole"`
+	Enabled              bool            `json:"enabled"`
+	DisablePasswordLogin bool            `json:"disablePasswordLogin"`
+	LoginPath            string          `json:"loginPath"` // Send the browser here to sign in
+}
+
+func toSSOConfigResponse(provider *models.OIDCProvider, orgSlug string) SSOConfigResponse {
+	return SSOConfigResponse{
+		Issuer:               provider.Issuer,
+		ClientID:             provider.ClientID,
+		HasClientSecret:      provider.ClientSecret != "",
+		AllowedDomains:       provider.DomainList(),
+		DefaultRole:          provider.DefaultRole,
+		Enabled:              provider.Enabled,
+		DisablePasswordLogin: provider.DisablePasswordLogin,
+		LoginPath:            "/auth/sso/" + orgSlug + "/start",
+	}
+}
+
+// StartSSO redirects the browser to the organization's identity provider
+func (h *SSOHandler) StartSSO(c *gin.Context) {
+	authURL, stateToken, err := h.sso.BeginLogin(c.Param("slug"), safeRedirectPath(c.Query("redirect")))
+	if err != nil {
+		if err == service.ErrSSONotConfigured {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Println("SSO start failed:", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	setSSOStateCookie(c, stateToken, int(auth.SSOStateExpiry.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// SSOCallback finishes the login when the identity provider redirects back. Second
+// factors and the organization's MFA policy apply as they do to password logins: the
+// browser returns to the login page with a challenge token in the URL fragment, which
+// browsers neither send to servers nor pass on in Referer headers.
+func (h *SSOHandler) SSOCallback(c *gin.Context) {
+	stateToken, _ := c.Cookie(ssoStateCookie)
+	setSSOStateCookie(c, "", -1)
+
+	if idpError := c.Query("error"); idpError != "" {
+		h.redirectToLogin(c, "Sign-in was cancelled or refused by the identity provider")
+		return
+	}
+
+	user, redirect, err := h.sso.CompleteLogin(c.Request.Context(), stateToken, c.Query("state"), c.Query("code"))
+	if err != nil {
+		log.Println("SSO login failed:", err)
+		message := "Single sign-on failed, please try again"
+		switch err {
+		case service.ErrSSOInvalidState, service.ErrSSONotConfigured, service.ErrSSOEmailMissing,
+			service.ErrSSOEmailNotAllowed, service.ErrSSOAccountConflict:
+			message = err.Error()
+		}
+		h.redirectToLogin(c, message)
+		return
+	}
+
+	if retryAfter := h.loginGuard.RetryAfter(user); retryAfter > 0 {
+		h.loginGuard.RecordFailure(user, loginAttempt(c, user.Email), service.LoginFailureLocked)
+		h.redirectToLogin(c, "Too many failed login attempts, please try again later")
+		return
+	}
+
+	if challengeType := secondFactorStep(h.mfa, user); challengeType != "" {
+		challenge, err := auth.GenerateChallengeToken(user, challengeType, models.SessionAuthSSO)
+		if err != nil {
+			h.redirectToLogin(c, "Single sign-on failed, please try again")
+			return
+		}
+		fragment := url.Values{"challengeToken": {challenge}, "redirect": {redirect}}
+		if challengeType == auth.TokenTypeMFAVerify {
+			fragment.Set("mfaRequired", "true")
+		} else {
+			fragment.Set("mfaSetupRequired", "true")
+		}
+		c.Redirect(http.StatusFound, h.sso.AppURL()+"/login#"+fragment.Encode())
+		return
+	}
+
+	if _, ok := startSession(c, h.sessions, h.loginGuard, user, service.SessionAuth{Method: models.SessionAuthSSO}); !ok {
+		return
+	}
+
+	c.Redirect(http.StatusFound, h.sso.AppURL()+redirect)
+}
+
+// GetSSOConfig returns the organization's SSO configuration
+func (h *SSOHandler) GetSSOConfig(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	provider, err := h.sso.GetConfig(orgID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toSSOConfigResponse(provider, h.orgSlug(c)))
+}
+
+// UpdateSSOConfig creates or updates the organization's SSO configuration
+func (h *SSOHandler) UpdateSSOConfig(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	var req SSOConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// New members get the default role, so it can't exceed the caller's own access
+	if req.DefaultRole != "" {
+		if err := h.permissions.CanAssignRole(orgID, req.DefaultRole, currentPermissions(c)); err != nil {
+			respondRoleError(c, err)
+			return
+		}
+	}
+
+	provider, err := h.sso.SaveConfig(c.Request.Context(), orgID, service.SSOConfigInput{
+		Issuer:               req.Issuer,
+		ClientID:             req.ClientID,
+		ClientSecret:         req.ClientSecret,
+		AllowedDomains:       req.AllowedDomains,
+		DefaultRole:          req.DefaultRole,
+		Enabled:              req.Enabled,
+		DisablePasswordLogin: req.DisablePasswordLogin,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toSSOConfigResponse(provider, h.orgSlug(c)))
+}
+
+// DeleteSSOConfig removes the organization's SSO configuration
+func (h *SSOHandler) DeleteSSOConfig(c *gin.Context) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	if err := h.sso.DeleteConfig(c.Request.Context(), orgID); err != nil {
+		if err == service.ErrSSONotConfigured {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete SSO configuration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "SSO configuration removed"})
+}
+
+// orgSlug returns the current organization's slug for building the login path
+func (h *SSOHandler) orgSlug(c *gin.Context) string {
+	userID, _ := c.Get("user_id")
+	orgID, _ := getOrganizationID(c)
+	user, err := h.userRepo.FindMember(userID.(uint), orgID)
+	if err != nil {
+		return ""
+	}
+	return user.Organization.Slug
+}
+
+// redirectToLogin sends the browser back to the frontend login page with an error message
+func (h *SSOHandler) redirectToLogin(c *gin.Context, message string) {
+	c.Redirect(http.StatusFound, h.sso.AppURL()+"/login?error="+url.QueryEscape(message))
+}
+
+// safeRedirectPath only allows same-site paths so the SSO flow can't be used as an open redirect
+func safeRedirectPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.ContainsAny(path, "\\\r\n") {
+		return "/"
+	}
+	return path
+}
+
+// setSSOStateCookie sets or clears the state cookie. It must survive the top-level
+// redirect back from the identity provider, so it is always SameSite=Lax.
+func setSSOStateCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoStateCookie, value, maxAge, "/auth/sso", "", os.Getenv("GIN_MODE") == "release", true)
+}
@@ -0,0 +1,222 @@
+package handler_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"ventura/internal/di"
+	"ventura/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const testAppURL = "https://app.example.com"
+
+// mockIdP is an OpenID Connect provider that signs in whoever next is
+type mockIdP struct {
+	*httptest.Server
+	key  *rsa.PrivateKey
+	next jwt.MapClaims // Claims of the next ID token, besides iss, aud, exp and nonce
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                idp.URL,
+			"authorization_endpoint":                idp.URL + "/authorize",
+			"token_endpoint":                        idp.URL + "/token",
+			"jwks_uri":                              idp.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		claims := jwt.MapClaims{"iss": idp.URL, "aud": "ventura", "iat": time.Now().Unix(), "exp": time.Now().Add(time.Minute).Unix()}
+		for k, v := range idp.next {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		idToken, err := token.SignedString(idp.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "access", "token_type": "Bearer", "expires_in": 60, "id_token": idToken})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// newSSORouter serves the SSO login and the MFA steps that can follow it
+func newSSORouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	c := di.NewContainer(db)
+
+	r := gin.New()
+	r.GET("/auth/sso/callback", c.SSOHandler.SSOCallback)
+	r.GET("/auth/sso/:slug/start", c.SSOHandler.StartSSO)
+	r.POST("/auth/mfa/verify", c.MFAHandler.VerifyMFA)
+	return r
+}
+
+// signInWithSSO runs an SSO login for org as the identity the provider returns for claims,
+// and returns the callback's response
+func signInWithSSO(t *testing.T, r *gin.Engine, idp *mockIdP, org models.Organization, claims jwt.MapClaims) *httptest.ResponseRecorder {
+	t.Helper()
+	start := httptest.NewRecorder()
+	r.ServeHTTP(start, httptest.NewRequest(http.MethodGet, "/auth/sso/"+org.Slug+"/start?redirect=/deals", nil))
+	if start.Code != http.StatusFound {
+		t.Fatalf("start: status = %d, want %d: %s", start.Code, http.StatusFound, start.Body)
+	}
+	authURL, err := url.Parse(start.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The provider authenticates the user and sends the browser back with a code
+	idp.next = jwt.MapClaims{"nonce": authURL.Query().Get("nonce")}
+	for k, v := range claims {
+		idp.next[k] = v
+	}
+	callback := httptest.NewRequest(http.MethodGet, "/auth/sso/callback?code=code&state="+url.QueryEscape(authURL.Query().Get("state")), nil)
+	for _, cookie := range start.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, callback)
+	if w.Code != http.StatusFound {
+		t.Fatalf("callback: status = %d, want %d: %s", w.Code, http.StatusFound, w.Body)
+	}
+	return w
+}
+
+// hasSessionCookie reports whether a response signed the browser in
+func hasSessionCookie(w *httptest.ResponseRecorder) bool {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "access_token" && cookie.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSSOCallback(t *testing.T) {
+	loadTestKeys(t)
+	t.Setenv("APP_URL", testAppURL)
+	idp := newMockIdP(t)
+	db := newTestDB(t)
+	acme := newTenant(t, db, "Acme")
+	mustCreate(t, db, &models.OIDCProvider{OrganizationID: acme.org.ID, Issuer: idp.URL, ClientID: "ventura", ClientSecret: "secret", DefaultRole: models.RoleViewer, Enabled: true})
+
+	member := models.User{OrganizationID: acme.org.ID, Email: "member@acme.com", Password: "-", Name: "Member", Role: models.RoleEditor}
+	mustCreate(t, db, &member)
+	mustCreate(t, db, &models.Membership{UserID: member.ID, OrganizationID: acme.org.ID, Role: models.RoleEditor})
+	secured := models.User{OrganizationID: acme.org.ID, Email: "secured@acme.com", Password: "-", Name: "Secured", Role: models.RoleEditor, MFAEnabled: true, TOTPSecret: testTOTPSecret}
+	mustCreate(t, db, &secured)
+	mustCreate(t, db, &models.Membership{UserID: secured.ID, OrganizationID: acme.org.ID, Role: models.RoleEditor})
+
+	r := newSSORouter(db)
+	session := func(userID uint) models.Session {
+		t.Helper()
+		var s models.Session
+		if err := db.Where("user_id = ?", userID).Order("id DESC").First(&s).Error; err != nil {
+			t.Fatalf("no session for user %d: %v", userID, err)
+		}
+		return s
+	}
+
+	t.Run("links an existing member", func(t *testing.T) {
+		w := signInWithSSO(t, r, idp, acme.org, jwt.MapClaims{"sub": "member-1", "email": "member@acme.com", "email_verified": true})
+		if got := w.Header().Get("Location"); got != testAppURL+"/deals" || !hasSessionCookie(w) {
+			t.Fatalf("redirected to %q, signed in = %v; want a session and %s/deals", got, hasSessionCookie(w), testAppURL)
+		}
+		var identity models.UserIdentity
+		if err := db.Where("issuer = ? AND subject = ?", idp.URL, "member-1").First(&identity).Error; err != nil || identity.UserID != member.ID {
+			t.Errorf("identity = %+v, %v; want it linked to user %d", identity, err, member.ID)
+		}
+		if s := session(member.ID); s.AuthMethod != models.SessionAuthSSO || s.MFAVerified {
+			t.Errorf("session AuthMethod = %q, MFAVerified = %v; want an SSO sign-in without a second factor", s.AuthMethod, s.MFAVerified)
+		}
+	})
+
+	t.Run("provisions a new member", func(t *testing.T) {
+		w := signInWithSSO(t, r, idp, acme.org, jwt.MapClaims{"sub": "new-1", "email": "New@Acme.com", "name": "New Member"})
+		if !hasSessionCookie(w) {
+			t.Fatalf("not signed in, redirected to %q", w.Header().Get("Location"))
+		}
+		var user models.User
+		if err := db.Where("email = ?", "new@acme.com").First(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+		if m := findMembership(t, db, user.ID, acme.org.ID); m.Role != models.RoleViewer {
+			t.Errorf("new member role = %s, want the provider's default %s", m.Role, models.RoleViewer)
+		}
+	})
+
+	t.Run("challenges members with MFA", func(t *testing.T) {
+		w := signInWithSSO(t, r, idp, acme.org, jwt.MapClaims{"sub": "secured-1", "email": "secured@acme.com"})
+		if hasSessionCookie(w) {
+			t.Fatal("signed in without the second factor")
+		}
+		location, err := url.Parse(w.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fragment, err := url.ParseQuery(location.Fragment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if location.Path != "/login" || location.RawQuery != "" || fragment.Get("mfaRequired") != "true" || fragment.Get("redirect") != "/deals" {
+			t.Fatalf("redirected to %s, want the login page with an MFA challenge in the fragment", location)
+		}
+
+		body := fmt.Sprintf(`{"challengeToken":%q,"code":%q}`, fragment.Get("challengeToken"), totpCode(t, testTOTPSecret))
+		if verify := serve(r, http.MethodPost, "/auth/mfa/verify", body); verify.Code != http.StatusOK || !hasSessionCookie(verify) {
+			t.Fatalf("verify: status = %d, want %d with a session: %s", verify.Code, http.StatusOK, verify.Body)
+		}
+		if s := session(secured.ID); s.AuthMethod != models.SessionAuthSSO || !s.MFAVerified {
+			t.Errorf("session AuthMethod = %q, MFAVerified = %v; want an SSO sign-in with a second factor", s.AuthMethod, s.MFAVerified)
+		}
+	})
+
+	t.Run("applies the MFA policy", func(t *testing.T) {
+		if err := db.Model(&acme.org).Update("mfa_policy", models.MFAPolicyAll).Error; err != nil {
+			t.Fatal(err)
+		}
+		w := signInWithSSO(t, r, idp, acme.org, jwt.MapClaims{"sub": "member-1", "email": "member@acme.com"})
+		location := w.Header().Get("Location")
+		if hasSessionCookie(w) || !strings.Contains(location, "mfaSetupRequired=true") {
+			t.Errorf("redirected to %q, signed in = %v; want an enrollment challenge", location, hasSessionCookie(w))
+		}
+	})
+}
@@ -5,28 +5,26 @@ import (
 	"strconv"
 	"ventura/internal/models"
 	"ventura/internal/repository"
+	"ventura/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 type TeamHandler struct {
-	teamRepo      *repository.TeamAssignmentRepository
-	userRepo      *repository.UserRepository
-	portfolioRepo *repository.PortfolioRepository
-	auditLogRepo  *repository.AuditLogRepository
+	teamRepo *repository.TeamAssignmentRepository
+	userRepo *repository.UserRepository
+	access   *service.CompanyAccessService
 }
 
 func NewTeamHandler(
 	teamRepo *repository.TeamAssignmentRepository,
 	userRepo *repository.UserRepository,
-	portfolioRepo *repository.PortfolioRepository,
-	auditLogRepo *repository.AuditLogRepository,
+	access *service.CompanyAccessService,
 ) *TeamHandler {
 	return &TeamHandler{
-		teamRepo:      teamRepo,
-		userRepo:      userRepo,
-		portfolioRepo: portfolioRepo,
-		auditLogRepo:  auditLogRepo,
+		teamRepo: teamRepo,
+		userRepo: userRepo,
+		access:   access,
 	}
 }
 
@@ -54,14 +52,13 @@ func (h *TeamHandler) GetCompanyTeam(c *gin.Context) {
 		return
 	}
 
-	// Check if company exists
-	_, err = h.portfolioRepo.GetByID(uint(companyID))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+	// Check if company exists and is visible
+	if _, err := h.access.View(companyScope(c), uint(companyID)); err != nil {
+		respondCompanyError(c, err)
 		return
 	}
 
-	assignments, err := h.teamRepo.GetByCompanyID(uint(companyID))
+	assignments, err := h.teams(c).GetByCompanyID(uint(companyID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team members"})
 		return
@@ -100,22 +97,22 @@ func (h *TeamHandler) AddTeamMember(c *gin.Context) {
 		return
 	}
 
-	// Check if company exists
-	company, err := h.portfolioRepo.GetByID(uint(companyID))
+	// Check if company exists and its team may be changed
+	company, err := h.access.Edit(companyScope(c), uint(companyID))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		respondCompanyError(c, err)
 		return
 	}
 
-	// Check if user exists
-	user, err := h.userRepo.FindByID(req.UserID)
+	// Check if user is a member of the company's organization
+	user, err := h.userRepo.FindMember(req.UserID, company.OrganizationID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	// Check if assignment already exists
-	existing, _ := h.teamRepo.GetByUserAndCompany(req.UserID, uint(companyID))
+	existing, _ := h.teams(c).GetByUserAndCompany(req.UserID, uint(companyID))
 	if existing != nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "User is already assigned to this company"})
 		return
@@ -133,15 +130,11 @@ func (h *TeamHandler) AddTeamMember(c *gin.Context) {
 		Role:      role,
 	}
 
-	if err := h.teamRepo.Create(assignment); err != nil {
+	if err := h.teams(c).Create(assignment); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add team member"})
 		return
 	}
 
-	// Log the action
-	h.logAction(c, models.ActionCreate, models.EntityTeam, assignment.ID,
-		"Added "+user.Name+" to "+company.Name+" as "+string(role))
-
 	c.JSON(http.StatusCreated, TeamMemberResponse{
 		ID:        assignment.ID,
 		UserID:    assignment.UserID,
@@ -166,43 +159,28 @@ func (h *TeamHandler) RemoveTeamMember(c *gin.Context) {
 		return
 	}
 
+	// Check if company exists and its team may be changed
+	if _, err := h.access.Edit(companyScope(c), uint(companyID)); err != nil {
+		respondCompanyError(c, err)
+		return
+	}
+
 	// Check if assignment exists
-	assignment, err := h.teamRepo.GetByUserAndCompany(uint(userID), uint(companyID))
+	assignment, err := h.teams(c).GetByUserAndCompany(uint(userID), uint(companyID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Team assignment not found"})
 		return
 	}
 
-	if err := h.teamRepo.Delete(assignment.ID); err != nil {
+	if err := h.teams(c).Delete(assignment.ID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove team member"})
 		return
 	}
 
-	// Log the action
-	h.logAction(c, models.ActionDelete, models.EntityTeam, assignment.ID,
-		"Removed user from company team")
-
 	c.JSON(http.StatusOK, gin.H{"message": "Team member removed successfully"})
 }
 
-// Helper function to log audit actions
-func (h *TeamHandler) logAction(c *gin.Context, action, entity string, entityID uint, details string) {
-	userID, _ := c.Get("user_id")
-	userEmail, _ := c.Get("user_email")
-	userName := ""
-	if user, err := h.userRepo.FindByID(userID.(uint)); err == nil {
-		userName = user.Name
-	}
-
-	log := &models.AuditLog{
-		UserID:    userID.(uint),
-		UserEmail: userEmail.(string),
-		UserName:  userName,
-		Action:    action,
-		Entity:    entity,
-		EntityID:  entityID,
-		Details:   details,
-		IPAddress: c.ClientIP(),
-	}
-	h.auditLogRepo.Create(log)
+// teams returns the team repository confined to the caller's companies and bound to the request
+func (h *TeamHandler) teams(c *gin.Context) *repository.TeamAssignmentRepository {
+	return h.teamRepo.Within(companyScope(c)).WithContext(c.Request.Context())
 }
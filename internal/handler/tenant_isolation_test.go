@@ -0,0 +1,298 @@
+package handler_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"ventura/internal/di"
+	"ventura/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// tenantFixture is one organization with an admin and a record of each kind to look up
+type tenantFixture struct {
+	org     models.Organization
+	admin   models.User
+	company models.PortfolioCompany
+	founder models.Founder
+	update  models.MonthlyUpdate
+	deal    models.Deal
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	// Every connection to :memory: is a database of its own
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	err = db.AutoMigrate(
+		&models.Organization{},
+		&models.User{},
+		&models.Membership{},
+		&models.Session{},
+		&models.CustomRole{},
+		&models.RecoveryCode{},
+		&models.OIDCProvider{},
+		&models.UserIdentity{},
+		&models.PortfolioCompany{},
+		&models.Deal{},
+		&models.Founder{},
+		&models.MonthlyUpdate{},
+		&models.Document{},
+		&models.AuditLog{},
+		&models.TeamAssignment{},
+		&models.DealStageTransition{},
+		&models.DealStageEvent{},
+		&models.DealActivity{},
+		&models.DealActivityMention{},
+		&models.DealActivityRevision{},
+		&models.DealAnalysis{},
+		&models.ScoringCriterion{},
+		&models.DealScore{},
+		&models.Notification{},
+	)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// newTenant creates an organization named name with an admin member and one company, founder,
+// monthly update, team assignment and deal
+func newTenant(t *testing.T, db *gorm.DB, name string) tenantFixture {
+	t.Helper()
+	var f tenantFixture
+	f.org = models.Organization{Name: name, Slug: strings.ToLower(name)}
+	mustCreate(t, db, &f.org)
+	f.admin = models.User{OrganizationID: f.org.ID, Email: strings.ToLower(name) + "@example.com", Password: "-", Name: name + " Admin", Role: models.RoleAdmin}
+	mustCreate(t, db, &f.admin)
+	mustCreate(t, db, &models.Membership{UserID: f.admin.ID, OrganizationID: f.org.ID, Role: models.RoleAdmin})
+
+	f.company = models.PortfolioCompany{
+		OrganizationID:   f.org.ID,
+		Name:             name + " Company",
+		Sector:           name + " Sector",
+		AmountInvested:   decimal.NewFromInt(100),
+		CurrentValuation: decimal.NewFromInt(200),
+		RoundStage:       "Seed",
+		InvestedAt:       time.Now(),
+	}
+	mustCreate(t, db, &f.company)
+	f.founder = models.Founder{Name: name + " Founder", Email: "founder@" + strings.ToLower(name) + ".com", Role: "CEO", CompanyID: f.company.ID}
+	mustCreate(t, db, &f.founder)
+	f.update = models.MonthlyUpdate{CompanyID: f.company.ID, CashInBank: decimal.NewFromInt(50), BurnRate: decimal.NewFromInt(5), ReportMonth: time.Now()}
+	mustCreate(t, db, &f.update)
+	mustCreate(t, db, &models.TeamAssignment{UserID: f.admin.ID, CompanyID: f.company.ID, Role: models.TeamRoleLead})
+	f.deal = models.Deal{OrganizationID: f.org.ID, CompanyName: name + " Deal", Sector: name + " Sector", Stage: models.StageIncoming}
+	mustCreate(t, db, &f.deal)
+	return f
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value interface{}) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatalf("create %T: %v", value, err)
+	}
+}
+
+// newTestRouter serves the handlers of the routes that look up an organization's records,
+// as the admin of f's organization. Authentication and its permission checks are out of scope.
+func newTestRouter(db *gorm.DB, f tenantFixture) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	c := di.NewContainer(db)
+	permissions, _ := models.BuiltInRolePermissions(models.RoleAdmin)
+
+	r := gin.New()
+	r.Use(func(ctx *gin.Context) {
+		ctx.Set("user_id", f.admin.ID)
+		ctx.Set("organization_id", f.org.ID)
+		ctx.Set("user_email", f.admin.Email)
+		ctx.Set("user_role", f.admin.Role)
+		ctx.Set("permissions", permissions)
+	})
+
+	api := r.Group("/api")
+	api.GET("/dashboard/ai-insight", c.DashboardHandler.GetAIInsight)
+
+	api.GET("/portfolio/companies/:id", c.PortfolioHandler.GetCompany)
+	api.PUT("/portfolio/companies/:id", c.PortfolioHandler.UpdateCompany)
+	api.DELETE("/portfolio/companies/:id", c.PortfolioHandler.DeleteCompany)
+	api.PATCH("/portfolio/companies/:id/notifications", c.PortfolioHandler.ToggleNotifications)
+	api.PATCH("/portfolio/companies/:id/restriction", c.PortfolioHandler.SetRestriction)
+	api.GET("/companies/:id/history", c.HistoryHandler.GetCompanyHistory)
+
+	api.GET("/founders/:id", c.FounderHandler.GetFounder)
+	api.GET("/founders/:id/history", c.HistoryHandler.GetFounderHistory)
+	api.PUT("/founders/:id", c.FounderHandler.UpdateFounder)
+	api.DELETE("/founders/:id", c.FounderHandler.DeleteFounder)
+	api.GET("/companies/:id/founders", c.FounderHandler.GetFoundersByCompany)
+	api.POST("/companies/:id/founders", c.FounderHandler.CreateFounder)
+
+	api.GET("/monthly-updates/:id", c.MonthlyUpdateHandler.GetMonthlyUpdate)
+	api.GET("/monthly-updates/:id/history", c.HistoryHandler.GetMonthlyUpdateHistory)
+	api.PUT("/monthly-updates/:id", c.MonthlyUpdateHandler.UpdateMonthlyUpdate)
+	api.DELETE("/monthly-updates/:id", c.MonthlyUpdateHandler.DeleteMonthlyUpdate)
+	api.GET("/companies/:id/updates", c.MonthlyUpdateHandler.GetMonthlyUpdatesByCompany)
+	api.POST("/companies/:id/updates", c.MonthlyUpdateHandler.CreateMonthlyUpdate)
+
+	api.GET("/companies/:id/team", c.TeamHandler.GetCompanyTeam)
+	api.POST("/companies/:id/team", c.TeamHandler.AddTeamMember)
+	api.DELETE("/companies/:id/team/:userId", c.TeamHandler.RemoveTeamMember)
+
+	api.GET("/deals/:id/history", c.HistoryHandler.GetDealHistory)
+	api.GET("/deals/:id/stage-events", c.DealHandler.GetStageEvents)
+	api.GET("/deals/:id/timeline", c.DealActivityHandler.GetTimeline)
+	api.GET("/deals/:id/analyses", c.DealAnalysisHandler.GetAnalyses)
+	api.GET("/deals/:id/scores", c.ScorecardHandler.GetDealScores)
+	api.PATCH("/deals/:id/stage", c.DealHandler.UpdateDealStage)
+	api.PATCH("/deals/:id/close", c.DealHandler.CloseDeal)
+	api.PATCH("/deals/:id/lose", c.DealHandler.LoseDeal)
+	api.POST("/deals/:id/activities", c.DealActivityHandler.CreateActivity)
+	api.PUT("/deals/:id/scores", c.ScorecardHandler.SetDealScores)
+	return r
+}
+
+func serve(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestForeignRecordsAreNotFound(t *testing.T) {
+	db := newTestDB(t)
+	own := newTenant(t, db, "Acme")
+	other := newTenant(t, db, "Globex")
+	r := newTestRouter(db, own)
+
+	company := fmt.Sprintf("/api/portfolio/companies/%d", other.company.ID)
+	companies := fmt.Sprintf("/api/companies/%d", other.company.ID)
+	founder := fmt.Sprintf("/api/founders/%d", other.founder.ID)
+	update := fmt.Sprintf("/api/monthly-updates/%d", other.update.ID)
+	deal := fmt.Sprintf("/api/deals/%d", other.deal.ID)
+
+	requests := []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, company, ""},
+		{http.MethodPut, company, `{"name":"Renamed"}`},
+		{http.MethodDelete, company, ""},
+		{http.MethodPatch, company + "/notifications", `{"enabled":false}`},
+		{http.MethodPatch, company + "/restriction", `{"restricted":true}`},
+		{http.MethodGet, companies + "/history", ""},
+
+		{http.MethodGet, founder, ""},
+		{http.MethodGet, founder + "/history", ""},
+		{http.MethodPut, founder, `{"name":"Renamed"}`},
+		{http.MethodDelete, founder, ""},
+		{http.MethodGet, companies + "/founders", ""},
+		{http.MethodPost, companies + "/founders", `{"name":"Intruder","email":"intruder@example.com","role":"CEO"}`},
+
+		{http.MethodGet, update, ""},
+		{http.MethodGet, update + "/history", ""},
+		{http.MethodPut, update, `{"notes":"Changed"}`},
+		{http.MethodDelete, update, ""},
+		{http.MethodGet, companies + "/updates", ""},
+		{http.MethodPost, companies + "/updates", `{"cashInBank":"1","burnRate":"1","reportMonth":"2024-01-01T00:00:00Z"}`},
+
+		{http.MethodGet, companies + "/team", ""},
+		{http.MethodPost, companies + "/team", fmt.Sprintf(`{"userId":%d,"role":"observer"}`, own.admin.ID)},
+		{http.MethodDelete, fmt.Sprintf("%s/team/%d", companies, other.admin.ID), ""},
+
+		{http.MethodGet, deal + "/history", ""},
+		{http.MethodGet, deal + "/stage-events", ""},
+		{http.MethodGet, deal + "/timeline", ""},
+		{http.MethodGet, deal + "/analyses", ""},
+		{http.MethodGet, deal + "/scores", ""},
+		{http.MethodPatch, deal + "/stage", `{"stage":"screening"}`},
+		{http.MethodPatch, deal + "/close", `{}`},
+		{http.MethodPatch, deal + "/lose", `{"reason":"Passed"}`},
+		{http.MethodPost, deal + "/activities", `{"type":"note","body":"Hello"}`},
+		{http.MethodPut, deal + "/scores", `{"scores":{"team":5}}`},
+	}
+	for _, req := range requests {
+		t.Run(req.method+" "+req.path, func(t *testing.T) {
+			if w := serve(r, req.method, req.path, req.body); w.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body)
+			}
+		})
+	}
+
+	// Nothing of the other organization's changed
+	var company2 models.PortfolioCompany
+	if err := db.First(&company2, other.company.ID).Error; err != nil || company2.Name != other.company.Name || company2.Restricted {
+		t.Errorf("other organization's company = %+v, %v; want it unchanged", company2, err)
+	}
+	var deal2 models.Deal
+	if err := db.First(&deal2, other.deal.ID).Error; err != nil || deal2.Stage != models.StageIncoming {
+		t.Errorf("other organization's deal = %+v, %v; want it unchanged", deal2, err)
+	}
+	for _, model := range []interface{}{&models.Founder{}, &models.MonthlyUpdate{}, &models.TeamAssignment{}} {
+		var count int64
+		db.Model(model).Count(&count)
+		if count != 2 {
+			t.Errorf("%T: %d rows, want the 2 each organization started with", model, count)
+		}
+	}
+}
+
+func TestOwnRecordsAreFound(t *testing.T) {
+	db := newTestDB(t)
+	own := newTenant(t, db, "Acme")
+	newTenant(t, db, "Globex")
+	r := newTestRouter(db, own)
+
+	for _, path := range []string{
+		fmt.Sprintf("/api/portfolio/companies/%d", own.company.ID),
+		fmt.Sprintf("/api/companies/%d/founders", own.company.ID),
+		fmt.Sprintf("/api/companies/%d/updates", own.company.ID),
+		fmt.Sprintf("/api/companies/%d/team", own.company.ID),
+		fmt.Sprintf("/api/founders/%d", own.founder.ID),
+		fmt.Sprintf("/api/monthly-updates/%d", own.update.ID),
+		fmt.Sprintf("/api/deals/%d/stage-events", own.deal.ID),
+		fmt.Sprintf("/api/deals/%d/history", own.deal.ID),
+	} {
+		t.Run(path, func(t *testing.T) {
+			if w := serve(r, http.MethodGet, path, ""); w.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+		})
+	}
+}
+
+func TestAIInsightSeesOnlyOwnCompanies(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "")
+	db := newTestDB(t)
+	own := newTenant(t, db, "Acme")
+	other := newTenant(t, db, "Globex")
+	r := newTestRouter(db, own)
+
+	w := serve(r, http.MethodGet, "/api/dashboard/ai-insight", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	insight := w.Body.String()
+	if !strings.Contains(insight, "portfolio of 1 companies") || !strings.Contains(insight, own.company.Sector) {
+		t.Errorf("insight = %s, want one company in %s", insight, own.company.Sector)
+	}
+	if strings.Contains(insight, other.company.Sector) {
+		t.Errorf("insight = %s, mentions the other organization's %s", insight, other.company.Sector)
+	}
+}
@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 	"ventura/internal/models"
 	"ventura/internal/repository"
+	"ventura/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,60 +16,71 @@ import (
 type UserHandler struct {
 	userRepo     *repository.UserRepository
 	auditLogRepo *repository.AuditLogRepository
+	loginGuard   *service.LoginGuard
+	permissions  *service.PermissionService
+	memberships  *service.MembershipService
 }
 
-func NewUserHandler(userRepo *repository.UserRepository, auditLogRepo *repository.AuditLogRepository) *UserHandler {
+func NewUserHandler(userRepo *repository.UserRepository, auditLogRepo *repository.AuditLogRepository, loginGuard *service.LoginGuard, permissions *service.PermissionService, memberships *service.MembershipService) *UserHandler {
 	return &UserHandler{
 		userRepo:     userRepo,
 		auditLogRepo: auditLogRepo,
+		loginGuard:   loginGuard,
+		permissions:  permissions,
+		memberships:  memberships,
 	}
 }
 
 // UserListResponse represents a user in the admin list
 type UserListResponse struct {
-	ID        uint            `json:"id"`
-	Email     string          `json:"email"`
-	Name      string          `json:"name"`
-	Role      models.UserRole `json:"role"`
-	CreatedAt string          `json:"createdAt"`
+	ID            uint            `json:"id"`
+	Email         string          `json:"email"`
+	Name          string          `json:"name"`
+	Role          models.UserRole `json:"role"`
+	LockedUntil   *time.Time      `json:"lockedUntil,omitempty"`   // Set while login is blocked after failed attempts
+	DeactivatedAt *time.Time      `json:"deactivatedAt,omitempty"` // Set while the member is deactivated
+	CreatedAt     string          `json:"createdAt"`
 }
 
-// UpdateUserRequest represents the update user request body
+// UpdateUserRequest changes a member's role. Their name is shared by every organization
+// they belong to, so only they can change it, from their profile.
 type UpdateUserRequest struct {
-	Name string          `json:"name"`
-	Role models.UserRole `json:"role" binding:"omitempty,oneof=admin viewer"`
+	Role models.UserRole `json:"role" binding:"required"` // Built-in or custom role key
 }
 
-// InviteUserRequest represents the invite user request body
-type InviteUserRequest struct {
-	Email string          `json:"email" binding:"required,email"`
-	Name  string          `json:"name" binding:"required"`
-	Role  models.UserRole `json:"role" binding:"omitempty,oneof=admin viewer"`
+// DeactivateUserRequest optionally hands the member's work over to another member first
+type DeactivateUserRequest struct {
+	HandoverTo *uint `json:"handoverTo"`
 }
 
-// GetUsers returns all users (admin only)
+// HandoverRequest names the member who takes over another member's work
+type HandoverRequest struct {
+	ToUserID uint `json:"toUserId" binding:"required"`
+}
+
+// GetUsers returns the members of the organization (admin only)
 func (h *UserHandler) GetUsers(c *gin.Context) {
-	users, err := h.userRepo.GetAll()
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	users, err := h.userRepo.GetAllByOrganization(orgID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
 	}
 
 	response := make([]UserListResponse, len(users))
-	for i, user := range users {
-		response[i] = UserListResponse{
-			ID:        user.ID,
-			Email:     user.Email,
-			Name:      user.Name,
-			Role:      user.Role,
-			CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		}
+	for i := range users {
+		response[i] = toUserListResponse(&users[i])
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// GetUser returns a single user by ID
+// GetUser returns a single member of the organization by ID
 func (h *UserHandler) GetUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -73,22 +88,15 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userRepo.FindByID(uint(id))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, ok := h.findMember(c, uint(id))
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, UserListResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		Name:      user.Name,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	})
+	c.JSON(http.StatusOK, toUserListResponse(user))
 }
 
-// UpdateUser updates a user's name and/or role
+// UpdateUser changes a member's role in the organization
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -102,119 +110,224 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userRepo.FindByID(uint(id))
+	user, ok := h.findMember(c, uint(id))
+	if !ok {
+		return
+	}
+
+	// The role belongs to this organization's membership. Changing it forces a fresh
+	// login here so the new role is reflected in every token.
+	if req.Role != user.Role {
+		if err := h.permissions.CanChangeRole(user.OrganizationID, user.Role, req.Role, currentPermissions(c)); err != nil {
+			respondRoleError(c, err)
+			return
+		}
+		if err := h.memberships.ChangeRole(c.Request.Context(), user.ID, user.OrganizationID, req.Role); err != nil {
+			h.respondMembershipError(c, err, "Failed to update user")
+			return
+		}
+		user.Role = req.Role
+	}
+
+	c.JSON(http.StatusOK, toUserListResponse(user))
+}
+
+// DeactivateUser deactivates a member of the organization: they can no longer sign in to
+// it and their sessions there end, but their history is kept and they can be reactivated.
+// With handoverTo, their team assignments and deals go to that member first.
+func (h *UserHandler) DeactivateUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Update fields if provided
-	if req.Name != "" {
-		user.Name = req.Name
+	var req DeactivateUserRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
-	if req.Role != "" {
-		user.Role = req.Role
+
+	// Prevent self-deactivation
+	currentUserID := c.GetUint("user_id")
+	if currentUserID == uint(id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot deactivate your own account"})
+		return
 	}
 
-	if err := h.userRepo.Update(user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+	user, ok := h.findMember(c, uint(id))
+	if !ok {
+		return
+	}
+	if err := h.permissions.CanManage(user.OrganizationID, user.Role, currentPermissions(c)); err != nil {
+		respondRoleError(c, err)
 		return
 	}
 
-	// Log the action
-	h.logAction(c, models.ActionUpdate, models.EntityUser, user.ID, "Updated user details")
+	handover, err := h.memberships.Deactivate(c.Request.Context(), user.ID, user.OrganizationID, currentUserID, req.HandoverTo)
+	if err != nil {
+		h.respondMembershipError(c, err, "Failed to deactivate user")
+		return
+	}
+
+	if handover != nil {
+		h.logHandover(c, user, *req.HandoverTo, handover)
+	}
+	h.logAction(c, models.ActionDeactivate, models.EntityUser, user.ID, "Deactivated user: "+user.Email)
 
-	c.JSON(http.StatusOK, UserListResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		Name:      user.Name,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "User deactivated successfully", "handover": handover})
 }
 
-// DeleteUser deletes a user
-func (h *UserHandler) DeleteUser(c *gin.Context) {
+// ReactivateUser lets a deactivated member sign in to the organization again
+func (h *UserHandler) ReactivateUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Prevent self-deletion
-	currentUserID, _ := c.Get("user_id")
-	if currentUserID.(uint) == uint(id) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot delete your own account"})
+	user, ok := h.findMember(c, uint(id))
+	if !ok {
 		return
 	}
-
-	user, err := h.userRepo.FindByID(uint(id))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if err := h.permissions.CanManage(user.OrganizationID, user.Role, currentPermissions(c)); err != nil {
+		respondRoleError(c, err)
 		return
 	}
 
-	if err := h.userRepo.Delete(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+	if err := h.memberships.Reactivate(c.Request.Context(), user.ID, user.OrganizationID); err != nil {
+		h.respondMembershipError(c, err, "Failed to reactivate user")
 		return
 	}
 
-	// Log the action
-	h.logAction(c, models.ActionDelete, models.EntityUser, uint(id), "Deleted user: "+user.Email)
+	h.logAction(c, models.ActionReactivate, models.EntityUser, user.ID, "Reactivated user: "+user.Email)
 
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "User reactivated successfully"})
 }
 
-// InviteUser creates a new user with a temporary password
-func (h *UserHandler) InviteUser(c *gin.Context) {
-	var req InviteUserRequest
+// HandoverUser moves a member's team assignments and deal ownership to another member,
+// typically before or after deactivating them
+func (h *UserHandler) HandoverUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req HandoverRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Check if user already exists
-	existingUser, _ := h.userRepo.FindByEmail(req.Email)
-	if existingUser != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
+	user, ok := h.findMember(c, uint(id))
+	if !ok {
+		return
+	}
+	if err := h.permissions.CanManage(user.OrganizationID, user.Role, currentPermissions(c)); err != nil {
+		respondRoleError(c, err)
 		return
 	}
 
-	// Set default role if not specified
-	role := req.Role
-	if role == "" {
-		role = models.RoleViewer
+	handover, err := h.memberships.Handover(c.Request.Context(), user.ID, req.ToUserID, user.OrganizationID)
+	if err != nil {
+		h.respondMembershipError(c, err, "Failed to hand over user's work")
+		return
 	}
 
-	// Generate a temporary password (in production, you'd send this via email)
-	tempPassword := "TempPass123!" // In production, generate a random password
+	h.logHandover(c, user, req.ToUserID, handover)
+
+	c.JSON(http.StatusOK, handover)
+}
 
-	user := &models.User{
-		Email:    req.Email,
-		Name:     req.Name,
-		Password: tempPassword,
-		Role:     role,
+// UnlockUser clears a user's login lockout after failed attempts. Only users who belong to
+// no other organization can be unlocked by an admin; others wait for the lockout to expire.
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
 	}
 
-	if err := h.userRepo.Create(user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	user, ok := h.findMember(c, uint(id))
+	if !ok {
+		return
+	}
+	if err := h.permissions.CanManage(user.OrganizationID, user.Role, currentPermissions(c)); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	// The lockout is on the account, which other organizations' members also sign in to
+	if err := h.memberships.CheckSoleMembership(user.ID); err != nil {
+		h.respondMembershipError(c, err, "Failed to unlock user")
+		return
+	}
+
+	if err := h.loginGuard.Unlock(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
 		return
 	}
 
 	// Log the action
-	h.logAction(c, models.ActionInvite, models.EntityUser, user.ID, "Invited new user: "+user.Email)
+	h.logAction(c, models.ActionUnlock, models.EntityUser, user.ID, "Unlocked login for user: "+user.Email)
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
+
+// findMember loads a user as a member of the caller's organization. It writes a 404 for
+// users of other organizations and returns false.
+func (h *UserHandler) findMember(c *gin.Context, id uint) (*models.User, bool) {
+	orgID, ok := getOrganizationID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organization not found"})
+		return nil, false
+	}
+
+	user, err := h.userRepo.FindMemberIncludingDeactivated(id, orgID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return user, true
+}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "User invited successfully",
-		"user": UserListResponse{
-			ID:        user.ID,
-			Email:     user.Email,
-			Name:      user.Name,
-			Role:      user.Role,
-			CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		},
-		"tempPassword": tempPassword, // In production, send via email instead
-	})
+// respondMembershipError writes the response for an error of the membership service
+func (h *UserHandler) respondMembershipError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrNotMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, service.ErrMemberElsewhere):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidHandoverUser):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAlreadyDeactivated), errors.Is(err, service.ErrNotDeactivated), errors.Is(err, service.ErrLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// logHandover records what a handover moved from a member to another
+func (h *UserHandler) logHandover(c *gin.Context, from *models.User, toUserID uint, handover *service.HandoverResult) {
+	h.logAction(c, models.ActionHandover, models.EntityUser, from.ID, fmt.Sprintf("Handed over %d team assignments and %d deals from %s to user %d",
+		handover.TeamAssignments, handover.Deals, from.Email, toUserID))
+}
+
+func toUserListResponse(user *models.User) UserListResponse {
+	response := UserListResponse{
+		ID:            user.ID,
+		Email:         user.Email,
+		Name:          user.Name,
+		Role:          user.Role,
+		DeactivatedAt: user.DeactivatedAt,
+		CreatedAt:     user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if user.IsLocked() {
+		response.LockedUntil = user.LockedUntil
+	}
+	return response
 }
 
 // Helper function to log audit actions
@@ -227,14 +340,16 @@ func (h *UserHandler) logAction(c *gin.Context, action, entity string, entityID
 	}
 
 	log := &models.AuditLog{
-		UserID:    userID.(uint),
-		UserEmail: userEmail.(string),
-		UserName:  userName,
-		Action:    action,
-		Entity:    entity,
-		EntityID:  entityID,
-		Details:   details,
-		IPAddress: c.ClientIP(),
+		OrganizationID: c.GetUint("organization_id"),
+		UserID:         userID.(uint),
+		UserEmail:      userEmail.(string),
+		UserName:       userName,
+		Action:         action,
+		Entity:         entity,
+		EntityID:       entityID,
+		Details:        details,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
 	}
 	h.auditLogRepo.Create(log)
 }
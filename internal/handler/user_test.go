@@ -0,0 +1,196 @@
+package handler_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"ventura/internal/di"
+	"ventura/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newUserRouter serves the member management routes as actor, a member of orgID holding
+// permissions
+func newUserRouter(db *gorm.DB, actor models.User, orgID uint, permissions []models.Permission) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	c := di.NewContainer(db)
+
+	r := gin.New()
+	r.Use(func(ctx *gin.Context) {
+		ctx.Set("user_id", actor.ID)
+		ctx.Set("organization_id", orgID)
+		ctx.Set("user_email", actor.Email)
+		ctx.Set("user_role", actor.Role)
+		ctx.Set("permissions", permissions)
+	})
+
+	admin := r.Group("/api/admin")
+	admin.PUT("/users/:id", c.UserHandler.UpdateUser)
+	admin.POST("/users/:id/deactivate", c.UserHandler.DeactivateUser)
+	admin.POST("/users/:id/reactivate", c.UserHandler.ReactivateUser)
+	admin.POST("/users/:id/handover", c.UserHandler.HandoverUser)
+	admin.POST("/users/:id/unlock", c.UserHandler.UnlockUser)
+	admin.DELETE("/users/:id/mfa", c.MFAHandler.ResetUserMFA)
+	return r
+}
+
+// newMember adds a user to org with role
+func newMember(t *testing.T, db *gorm.DB, org models.Organization, email string, role models.UserRole) models.User {
+	t.Helper()
+	user := models.User{OrganizationID: org.ID, Email: email, Password: "-", Name: email, Role: role}
+	mustCreate(t, db, &user)
+	mustCreate(t, db, &models.Membership{UserID: user.ID, OrganizationID: org.ID, Role: role})
+	return user
+}
+
+// findMembership loads the membership of a user in an organization
+func findMembership(t *testing.T, db *gorm.DB, userID, orgID uint) models.Membership {
+	t.Helper()
+	var membership models.Membership
+	if err := db.Where("user_id = ? AND organization_id = ?", userID, orgID).First(&membership).Error; err != nil {
+		t.Fatal(err)
+	}
+	return membership
+}
+
+func TestUserManagersCannotActOnHigherRoles(t *testing.T) {
+	db := newTestDB(t)
+	f := newTenant(t, db, "Acme")
+	mustCreate(t, db, &models.CustomRole{OrganizationID: f.org.ID, Key: "people", Name: "People", Permissions: string(models.PermUserManage)})
+	manager := newMember(t, db, f.org, "people@acme.com", "people")
+	viewer := newMember(t, db, f.org, "viewer@acme.com", models.RoleViewer)
+	deactivatedAdmin := newMember(t, db, f.org, "former@acme.com", models.RoleAdmin)
+	if err := db.Model(&models.Membership{}).Where("user_id = ?", deactivatedAdmin.ID).Update("deactivated_at", gorm.Expr("CURRENT_TIMESTAMP")).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	r := newUserRouter(db, manager, f.org.ID, []models.Permission{models.PermUserManage})
+
+	tests := []struct {
+		name, path, body string
+	}{
+		{"deactivate an admin", fmt.Sprintf("/api/admin/users/%d/deactivate", f.admin.ID), ""},
+		{"hand over an admin's work", fmt.Sprintf("/api/admin/users/%d/handover", f.admin.ID), fmt.Sprintf(`{"toUserId":%d}`, manager.ID)},
+		{"reactivate an admin", fmt.Sprintf("/api/admin/users/%d/reactivate", deactivatedAdmin.ID), ""},
+		{"unlock an admin", fmt.Sprintf("/api/admin/users/%d/unlock", f.admin.ID), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve(r, http.MethodPost, tt.path, tt.body); w.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body)
+			}
+		})
+	}
+	if findMembership(t, db, f.admin.ID, f.org.ID).DeactivatedAt != nil {
+		t.Error("the admin was deactivated")
+	}
+	if findMembership(t, db, deactivatedAdmin.ID, f.org.ID).DeactivatedAt == nil {
+		t.Error("the deactivated admin was reactivated")
+	}
+
+	// Members whose role is within the manager's access can still be managed
+	path := fmt.Sprintf("/api/admin/users/%d/deactivate", viewer.ID)
+	if w := serve(r, http.MethodPost, path, ""); w.Code != http.StatusOK {
+		t.Errorf("deactivate a viewer: status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+}
+
+func TestLastActiveAdminIsKept(t *testing.T) {
+	db := newTestDB(t)
+	f := newTenant(t, db, "Acme")
+	// An admin service account acting through an API token doesn't count as an admin
+	bot := models.User{OrganizationID: f.org.ID, Email: "svc-bot@service-accounts.ventura.invalid", Password: "-", Name: "Bot", Role: models.RoleAdmin, IsServiceAccount: true}
+	mustCreate(t, db, &bot)
+	mustCreate(t, db, &models.Membership{UserID: bot.ID, OrganizationID: f.org.ID, Role: models.RoleAdmin})
+	permissions, _ := models.BuiltInRolePermissions(models.RoleAdmin)
+
+	r := newUserRouter(db, bot, f.org.ID, permissions)
+	deactivate := func(user models.User) int {
+		return serve(r, http.MethodPost, fmt.Sprintf("/api/admin/users/%d/deactivate", user.ID), "").Code
+	}
+	demote := func(user models.User) int {
+		return serve(r, http.MethodPut, fmt.Sprintf("/api/admin/users/%d", user.ID), `{"role":"editor"}`).Code
+	}
+
+	if code := deactivate(f.admin); code != http.StatusConflict {
+		t.Errorf("deactivate the last admin: status = %d, want %d", code, http.StatusConflict)
+	}
+	if code := demote(f.admin); code != http.StatusConflict {
+		t.Errorf("demote the last admin: status = %d, want %d", code, http.StatusConflict)
+	}
+	if m := findMembership(t, db, f.admin.ID, f.org.ID); m.Role != models.RoleAdmin || m.DeactivatedAt != nil {
+		t.Fatalf("last admin membership = %s, deactivated %v; want an active admin", m.Role, m.DeactivatedAt)
+	}
+
+	// With a second admin either can be demoted, but then the other is the last one
+	second := newMember(t, db, f.org, "second@acme.com", models.RoleAdmin)
+	if code := demote(f.admin); code != http.StatusOK {
+		t.Errorf("demote one of two admins: status = %d, want %d", code, http.StatusOK)
+	}
+	if code := deactivate(second); code != http.StatusConflict {
+		t.Errorf("deactivate the remaining admin: status = %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestAccountResetsNeedSoleMembership(t *testing.T) {
+	db := newTestDB(t)
+	acme := newTenant(t, db, "Acme")
+	globex := newTenant(t, db, "Globex")
+	lockedUntil := time.Now().Add(time.Hour)
+	newAccount := func(email string) models.User {
+		user := newMember(t, db, acme.org, email, models.RoleEditor)
+		err := db.Model(&user).Updates(map[string]interface{}{
+			"mfa_enabled":           true,
+			"totp_secret":           "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			"failed_login_attempts": 5,
+			"locked_until":          lockedUntil,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	// shared also signs in to Globex, so Acme's admins mustn't remove what protects it there
+	shared := newAccount("shared@example.com")
+	mustCreate(t, db, &models.Membership{UserID: shared.ID, OrganizationID: globex.org.ID, Role: models.RoleViewer})
+	own := newAccount("own@acme.com")
+	permissions, _ := models.BuiltInRolePermissions(models.RoleAdmin)
+	r := newUserRouter(db, acme.admin, acme.org.ID, permissions)
+
+	reset := func(user models.User) int {
+		return serve(r, http.MethodDelete, fmt.Sprintf("/api/admin/users/%d/mfa", user.ID), "").Code
+	}
+	unlock := func(user models.User) int {
+		return serve(r, http.MethodPost, fmt.Sprintf("/api/admin/users/%d/unlock", user.ID), "").Code
+	}
+	reload := func(user models.User) models.User {
+		var stored models.User
+		if err := db.First(&stored, user.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+
+	if code := reset(shared); code != http.StatusForbidden {
+		t.Errorf("reset MFA of a member of another organization: status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := unlock(shared); code != http.StatusForbidden {
+		t.Errorf("unlock a member of another organization: status = %d, want %d", code, http.StatusForbidden)
+	}
+	if stored := reload(shared); !stored.MFAEnabled || stored.LockedUntil == nil {
+		t.Errorf("shared account: MFAEnabled = %v, LockedUntil = %v; want both kept", stored.MFAEnabled, stored.LockedUntil)
+	}
+
+	if code := reset(own); code != http.StatusOK {
+		t.Errorf("reset MFA of a member of this organization only: status = %d, want %d", code, http.StatusOK)
+	}
+	if code := unlock(own); code != http.StatusOK {
+		t.Errorf("unlock a member of this organization only: status = %d, want %d", code, http.StatusOK)
+	}
+	if stored := reload(own); stored.MFAEnabled || stored.LockedUntil != nil {
+		t.Errorf("own account: MFAEnabled = %v, LockedUntil = %v; want both cleared", stored.MFAEnabled, stored.LockedUntil)
+	}
+}
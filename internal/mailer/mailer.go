@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer delivers email. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// FromEnv returns an SMTP mailer when SMTP_HOST is set, otherwise a mailer that only
+// logs messages so local development works without a mail server
+func FromEnv() Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		log.Println("SMTP_HOST not set, emails will be written to the log")
+		return LogMailer{}
+	}
+
+	port := 587
+	if value := os.Getenv("SMTP_PORT"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid SMTP_PORT %q", value)
+		}
+		port = parsed
+	}
+
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Ventura <no-reply@ventura.local>"
+	}
+
+	return NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+}
+
+// LogMailer writes messages to the log instead of sending them
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
@@ -1,73 +1,207 @@
 package middleware
 
 import (
-	"fmt"
+	"crypto/subtle"
 	"net/http"
+	"strings"
+	"ventura/internal/audit"
 	"ventura/internal/auth"
+	"ventura/internal/models"
+	"ventura/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates JWT tokens from cookies
-func AuthMiddleware() gin.HandlerFunc {
+// Authentication methods stored under "auth_method" in the request context
+const (
+	AuthMethodCookie   = "cookie"
+	AuthMethodBearer   = "bearer"
+	AuthMethodAPIToken = "api_token"
+)
+
+// AuthMiddleware authenticates a request by, in order of precedence:
+// an API token or JWT access token in the Authorization: Bearer header,
+// or the access_token cookie. JWTs must belong to an active session.
+// The role's permissions are resolved into the context for RequirePermission.
+func AuthMiddleware(sessions *service.SessionService, apiTokens *service.APITokenService, permissions *service.PermissionService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get access token from cookie
-		tokenString, err := c.Cookie("access_token")
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "No authentication token provided"})
-			c.Abort()
+		authMethod := AuthMethodBearer
+		tokenString := bearerToken(c)
+
+		if tokenString != "" && service.IsAPIToken(tokenString) {
+			authenticateAPIToken(c, apiTokens, permissions, tokenString)
 			return
 		}
 
+		if tokenString == "" {
+			// Get access token from cookie
+			cookie, err := c.Cookie("access_token")
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "No authentication token provided"})
+				c.Abort()
+				return
+			}
+			tokenString = cookie
+			authMethod = AuthMethodCookie
+		}
+
 		// Validate token
-		claims, err := auth.ValidateToken(tokenString)
+		claims, err := auth.ValidateAccessToken(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
+		// Reject tokens whose session was revoked (logout, password or role change)
+		if !sessions.IsActive(claims.SessionID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		}
+
+		// Browsers attach cookies to cross-site requests; writes must prove they come from our frontend
+		if authMethod == AuthMethodCookie && !isSafeMethod(c.Request.Method) && !validCSRFToken(c, claims) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Missing or invalid CSRF token", "csrfRequired": true})
+			c.Abort()
+			return
+		}
+
 		// Attach user info to context
-		c.Set("user_id", claims.UserID)
-		c.Set("organization_id", claims.OrganizationID)
-		c.Set("user_email", claims.Email)
-		c.Set("user_role", claims.Role)
+		setClaims(c, claims)
+		c.Set("auth_method", authMethod)
+		c.Set("csrf_token", claims.CSRFToken)
+		c.Set("permissions", permissions.Permissions(claims.OrganizationID, claims.Role))
+
+		c.Next()
+	}
+}
+
+// authenticateAPIToken authenticates the request with an API token and audits its use
+func authenticateAPIToken(c *gin.Context, apiTokens *service.APITokenService, permissions *service.PermissionService, plaintext string) {
+	token, err := apiTokens.Authenticate(plaintext, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked API token"})
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", token.UserID)
+	c.Set("organization_id", token.OrganizationID)
+	c.Set("user_email", token.User.Email)
+	c.Set("user_role", token.User.Role)
+	c.Set("auth_method", AuthMethodAPIToken)
+	c.Set("api_token_id", token.ID)
+	c.Set("token_scopes", token.ScopeList())
+	c.Set("permissions", permissions.Permissions(token.OrganizationID, token.User.Role))
+	setActor(c, token.UserID, token.User.Email, token.OrganizationID)
+
+	c.Next()
+
+	apiTokens.RecordUse(token, c.Request.Method, c.Request.URL.Path, c.Writer.Status(), c.ClientIP())
+}
+
+// bearerToken returns the credential from an Authorization: Bearer header, if any
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// RequireScope restricts API-token requests to tokens granted the resource.
+// GET and HEAD need "<resource>:read", every other method "<resource>:write".
+// Requests authenticated any other way are not scope-limited.
+func RequireScope(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") != AuthMethodAPIToken {
+			c.Next()
+			return
+		}
+
+		required := resource + ":write"
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			required = resource + ":read"
+		}
+
+		scopes, _ := c.Get("token_scopes")
+		granted, _ := scopes.([]string)
+		if !models.ScopesAllow(granted, required) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API token is missing the " + required + " scope"})
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
 }
 
+// RequireSession rejects API-token requests, e.g. for managing the tokens themselves
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") == AuthMethodAPIToken {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint cannot be used with an API token"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // OptionalAuthMiddleware validates JWT tokens but doesn't require them
-func OptionalAuthMiddleware() gin.HandlerFunc {
+func OptionalAuthMiddleware(sessions *service.SessionService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString, err := c.Cookie("access_token")
 		if err == nil {
-			claims, err := auth.ValidateToken(tokenString)
-			if err == nil {
-				c.Set("user_id", claims.UserID)
-				c.Set("organization_id", claims.OrganizationID)
-				c.Set("user_email", claims.Email)
-				c.Set("user_role", claims.Role)
+			claims, err := auth.ValidateAccessToken(tokenString)
+			if err == nil && sessions.IsActive(claims.SessionID) {
+				setClaims(c, claims)
 			}
 		}
 		c.Next()
 	}
 }
 
-// RequireRole middleware checks if user has required role
-func RequireRole(role string) gin.HandlerFunc {
+// setClaims attaches the authenticated user's identity to the request context
+func setClaims(c *gin.Context, claims *auth.Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("organization_id", claims.OrganizationID)
+	c.Set("user_email", claims.Email)
+	c.Set("user_role", claims.Role)
+	c.Set("session_id", claims.SessionID)
+	setActor(c, claims.UserID, claims.Email, claims.OrganizationID)
+}
+
+// AuditActor attributes database changes made by unauthenticated requests, such as
+// registration or a password reset, to the client. AuthMiddleware replaces the actor
+// with the authenticated user.
+func AuditActor() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, exists := c.Get("user_role")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
-			c.Abort()
-			return
-		}
+		setActor(c, 0, "", 0)
+		c.Next()
+	}
+}
 
-		// Convert userRole to string for comparison (it's models.UserRole which is a string type)
-		userRoleStr := fmt.Sprintf("%v", userRole)
-		if userRoleStr != role {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+// setActor attributes the request's database changes to the user in the audit log
+func setActor(c *gin.Context, userID uint, email string, orgID uint) {
+	c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), audit.Actor{
+		UserID:         userID,
+		Email:          email,
+		OrganizationID: orgID,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}))
+}
+
+// RequirePermission rejects requests whose role doesn't grant the permission.
+// Must run after AuthMiddleware, which resolves the role's permissions.
+func RequirePermission(permission models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, _ := c.Get("permissions")
+		permissions, _ := granted.([]models.Permission)
+		if !models.HasPermission(permissions, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions: " + string(permission) + " is required"})
 			c.Abort()
 			return
 		}
@@ -75,3 +209,18 @@ func RequireRole(role string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// isSafeMethod reports whether an HTTP method only reads
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// validCSRFToken reports whether the request echoes the CSRF token of its session
+func validCSRFToken(c *gin.Context, claims *auth.Claims) bool {
+	header := c.GetHeader(auth.CSRFHeader)
+	return claims.CSRFToken != "" && subtle.ConstantTimeCompare([]byte(header), []byte(claims.CSRFToken)) == 1
+}
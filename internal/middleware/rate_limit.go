@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ipWindow counts requests from one client IP in the current fixed window
+type ipWindow struct {
+	start time.Time
+	count int
+}
+
+// ipRateLimiter is an in-memory fixed-window limiter keyed by client IP.
+// It is per process; put a shared limiter in front of the API when running several instances.
+type ipRateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	clients   map[string]*ipWindow
+	lastSweep time.Time
+}
+
+// allow counts a request and reports whether it is within the limit,
+// or how long the client must wait if it isn't
+func (l *ipRateLimiter) allow(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	// Drop finished windows now and then so the map doesn't grow without bound
+	if now.Sub(l.lastSweep) > l.window {
+		for key, w := range l.clients {
+			if now.Sub(w.start) >= l.window {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.clients[ip]
+	if !ok || now.Sub(w.start) >= l.window {
+		l.clients[ip] = &ipWindow{start: now, count: 1}
+		return true, 0
+	}
+
+	w.count++
+	if w.count > l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	return true, 0
+}
+
+// RateLimit allows at most limit requests per window from each client IP
+func RateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	limiter := &ipRateLimiter{
+		limit:     limit,
+		window:    window,
+		clients:   make(map[string]*ipWindow),
+		lastSweep: time.Now(),
+	}
+
+	return func(c *gin.Context) {
+		allowed, retryAfter := limiter.allow(c.ClientIP())
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please slow down"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
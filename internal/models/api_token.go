@@ -0,0 +1,100 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APITokenKind distinguishes tokens owned by a person from tokens owned by a service account
+type APITokenKind string
+
+const (
+	APITokenPersonal       APITokenKind = "personal"
+	APITokenServiceAccount APITokenKind = "service_account"
+)
+
+// APIToken is an org-scoped bearer token for scripts and integrations.
+// Only the SHA-256 hash of the token is stored; the plaintext is shown once at creation.
+type APIToken struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	OrganizationID uint         `gorm:"not null;index" json:"organizationId"`
+	UserID         uint         `gorm:"not null;index" json:"userId"` // Personal owner or service account user
+	Kind           APITokenKind `gorm:"type:varchar(20);not null" json:"kind"`
+	Name           string       `gorm:"not null" json:"name"`
+	Prefix         string       `gorm:"not null" json:"prefix"` // First characters of the token, for identification
+	TokenHash      string       `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes         string       `gorm:"type:text;not null" json:"-"` // Space-separated, see Scopes()
+	ExpiresAt      *time.Time   `json:"expiresAt,omitempty"`
+	LastUsedAt     *time.Time   `json:"lastUsedAt,omitempty"`
+	LastUsedIP     string       `json:"lastUsedIp,omitempty"`
+	RevokedAt      *time.Time   `json:"revokedAt,omitempty"`
+	CreatedByID    uint         `gorm:"not null" json:"createdById"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	UpdatedAt      time.Time    `json:"updatedAt"`
+
+	// Relationships
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// ScopeList returns the token's scopes as a slice
+func (t *APIToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+// IsActive reports whether the token can still be used
+func (t *APIToken) IsActive() bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || time.Now().Before(*t.ExpiresAt)
+}
+
+// Scope resources; a scope is "<resource>:read" or "<resource>:write"
+const (
+	ScopeResourceDashboard = "dashboard"
+	ScopeResourcePortfolio = "portfolio"
+	ScopeResourceDeals     = "deals"
+	ScopeResourceFounders  = "founders"
+	ScopeResourceUpdates   = "updates"
+	ScopeResourceTeam      = "team"
+	ScopeResourceAdmin     = "admin"
+)
+
+// ScopeResources lists every resource that can be granted to a token
+var ScopeResources = []string{
+	ScopeResourceDashboard,
+	ScopeResourcePortfolio,
+	ScopeResourceDeals,
+	ScopeResourceFounders,
+	ScopeResourceUpdates,
+	ScopeResourceTeam,
+	ScopeResourceAdmin,
+}
+
+// IsValidScope reports whether scope is a known resource:read or resource:write pair
+func IsValidScope(scope string) bool {
+	resource, access, ok := strings.Cut(scope, ":")
+	if !ok || (access != "read" && access != "write") {
+		return false
+	}
+	for _, r := range ScopeResources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopesAllow reports whether granted covers required. Write access implies read access.
+func ScopesAllow(granted []string, required string) bool {
+	resource, access, _ := strings.Cut(required, ":")
+	for _, scope := range granted {
+		if scope == required {
+			return true
+		}
+		if access == "read" && scope == resource+":write" {
+			return true
+		}
+	}
+	return false
+}
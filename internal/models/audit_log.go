@@ -1,21 +1,30 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
-// AuditLog tracks user actions for audit trail
+// AuditLog tracks user actions for audit trail. Each organization's entries form a hash
+// chain: an entry's Hash covers its contents and the previous entry's hash, so editing or
+// removing an entry breaks every hash after it.
 type AuditLog struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `gorm:"not null;index" json:"userId"`
-	UserEmail string    `gorm:"not null" json:"userEmail"`
-	UserName  string    `gorm:"not null" json:"userName"`
-	Action    string    `gorm:"not null" json:"action"`   // "create", "update", "delete", "login", "logout"
-	Entity    string    `gorm:"not null" json:"entity"`   // "company", "deal", "user", "founder", etc.
-	EntityID  uint      `json:"entityId"`                 // ID of the affected entity (0 if N/A)
-	Details   string    `gorm:"type:text" json:"details"` // JSON with change details
-	IPAddress string    `json:"ipAddress"`
-	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+	ID             uint            `gorm:"primaryKey" json:"id"`
+	OrganizationID uint            `gorm:"uniqueIndex:idx_audit_logs_chain" json:"organizationId"`              // Organization the action happened in (0 if N/A)
+	Sequence       uint64          `gorm:"not null;default:0;uniqueIndex:idx_audit_logs_chain" json:"sequence"` // Position in the organization's chain, starting at 1
+	PrevHash       string          `gorm:"type:varchar(64);not null;default:''" json:"prevHash"`                // Hash of the previous entry, empty for the first
+	Hash           string          `gorm:"type:varchar(64);not null;default:''" json:"hash"`
+	UserID         uint            `gorm:"not null;index" json:"userId"`
+	UserEmail      string          `gorm:"not null" json:"userEmail"`
+	UserName       string          `gorm:"not null" json:"userName"`
+	Action         string          `gorm:"not null" json:"action"`              // "create", "update", "delete", "login", "logout"
+	Entity         string          `gorm:"not null" json:"entity"`              // "company", "deal", "user", "founder", etc.
+	EntityID       uint            `json:"entityId"`                            // ID of the affected entity (0 if N/A)
+	Details        string          `gorm:"type:text" json:"details"`            // Human-readable summary
+	Changes        json.RawMessage `gorm:"type:jsonb" json:"changes,omitempty"` // Changed columns as {"column": {"old": ..., "new": ...}}
+	IPAddress      string          `json:"ipAddress"`
+	UserAgent      string          `json:"userAgent,omitempty"`
+	CreatedAt      time.Time       `gorm:"index" json:"createdAt"`
 }
 
 // Common action constants
@@ -26,13 +35,87 @@ const (
 	ActionLogin  = "login"
 	ActionLogout = "logout"
 	ActionInvite = "invite"
+	ActionRevoke = "revoke"
+	ActionUnlock = "unlock"
+
+	// Account recovery
+	ActionPasswordReset = "password_reset"
+	ActionEmailVerify   = "email_verify"
+
+	// MFA lifecycle
+	ActionMFAEnroll          = "mfa_enroll"
+	ActionMFARemove          = "mfa_remove"
+	ActionMFARecoveryCodes   = "mfa_recovery_codes"
+	ActionMFARecoveryCodeUse = "mfa_recovery_code_use"
+
+	// Organization membership
+	ActionJoin       = "join"
+	ActionOrgSwitch  = "org_switch"
+	ActionDeactivate = "deactivate"
+	ActionReactivate = "reactivate"
+	ActionHandover   = "handover"
+
+	// ActionAPITokenUse is recorded for every request authenticated by an API token
+	ActionAPITokenUse = "api_token_use"
+
+	// Audit log housekeeping
+	ActionExport  = "export"
+	ActionArchive = "archive"
+
+	// Organization deletion
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
 )
 
 // Common entity constants
 const (
-	EntityUser    = "user"
-	EntityCompany = "company"
-	EntityDeal    = "deal"
-	EntityFounder = "founder"
-	EntityTeam    = "team_assignment"
+	EntityUser            = "user"
+	EntityCompany         = "company"
+	EntityDeal            = "deal"
+	EntityAnalysis        = "deal_analysis"
+	EntityScore           = "deal_score"
+	EntityCriterion       = "scoring_criterion"
+	EntityStageEvent      = "deal_stage_event"
+	EntityStageTransition = "deal_stage_transition"
+	EntityActivity        = "deal_activity"
+	EntityMention         = "deal_activity_mention"
+	EntityRevision        = "deal_activity_revision"
+	EntityNotification    = "notification"
+	EntityFounder         = "founder"
+	EntityTeam            = "team_assignment"
+	EntityAPIToken        = "api_token"
+	EntityOrganization    = "organization"
+	EntityRole            = "role"
+	EntityInvitation      = "invitation"
+	EntityMembership      = "membership"
+	EntitySSOProvider     = "sso_provider"
+	EntityMonthlyUpdate   = "monthly_update"
+	EntityDocument        = "document"
+	EntityInvestment      = "investment"
+	EntityAuditLog        = "audit_log"
 )
+
+// AuditChainHead is the last entry of an organization's audit chain. New entries lock it
+// to chain onto it, and verification checks the chain still ends there, so entries can't
+// be cut off the end unnoticed.
+type AuditChainHead struct {
+	OrganizationID uint   `gorm:"primaryKey;autoIncrement:false"`
+	Sequence       uint64 `gorm:"not null"`
+	Hash           string `gorm:"type:varchar(64);not null"`
+}
+
+// AuditArchive records a run of audit entries moved out of the database by the retention
+// policy into a gzipped JSON Lines file. The live chain continues from LastHash.
+type AuditArchive struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"not null;index" json:"organizationId"`
+	FirstSequence  uint64    `gorm:"not null" json:"firstSequence"`
+	LastSequence   uint64    `gorm:"not null" json:"lastSequence"`
+	LastHash       string    `gorm:"type:varchar(64);not null" json:"lastHash"`
+	Entries        int       `gorm:"not null" json:"entries"`
+	From           time.Time `gorm:"not null" json:"from"`
+	To             time.Time `gorm:"not null" json:"to"`
+	Path           string    `gorm:"not null" json:"-"`
+	SHA256         string    `gorm:"type:varchar(64);not null" json:"sha256"` // Checksum of the archive file
+	CreatedAt      time.Time `json:"createdAt"`
+}
@@ -39,12 +39,9 @@ type Deal struct {
 	Valuation       decimal.Decimal `gorm:"type:decimal(20,2)"`
 	RoundStage      string          // Seed, Series A, etc.
 
-	// Scoring (1-10)
-	TeamScore     int `gorm:"default:0"`
-	ProductScore  int `gorm:"default:0"`
-	MarketScore   int `gorm:"default:0"`
-	TractionScore int `gorm:"default:0"`
-	TotalScore    int `gorm:"-"` // Calculated
+	// Scoring on the organization's scorecard, calculated from its reviewers' DealScores
+	Scores     map[string]float64 `gorm:"-"` // Average score (1-10) on each criterion scored
+	TotalScore float64            `gorm:"-"` // Weighted average of Scores
 
 	// Contact
 	FounderName  string
@@ -55,13 +52,11 @@ type Deal struct {
 	ArchivedAt         *time.Time `gorm:"index"`             // When deal was archived (null = active)
 	ConvertedCompanyID *uint      // Foreign key to created portfolio company
 
+	// Member responsible for the deal
+	OwnerID *uint `gorm:"index"`
+
 	// Metadata
 	Notes     string `gorm:"type:text"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
-
-// CalculateTotalScore computes the aggregate deal score
-func (d *Deal) CalculateTotalScore() {
-	d.TotalScore = d.TeamScore + d.ProductScore + d.MarketScore + d.TractionScore
-}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ActivityType is the kind of entry in a deal's activity log
+type ActivityType string
+
+const (
+	ActivityComment ActivityType = "comment"
+	ActivityCall    ActivityType = "call"
+	ActivityMeeting ActivityType = "meeting"
+	ActivityEmail   ActivityType = "email"
+)
+
+// IsValid reports whether t is a known activity type
+func (t ActivityType) IsValid() bool {
+	switch t {
+	case ActivityComment, ActivityCall, ActivityMeeting, ActivityEmail:
+		return true
+	default:
+		return false
+	}
+}
+
+// DealActivity is a comment, call, meeting or email logged on a deal
+type DealActivity struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizationID uint           `gorm:"not null;index" json:"organizationId"`
+	DealID         uint           `gorm:"not null;index" json:"dealId"`
+	Type           ActivityType   `gorm:"type:varchar(20);not null" json:"type"`
+	AuthorID       uint           `gorm:"not null;index" json:"authorId"`
+	AuthorName     string         `json:"authorName"`
+	Subject        string         `json:"subject,omitempty"`
+	Body           string         `gorm:"type:text" json:"body"`
+	OccurredAt     time.Time      `gorm:"index" json:"occurredAt"` // When the call, meeting or email took place
+	EditedAt       *time.Time     `json:"editedAt,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relationships
+	Mentions []DealActivityMention `gorm:"foreignKey:ActivityID" json:"mentions,omitempty"`
+}
+
+// DealActivityMention is a member @mentioned in an activity
+type DealActivityMention struct {
+	ID         uint   `gorm:"primaryKey" json:"-"`
+	ActivityID uint   `gorm:"not null;uniqueIndex:idx_activity_mention" json:"-"`
+	UserID     uint   `gorm:"not null;uniqueIndex:idx_activity_mention;index" json:"userId"`
+	UserName   string `json:"userName"`
+}
+
+// DealActivityRevision keeps an activity's subject and body as they were before an edit
+type DealActivityRevision struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ActivityID uint      `gorm:"not null;index" json:"activityId"`
+	Subject    string    `json:"subject,omitempty"`
+	Body       string    `gorm:"type:text" json:"body"`
+	EditedByID uint      `json:"editedById"`
+	CreatedAt  time.Time `json:"createdAt"` // When the edit replaced this version
+}
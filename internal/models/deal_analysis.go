@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AnalysisStatus is what an analyst did with the scores an AI analysis suggests
+type AnalysisStatus string
+
+const (
+	AnalysisPending  AnalysisStatus = "pending"
+	AnalysisAccepted AnalysisStatus = "accepted" // Its scores are the deal's AI scores
+	AnalysisRejected AnalysisStatus = "rejected"
+)
+
+// DealAnalysis is one AI analysis of a deal: what the model was asked, what it answered and
+// the scores it suggests. The deal's scores change only when an analyst accepts them.
+type DealAnalysis struct {
+	ID             uint            `gorm:"primaryKey" json:"id"`
+	OrganizationID uint            `gorm:"not null;index" json:"organizationId"`
+	DealID         uint            `gorm:"not null;index" json:"dealId"`
+	Model          string          `gorm:"type:varchar(100);not null" json:"model"`
+	PromptVersion  string          `gorm:"type:varchar(50);not null" json:"promptVersion"`
+	Input          json.RawMessage `gorm:"type:jsonb" json:"input"` // The deal fields and scorecard the prompt was built from
+	RawResponse    string          `gorm:"type:text" json:"rawResponse"`
+
+	// Parsed from the response
+	Scores       map[string]int `gorm:"type:jsonb;serializer:json" json:"scores"` // 1-10 by scorecard criterion key
+	Summary      string         `gorm:"type:text" json:"summary"`
+	KeyStrengths string         `gorm:"type:text" json:"keyStrengths"`
+	KeyRisks     string         `gorm:"type:text" json:"keyRisks"`
+
+	Status          AnalysisStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	RequestedByID   uint           `json:"requestedById"`
+	RequestedByName string         `json:"requestedByName"`
+	ReviewedByID    *uint          `json:"reviewedById,omitempty"`
+	ReviewedByName  string         `json:"reviewedByName,omitempty"`
+	ReviewedAt      *time.Time     `json:"reviewedAt,omitempty"`
+	CreatedAt       time.Time      `json:"createdAt"`
+}
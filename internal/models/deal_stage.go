@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// DealStages lists the pipeline stages in order
+var DealStages = []DealStage{StageIncoming, StageScreening, StageDueDiligence, StageTermSheet, StageClosed, StageLost}
+
+// IsValid reports whether s is one of the pipeline stages
+func (s DealStage) IsValid() bool {
+	for _, stage := range DealStages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether a deal in s is finished: closed and lost deals are archived
+// and leave the pipeline
+func (s DealStage) IsTerminal() bool {
+	return s == StageClosed || s == StageLost
+}
+
+// DealStageTransition allows an organization's deals to move from one stage to another.
+// Organizations without any use DefaultDealStageTransitions.
+type DealStageTransition struct {
+	ID             uint      `gorm:"primaryKey" json:"-"`
+	OrganizationID uint      `gorm:"not null;uniqueIndex:idx_deal_stage_transition" json:"-"`
+	FromStage      DealStage `gorm:"type:varchar(50);not null;uniqueIndex:idx_deal_stage_transition" json:"from"`
+	ToStage        DealStage `gorm:"type:varchar(50);not null;uniqueIndex:idx_deal_stage_transition" json:"to"`
+}
+
+// DefaultDealStageTransitions returns the transitions of an organization that hasn't set
+// its own: forward one stage at a time or back one, and out to lost from any open stage.
+// Only a term sheet can close.
+func DefaultDealStageTransitions() []DealStageTransition {
+	return []DealStageTransition{
+		{FromStage: StageIncoming, ToStage: StageScreening},
+		{FromStage: StageIncoming, ToStage: StageLost},
+		{FromStage: StageScreening, ToStage: StageIncoming},
+		{FromStage: StageScreening, ToStage: StageDueDiligence},
+		{FromStage: StageScreening, ToStage: StageLost},
+		{FromStage: StageDueDiligence, ToStage: StageScreening},
+		{FromStage: StageDueDiligence, ToStage: StageTermSheet},
+		{FromStage: StageDueDiligence, ToStage: StageLost},
+		{FromStage: StageTermSheet, ToStage: StageDueDiligence},
+		{FromStage: StageTermSheet, ToStage: StageClosed},
+		{FromStage: StageTermSheet, ToStage: StageLost},
+	}
+}
+
+// DealStageEvent records a deal moving from one stage to another
+type DealStageEvent struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"not null;index" json:"organizationId"`
+	DealID         uint      `gorm:"not null;index" json:"dealId"`
+	FromStage      DealStage `gorm:"type:varchar(50);not null" json:"from"`
+	ToStage        DealStage `gorm:"type:varchar(50);not null" json:"to"`
+	ActorID        uint      `gorm:"index" json:"actorId"` // Member who moved the deal
+	ActorName      string    `json:"actorName"`
+	Comment        string    `gorm:"type:text" json:"comment,omitempty"`
+	CreatedAt      time.Time `gorm:"index" json:"createdAt"`
+}
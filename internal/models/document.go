@@ -12,19 +12,19 @@ const (
 )
 
 type Document struct {
-	ID        uint             `gorm:"primaryKey"`
-	CompanyID uint             `gorm:"not null;index"`
-	Company   PortfolioCompany `gorm:"foreignKey:CompanyID"`
+	ID        uint             `gorm:"primaryKey" json:"id"`
+	CompanyID uint             `gorm:"not null;index" json:"companyId"`
+	Company   PortfolioCompany `gorm:"foreignKey:CompanyID" json:"-"`
 
-	FileName string       `gorm:"not null"`
-	FileType DocumentType `gorm:"type:varchar(50);not null"`
-	FileSize int64        // Size in bytes
-	FilePath string       `gorm:"not null"` // S3 path or local path
-	MimeType string
+	FileName string       `gorm:"not null" json:"fileName"`
+	FileType DocumentType `gorm:"type:varchar(50);not null" json:"fileType"`
+	FileSize int64        `json:"fileSize"`                 // Size in bytes
+	FilePath string       `gorm:"not null" json:"filePath"` // S3 path or local path
+	MimeType string       `json:"mimeType"`
 
-	Description string `gorm:"type:text"`
-	UploadedBy  string // Email of uploader
+	Description string `gorm:"type:text" json:"description"`
+	UploadedBy  string `json:"uploadedBy"` // Email of uploader
 
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
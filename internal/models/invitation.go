@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+)
+
+// Invitation status values, derived from the invitation's fields
+const (
+	InvitationPending = "pending"
+	InvitationUsed    = "used"
+	InvitationExpired = "expired"
+	InvitationRevoked = "revoked"
+)
+
+// Invitation lets people join an organization with a code. It grants a role and can set
+// up team assignments for the new member. An invitation addressed to an email can only be
+// accepted by that address and is single use; without one it is a shareable code.
+type Invitation struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	OrganizationID uint       `gorm:"not null;index" json:"organizationId"`
+	Code           string     `gorm:"uniqueIndex;not null" json:"code"`
+	Email          string     `gorm:"index" json:"email,omitempty"`
+	Role           UserRole   `gorm:"type:varchar(20);not null;default:'viewer'" json:"role"`
+	MaxUses        int        `gorm:"not null;default:1" json:"maxUses"`
+	UseCount       int        `gorm:"not null;default:0" json:"useCount"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expiresAt"`
+	RevokedAt      *time.Time `json:"revokedAt,omitempty"`
+	LastSentAt     *time.Time `json:"lastSentAt,omitempty"`
+	CreatedByID    uint       `gorm:"not null" json:"createdById"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+
+	// Relationships
+	Organization    *Organization              `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	CreatedBy       *User                      `gorm:"foreignKey:CreatedByID" json:"createdBy,omitempty"`
+	TeamAssignments []InvitationTeamAssignment `gorm:"foreignKey:InvitationID" json:"teamAssignments"`
+}
+
+// InvitationTeamAssignment is a team assignment applied to whoever accepts the invitation
+type InvitationTeamAssignment struct {
+	ID           uint     `gorm:"primaryKey" json:"-"`
+	InvitationID uint     `gorm:"not null;index" json:"-"`
+	CompanyID    uint     `gorm:"not null" json:"companyId"`
+	Role         TeamRole `gorm:"type:varchar(20);not null;default:'observer'" json:"role"`
+}
+
+// Status reports whether the invitation can still be accepted, or why not
+func (i *Invitation) Status() string {
+	switch {
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case i.UseCount >= i.MaxUses:
+		return InvitationUsed
+	case time.Now().After(i.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Membership gives a user access to an organization with a role in it.
+// A user can belong to several organizations; User.OrganizationID is the one
+// they land in at login.
+type Membership struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	UserID         uint      `gorm:"not null;uniqueIndex:idx_membership_user_org" json:"userId"`
+	OrganizationID uint      `gorm:"not null;uniqueIndex:idx_membership_user_org;index" json:"organizationId"`
+	Role           UserRole  `gorm:"type:varchar(20);not null;default:'viewer'" json:"role"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+
+	// A deactivated member can't sign in to the organization, but keeps their history in it
+	DeactivatedAt   *time.Time `gorm:"index" json:"deactivatedAt,omitempty"`
+	DeactivatedByID *uint      `json:"deactivatedById,omitempty"`
+
+	// Relationships
+	User         *User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+// AsUser returns the member with OrganizationID, Role, Organization and DeactivatedAt
+// describing this membership, which is how the rest of the app sees a user inside an organization
+func (m *Membership) AsUser() *User {
+	user := *m.User
+	user.OrganizationID = m.OrganizationID
+	user.Role = m.Role
+	user.Organization = m.Organization
+	user.DeactivatedAt = m.DeactivatedAt
+	return &user
+}
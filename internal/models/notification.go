@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// NotificationType is what a notification tells its recipient about
+type NotificationType string
+
+const (
+	NotificationMention NotificationType = "mention"
+)
+
+// Notification tells a member about something that concerns them in an organization
+type Notification struct {
+	ID             uint             `gorm:"primaryKey" json:"id"`
+	OrganizationID uint             `gorm:"not null;index:idx_notification_recipient" json:"organizationId"`
+	UserID         uint             `gorm:"not null;index:idx_notification_recipient" json:"-"`
+	Type           NotificationType `gorm:"type:varchar(20);not null" json:"type"`
+	ActorName      string           `json:"actorName"`
+	Message        string           `json:"message"`
+	DealID         *uint            `json:"dealId,omitempty"`
+	ActivityID     *uint            `json:"activityId,omitempty"`
+	ReadAt         *time.Time       `gorm:"index" json:"readAt,omitempty"`
+	CreatedAt      time.Time        `json:"createdAt"`
+}
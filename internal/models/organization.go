@@ -6,33 +6,136 @@ import (
 	"gorm.io/gorm"
 )
 
+// MFAPolicy controls which members of an organization must use two-factor authentication
+type MFAPolicy string
+
+const (
+	MFAPolicyOptional MFAPolicy = "optional"
+	MFAPolicyAdmins   MFAPolicy = "admins"
+	MFAPolicyAll      MFAPolicy = "all"
+)
+
+// UpdateCadence is how often portfolio companies are expected to send an update
+type UpdateCadence string
+
+const (
+	UpdateCadenceMonthly   UpdateCadence = "monthly"
+	UpdateCadenceQuarterly UpdateCadence = "quarterly"
+)
+
+// OrganizationSettings are an organization's reporting preferences
+type OrganizationSettings struct {
+	BaseCurrency             string        `gorm:"type:varchar(3);not null;default:'USD'" json:"baseCurrency"`       // ISO 4217 code amounts are recorded in
+	FiscalYearStartMonth     int           `gorm:"not null;default:1" json:"fiscalYearStartMonth"`                   // 1 (January) to 12
+	Timezone                 string        `gorm:"type:varchar(64);not null;default:'UTC'" json:"timezone"`          // IANA name, e.g. Europe/London
+	UpdateCadence            UpdateCadence `gorm:"type:varchar(20);not null;default:'monthly'" json:"updateCadence"` // How often companies report
+	HealthGreenRunwayMonths  int           `gorm:"not null;default:6" json:"healthGreenRunwayMonths"`                // Runway from which a company is green
+	HealthYellowRunwayMonths int           `gorm:"not null;default:3" json:"healthYellowRunwayMonths"`               // Runway from which a company is yellow; red below
+}
+
+// DefaultOrganizationSettings returns the settings organizations start out with
+func DefaultOrganizationSettings() OrganizationSettings {
+	return OrganizationSettings{
+		BaseCurrency:             "USD",
+		FiscalYearStartMonth:     1,
+		Timezone:                 "UTC",
+		UpdateCadence:            UpdateCadenceMonthly,
+		HealthGreenRunwayMonths:  6,
+		HealthYellowRunwayMonths: 3,
+	}
+}
+
+// Location returns the organization's timezone, or UTC if it is unknown
+func (s OrganizationSettings) Location() *time.Location {
+	if location, err := time.LoadLocation(s.Timezone); err == nil {
+		return location
+	}
+	return time.UTC
+}
+
+// CurrentMonth returns the month it is at t in the organization's timezone. Like report
+// months, it is the first of the month at midnight UTC.
+func (s OrganizationSettings) CurrentMonth(t time.Time) time.Time {
+	local := t.In(s.Location())
+	return time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// LastReportingPeriod returns the months [from, to) of the last reporting period that has
+// ended at t: the previous month, or the previous fiscal quarter for a quarterly cadence
+func (s OrganizationSettings) LastReportingPeriod(t time.Time) (from, to time.Time) {
+	to = s.CurrentMonth(t)
+	if s.UpdateCadence == UpdateCadenceQuarterly {
+		to = s.FiscalQuarterStart(to)
+		return to.AddDate(0, -3, 0), to
+	}
+	return to.AddDate(0, -1, 0), to
+}
+
+// FiscalQuarterStart returns the first month of the fiscal quarter month falls in
+func (s OrganizationSettings) FiscalQuarterStart(month time.Time) time.Time {
+	offset := (int(month.Month()) - s.fiscalYearStartMonth() + 12) % 12
+	return time.Date(month.Year(), month.Month()-time.Month(offset%3), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// FiscalQuarter returns the fiscal year and quarter (1 to 4) month falls in. A fiscal year
+// is named after the calendar year it ends in.
+func (s OrganizationSettings) FiscalQuarter(month time.Time) (year, quarter int) {
+	start := s.fiscalYearStartMonth()
+	offset := (int(month.Month()) - start + 12) % 12
+	year = month.Year()
+	if start > 1 && int(month.Month()) >= start {
+		year++
+	}
+	return year, offset/3 + 1
+}
+
+// HealthStatus returns the health of a company with runwayMonths of runway left
+func (s OrganizationSettings) HealthStatus(runwayMonths int) string {
+	switch {
+	case runwayMonths >= s.HealthGreenRunwayMonths:
+		return "green"
+	case runwayMonths >= s.HealthYellowRunwayMonths:
+		return "yellow"
+	default:
+		return "red"
+	}
+}
+
+func (s OrganizationSettings) fiscalYearStartMonth() int {
+	if s.FiscalYearStartMonth < 1 || s.FiscalYearStartMonth > 12 {
+		return 1
+	}
+	return s.FiscalYearStartMonth
+}
+
 // Organization represents a tenant/firm in the multi-tenant system
 type Organization struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Name      string         `gorm:"not null" json:"name"`
-	Slug      string         `gorm:"uniqueIndex;not null" json:"slug"`
-	CreatedAt time.Time      `json:"createdAt"`
-	UpdatedAt time.Time      `json:"updatedAt"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                 uint                 `gorm:"primaryKey" json:"id"`
+	Name               string               `gorm:"not null" json:"name"`
+	Slug               string               `gorm:"uniqueIndex;not null" json:"slug"`
+	MFAPolicy          MFAPolicy            `gorm:"type:varchar(20);default:'optional'" json:"mfaPolicy"`
+	AuditRetentionDays int                  `gorm:"not null;default:0" json:"auditRetentionDays"` // Days before audit entries are archived; 0 keeps them
+	Settings           OrganizationSettings `gorm:"embedded" json:"settings"`
+	CreatedAt          time.Time            `json:"createdAt"`
+	UpdatedAt          time.Time            `json:"updatedAt"`
+	DeletedAt          gorm.DeletedAt       `gorm:"index" json:"-"`
+
+	// Deletion: a deleted organization can be restored until PurgeAt, when its data is erased
+	DeletionRequestedByID *uint      `json:"-"`
+	PurgeAt               *time.Time `gorm:"index" json:"purgeAt,omitempty"`
 
 	// Relationships
 	Users []User `gorm:"foreignKey:OrganizationID" json:"users,omitempty"`
 }
 
-// InviteCode represents an invitation to join an organization
-type InviteCode struct {
-	ID             uint           `gorm:"primaryKey" json:"id"`
-	OrganizationID uint           `gorm:"not null;index" json:"organizationId"`
-	Code           string         `gorm:"uniqueIndex;not null" json:"code"`
-	CreatedByID    uint           `gorm:"not null" json:"createdById"`
-	UsedByID       *uint          `json:"usedById,omitempty"`
-	ExpiresAt      time.Time      `gorm:"not null" json:"expiresAt"`
-	UsedAt         *time.Time     `json:"usedAt,omitempty"`
-	CreatedAt      time.Time      `json:"createdAt"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
-
-	// Relationships
-	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
-	CreatedBy    *User         `gorm:"foreignKey:CreatedByID" json:"createdBy,omitempty"`
-	UsedBy       *User         `gorm:"foreignKey:UsedByID" json:"usedBy,omitempty"`
+// RequiresMFA reports whether the policy obliges a user with this role to enroll
+func (o *Organization) RequiresMFA(role UserRole) bool {
+	switch o.MFAPolicy {
+	case MFAPolicyAll:
+		return true
+	case MFAPolicyAdmins:
+		return role == RoleAdmin
+	default:
+		return false
+	}
 }
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ExportStatus is the progress of an organization export
+type ExportStatus string
+
+const (
+	ExportPending   ExportStatus = "pending"
+	ExportRunning   ExportStatus = "running"
+	ExportCompleted ExportStatus = "completed"
+	ExportFailed    ExportStatus = "failed"
+	ExportExpired   ExportStatus = "expired" // The file was deleted after ExpiresAt
+)
+
+// OrganizationExport is a zip of all of an organization's data, built in the background
+// at an admin's request and kept for download until ExpiresAt
+type OrganizationExport struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	OrganizationID uint         `gorm:"not null;index" json:"organizationId"`
+	RequestedByID  uint         `gorm:"not null" json:"requestedById"`
+	Status         ExportStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Error          string       `gorm:"type:text" json:"error,omitempty"`
+	Path           string       `json:"-"`
+	Size           int64        `json:"size,omitempty"`                           // Bytes
+	SHA256         string       `gorm:"type:varchar(64)" json:"sha256,omitempty"` // Checksum of the zip file
+	CompletedAt    *time.Time   `json:"completedAt,omitempty"`
+	ExpiresAt      *time.Time   `gorm:"index" json:"expiresAt,omitempty"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	UpdatedAt      time.Time    `json:"updatedAt"`
+}
+
+// InProgress reports whether the export is still being built
+func (e *OrganizationExport) InProgress() bool {
+	return e.Status == ExportPending || e.Status == ExportRunning
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Permission is a single capability a role can grant
+type Permission string
+
+const (
+	PermCompanyWrite    Permission = "company.write"
+	PermCompanyDelete   Permission = "company.delete"
+	PermCompanyAll      Permission = "company.all"
+	PermInvestmentWrite Permission = "investment.write"
+	PermDealWrite       Permission = "deal.write"
+	PermDealScore       Permission = "deal.score"
+	PermFounderWrite    Permission = "founder.write"
+	PermFounderDelete   Permission = "founder.delete"
+	PermUpdateWrite     Permission = "update.write"
+	PermUpdateDelete    Permission = "update.delete"
+	PermTeamManage      Permission = "team.manage"
+	PermUserManage      Permission = "user.manage"
+	PermRoleManage      Permission = "role.manage"
+	PermAuditRead       Permission = "audit.read"
+	PermOrgManage       Permission = "org.manage"
+	PermAPITokenManage  Permission = "token.manage"
+)
+
+// PermissionInfo describes a permission for the admin UI
+type PermissionInfo struct {
+	Key         Permission `json:"key"`
+	Description string     `json:"description"`
+}
+
+// PermissionCatalogue lists every permission that can be granted to a role
+var PermissionCatalogue = []PermissionInfo{
+	{PermCompanyWrite, "Create and edit portfolio companies"},
+	{PermCompanyDelete, "Delete portfolio companies"},
+	{PermCompanyAll, "See and edit restricted companies without a team assignment"},
+	{PermInvestmentWrite, "Record investments"},
+	{PermDealWrite, "Create deals and move them through the pipeline"},
+	{PermDealScore, "Run AI scoring on deals"},
+	{PermFounderWrite, "Add and edit founders"},
+	{PermFounderDelete, "Delete founders"},
+	{PermUpdateWrite, "Add and edit monthly updates"},
+	{PermUpdateDelete, "Delete monthly updates"},
+	{PermTeamManage, "Assign team members to companies"},
+	{PermUserManage, "Invite, edit, unlock and remove users"},
+	{PermRoleManage, "Create and edit custom roles"},
+	{PermAuditRead, "View the audit log"},
+	{PermOrgManage, "Change organization security settings (MFA policy, SSO)"},
+	{PermAPITokenManage, "Manage API tokens and service accounts"},
+}
+
+// IsValidPermission reports whether p is in the catalogue
+func IsValidPermission(p Permission) bool {
+	for _, info := range PermissionCatalogue {
+		if info.Key == p {
+			return true
+		}
+	}
+	return false
+}
+
+// editorPermissions lets editors change portfolio and deal data but not administer the firm
+var editorPermissions = []Permission{
+	PermCompanyWrite,
+	PermCompanyDelete,
+	PermInvestmentWrite,
+	PermDealWrite,
+	PermDealScore,
+	PermFounderWrite,
+	PermFounderDelete,
+	PermUpdateWrite,
+	PermUpdateDelete,
+	PermTeamManage,
+}
+
+// BuiltInRolePermissions returns the permissions of a built-in role; ok is false for custom roles
+func BuiltInRolePermissions(role UserRole) (permissions []Permission, ok bool) {
+	switch role {
+	case RoleAdmin:
+		permissions = make([]Permission, len(PermissionCatalogue))
+		for i, info := range PermissionCatalogue {
+			permissions[i] = info.Key
+		}
+		return permissions, true
+	case RoleEditor:
+		return append([]Permission(nil), editorPermissions...), true
+	case RoleViewer:
+		return []Permission{}, true
+	default:
+		return nil, false
+	}
+}
+
+// IsBuiltInRole reports whether role is admin, editor or viewer
+func IsBuiltInRole(role UserRole) bool {
+	_, ok := BuiltInRolePermissions(role)
+	return ok
+}
+
+// HasPermission reports whether granted contains p
+func HasPermission(granted []Permission, p Permission) bool {
+	for _, g := range granted {
+		if g == p {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomRole is an organization-defined role. Users reference it by Key in User.Role.
+type CustomRole struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"not null;uniqueIndex:idx_custom_role_org_key" json:"organizationId"`
+	Key            UserRole  `gorm:"type:varchar(20);not null;uniqueIndex:idx_custom_role_org_key" json:"key"`
+	Name           string    `gorm:"not null" json:"name"`
+	Description    string    `json:"description"`
+	Permissions    string    `gorm:"type:text;not null" json:"-"` // Space-separated, see PermissionList()
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// PermissionList returns the role's permissions as a slice
+func (r *CustomRole) PermissionList() []Permission {
+	fields := strings.Fields(r.Permissions)
+	permissions := make([]Permission, len(fields))
+	for i, field := range fields {
+		permissions[i] = Permission(field)
+	}
+	return permissions
+}
@@ -25,6 +25,9 @@ type PortfolioCompany struct {
 	// Notification Settings
 	UpdatesNotificationsEnabled bool `gorm:"default:true" json:"updatesNotificationsEnabled"`
 
+	// Access control: restricted companies are only visible to their team and to admins
+	Restricted bool `gorm:"default:false;index" json:"restricted"`
+
 	// Calculated fields
 	RunwayMonths int    `gorm:"-" json:"runwayMonths"` // Calculated: CashRemaining / MonthlyBurnRate
 	HealthStatus string `gorm:"-" json:"healthStatus"` // green, yellow, red
@@ -49,14 +52,8 @@ func (p *PortfolioCompany) CalculateRunway() {
 	}
 }
 
-// CalculateHealthStatus determines health based on runway
-func (p *PortfolioCompany) CalculateHealthStatus() {
+// CalculateHealthStatus determines health based on runway, against the organization's thresholds
+func (p *PortfolioCompany) CalculateHealthStatus(settings OrganizationSettings) {
 	p.CalculateRunway()
-	if p.RunwayMonths >= 6 {
-		p.HealthStatus = "green"
-	} else if p.RunwayMonths >= 3 {
-		p.HealthStatus = "yellow"
-	} else {
-		p.HealthStatus = "red"
-	}
+	p.HealthStatus = settings.HealthStatus(p.RunwayMonths)
 }
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// ScoringCriterion is one criterion of an organization's deal scorecard, scored 1-10.
+// Organizations without any use DefaultScoringCriteria.
+type ScoringCriterion struct {
+	ID             uint         `gorm:"primaryKey" json:"-"`
+	OrganizationID uint         `gorm:"not null;uniqueIndex:idx_scoring_criterion" json:"-"`
+	Key            string       `gorm:"type:varchar(50);not null;uniqueIndex:idx_scoring_criterion" json:"key"` // What scores refer to it by, e.g. team
+	Name           string       `gorm:"not null" json:"name"`
+	Description    string       `gorm:"type:text" json:"description,omitempty"`
+	Weight         int          `gorm:"not null" json:"weight"` // Percent of the total score; a scorecard's weights add up to 100
+	Scale          []ScaleLevel `gorm:"type:jsonb;serializer:json" json:"scale,omitempty"`
+	Position       int          `gorm:"not null" json:"-"`
+}
+
+// ScaleLevel describes what a score on a criterion's 1-10 scale means
+type ScaleLevel struct {
+	Score       int    `json:"score"`
+	Description string `json:"description"`
+}
+
+// DefaultScoringCriteria returns the scorecard of an organization that hasn't set its own:
+// team, product, market and traction, weighted equally
+func DefaultScoringCriteria() []ScoringCriterion {
+	return []ScoringCriterion{
+		{Key: "team", Name: "Team", Weight: 25},
+		{Key: "product", Name: "Product", Weight: 25},
+		{Key: "market", Name: "Market", Weight: 25},
+		{Key: "traction", Name: "Traction", Weight: 25},
+	}
+}
+
+// ScoreSource is who gave a deal score
+type ScoreSource string
+
+const (
+	ScoreSourceReviewer ScoreSource = "reviewer" // A member
+	ScoreSourceAI       ScoreSource = "ai"       // An accepted AI analysis
+	ScoreSourceLegacy   ScoreSource = "legacy"   // Imported from before scorecards
+)
+
+// DealScore is one reviewer's score of a deal on one criterion. A deal's score on a
+// criterion is the average of its reviewers'; the accepted AI analysis counts as one reviewer.
+type DealScore struct {
+	ID             uint        `gorm:"primaryKey" json:"-"`
+	OrganizationID uint        `gorm:"not null;index" json:"-"`
+	DealID         uint        `gorm:"not null;uniqueIndex:idx_deal_score" json:"dealId"`
+	Criterion      string      `gorm:"type:varchar(50);not null;uniqueIndex:idx_deal_score" json:"criterion"`
+	Source         ScoreSource `gorm:"type:varchar(20);not null;uniqueIndex:idx_deal_score" json:"source"`
+	ReviewerID     uint        `gorm:"not null;uniqueIndex:idx_deal_score" json:"reviewerId,omitempty"` // 0 unless a member gave it
+	ReviewerName   string      `json:"reviewerName"`
+	AnalysisID     *uint       `json:"analysisId,omitempty"` // The accepted analysis an AI score comes from
+	Score          int         `gorm:"not null" json:"score"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+}
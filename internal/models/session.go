@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is a server-side refresh session created at login.
+// Every refresh rotates TokenID; presenting any older token ID means the
+// refresh token was replayed and the whole session is revoked.
+// A session is only valid in the organization its sign-in was for: switching to another
+// organization signs in again and replaces the session.
+type Session struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"not null;index" json:"userId"`
+	OrganizationID  uint       `gorm:"not null;index" json:"organizationId"`
+	AuthMethod      string     `gorm:"type:varchar(20);not null;default:''" json:"authMethod"` // How the user signed in, see SessionAuth*
+	MFAVerified     bool       `gorm:"not null;default:false" json:"mfaVerified"`              // A second factor was checked at sign-in
+	TokenID         string     `gorm:"not null" json:"-"`                                      // jti of the current refresh token
+	CSRFToken       string     `gorm:"not null;default:''" json:"-"`                           // Required on cookie-authenticated writes; carried in the access tokens
+	PreviousTokenID string     `json:"-"`                                                      // jti replaced by the last rotation
+	RotatedAt       *time.Time `json:"-"`
+	UserAgent       string     `json:"userAgent"`
+	IPAddress       string     `json:"ipAddress"`
+	LastUsedAt      time.Time  `json:"lastUsedAt"`
+	ExpiresAt       time.Time  `gorm:"not null;index" json:"expiresAt"`
+	RevokedAt       *time.Time `gorm:"index" json:"revokedAt,omitempty"`
+	RevokedReason   string     `json:"-"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+// How the user of a session signed in
+const (
+	SessionAuthPassword = "password"
+	SessionAuthSSO      = "sso" // Through the organization's identity provider
+)
+
+// Session revocation reasons
+const (
+	SessionRevokedLogout         = "logout"
+	SessionRevokedByUser         = "signed_out_by_user"
+	SessionRevokedPasswordChange = "password_changed"
+	SessionRevokedRoleChange     = "role_changed"
+	SessionRevokedTokenReuse     = "refresh_token_reuse"
+	SessionRevokedPasswordReset  = "password_reset"
+	SessionRevokedMFAReset       = "mfa_reset"
+	SessionRevokedMFARequired    = "mfa_required"
+	SessionRevokedOrgSwitch      = "organization_switched"
+	SessionRevokedMembership     = "removed_from_organization"
+	SessionRevokedOrgDeleted     = "organization_deleted"
+	SessionRevokedDeactivated    = "deactivated"
+)
+
+// IsActive reports whether the session can still be used
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
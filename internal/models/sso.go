@@ -0,0 +1,59 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// OIDCProvider is an organization's OpenID Connect identity provider configuration
+type OIDCProvider struct {
+	ID                   uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID       uint      `gorm:"uniqueIndex;not null" json:"organizationId"`
+	Issuer               string    `gorm:"not null" json:"issuer"`
+	ClientID             string    `gorm:"not null" json:"clientId"`
+	ClientSecret         string    `gorm:"not null" json:"-"`
+	AllowedDomains       string    `gorm:"type:text" json:"-"` // Space-separated email domains; empty allows any
+	DefaultRole          UserRole  `gorm:"type:varchar(20);default:'viewer'" json:"defaultRole"`
+	Enabled              bool      `gorm:"default:true" json:"enabled"`
+	DisablePasswordLogin bool      `gorm:"default:false" json:"disablePasswordLogin"` // Admins keep password login as a break-glass path
+	CreatedAt            time.Time `json:"createdAt"`
+	UpdatedAt            time.Time `json:"updatedAt"`
+}
+
+// DomainList returns the allowed email domains
+func (p *OIDCProvider) DomainList() []string {
+	if p.AllowedDomains == "" {
+		return []string{}
+	}
+	return strings.Fields(p.AllowedDomains)
+}
+
+// AllowsEmail reports whether an email address is in one of the allowed domains
+func (p *OIDCProvider) AllowsEmail(email string) bool {
+	domains := p.DomainList()
+	if len(domains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range domains {
+		if domain == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// UserIdentity links a user to an account at an external identity provider
+type UserIdentity struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;index" json:"userId"`
+	Issuer      string    `gorm:"not null;uniqueIndex:idx_identity_issuer_subject" json:"issuer"`
+	Subject     string    `gorm:"not null;uniqueIndex:idx_identity_issuer_subject" json:"subject"`
+	Email       string    `json:"email"`
+	LastLoginAt time.Time `json:"lastLoginAt"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
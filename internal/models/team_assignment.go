@@ -13,6 +13,20 @@ const (
 	TeamRoleObserver TeamRole = "observer"
 )
 
+// Rank orders team roles from observer (1) to lead (3)
+func (r TeamRole) Rank() int {
+	switch r {
+	case TeamRoleLead:
+		return 3
+	case TeamRoleAnalyst:
+		return 2
+	case TeamRoleObserver:
+		return 1
+	default:
+		return 0
+	}
+}
+
 // TeamAssignment links users to portfolio companies
 type TeamAssignment struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
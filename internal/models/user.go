@@ -11,18 +11,32 @@ type UserRole string
 
 const (
 	RoleAdmin  UserRole = "admin"
+	RoleEditor UserRole = "editor"
 	RoleViewer UserRole = "viewer"
 )
 
 type User struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	OrganizationID uint      `gorm:"not null;index" json:"organizationId"`
-	Email          string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password       string    `gorm:"not null" json:"-"` // Never send password in JSON
-	Name           string    `gorm:"not null" json:"name"`
-	Role           UserRole  `gorm:"type:varchar(20);default:'viewer'" json:"role"`
-	CreatedAt      time.Time `json:"createdAt"`
-	UpdatedAt      time.Time `json:"updatedAt"`
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	OrganizationID      uint       `gorm:"not null;index" json:"organizationId"`
+	Email               string     `gorm:"uniqueIndex;not null" json:"email"`
+	Password            string     `gorm:"not null" json:"-"` // Never send password in JSON
+	Name                string     `gorm:"not null" json:"name"`
+	Role                UserRole   `gorm:"type:varchar(20);default:'viewer'" json:"role"`
+	IsServiceAccount    bool       `gorm:"default:false" json:"isServiceAccount"` // Can't log in, acts only through API tokens
+	EmailVerifiedAt     *time.Time `json:"emailVerifiedAt,omitempty"`
+	MustChangePassword  bool       `gorm:"default:false" json:"mustChangePassword"` // Set for users invited with a temporary password (before invitation codes) until they replace it
+	FailedLoginAttempts int        `gorm:"default:0" json:"failedLoginAttempts"`
+	LockedUntil         *time.Time `json:"lockedUntil,omitempty"` // Further logins are refused until then
+	MFAEnabled          bool       `gorm:"default:false" json:"mfaEnabled"`
+	MFAEnrolledAt       *time.Time `json:"mfaEnrolledAt,omitempty"`
+	TOTPSecret          string     `json:"-"` // Base32 secret of the confirmed authenticator
+	TOTPPendingSecret   string     `json:"-"` // Secret awaiting confirmation during enrollment
+	TOTPLastUsedStep    int64      `json:"-"` // Last accepted time step, so a code can't be replayed
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+
+	// Set when the user was loaded as a deactivated member of OrganizationID
+	DeactivatedAt *time.Time `gorm:"-" json:"deactivatedAt,omitempty"`
 
 	// Relationships
 	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
@@ -52,6 +66,27 @@ func (u *User) BeforeUpdate(tx *gorm.DB) error {
 	return nil
 }
 
+// IsEmailVerified reports whether the user has proven ownership of their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// SetPassword hashes and sets a new password. Use it for updates; Save does not
+// reliably trigger the BeforeUpdate hashing hook.
+func (u *User) SetPassword(password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.Password = string(hashedPassword)
+	return nil
+}
+
+// IsLocked reports whether login is temporarily blocked after failed attempts
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
 // CheckPassword compares a plain text password with the hashed password
 func (u *User) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// UserToken purposes
+const (
+	UserTokenPasswordReset       = "password_reset"
+	UserTokenEmailVerification   = "email_verification"
+	UserTokenOrganizationRestore = "organization_restore"
+)
+
+// UserToken records an emailed action token (password reset, email verification,
+// organization restore)
+// by its jti so that it can only be redeemed once
+type UserToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"userId"`
+	Purpose   string     `gorm:"type:varchar(30);not null" json:"purpose"`
+	TokenID   string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
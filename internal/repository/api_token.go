@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"ventura/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type APITokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAPITokenRepository(db *gorm.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+// WithContext returns the repository bound to ctx
+func (r *APITokenRepository) WithContext(ctx context.Context) *APITokenRepository {
+	return &APITokenRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create creates a new API token
+func (r *APITokenRepository) Create(token *models.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByHash finds a token by the hash of its plaintext, with its user preloaded
+func (r *APITokenRepository) FindByHash(hash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.Preload("User").Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetByIDAndOrganization returns a token by ID only if it belongs to the organization
+func (r *APITokenRepository) GetByIDAndOrganization(id uint, orgID uint) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.Preload("User").Where("id = ? AND organization_id = ?", id, orgID).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetAllByOrganization returns all tokens of an organization, newest first
+func (r *APITokenRepository) GetAllByOrganization(orgID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := r.db.Preload("User").Where("organization_id = ?", orgID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// TouchLastUsed records when and from where a token was last used
+func (r *APITokenRepository) TouchLastUsed(id uint, ipAddress string) error {
+	return r.db.Model(&models.APIToken{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": time.Now(),
+		"last_used_ip": ipAddress,
+	}).Error
+}
+
+// Revoke revokes a token
+func (r *APITokenRepository) Revoke(id uint) error {
+	return r.db.Model(&models.APIToken{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now()).Error
+}
@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"time"
 	"ventura/internal/models"
+	"ventura/internal/tenancy"
 
 	"gorm.io/gorm"
 )
@@ -14,77 +16,161 @@ func NewAuditLogRepository(db *gorm.DB) *AuditLogRepository {
 	return &AuditLogRepository{db: db}
 }
 
+// AuditLogFilter selects an organization's audit logs. Zero fields don't filter.
+type AuditLogFilter struct {
+	OrganizationID uint
+	UserID         uint
+	Entity         string
+	EntityID       uint
+	Action         string
+	From           *time.Time // Inclusive
+	To             *time.Time // Exclusive
+}
+
 // Create creates a new audit log entry
 func (r *AuditLogRepository) Create(log *models.AuditLog) error {
 	return r.db.Create(log).Error
 }
 
-// GetAll returns all audit logs with pagination
-func (r *AuditLogRepository) GetAll(limit, offset int) ([]models.AuditLog, int64, error) {
+// GetFiltered returns an organization's audit logs with optional filters, most recent first
+func (r *AuditLogRepository) GetFiltered(filter AuditLogFilter, limit, offset int) ([]models.AuditLog, int64, error) {
 	var logs []models.AuditLog
 	var total int64
 
-	// Get total count
-	if err := r.db.Model(&models.AuditLog{}).Count(&total).Error; err != nil {
+	query := r.filtered(r.organization(filter.OrganizationID), filter)
+
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// Get paginated results ordered by most recent first
-	err := r.db.Order("created_at DESC").Limit(limit).Offset(offset).Find(&logs).Error
+	err := query.Order("sequence DESC").Limit(limit).Offset(offset).Find(&logs).Error
 	return logs, total, err
 }
 
-// GetByUserID returns audit logs for a specific user
-func (r *AuditLogRepository) GetByUserID(userID uint, limit, offset int) ([]models.AuditLog, int64, error) {
-	var logs []models.AuditLog
-	var total int64
+// Stream calls fn for each of an organization's audit logs matching filter in chain order,
+// without loading them all at once
+func (r *AuditLogRepository) Stream(filter AuditLogFilter, fn func(*models.AuditLog) error) error {
+	return r.each(filter.OrganizationID, func(tx *gorm.DB) *gorm.DB {
+		return r.filtered(tx, filter).Order("sequence")
+	}, fn)
+}
 
-	query := r.db.Model(&models.AuditLog{}).Where("user_id = ?", userID)
+// organization returns the repository's db admitted to an organization's entries
+func (r *AuditLogRepository) organization(orgID uint) *gorm.DB {
+	return tenancy.Organization(r.db, orgID)
+}
 
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
+func (r *AuditLogRepository) filtered(db *gorm.DB, filter AuditLogFilter) *gorm.DB {
+	query := db.Model(&models.AuditLog{}).Where("organization_id = ?", filter.OrganizationID)
 
-	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&logs).Error
-	return logs, total, err
+	if filter.UserID > 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Entity != "" {
+		query = query.Where("entity = ?", filter.Entity)
+	}
+	if filter.EntityID > 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
 }
 
-// GetByEntity returns audit logs for a specific entity type
-func (r *AuditLogRepository) GetByEntity(entity string, limit, offset int) ([]models.AuditLog, int64, error) {
-	var logs []models.AuditLog
-	var total int64
-
-	query := r.db.Model(&models.AuditLog{}).Where("entity = ?", entity)
+// GetChainHead returns the last entry of an organization's chain; an empty chain has sequence 0
+func (r *AuditLogRepository) GetChainHead(orgID uint) (*models.AuditChainHead, error) {
+	head := models.AuditChainHead{OrganizationID: orgID}
+	err := r.db.Where("organization_id = ?", orgID).Limit(1).Find(&head).Error
+	return &head, err
+}
 
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+// LastSequenceBefore returns the sequence of an organization's last entry written before cutoff, or 0
+func (r *AuditLogRepository) LastSequenceBefore(orgID uint, cutoff time.Time) (uint64, error) {
+	var sequences []uint64
+	err := r.organization(orgID).Model(&models.AuditLog{}).Where("organization_id = ? AND created_at < ?", orgID, cutoff).
+		Pluck("COALESCE(MAX(sequence), 0)", &sequences).Error
+	if err != nil || len(sequences) == 0 {
+		return 0, err
 	}
+	return sequences[0], nil
+}
 
-	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&logs).Error
-	return logs, total, err
+// StreamThrough calls fn for each of an organization's entries up to and including sequence
+func (r *AuditLogRepository) StreamThrough(orgID uint, sequence uint64, fn func(*models.AuditLog) error) error {
+	return r.each(orgID, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.AuditLog{}).Where("organization_id = ? AND sequence <= ?", orgID, sequence).Order("sequence")
+	}, fn)
 }
 
-// GetFiltered returns audit logs with optional filters
-func (r *AuditLogRepository) GetFiltered(userID uint, entity, action string, limit, offset int) ([]models.AuditLog, int64, error) {
-	var logs []models.AuditLog
-	var total int64
+// each scans the rows of an organization's query one at a time, in a transaction that keeps
+// the organization's entries visible until the last one is read
+func (r *AuditLogRepository) each(orgID uint, query func(tx *gorm.DB) *gorm.DB, fn func(*models.AuditLog) error) error {
+	return r.organization(orgID).Transaction(func(tx *gorm.DB) error {
+		rows, err := query(tx).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var log models.AuditLog
+			if err := tx.ScanRows(rows, &log); err != nil {
+				return err
+			}
+			if err := fn(&log); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
 
-	query := r.db.Model(&models.AuditLog{})
+// Archive records an archive and deletes the entries it holds
+func (r *AuditLogRepository) Archive(archive *models.AuditArchive) error {
+	return r.organization(archive.OrganizationID).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(archive).Error; err != nil {
+			return err
+		}
+		return tx.Where("organization_id = ? AND sequence <= ?", archive.OrganizationID, archive.LastSequence).
+			Delete(&models.AuditLog{}).Error
+	})
+}
 
-	if userID > 0 {
-		query = query.Where("user_id = ?", userID)
-	}
-	if entity != "" {
-		query = query.Where("entity = ?", entity)
-	}
-	if action != "" {
-		query = query.Where("action = ?", action)
-	}
+// GetArchives returns an organization's archives, oldest first
+func (r *AuditLogRepository) GetArchives(orgID uint) ([]models.AuditArchive, error) {
+	var archives []models.AuditArchive
+	err := r.db.Where("organization_id = ?", orgID).Order("last_sequence").Find(&archives).Error
+	return archives, err
+}
 
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+// FindArchive finds one of an organization's archives
+func (r *AuditLogRepository) FindArchive(id, orgID uint) (*models.AuditArchive, error) {
+	var archive models.AuditArchive
+	err := r.db.Where("id = ? AND organization_id = ?", id, orgID).First(&archive).Error
+	return &archive, err
+}
+
+// GetLastArchive returns the archive the live chain continues from, or nil if there is none
+func (r *AuditLogRepository) GetLastArchive(orgID uint) (*models.AuditArchive, error) {
+	var archives []models.AuditArchive
+	err := r.db.Where("organization_id = ?", orgID).Order("last_sequence DESC").Limit(1).Find(&archives).Error
+	if err != nil || len(archives) == 0 {
+		return nil, err
 	}
+	return &archives[0], nil
+}
 
-	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&logs).Error
-	return logs, total, err
+// GetEntityHistory returns the entries recorded for one entity of an organization, most recent first
+func (r *AuditLogRepository) GetEntityHistory(orgID uint, entity string, entityID uint) ([]models.AuditLog, error) {
+	var logs []models.AuditLog
+	err := r.filtered(r.organization(orgID), AuditLogFilter{OrganizationID: orgID, Entity: entity, EntityID: entityID}).
+		Order("sequence DESC").Find(&logs).Error
+	return logs, err
 }
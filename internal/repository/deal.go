@@ -1,126 +1,172 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"time"
 	"ventura/internal/models"
+	"ventura/internal/tenancy"
 
 	"gorm.io/gorm"
 )
 
+// ErrStageChanged is returned when a deal left the stage it was being moved from
+var ErrStageChanged = errors.New("deal has moved to another stage")
+
+// DealRepository reads and writes the deals of its tenant's organization. Every query fails
+// with ErrNoTenant until it is confined with Within.
 type DealRepository struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	tenant tenant
 }
 
 func NewDealRepository(db *gorm.DB) *DealRepository {
 	return &DealRepository{DB: db}
 }
 
-// GetAll returns all deals (deprecated - use GetAllByOrganization)
-func (r *DealRepository) GetAll() ([]models.Deal, error) {
-	var deals []models.Deal
-	err := r.DB.Order("created_at DESC").Find(&deals).Error
-	return deals, err
+// WithContext returns the repository bound to ctx
+func (r *DealRepository) WithContext(ctx context.Context) *DealRepository {
+	return &DealRepository{DB: r.DB.WithContext(ctx), tenant: r.tenant}
+}
+
+// Within returns the repository confined to the deals of scope's organization
+func (r *DealRepository) Within(scope CompanyScope) *DealRepository {
+	return &DealRepository{DB: tenancy.Organization(r.DB, scope.OrganizationID), tenant: tenant{scope: scope}}
+}
+
+// deals returns a query over the tenant's deals
+func (r *DealRepository) deals() *gorm.DB {
+	return r.tenant.organizationRows(r.DB, "deals.organization_id").Model(&models.Deal{})
 }
 
-// GetAllByOrganization returns all deals for an organization
-func (r *DealRepository) GetAllByOrganization(orgID uint) ([]models.Deal, error) {
+// GetAll returns all of the tenant's deals
+func (r *DealRepository) GetAll() ([]models.Deal, error) {
 	var deals []models.Deal
-	err := r.DB.Where("organization_id = ?", orgID).Order("created_at DESC").Find(&deals).Error
+	err := r.deals().Order("created_at DESC").Find(&deals).Error
 	return deals, err
 }
 
-// GetByStage returns deals filtered by stage (deprecated - use GetByStageAndOrganization)
-func (r *DealRepository) GetByStage(stage models.DealStage) ([]models.Deal, error) {
+// GetByOwner returns the tenant's deals owned by a member
+func (r *DealRepository) GetByOwner(ownerID uint) ([]models.Deal, error) {
 	var deals []models.Deal
-	err := r.DB.Where("stage = ?", stage).Order("created_at DESC").Find(&deals).Error
+	err := r.deals().Where("owner_id = ?", ownerID).Order("created_at DESC").Find(&deals).Error
 	return deals, err
 }
 
-// GetByStageAndOrganization returns deals filtered by stage for an organization
-func (r *DealRepository) GetByStageAndOrganization(stage models.DealStage, orgID uint) ([]models.Deal, error) {
+// GetByStage returns the tenant's deals in a stage
+func (r *DealRepository) GetByStage(stage models.DealStage) ([]models.Deal, error) {
 	var deals []models.Deal
-	err := r.DB.Where("stage = ? AND organization_id = ?", stage, orgID).Order("created_at DESC").Find(&deals).Error
+	err := r.deals().Where("stage = ?", stage).Order("created_at DESC").Find(&deals).Error
 	return deals, err
 }
 
-// GetByID returns a deal by ID
+// GetByID returns one of the tenant's deals by ID
 func (r *DealRepository) GetByID(id uint) (*models.Deal, error) {
 	var deal models.Deal
-	err := r.DB.First(&deal, id).Error
-	return &deal, err
-}
-
-// GetByIDAndOrganization returns a deal by ID only if it belongs to the organization
-func (r *DealRepository) GetByIDAndOrganization(id uint, orgID uint) (*models.Deal, error) {
-	var deal models.Deal
-	err := r.DB.Where("id = ? AND organization_id = ?", id, orgID).First(&deal).Error
+	err := r.deals().Where("id = ?", id).First(&deal).Error
 	return &deal, err
 }
 
-func (r *DealRepository) Create(deal *models.Deal) error {
-	return r.DB.Create(deal).Error
+// Create creates a deal in the tenant's organization and records event, its entry into
+// the pipeline, in the same transaction
+func (r *DealRepository) Create(deal *models.Deal, event *models.DealStageEvent) error {
+	if err := r.tenant.claim(&deal.OrganizationID); err != nil {
+		return err
+	}
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(deal).Error; err != nil {
+			return err
+		}
+		event.OrganizationID = deal.OrganizationID
+		event.DealID = deal.ID
+		return tx.Create(event).Error
+	})
 }
 
+// Update saves one of the tenant's deals
 func (r *DealRepository) Update(deal *models.Deal) error {
+	if err := exists(r.deals().Where("id = ?", deal.ID)); err != nil {
+		return err
+	}
+	if err := r.tenant.claim(&deal.OrganizationID); err != nil {
+		return err
+	}
 	return r.DB.Save(deal).Error
 }
 
-func (r *DealRepository) UpdateStage(id uint, stage models.DealStage) error {
-	return r.DB.Model(&models.Deal{}).Where("id = ?", id).Update("stage", stage).Error
+// ReassignOwner hands the tenant's deals owned by one member over to another and returns
+// how many changed hands
+func (r *DealRepository) ReassignOwner(fromUserID, toUserID uint) (int64, error) {
+	result := r.deals().Where("owner_id = ?", fromUserID).Update("owner_id", toUserID)
+	return result.RowsAffected, result.Error
 }
 
-// UpdateStageByOrganization updates stage only if deal belongs to organization
-func (r *DealRepository) UpdateStageByOrganization(id uint, stage models.DealStage, orgID uint) error {
-	return r.DB.Model(&models.Deal{}).Where("id = ? AND organization_id = ?", id, orgID).Update("stage", stage).Error
-}
-
-// GetActiveByOrganization returns all non-archived deals for an organization
-func (r *DealRepository) GetActiveByOrganization(orgID uint) ([]models.Deal, error) {
+// GetActive returns the tenant's non-archived deals
+func (r *DealRepository) GetActive() ([]models.Deal, error) {
 	var deals []models.Deal
-	err := r.DB.Where("organization_id = ? AND archived_at IS NULL", orgID).Order("created_at DESC").Find(&deals).Error
+	err := r.deals().Where("archived_at IS NULL").Order("created_at DESC").Find(&deals).Error
 	return deals, err
 }
 
-// GetArchivedByOrganization returns all archived deals for an organization
-func (r *DealRepository) GetArchivedByOrganization(orgID uint) ([]models.Deal, error) {
+// GetArchived returns the tenant's archived deals
+func (r *DealRepository) GetArchived() ([]models.Deal, error) {
 	var deals []models.Deal
-	err := r.DB.Where("organization_id = ? AND archived_at IS NOT NULL", orgID).Order("archived_at DESC").Find(&deals).Error
+	err := r.deals().Where("archived_at IS NOT NULL").Order("archived_at DESC").Find(&deals).Error
 	return deals, err
 }
 
 // ArchiveDeal archives a deal by setting archived_at timestamp
-func (r *DealRepository) ArchiveDeal(id uint, orgID uint) error {
-	now := time.Now()
-	return r.DB.Model(&models.Deal{}).Where("id = ? AND organization_id = ?", id, orgID).Updates(map[string]interface{}{
-		"archived_at": now,
-	}).Error
-}
-
-// SetLossReasonAndArchive sets the loss reason and archives the deal
-func (r *DealRepository) SetLossReasonAndArchive(id uint, reason string, orgID uint) error {
+func (r *DealRepository) ArchiveDeal(id uint) error {
 	now := time.Now()
-	return r.DB.Model(&models.Deal{}).Where("id = ? AND organization_id = ?", id, orgID).Updates(map[string]interface{}{
-		"loss_reason": reason,
-		"stage":       models.StageLost,
+	return r.deals().Where("id = ?", id).Updates(map[string]interface{}{
 		"archived_at": now,
 	}).Error
 }
 
-// CloseAndConvert closes a deal and links it to a converted company
-func (r *DealRepository) CloseAndConvert(id uint, companyID uint, orgID uint) error {
-	now := time.Now()
-	return r.DB.Model(&models.Deal{}).Where("id = ? AND organization_id = ?", id, orgID).Updates(map[string]interface{}{
-		"stage":                models.StageClosed,
-		"archived_at":          now,
-		"converted_company_id": companyID,
-	}).Error
-}
-
-// CloseDeal closes a deal without conversion
-func (r *DealRepository) CloseDeal(id uint, orgID uint) error {
-	now := time.Now()
-	return r.DB.Model(&models.Deal{}).Where("id = ? AND organization_id = ?", id, orgID).Updates(map[string]interface{}{
-		"stage":       models.StageClosed,
-		"archived_at": now,
-	}).Error
+// Transition moves one of the tenant's deals from event's FromStage to its ToStage,
+// setting fields along with the stage, and records event. It returns ErrStageChanged when
+// the deal is no longer in FromStage, so concurrent moves can't both apply.
+func (r *DealRepository) Transition(event *models.DealStageEvent, fields map[string]interface{}) error {
+	if err := r.tenant.claim(&event.OrganizationID); err != nil {
+		return err
+	}
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		repo := &DealRepository{DB: tx, tenant: r.tenant}
+		updates := map[string]interface{}{"stage": event.ToStage}
+		for column, value := range fields {
+			updates[column] = value
+		}
+		result := repo.deals().Where("id = ? AND stage = ?", event.DealID, event.FromStage).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStageChanged
+		}
+		return tx.Create(event).Error
+	})
+}
+
+// GetStageEvents returns the stage history of one of the tenant's deals, oldest first
+func (r *DealRepository) GetStageEvents(dealID uint) ([]models.DealStageEvent, error) {
+	var events []models.DealStageEvent
+	err := r.tenant.organizationRows(r.DB, "organization_id").Where("deal_id = ?", dealID).Order("created_at, id").Find(&events).Error
+	return events, err
+}
+
+// GetStageEventsByDeals returns the stage history of some of the tenant's deals, oldest first
+func (r *DealRepository) GetStageEventsByDeals(dealIDs []uint) ([]models.DealStageEvent, error) {
+	var events []models.DealStageEvent
+	if len(dealIDs) == 0 {
+		return events, r.tenant.check(r.DB).Error
+	}
+	err := r.tenant.organizationRows(r.DB, "organization_id").Where("deal_id IN ?", dealIDs).Order("created_at, id").Find(&events).Error
+	return events, err
+}
+
+// GetAllStageEvents returns the stage history of all of the tenant's deals, oldest first
+func (r *DealRepository) GetAllStageEvents() ([]models.DealStageEvent, error) {
+	var events []models.DealStageEvent
+	err := r.tenant.organizationRows(r.DB, "organization_id").Order("created_at, id").Find(&events).Error
+	return events, err
 }
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"ventura/internal/models"
+	"ventura/internal/tenancy"
+
+	"gorm.io/gorm"
+)
+
+// DealActivityRepository reads and writes the activities logged on its tenant's deals. Every
+// query fails with ErrNoTenant until it is confined with Within.
+type DealActivityRepository struct {
+	db     *gorm.DB
+	tenant tenant
+}
+
+func NewDealActivityRepository(db *gorm.DB) *DealActivityRepository {
+	return &DealActivityRepository{db: db}
+}
+
+// WithContext returns the repository bound to ctx
+func (r *DealActivityRepository) WithContext(ctx context.Context) *DealActivityRepository {
+	return &DealActivityRepository{db: r.db.WithContext(ctx), tenant: r.tenant}
+}
+
+// Within returns the repository confined to the activities of scope's organization
+func (r *DealActivityRepository) Within(scope CompanyScope) *DealActivityRepository {
+	return &DealActivityRepository{db: tenancy.Organization(r.db, scope.OrganizationID), tenant: tenant{scope: scope}}
+}
+
+// activities returns a query over the tenant's activities
+func (r *DealActivityRepository) activities() *gorm.DB {
+	return r.tenant.organizationRows(r.db, "deal_activities.organization_id").Model(&models.DealActivity{})
+}
+
+// Create logs an activity with its mentions on one of the tenant's deals
+func (r *DealActivityRepository) Create(activity *models.DealActivity) error {
+	if err := r.tenant.claim(&activity.OrganizationID); err != nil {
+		return err
+	}
+	return r.db.Create(activity).Error
+}
+
+// GetByID returns one of the tenant's activities with its mentions
+func (r *DealActivityRepository) GetByID(id uint) (*models.DealActivity, error) {
+	var activity models.DealActivity
+	err := r.activities().Preload("Mentions").Where("id = ?", id).First(&activity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &activity, nil
+}
+
+// GetByDeal returns the activities of one of the tenant's deals with their mentions, oldest first
+func (r *DealActivityRepository) GetByDeal(dealID uint) ([]models.DealActivity, error) {
+	var activities []models.DealActivity
+	err := r.activities().Preload("Mentions").Where("deal_id = ?", dealID).Order("occurred_at, id").Find(&activities).Error
+	return activities, err
+}
+
+// GetAll returns all of the tenant's activities, oldest first
+func (r *DealActivityRepository) GetAll() ([]models.DealActivity, error) {
+	var activities []models.DealActivity
+	err := r.activities().Preload("Mentions").Order("occurred_at, id").Find(&activities).Error
+	return activities, err
+}
+
+// Update saves an edited activity along with the revision it replaces, and replaces its
+// mentions with the activity's
+func (r *DealActivityRepository) Update(activity *models.DealActivity, revision *models.DealActivityRevision) error {
+	if err := exists(r.activities().Where("id = ?", activity.ID)); err != nil {
+		return err
+	}
+	if err := r.tenant.claim(&activity.OrganizationID); err != nil {
+		return err
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("activity_id = ?", activity.ID).Delete(&models.DealActivityMention{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Mentions").Save(activity).Error; err != nil {
+			return err
+		}
+		for i := range activity.Mentions {
+			activity.Mentions[i].ID = 0
+			activity.Mentions[i].ActivityID = activity.ID
+		}
+		if len(activity.Mentions) == 0 {
+			return nil
+		}
+		return tx.Create(&activity.Mentions).Error
+	})
+}
+
+// Delete deletes one of the tenant's activities; its revisions are kept
+func (r *DealActivityRepository) Delete(id uint) error {
+	if err := exists(r.activities().Where("id = ?", id)); err != nil {
+		return err
+	}
+	return r.db.Delete(&models.DealActivity{}, id).Error
+}
+
+// GetRevisions returns the earlier versions of one of the tenant's activities, oldest first
+func (r *DealActivityRepository) GetRevisions(activityID uint) ([]models.DealActivityRevision, error) {
+	var revisions []models.DealActivityRevision
+	if err := exists(r.activities().Where("id = ?", activityID)); err != nil {
+		return nil, err
+	}
+	err := r.db.Where("activity_id = ?", activityID).Order("created_at, id").Find(&revisions).Error
+	return revisions, err
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"ventura/internal/models"
+	"ventura/internal/tenancy"
+
+	"gorm.io/gorm"
+)
+
+// ErrAnalysisReviewed is returned when an analysis was already accepted or rejected
+var ErrAnalysisReviewed = errors.New("analysis has already been reviewed")
+
+// DealAnalysisRepository reads and writes the AI analyses of its tenant's deals. Every query
+// fails with ErrNoTenant until it is confined with Within.
+type DealAnalysisRepository struct {
+	db     *gorm.DB
+	tenant tenant
+}
+
+func NewDealAnalysisRepository(db *gorm.DB) *DealAnalysisRepository {
+	return &DealAnalysisRepository{db: db}
+}
+
+// WithContext returns the repository bound to ctx
+func (r *DealAnalysisRepository) WithContext(ctx context.Context) *DealAnalysisRepository {
+	return &DealAnalysisRepository{db: r.db.WithContext(ctx), tenant: r.tenant}
+}
+
+// Within returns the repository confined to the analyses of scope's organization
+func (r *DealAnalysisRepository) Within(scope CompanyScope) *DealAnalysisRepository {
+	return &DealAnalysisRepository{db: tenancy.Organization(r.db, scope.OrganizationID), tenant: tenant{scope: scope}}
+}
+
+// analyses returns a query over the tenant's analyses
+func (r *DealAnalysisRepository) analyses() *gorm.DB {
+	return r.tenant.organizationRows(r.db, "deal_analyses.organization_id").Model(&models.DealAnalysis{})
+}
+
+// Create saves an analysis of one of the tenant's deals
+func (r *DealAnalysisRepository) Create(analysis *models.DealAnalysis) error {
+	if err := r.tenant.claim(&analysis.OrganizationID); err != nil {
+		return err
+	}
+	return r.db.Create(analysis).Error
+}
+
+// GetByID returns one of the tenant's analyses
+func (r *DealAnalysisRepository) GetByID(id uint) (*models.DealAnalysis, error) {
+	var analysis models.DealAnalysis
+	if err := r.analyses().Where("id = ?", id).First(&analysis).Error; err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
+
+// GetByDeal returns the analyses of one of the tenant's deals, newest first
+func (r *DealAnalysisRepository) GetByDeal(dealID uint) ([]models.DealAnalysis, error) {
+	var analyses []models.DealAnalysis
+	err := r.analyses().Where("deal_id = ?", dealID).Order("created_at DESC, id DESC").Find(&analyses).Error
+	return analyses, err
+}
+
+// GetAll returns all of the tenant's analyses, oldest first
+func (r *DealAnalysisRepository) GetAll() ([]models.DealAnalysis, error) {
+	var analyses []models.DealAnalysis
+	err := r.analyses().Order("created_at, id").Find(&analyses).Error
+	return analyses, err
+}
+
+// Review saves an analyst's decision on a pending analysis. Accepting it replaces its deal's
+// AI scores with scores in the same transaction. It returns ErrAnalysisReviewed if the
+// analysis is no longer pending.
+func (r *DealAnalysisRepository) Review(analysis *models.DealAnalysis, scores []models.DealScore) error {
+	if err := r.tenant.claim(&analysis.OrganizationID); err != nil {
+		return err
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		repo := &DealAnalysisRepository{db: tx, tenant: r.tenant}
+		result := repo.analyses().Where("id = ? AND status = ?", analysis.ID, models.AnalysisPending).Updates(map[string]interface{}{
+			"status":           analysis.Status,
+			"reviewed_by_id":   analysis.ReviewedByID,
+			"reviewed_by_name": analysis.ReviewedByName,
+			"reviewed_at":      analysis.ReviewedAt,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAnalysisReviewed
+		}
+		if analysis.Status != models.AnalysisAccepted {
+			return nil
+		}
+
+		dealScores := &DealScoreRepository{db: tx, tenant: r.tenant}
+		return dealScores.replace(analysis.DealID, models.ScoreSourceAI, 0, scores)
+	})
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"ventura/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DealStageTransitionRepository reads and writes the stage transitions organizations allow
+type DealStageTransitionRepository struct {
+	db *gorm.DB
+}
+
+func NewDealStageTransitionRepository(db *gorm.DB) *DealStageTransitionRepository {
+	return &DealStageTransitionRepository{db: db}
+}
+
+// WithContext returns the repository bound to ctx
+func (r *DealStageTransitionRepository) WithContext(ctx context.Context) *DealStageTransitionRepository {
+	return &DealStageTransitionRepository{db: r.db.WithContext(ctx)}
+}
+
+// GetByOrganization returns the transitions an organization has set
+func (r *DealStageTransitionRepository) GetByOrganization(orgID uint) ([]models.DealStageTransition, error) {
+	var transitions []models.DealStageTransition
+	err := r.db.Where("organization_id = ?", orgID).Order("id").Find(&transitions).Error
+	return transitions, err
+}
+
+// Replace sets an organization's transitions, dropping the ones it had
+func (r *DealStageTransitionRepository) Replace(orgID uint, transitions []models.DealStageTransition) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", orgID).Delete(&models.DealStageTransition{}).Error; err != nil {
+			return err
+		}
+		for i := range transitions {
+			transitions[i].ID = 0
+			transitions[i].OrganizationID = orgID
+		}
+		if len(transitions) == 0 {
+			return nil
+		}
+		return tx.Create(&transitions).Error
+	})
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"ventura/internal/models"
+	"ventura/internal/tenancy"
+
+	"gorm.io/gorm"
+)
+
+// DocumentRepository reads the documents of its tenant's companies. Every query fails with
+// ErrNoTenant until it is confined with Within.
+type DocumentRepository struct {
+	DB     *gorm.DB
+	tenant tenant
+}
+
+func NewDocumentRepository(db *gorm.DB) *DocumentRepository {
+	return &DocumentRepository{DB: db}
+}
+
+// Within returns the repository confined to the documents of the companies visible in scope
+func (r *DocumentRepository) Within(scope CompanyScope) *DocumentRepository {
+	return &DocumentRepository{DB: tenancy.Organization(r.DB, scope.OrganizationID), tenant: tenant{scope: scope}}
+}
+
+// GetAll returns the documents of the tenant's companies
+func (r *DocumentRepository) GetAll() ([]models.Document, error) {
+	var documents []models.Document
+	err := r.tenant.companyRows(r.DB).Order("id").Find(&documents).Error
+	return documents, err
+}
@@ -1,51 +1,75 @@
 package repository
 
 import (
+	"context"
 	"ventura/internal/models"
+	"ventura/internal/tenancy"
 
 	"gorm.io/gorm"
 )
 
+// FounderRepository reads and writes the founders of its tenant's companies. Every query
+// fails with ErrNoTenant until it is confined with Within.
 type FounderRepository struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	tenant tenant
 }
 
 func NewFounderRepository(db *gorm.DB) *FounderRepository {
 	return &FounderRepository{DB: db}
 }
 
-// GetAll returns all founders
+// WithContext returns the repository bound to ctx
+func (r *FounderRepository) WithContext(ctx context.Context) *FounderRepository {
+	return &FounderRepository{DB: r.DB.WithContext(ctx), tenant: r.tenant}
+}
+
+// Within returns the repository confined to the founders of the companies visible in scope
+func (r *FounderRepository) Within(scope CompanyScope) *FounderRepository {
+	return &FounderRepository{DB: tenancy.Organization(r.DB, scope.OrganizationID), tenant: tenant{scope: scope}}
+}
+
+// GetAll returns the founders of the tenant's companies
 func (r *FounderRepository) GetAll() ([]models.Founder, error) {
 	var founders []models.Founder
-	err := r.DB.Find(&founders).Error
+	err := r.tenant.companyRows(r.DB).Find(&founders).Error
 	return founders, err
 }
 
-// GetByID returns a single founder by ID
+// GetByID returns a founder of one of the tenant's companies by ID
 func (r *FounderRepository) GetByID(id uint) (*models.Founder, error) {
 	var founder models.Founder
-	err := r.DB.First(&founder, id).Error
+	err := r.tenant.companyRows(r.DB).First(&founder, id).Error
 	return &founder, err
 }
 
-// GetByCompanyID returns all founders for a specific company
+// GetByCompanyID returns all founders for one of the tenant's companies
 func (r *FounderRepository) GetByCompanyID(companyID uint) ([]models.Founder, error) {
 	var founders []models.Founder
-	err := r.DB.Where("company_id = ?", companyID).Find(&founders).Error
+	err := r.tenant.companyRows(r.DB).Where("company_id = ?", companyID).Find(&founders).Error
 	return founders, err
 }
 
-// Create creates a new founder
+// Create creates a founder of one of the tenant's companies
 func (r *FounderRepository) Create(founder *models.Founder) error {
+	if err := r.tenant.hasCompany(r.DB, founder.CompanyID); err != nil {
+		return err
+	}
 	return r.DB.Create(founder).Error
 }
 
-// Update updates an existing founder
+// Update saves a founder of one of the tenant's companies
 func (r *FounderRepository) Update(founder *models.Founder) error {
+	if err := exists(r.tenant.companyRows(r.DB).Model(&models.Founder{}).Where("id = ?", founder.ID)); err != nil {
+		return err
+	}
+	if err := r.tenant.hasCompany(r.DB, founder.CompanyID); err != nil {
+		return err
+	}
 	return r.DB.Save(founder).Error
 }
 
-// Delete deletes a founder by ID
+// Delete deletes a founder of one of the tenant's companies
 func (r *FounderRepository) Delete(id uint) error {
-	return r.DB.Delete(&models.Founder{}, id).Error
+	return r.tenant.companyRows(r.DB).Delete(&models.Founder{}, id).Error
 }
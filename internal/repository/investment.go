@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"ventura/internal/models"
 
 	"gorm.io/gorm"
@@ -14,6 +15,11 @@ func NewInvestmentRepository(db *gorm.DB) *InvestmentRepository {
 	return &InvestmentRepository{DB: db}
 }
 
+// WithContext returns the repository bound to ctx
+func (r *InvestmentRepository) WithContext(ctx context.Context) *InvestmentRepository {
+	return &InvestmentRepository{DB: r.DB.WithContext(ctx)}
+}
+
 func (r *InvestmentRepository) Create(investment *models.Investment) error {
 	return r.DB.Create(investment).Error
 }
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"ventura/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type InvitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+// WithContext returns the repository bound to ctx
+func (r *InvitationRepository) WithContext(ctx context.Context) *InvitationRepository {
+	return &InvitationRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create creates an invitation with its team assignments
+func (r *InvitationRepository) Create(invitation *models.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+// FindByCode finds an invitation by its code with its organization and team assignments
+func (r *InvitationRepository) FindByCode(code string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.Preload("Organization").Preload("TeamAssignments").Where("code = ?", code).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// FindByIDAndOrganization finds an invitation of an organization
+func (r *InvitationRepository) FindByIDAndOrganization(id, orgID uint) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.Preload("Organization").Preload("TeamAssignments").
+		Where("id = ? AND organization_id = ?", id, orgID).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// GetByOrganization returns an organization's invitations, newest first
+func (r *InvitationRepository) GetByOrganization(orgID uint) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	err := r.db.Preload("CreatedBy").Preload("TeamAssignments").
+		Where("organization_id = ?", orgID).Order("created_at DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// Revoke stops an invitation from being accepted
+func (r *InvitationRepository) Revoke(id uint) error {
+	return r.db.Model(&models.Invitation{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now()).Error
+}
+
+// MarkSent records that the invitation was emailed and sets its new expiry
+func (r *InvitationRepository) MarkSent(id uint, expiresAt time.Time) error {
+	return r.db.Model(&models.Invitation{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_sent_at": time.Now(),
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+// ClaimUse atomically counts one use of an invitation. It reports false if the
+// invitation was revoked, expired or used up in the meantime.
+func (r *InvitationRepository) ClaimUse(id uint) (bool, error) {
+	result := r.db.Model(&models.Invitation{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ? AND use_count < max_uses", id, time.Now()).
+		Update("use_count", gorm.Expr("use_count + 1"))
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReleaseUse gives back a use claimed for an acceptance that then failed
+func (r *InvitationRepository) ReleaseUse(id uint) error {
+	return r.db.Model(&models.Invitation{}).Where("id = ? AND use_count > 0", id).
+		Update("use_count", gorm.Expr("use_count - 1")).Error
+}
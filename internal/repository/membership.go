@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"ventura/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type MembershipRepository struct {
+	db *gorm.DB
+}
+
+func NewMembershipRepository(db *gorm.DB) *MembershipRepository {
+	return &MembershipRepository{db: db}
+}
+
+// WithContext returns the repository bound to ctx
+func (r *MembershipRepository) WithContext(ctx context.Context) *MembershipRepository {
+	return &MembershipRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create adds a user to an organization
+func (r *MembershipRepository) Create(membership *models.Membership) error {
+	return r.db.Create(membership).Error
+}
+
+// Find returns a user's membership of an organization
+func (r *MembershipRepository) Find(userID, orgID uint) (*models.Membership, error) {
+	var membership models.Membership
+	err := r.db.Where("user_id = ? AND organization_id = ?", userID, orgID).First(&membership).Error
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// GetByUser returns a user's active memberships with their organizations, oldest first
+func (r *MembershipRepository) GetByUser(userID uint) ([]models.Membership, error) {
+	var memberships []models.Membership
+	err := r.db.Preload("Organization").Joins("JOIN organizations ON organizations.id = memberships.organization_id AND organizations.deleted_at IS NULL").
+		Where("memberships.user_id = ? AND memberships.deactivated_at IS NULL", userID).Order("memberships.created_at").Find(&memberships).Error
+	return memberships, err
+}
+
+// UpdateRole changes a member's role. The role mirrored on the user row follows
+// when this is their default organization.
+func (r *MembershipRepository) UpdateRole(userID, orgID uint, role models.UserRole) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Membership{}).Where("user_id = ? AND organization_id = ?", userID, orgID).
+			Update("role", role).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ? AND organization_id = ?", userID, orgID).
+			Update("role", role).Error
+	})
+}
+
+// Deactivate blocks a member from the organization, recording who did it
+func (r *MembershipRepository) Deactivate(userID, orgID, deactivatedByID uint) error {
+	return r.db.Model(&models.Membership{}).Where("user_id = ? AND organization_id = ? AND deactivated_at IS NULL", userID, orgID).
+		Updates(map[string]interface{}{
+			"deactivated_at":    time.Now(),
+			"deactivated_by_id": deactivatedByID,
+		}).Error
+}
+
+// Reactivate lets a deactivated member back into the organization
+func (r *MembershipRepository) Reactivate(userID, orgID uint) error {
+	return r.db.Model(&models.Membership{}).Where("user_id = ? AND organization_id = ?", userID, orgID).
+		Updates(map[string]interface{}{
+			"deactivated_at":    nil,
+			"deactivated_by_id": nil,
+		}).Error
+}
+
+// Delete removes a user from an organization
+func (r *MembershipRepository) Delete(userID, orgID uint) error {
+	return r.db.Where("user_id = ? AND organization_id = ?", userID, orgID).Delete(&models.Membership{}).Error
+}
+
+// CountByUser counts the organizations a user belongs to
+func (r *MembershipRepository) CountByUser(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Membership{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
@@ -1,58 +1,82 @@
 package repository
 
 import (
+	"context"
 	"ventura/internal/models"
+	"ventura/internal/tenancy"
 
 	"gorm.io/gorm"
 )
 
+// MonthlyUpdateRepository reads and writes the monthly updates of its tenant's companies.
+// Every query fails with ErrNoTenant until it is confined with Within.
 type MonthlyUpdateRepository struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	tenant tenant
 }
 
 func NewMonthlyUpdateRepository(db *gorm.DB) *MonthlyUpdateRepository {
 	return &MonthlyUpdateRepository{DB: db}
 }
 
-// GetAll returns all monthly updates
+// WithContext returns the repository bound to ctx
+func (r *MonthlyUpdateRepository) WithContext(ctx context.Context) *MonthlyUpdateRepository {
+	return &MonthlyUpdateRepository{DB: r.DB.WithContext(ctx), tenant: r.tenant}
+}
+
+// Within returns the repository confined to the updates of the companies visible in scope
+func (r *MonthlyUpdateRepository) Within(scope CompanyScope) *MonthlyUpdateRepository {
+	return &MonthlyUpdateRepository{DB: tenancy.Organization(r.DB, scope.OrganizationID), tenant: tenant{scope: scope}}
+}
+
+// GetAll returns the monthly updates of the tenant's companies
 func (r *MonthlyUpdateRepository) GetAll() ([]models.MonthlyUpdate, error) {
 	var updates []models.MonthlyUpdate
-	err := r.DB.Order("report_month DESC").Find(&updates).Error
+	err := r.tenant.companyRows(r.DB).Order("report_month DESC").Find(&updates).Error
 	return updates, err
 }
 
-// GetByID returns a single monthly update by ID
+// GetByID returns a monthly update of one of the tenant's companies by ID
 func (r *MonthlyUpdateRepository) GetByID(id uint) (*models.MonthlyUpdate, error) {
 	var update models.MonthlyUpdate
-	err := r.DB.First(&update, id).Error
+	err := r.tenant.companyRows(r.DB).First(&update, id).Error
 	return &update, err
 }
 
-// GetByCompanyID returns all monthly updates for a specific company, ordered by date
+// GetByCompanyID returns all monthly updates for one of the tenant's companies, ordered by date
 func (r *MonthlyUpdateRepository) GetByCompanyID(companyID uint) ([]models.MonthlyUpdate, error) {
 	var updates []models.MonthlyUpdate
-	err := r.DB.Where("company_id = ?", companyID).Order("report_month DESC").Find(&updates).Error
+	err := r.tenant.companyRows(r.DB).Where("company_id = ?", companyID).Order("report_month DESC").Find(&updates).Error
 	return updates, err
 }
 
-// GetLatestByCompanyID returns the most recent update for a company
+// GetLatestByCompanyID returns the most recent update for one of the tenant's companies
 func (r *MonthlyUpdateRepository) GetLatestByCompanyID(companyID uint) (*models.MonthlyUpdate, error) {
 	var update models.MonthlyUpdate
-	err := r.DB.Where("company_id = ?", companyID).Order("report_month DESC").First(&update).Error
+	err := r.tenant.companyRows(r.DB).Where("company_id = ?", companyID).Order("report_month DESC").First(&update).Error
 	return &update, err
 }
 
-// Create creates a new monthly update
+// Create creates a monthly update for one of the tenant's companies
 func (r *MonthlyUpdateRepository) Create(update *models.MonthlyUpdate) error {
+	if err := r.tenant.hasCompany(r.DB, update.CompanyID); err != nil {
+		return err
+	}
 	return r.DB.Create(update).Error
 }
 
-// Update updates an existing monthly update
+// Update saves a monthly update of one of the tenant's companies
 func (r *MonthlyUpdateRepository) Update(update *models.MonthlyUpdate) error {
+	if err := exists(r.tenant.companyRows(r.DB).Model(&models.MonthlyUpdate{}).Where("id = ?", update.ID)); err != nil {
+		return err
+	}
+	if err := r.tenant.hasCompany(r.DB, update.CompanyID); err != nil {
+		return err
+	}
 	return r.DB.Save(update).Error
 }
 
-// Delete deletes a monthly update by ID
+// Delete deletes a monthly update of one of the tenant's companies
 func (r *MonthlyUpdateRepository) Delete(id uint) error {
-	return r.DB.Delete(&models.MonthlyUpdate{}, id).Error
+	return r.tenant.companyRows(r.DB).Delete(&models.MonthlyUpdate{}, id).Error
 }
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"ventura/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// WithContext returns the repository bound to ctx
+func (r *NotificationRepository) WithContext(ctx context.Context) *NotificationRepository {
+	return &NotificationRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create creates notifications
+func (r *NotificationRepository) Create(notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return r.db.Create(&notifications).Error
+}
+
+// GetByUser returns a member's most recent notifications in an organization, newest first
+func (r *NotificationRepository) GetByUser(userID, orgID uint, unreadOnly bool, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	query := r.db.Where("user_id = ? AND organization_id = ?", userID, orgID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+// CountUnread counts a member's unread notifications in an organization
+func (r *NotificationRepository) CountUnread(userID, orgID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Notification{}).Where("user_id = ? AND organization_id = ? AND read_at IS NULL", userID, orgID).Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks one of a member's notifications read. It returns gorm.ErrRecordNotFound
+// if they have no such notification.
+func (r *NotificationRepository) MarkRead(id, userID, orgID uint) error {
+	result := r.db.Model(&models.Notification{}).Where("id = ? AND user_id = ? AND organization_id = ?", id, userID, orgID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now()))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks all of a member's notifications in an organization read
+func (r *NotificationRepository) MarkAllRead(userID, orgID uint) error {
+	return r.db.Model(&models.Notification{}).Where("user_id = ? AND organization_id = ? AND read_at IS NULL", userID, orgID).
+		Update("read_at", time.Now()).Error
+}
@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"context"
+	"time"
 	"ventura/internal/models"
+	"ventura/internal/tenancy"
 
 	"gorm.io/gorm"
 )
@@ -14,6 +17,11 @@ func NewOrganizationRepository(db *gorm.DB) *OrganizationRepository {
 	return &OrganizationRepository{db: db}
 }
 
+// WithContext returns the repository bound to ctx
+func (r *OrganizationRepository) WithContext(ctx context.Context) *OrganizationRepository {
+	return &OrganizationRepository{db: r.db.WithContext(ctx)}
+}
+
 // Create creates a new organization
 func (r *OrganizationRepository) Create(org *models.Organization) error {
 	return r.db.Create(org).Error
@@ -38,29 +46,153 @@ func (r *OrganizationRepository) Update(org *models.Organization) error {
 	return r.db.Save(org).Error
 }
 
-// GetInviteCodeByCode finds an invite code by its code string
-func (r *OrganizationRepository) GetInviteCodeByCode(code string) (*models.InviteCode, error) {
-	var invite models.InviteCode
-	err := r.db.Preload("Organization").Where("code = ?", code).First(&invite).Error
-	return &invite, err
+// GetWithAuditRetention returns the organizations that archive old audit entries
+func (r *OrganizationRepository) GetWithAuditRetention() ([]models.Organization, error) {
+	var orgs []models.Organization
+	err := r.db.Where("audit_retention_days > 0").Find(&orgs).Error
+	return orgs, err
+}
+
+// FindDeleted finds a deleted organization that hasn't been purged yet
+func (r *OrganizationRepository) FindDeleted(id uint) (*models.Organization, error) {
+	var org models.Organization
+	err := r.db.Unscoped().Where("deleted_at IS NOT NULL").First(&org, id).Error
+	return &org, err
 }
 
-// CreateInviteCode creates a new invite code
-func (r *OrganizationRepository) CreateInviteCode(invite *models.InviteCode) error {
-	return r.db.Create(invite).Error
+// Delete soft-deletes an organization and schedules its purge
+func (r *OrganizationRepository) Delete(org *models.Organization, requestedByID uint, purgeAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		org.DeletionRequestedByID, org.PurgeAt = &requestedByID, &purgeAt
+		if err := tx.Save(org).Error; err != nil {
+			return err
+		}
+		return tx.Delete(org).Error
+	})
 }
 
-// MarkInviteCodeUsed marks an invite code as used
-func (r *OrganizationRepository) MarkInviteCodeUsed(inviteID uint, userID uint) error {
-	return r.db.Model(&models.InviteCode{}).Where("id = ?", inviteID).Updates(map[string]interface{}{
-		"used_by_id": userID,
-		"used_at":    gorm.Expr("NOW()"),
+// Restore undeletes an organization and cancels its purge
+func (r *OrganizationRepository) Restore(org *models.Organization) error {
+	return r.db.Unscoped().Model(org).Updates(map[string]interface{}{
+		"deleted_at":               nil,
+		"deletion_requested_by_id": nil,
+		"purge_at":                 nil,
 	}).Error
 }
 
-// GetInviteCodesByOrganization gets all invite codes for an organization
-func (r *OrganizationRepository) GetInviteCodesByOrganization(orgID uint) ([]models.InviteCode, error) {
-	var invites []models.InviteCode
-	err := r.db.Where("organization_id = ?", orgID).Preload("CreatedBy").Find(&invites).Error
-	return invites, err
+// GetDueForPurge returns the deleted organizations whose grace period ended before now
+func (r *OrganizationRepository) GetDueForPurge(now time.Time) ([]models.Organization, error) {
+	var orgs []models.Organization
+	err := r.db.Unscoped().Where("deleted_at IS NOT NULL AND purge_at <= ?", now).Find(&orgs).Error
+	return orgs, err
+}
+
+// PurgeResult describes what purging an organization erased
+type PurgeResult struct {
+	Rows  int64    // Rows deleted, across every table
+	Users int64    // Accounts deleted because the organization was their only one
+	Files []string // Audit archives and exports the caller should remove from disk
+}
+
+// Purge permanently deletes an organization and everything it owns in one transaction.
+// Accounts that belong to no other organization are deleted with it; the others move to
+// their oldest remaining membership. Statements are raw SQL so the audit plugin doesn't
+// log every row it erases.
+func (r *OrganizationRepository) Purge(orgID uint) (*PurgeResult, error) {
+	result := &PurgeResult{}
+	err := tenancy.Organization(r.db, orgID).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AuditArchive{}).Where("organization_id = ?", orgID).Pluck("path", &result.Files).Error; err != nil {
+			return err
+		}
+		var exports []string
+		if err := tx.Model(&models.OrganizationExport{}).Where("organization_id = ? AND path <> ''", orgID).Pluck("path", &exports).Error; err != nil {
+			return err
+		}
+		result.Files = append(result.Files, exports...)
+
+		// Members left with no other organization
+		var orphans []uint
+		err := tx.Raw(`SELECT id FROM users WHERE (organization_id = ? OR id IN (SELECT user_id FROM memberships WHERE organization_id = ?))
+			AND id NOT IN (SELECT user_id FROM memberships WHERE organization_id <> ?)`, orgID, orgID, orgID).Pluck("id", &orphans).Error
+		if err != nil {
+			return err
+		}
+		result.Users = int64(len(orphans))
+
+		const companies = "company_id IN (SELECT id FROM portfolio_companies WHERE organization_id = @org)"
+		deletions := []struct {
+			model interface{}
+			where string
+		}{
+			{&models.Founder{}, companies},
+			{&models.MonthlyUpdate{}, companies},
+			{&models.Document{}, companies},
+			{&models.TeamAssignment{}, companies + " OR user_id IN @orphans"},
+			{&models.DealStageEvent{}, "organization_id = @org"},
+			{&models.DealActivityMention{}, "activity_id IN (SELECT id FROM deal_activities WHERE organization_id = @org)"},
+			{&models.DealActivityRevision{}, "activity_id IN (SELECT id FROM deal_activities WHERE organization_id = @org)"},
+			{&models.DealActivity{}, "organization_id = @org"},
+			{&models.DealAnalysis{}, "organization_id = @org"},
+			{&models.DealScore{}, "organization_id = @org"},
+			{&models.Notification{}, "organization_id = @org"},
+			{&models.Deal{}, "organization_id = @org"},
+			{&models.DealStageTransition{}, "organization_id = @org"},
+			{&models.ScoringCriterion{}, "organization_id = @org"},
+			{&models.PortfolioCompany{}, "organization_id = @org"},
+			{&models.InvitationTeamAssignment{}, "invitation_id IN (SELECT id FROM invitations WHERE organization_id = @org)"},
+			{&models.Invitation{}, "organization_id = @org"},
+			{&models.APIToken{}, "organization_id = @org OR user_id IN @orphans"},
+			{&models.Session{}, "organization_id = @org OR user_id IN @orphans"},
+			{&models.CustomRole{}, "organization_id = @org"},
+			{&models.OIDCProvider{}, "organization_id = @org"},
+			{&models.AuditLog{}, "organization_id = @org"},
+			{&models.AuditChainHead{}, "organization_id = @org"},
+			{&models.AuditArchive{}, "organization_id = @org"},
+			{&models.OrganizationExport{}, "organization_id = @org"},
+			{&models.Membership{}, "organization_id = @org OR user_id IN @orphans"},
+			{&models.RecoveryCode{}, "user_id IN @orphans"},
+			{&models.UserToken{}, "user_id IN @orphans"},
+			{&models.UserIdentity{}, "user_id IN @orphans"},
+			{&models.User{}, "id IN @orphans"},
+		}
+		// An empty IN list is invalid SQL; 0 matches no row
+		args := map[string]interface{}{"org": orgID, "orphans": append(orphans, 0)}
+		for _, deletion := range deletions {
+			table, err := tableName(tx, deletion.model)
+			if err != nil {
+				return err
+			}
+			deleted := tx.Exec("DELETE FROM "+table+" WHERE "+deletion.where, args)
+			if deleted.Error != nil {
+				return deleted.Error
+			}
+			result.Rows += deleted.RowsAffected
+		}
+
+		// Members of other organizations default to their oldest remaining one
+		err = tx.Exec(`UPDATE users SET
+			organization_id = (SELECT m.organization_id FROM memberships m WHERE m.user_id = users.id ORDER BY m.created_at LIMIT 1),
+			role = (SELECT m.role FROM memberships m WHERE m.user_id = users.id ORDER BY m.created_at LIMIT 1)
+			WHERE organization_id = ?`, orgID).Error
+		if err != nil {
+			return err
+		}
+
+		deleted := tx.Exec("DELETE FROM organizations WHERE id = ?", orgID)
+		result.Rows += deleted.RowsAffected
+		return deleted.Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// tableName returns the table gorm maps a model to
+func tableName(db *gorm.DB, model interface{}) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", err
+	}
+	return stmt.Quote(stmt.Table), nil
 }
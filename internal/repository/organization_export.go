@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"time"
+	"ventura/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type OrganizationExportRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationExportRepository(db *gorm.DB) *OrganizationExportRepository {
+	return &OrganizationExportRepository{db: db}
+}
+
+// Create creates a new export
+func (r *OrganizationExportRepository) Create(export *models.OrganizationExport) error {
+	return r.db.Create(export).Error
+}
+
+// Update saves an export
+func (r *OrganizationExportRepository) Update(export *models.OrganizationExport) error {
+	return r.db.Save(export).Error
+}
+
+// FindByID finds one of an organization's exports
+func (r *OrganizationExportRepository) FindByID(id, orgID uint) (*models.OrganizationExport, error) {
+	var export models.OrganizationExport
+	err := r.db.Where("id = ? AND organization_id = ?", id, orgID).First(&export).Error
+	return &export, err
+}
+
+// GetByOrganization returns an organization's exports, most recent first
+func (r *OrganizationExportRepository) GetByOrganization(orgID uint) ([]models.OrganizationExport, error) {
+	var exports []models.OrganizationExport
+	err := r.db.Where("organization_id = ?", orgID).Order("created_at DESC").Find(&exports).Error
+	return exports, err
+}
+
+// CountInProgress counts an organization's exports that are still being built
+func (r *OrganizationExportRepository) CountInProgress(orgID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.OrganizationExport{}).
+		Where("organization_id = ? AND status IN ?", orgID, []models.ExportStatus{models.ExportPending, models.ExportRunning}).
+		Count(&count).Error
+	return count, err
+}
+
+// FailStale fails the exports still in progress that haven't changed since before cutoff,
+// whose build was interrupted by a restart
+func (r *OrganizationExportRepository) FailStale(before time.Time) (int64, error) {
+	result := r.db.Model(&models.OrganizationExport{}).
+		Where("status IN ? AND updated_at < ?", []models.ExportStatus{models.ExportPending, models.ExportRunning}, before).
+		Updates(map[string]interface{}{"status": models.ExportFailed, "error": "export was interrupted"})
+	return result.RowsAffected, result.Error
+}
+
+// GetExpired returns the completed exports whose file should be deleted
+func (r *OrganizationExportRepository) GetExpired(now time.Time) ([]models.OrganizationExport, error) {
+	var exports []models.OrganizationExport
+	err := r.db.Where("status = ? AND expires_at < ?", models.ExportCompleted, now).Find(&exports).Error
+	return exports, err
+}
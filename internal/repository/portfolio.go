@@ -1,65 +1,99 @@
 package repository
 
 import (
+	"context"
 	"time"
 	"ventura/internal/models"
+	"ventura/internal/tenancy"
 
 	"gorm.io/gorm"
 )
 
+// CompanyScope limits company queries to what a member may see: every unrestricted
+// company of the organization, plus restricted ones they are assigned to
+type CompanyScope struct {
+	OrganizationID uint
+	UserID         uint
+	AllCompanies   bool // Sees restricted companies without a team assignment
+}
+
+// visibleCompanies returns a query over the companies visible in scope
+func visibleCompanies(db *gorm.DB, scope CompanyScope) *gorm.DB {
+	query := db.Model(&models.PortfolioCompany{}).Where("portfolio_companies.organization_id = ?", scope.OrganizationID)
+	if !scope.AllCompanies {
+		query = query.Where("(portfolio_companies.restricted = false OR portfolio_companies.id IN (?))",
+			db.Model(&models.TeamAssignment{}).Select("company_id").Where("user_id = ?", scope.UserID))
+	}
+	return query
+}
+
+// visibleCompanyIDs returns a subquery selecting the IDs of the companies visible in scope
+func visibleCompanyIDs(db *gorm.DB, scope CompanyScope) *gorm.DB {
+	return visibleCompanies(db, scope).Select("portfolio_companies.id")
+}
+
+// PortfolioRepository reads and writes the companies of its tenant. Every query fails with
+// ErrNoTenant until it is confined with Within.
 type PortfolioRepository struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	tenant tenant
 }
 
 func NewPortfolioRepository(db *gorm.DB) *PortfolioRepository {
 	return &PortfolioRepository{DB: db}
 }
 
-// GetAll returns all portfolio companies (deprecated - use GetAllByOrganization)
-func (r *PortfolioRepository) GetAll() ([]models.PortfolioCompany, error) {
-	var companies []models.PortfolioCompany
-	err := r.DB.Find(&companies).Error
-	return companies, err
+// WithContext returns the repository bound to ctx
+func (r *PortfolioRepository) WithContext(ctx context.Context) *PortfolioRepository {
+	return &PortfolioRepository{DB: r.DB.WithContext(ctx), tenant: r.tenant}
 }
 
-// GetAllByOrganization returns all portfolio companies for an organization
-func (r *PortfolioRepository) GetAllByOrganization(orgID uint) ([]models.PortfolioCompany, error) {
+// Within returns the repository confined to the companies visible in scope
+func (r *PortfolioRepository) Within(scope CompanyScope) *PortfolioRepository {
+	return &PortfolioRepository{DB: tenancy.Organization(r.DB, scope.OrganizationID), tenant: tenant{scope: scope}}
+}
+
+// GetAll returns the tenant's companies
+func (r *PortfolioRepository) GetAll() ([]models.PortfolioCompany, error) {
 	var companies []models.PortfolioCompany
-	err := r.DB.Where("organization_id = ?", orgID).Find(&companies).Error
+	err := r.tenant.companies(r.DB).Find(&companies).Error
 	return companies, err
 }
 
-// GetByID returns a portfolio company by ID
+// GetByID returns one of the tenant's companies by ID
 func (r *PortfolioRepository) GetByID(id uint) (*models.PortfolioCompany, error) {
 	var company models.PortfolioCompany
-	err := r.DB.First(&company, id).Error
-	return &company, err
-}
-
-// GetByIDAndOrganization returns a portfolio company by ID only if it belongs to the organization
-func (r *PortfolioRepository) GetByIDAndOrganization(id uint, orgID uint) (*models.PortfolioCompany, error) {
-	var company models.PortfolioCompany
-	err := r.DB.Where("id = ? AND organization_id = ?", id, orgID).First(&company).Error
+	err := r.tenant.companies(r.DB).Where("portfolio_companies.id = ?", id).First(&company).Error
 	return &company, err
 }
 
+// Create creates a company in the tenant's organization
 func (r *PortfolioRepository) Create(company *models.PortfolioCompany) error {
+	if err := r.tenant.claim(&company.OrganizationID); err != nil {
+		return err
+	}
 	return r.DB.Create(company).Error
 }
 
+// Update saves one of the tenant's companies
 func (r *PortfolioRepository) Update(company *models.PortfolioCompany) error {
+	if err := r.tenant.hasCompany(r.DB, company.ID); err != nil {
+		return err
+	}
+	if err := r.tenant.claim(&company.OrganizationID); err != nil {
+		return err
+	}
 	return r.DB.Save(company).Error
 }
 
+// Delete deletes one of the tenant's companies
 func (r *PortfolioRepository) Delete(id uint) error {
+	if err := r.tenant.hasCompany(r.DB, id); err != nil {
+		return err
+	}
 	return r.DB.Delete(&models.PortfolioCompany{}, id).Error
 }
 
-// DeleteByIDAndOrganization deletes a company only if it belongs to the organization
-func (r *PortfolioRepository) DeleteByIDAndOrganization(id uint, orgID uint) error {
-	return r.DB.Where("id = ? AND organization_id = ?", id, orgID).Delete(&models.PortfolioCompany{}).Error
-}
-
 // MissingUpdateInfo represents a company with missing update info
 type MissingUpdateInfo struct {
 	ID              uint       `json:"id"`
@@ -69,73 +103,24 @@ type MissingUpdateInfo struct {
 	DaysSinceUpdate int        `json:"daysSinceUpdate"`
 }
 
-// GetCompaniesWithMissingUpdates returns companies that haven't submitted updates for last month
-func (r *PortfolioRepository) GetCompaniesWithMissingUpdates() ([]MissingUpdateInfo, error) {
+// GetCompaniesWithMissingUpdates returns the tenant's companies that have no update for a month
+// of the reporting period [from, to)
+func (r *PortfolioRepository) GetCompaniesWithMissingUpdates(from, to time.Time) ([]MissingUpdateInfo, error) {
 	var result []MissingUpdateInfo
-
-	// Get first day of last month
-	now := time.Now()
-	firstOfCurrentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
-	firstOfLastMonth := firstOfCurrentMonth.AddDate(0, -1, 0)
-
-	// Query companies with notifications enabled that don't have an update for last month
-	rows, err := r.DB.Raw(`
-		SELECT 
-			pc.id,
-			pc.name,
-			pc.sector,
-			(SELECT MAX(mu.report_month) FROM monthly_updates mu WHERE mu.company_id = pc.id) as last_update_date
-		FROM portfolio_companies pc
-		WHERE pc.deleted_at IS NULL
-		AND pc.updates_notifications_enabled = true
-		AND NOT EXISTS (
-			SELECT 1 FROM monthly_updates mu 
-			WHERE mu.company_id = pc.id 
-			AND mu.report_month >= ?
-			AND mu.report_month < ?
-		)
-	`, firstOfLastMonth, firstOfCurrentMonth).Rows()
-
-	if err != nil {
+	if err := r.tenant.check(r.DB).Error; err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var info MissingUpdateInfo
-		var lastUpdate *time.Time
-		if err := rows.Scan(&info.ID, &info.Name, &info.Sector, &lastUpdate); err != nil {
-			return nil, err
-		}
-		info.LastUpdateDate = lastUpdate
-		if lastUpdate != nil {
-			info.DaysSinceUpdate = int(now.Sub(*lastUpdate).Hours() / 24)
-		} else {
-			info.DaysSinceUpdate = -1 // Never submitted
-		}
-		result = append(result, info)
-	}
-
-	return result, nil
-}
-
-// GetCompaniesWithMissingUpdatesByOrganization returns companies missing updates for a specific organization
-func (r *PortfolioRepository) GetCompaniesWithMissingUpdatesByOrganization(orgID uint) ([]MissingUpdateInfo, error) {
-	var result []MissingUpdateInfo
 
 	now := time.Now()
-	firstOfCurrentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
-	firstOfLastMonth := firstOfCurrentMonth.AddDate(0, -1, 0)
 
-	rows, err := r.DB.Raw(`
+	err := r.DB.Raw(`
 		SELECT 
 			pc.id,
 			pc.name,
 			pc.sector,
 			(SELECT MAX(mu.report_month) FROM monthly_updates mu WHERE mu.company_id = pc.id) as last_update_date
 		FROM portfolio_companies pc
-		WHERE pc.deleted_at IS NULL
-		AND pc.organization_id = ?
+		WHERE pc.id IN (?)
 		AND pc.updates_notifications_enabled = true
 		AND NOT EXISTS (
 			SELECT 1 FROM monthly_updates mu 
@@ -143,26 +128,17 @@ func (r *PortfolioRepository) GetCompaniesWithMissingUpdatesByOrganization(orgID
 			AND mu.report_month >= ?
 			AND mu.report_month < ?
 		)
-	`, orgID, firstOfLastMonth, firstOfCurrentMonth).Rows()
-
+	`, r.tenant.companies(r.DB).Select("portfolio_companies.id"), from, to).Find(&result).Error
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var info MissingUpdateInfo
-		var lastUpdate *time.Time
-		if err := rows.Scan(&info.ID, &info.Name, &info.Sector, &lastUpdate); err != nil {
-			return nil, err
-		}
-		info.LastUpdateDate = lastUpdate
-		if lastUpdate != nil {
-			info.DaysSinceUpdate = int(now.Sub(*lastUpdate).Hours() / 24)
+	for i := range result {
+		if lastUpdate := result[i].LastUpdateDate; lastUpdate != nil {
+			result[i].DaysSinceUpdate = int(now.Sub(*lastUpdate).Hours() / 24)
 		} else {
-			info.DaysSinceUpdate = -1
+			result[i].DaysSinceUpdate = -1
 		}
-		result = append(result, info)
 	}
 
 	return result, nil
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"ventura/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type RoleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+// WithContext returns the repository bound to ctx
+func (r *RoleRepository) WithContext(ctx context.Context) *RoleRepository {
+	return &RoleRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create creates a custom role
+func (r *RoleRepository) Create(role *models.CustomRole) error {
+	return r.db.Create(role).Error
+}
+
+// Update saves a custom role
+func (r *RoleRepository) Update(role *models.CustomRole) error {
+	return r.db.Save(role).Error
+}
+
+// Delete removes a custom role
+func (r *RoleRepository) Delete(id uint) error {
+	return r.db.Delete(&models.CustomRole{}, id).Error
+}
+
+// GetByOrganization returns an organization's custom roles
+func (r *RoleRepository) GetByOrganization(orgID uint) ([]models.CustomRole, error) {
+	var roles []models.CustomRole
+	err := r.db.Where("organization_id = ?", orgID).Order("name ASC").Find(&roles).Error
+	return roles, err
+}
+
+// FindByIDAndOrganization finds a custom role within an organization
+func (r *RoleRepository) FindByIDAndOrganization(id, orgID uint) (*models.CustomRole, error) {
+	var role models.CustomRole
+	err := r.db.Where("id = ? AND organization_id = ?", id, orgID).First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// FindByKey finds a custom role by the key users reference it with
+func (r *RoleRepository) FindByKey(orgID uint, key models.UserRole) (*models.CustomRole, error) {
+	var role models.CustomRole
+	err := r.db.Where("organization_id = ? AND key = ?", orgID, key).First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"ventura/internal/models"
+	"ventura/internal/tenancy"
+
+	"gorm.io/gorm"
+)
+
+// ScoringCriterionRepository reads and writes the deal scorecards organizations define
+type ScoringCriterionRepository struct {
+	db *gorm.DB
+}
+
+func NewScoringCriterionRepository(db *gorm.DB) *ScoringCriterionRepository {
+	return &ScoringCriterionRepository{db: db}
+}
+
+// WithContext returns the repository bound to ctx
+func (r *ScoringCriterionRepository) WithContext(ctx context.Context) *ScoringCriterionRepository {
+	return &ScoringCriterionRepository{db: r.db.WithContext(ctx)}
+}
+
+// GetByOrganization returns the criteria an organization has set, in order
+func (r *ScoringCriterionRepository) GetByOrganization(orgID uint) ([]models.ScoringCriterion, error) {
+	var criteria []models.ScoringCriterion
+	err := r.db.Where("organization_id = ?", orgID).Order("position, id").Find(&criteria).Error
+	return criteria, err
+}
+
+// Replace sets an organization's criteria in the order given, dropping the ones it had
+func (r *ScoringCriterionRepository) Replace(orgID uint, criteria []models.ScoringCriterion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", orgID).Delete(&models.ScoringCriterion{}).Error; err != nil {
+			return err
+		}
+		for i := range criteria {
+			criteria[i].ID = 0
+			criteria[i].OrganizationID = orgID
+			criteria[i].Position = i
+		}
+		if len(criteria) == 0 {
+			return nil
+		}
+		return tx.Create(&criteria).Error
+	})
+}
+
+// DealScoreRepository reads and writes the scores reviewers give its tenant's deals. Every
+// query fails with ErrNoTenant until it is confined with Within.
+type DealScoreRepository struct {
+	db     *gorm.DB
+	tenant tenant
+}
+
+func NewDealScoreRepository(db *gorm.DB) *DealScoreRepository {
+	return &DealScoreRepository{db: db}
+}
+
+// WithContext returns the repository bound to ctx
+func (r *DealScoreRepository) WithContext(ctx context.Context) *DealScoreRepository {
+	return &DealScoreRepository{db: r.db.WithContext(ctx), tenant: r.tenant}
+}
+
+// Within returns the repository confined to the scores of scope's organization
+func (r *DealScoreRepository) Within(scope CompanyScope) *DealScoreRepository {
+	return &DealScoreRepository{db: tenancy.Organization(r.db, scope.OrganizationID), tenant: tenant{scope: scope}}
+}
+
+// scores returns a query over the tenant's scores
+func (r *DealScoreRepository) scores() *gorm.DB {
+	return r.tenant.organizationRows(r.db, "deal_scores.organization_id").Model(&models.DealScore{})
+}
+
+// GetByDeal returns the scores of one of the tenant's deals
+func (r *DealScoreRepository) GetByDeal(dealID uint) ([]models.DealScore, error) {
+	var scores []models.DealScore
+	err := r.scores().Where("deal_id = ?", dealID).Order("source, reviewer_name, id").Find(&scores).Error
+	return scores, err
+}
+
+// GetByDeals returns the scores of some of the tenant's deals
+func (r *DealScoreRepository) GetByDeals(dealIDs []uint) ([]models.DealScore, error) {
+	var scores []models.DealScore
+	if len(dealIDs) == 0 {
+		return scores, r.tenant.check(r.db).Error
+	}
+	err := r.scores().Where("deal_id IN ?", dealIDs).Order("source, reviewer_name, id").Find(&scores).Error
+	return scores, err
+}
+
+// GetAll returns all of the tenant's scores
+func (r *DealScoreRepository) GetAll() ([]models.DealScore, error) {
+	var scores []models.DealScore
+	err := r.scores().Order("deal_id, id").Find(&scores).Error
+	return scores, err
+}
+
+// ReplaceReviewer sets the scores a member gives one of the tenant's deals, dropping the
+// ones they gave before
+func (r *DealScoreRepository) ReplaceReviewer(dealID, reviewerID uint, scores []models.DealScore) error {
+	return r.replace(dealID, models.ScoreSourceReviewer, reviewerID, scores)
+}
+
+func (r *DealScoreRepository) replace(dealID uint, source models.ScoreSource, reviewerID uint, scores []models.DealScore) error {
+	for i := range scores {
+		if err := r.tenant.claim(&scores[i].OrganizationID); err != nil {
+			return err
+		}
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		repo := &DealScoreRepository{db: tx, tenant: r.tenant}
+		err := repo.scores().Where("deal_id = ? AND source = ? AND reviewer_id = ?", dealID, source, reviewerID).Delete(&models.DealScore{}).Error
+		if err != nil {
+			return err
+		}
+		for i := range scores {
+			scores[i].ID = 0
+			scores[i].DealID = dealID
+			scores[i].Source = source
+			scores[i].ReviewerID = reviewerID
+		}
+		if len(scores) == 0 {
+			return nil
+		}
+		return tx.Create(&scores).Error
+	})
+}
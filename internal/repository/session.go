@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"time"
+	"ventura/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Create creates a new session
+func (r *SessionRepository) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// FindByID finds a session by ID
+func (r *SessionRepository) FindByID(id uint) (*models.Session, error) {
+	var session models.Session
+	err := r.db.First(&session, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// IsActive reports whether a session exists, is not revoked and has not expired
+func (r *SessionRepository) IsActive(id uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", id, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetActiveByUserID returns all active sessions for a user, most recently used first
+func (r *SessionRepository) GetActiveByUserID(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// Rotate swaps the current refresh token ID for a new one.
+// It only succeeds if oldTokenID is still current, so concurrent rotations cannot both win.
+func (r *SessionRepository) Rotate(id uint, oldTokenID, newTokenID string, expiresAt time.Time, ipAddress, userAgent string) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&models.Session{}).
+		Where("id = ? AND token_id = ? AND revoked_at IS NULL", id, oldTokenID).
+		Updates(map[string]interface{}{
+			"token_id":          newTokenID,
+			"previous_token_id": oldTokenID,
+			"rotated_at":        now,
+			"last_used_at":      now,
+			"expires_at":        expiresAt,
+			"ip_address":        ipAddress,
+			"user_agent":        userAgent,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Revoke revokes a single session
+func (r *SessionRepository) Revoke(id uint, reason string) error {
+	return r.db.Model(&models.Session{}).Where("id = ? AND revoked_at IS NULL", id).Updates(map[string]interface{}{
+		"revoked_at":     time.Now(),
+		"revoked_reason": reason,
+	}).Error
+}
+
+// RevokeForUser revokes a session only if it belongs to the user
+func (r *SessionRepository) RevokeForUser(id uint, userID uint, reason string) (bool, error) {
+	result := r.db.Model(&models.Session{}).Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).Updates(map[string]interface{}{
+		"revoked_at":     time.Now(),
+		"revoked_reason": reason,
+	})
+	return result.RowsAffected == 1, result.Error
+}
+
+// RevokeAllForUser revokes every active session of a user except exceptID (0 revokes all)
+func (r *SessionRepository) RevokeAllForUser(userID uint, exceptID uint, reason string) error {
+	query := r.db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptID != 0 {
+		query = query.Where("id <> ?", exceptID)
+	}
+	return query.Updates(map[string]interface{}{
+		"revoked_at":     time.Now(),
+		"revoked_reason": reason,
+	}).Error
+}
+
+// RevokeAllForUserInOrganization revokes a user's active sessions in one organization
+func (r *SessionRepository) RevokeAllForUserInOrganization(userID, orgID uint, reason string) error {
+	return r.db.Model(&models.Session{}).Where("user_id = ? AND organization_id = ? AND revoked_at IS NULL", userID, orgID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		}).Error
+}
+
+// RevokeAllInOrganization revokes every active session in an organization
+func (r *SessionRepository) RevokeAllInOrganization(orgID uint, reason string) error {
+	return r.db.Model(&models.Session{}).Where("organization_id = ? AND revoked_at IS NULL", orgID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		}).Error
+}
+
+// DeleteExpired removes sessions that expired before the cutoff
+func (r *SessionRepository) DeleteExpired(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"ventura/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type SSORepository struct {
+	db *gorm.DB
+}
+
+func NewSSORepository(db *gorm.DB) *SSORepository {
+	return &SSORepository{db: db}
+}
+
+// WithContext returns the repository bound to ctx
+func (r *SSORepository) WithContext(ctx context.Context) *SSORepository {
+	return &SSORepository{db: r.db.WithContext(ctx)}
+}
+
+// FindProviderByOrganization returns an organization's OIDC provider
+func (r *SSORepository) FindProviderByOrganization(orgID uint) (*models.OIDCProvider, error) {
+	var provider models.OIDCProvider
+	err := r.db.Where("organization_id = ?", orgID).First(&provider).Error
+	if err != nil {
+		return nil, err
+	}
+	return &provider, nil
+}
+
+// SaveProvider creates or updates an OIDC provider
+func (r *SSORepository) SaveProvider(provider *models.OIDCProvider) error {
+	return r.db.Save(provider).Error
+}
+
+// DeleteProvider removes an organization's OIDC provider
+func (r *SSORepository) DeleteProvider(orgID uint) error {
+	return r.db.Where("organization_id = ?", orgID).Delete(&models.OIDCProvider{}).Error
+}
+
+// FindIdentity finds a linked identity by issuer and subject
+func (r *SSORepository) FindIdentity(issuer, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// CreateIdentity links a user to an external identity
+func (r *SSORepository) CreateIdentity(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// TouchIdentity records a login through an identity
+func (r *SSORepository) TouchIdentity(id uint, email string) error {
+	return r.db.Model(&models.UserIdentity{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":         email,
+		"last_login_at": time.Now(),
+	}).Error
+}
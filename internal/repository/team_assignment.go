@@ -1,42 +1,68 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"ventura/internal/models"
+	"ventura/internal/tenancy"
 
 	"gorm.io/gorm"
 )
 
+// TeamAssignmentRepository reads and writes the team assignments of its tenant's companies.
+// Every query fails with ErrNoTenant until it is confined with Within.
 type TeamAssignmentRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	tenant tenant
 }
 
 func NewTeamAssignmentRepository(db *gorm.DB) *TeamAssignmentRepository {
 	return &TeamAssignmentRepository{db: db}
 }
 
-// Create creates a new team assignment
+// WithContext returns the repository bound to ctx
+func (r *TeamAssignmentRepository) WithContext(ctx context.Context) *TeamAssignmentRepository {
+	return &TeamAssignmentRepository{db: r.db.WithContext(ctx), tenant: r.tenant}
+}
+
+// Within returns the repository confined to the teams of the companies visible in scope
+func (r *TeamAssignmentRepository) Within(scope CompanyScope) *TeamAssignmentRepository {
+	return &TeamAssignmentRepository{db: tenancy.Organization(r.db, scope.OrganizationID), tenant: tenant{scope: scope}}
+}
+
+// Create assigns a user to one of the tenant's companies
 func (r *TeamAssignmentRepository) Create(assignment *models.TeamAssignment) error {
+	if err := r.tenant.hasCompany(r.db, assignment.CompanyID); err != nil {
+		return err
+	}
 	return r.db.Create(assignment).Error
 }
 
-// GetByCompanyID returns all team assignments for a company with user details
+// GetAll returns the team assignments on the tenant's companies
+func (r *TeamAssignmentRepository) GetAll() ([]models.TeamAssignment, error) {
+	var assignments []models.TeamAssignment
+	err := r.tenant.companyRows(r.db).Order("id").Find(&assignments).Error
+	return assignments, err
+}
+
+// GetByCompanyID returns all team assignments for one of the tenant's companies with user details
 func (r *TeamAssignmentRepository) GetByCompanyID(companyID uint) ([]models.TeamAssignment, error) {
 	var assignments []models.TeamAssignment
-	err := r.db.Preload("User").Where("company_id = ?", companyID).Find(&assignments).Error
+	err := r.tenant.companyRows(r.db).Preload("User").Where("company_id = ?", companyID).Find(&assignments).Error
 	return assignments, err
 }
 
-// GetByUserID returns all team assignments for a user with company details
+// GetByUserID returns a user's team assignments on the tenant's companies with company details
 func (r *TeamAssignmentRepository) GetByUserID(userID uint) ([]models.TeamAssignment, error) {
 	var assignments []models.TeamAssignment
-	err := r.db.Preload("Company").Where("user_id = ?", userID).Find(&assignments).Error
+	err := r.tenant.companyRows(r.db).Preload("Company").Where("user_id = ?", userID).Find(&assignments).Error
 	return assignments, err
 }
 
-// GetByID returns a team assignment by ID
+// GetByID returns a team assignment on one of the tenant's companies by ID
 func (r *TeamAssignmentRepository) GetByID(id uint) (*models.TeamAssignment, error) {
 	var assignment models.TeamAssignment
-	err := r.db.Preload("User").Preload("Company").First(&assignment, id).Error
+	err := r.tenant.companyRows(r.db).Preload("User").Preload("Company").First(&assignment, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -46,24 +72,68 @@ func (r *TeamAssignmentRepository) GetByID(id uint) (*models.TeamAssignment, err
 // GetByUserAndCompany returns a team assignment by user and company
 func (r *TeamAssignmentRepository) GetByUserAndCompany(userID, companyID uint) (*models.TeamAssignment, error) {
 	var assignment models.TeamAssignment
-	err := r.db.Where("user_id = ? AND company_id = ?", userID, companyID).First(&assignment).Error
+	err := r.tenant.companyRows(r.db).Where("user_id = ? AND company_id = ?", userID, companyID).First(&assignment).Error
 	if err != nil {
 		return nil, err
 	}
 	return &assignment, nil
 }
 
-// Update updates a team assignment
+// Update saves a team assignment on one of the tenant's companies
 func (r *TeamAssignmentRepository) Update(assignment *models.TeamAssignment) error {
+	if err := exists(r.tenant.companyRows(r.db).Model(&models.TeamAssignment{}).Where("id = ?", assignment.ID)); err != nil {
+		return err
+	}
+	if err := r.tenant.hasCompany(r.db, assignment.CompanyID); err != nil {
+		return err
+	}
 	return r.db.Save(assignment).Error
 }
 
-// Delete deletes a team assignment
+// Reassign hands a member's team assignments on the tenant's companies over to another
+// member and returns how many companies changed hands. Where both were assigned, the
+// other member keeps the higher of the two roles.
+func (r *TeamAssignmentRepository) Reassign(fromUserID, toUserID uint) (int, error) {
+	reassigned := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		repo := &TeamAssignmentRepository{db: tx, tenant: r.tenant}
+		assignments, err := repo.GetByUserID(fromUserID)
+		if err != nil {
+			return err
+		}
+		for i := range assignments {
+			from := &assignments[i]
+			from.Company = nil
+			to, err := repo.GetByUserAndCompany(toUserID, from.CompanyID)
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				from.UserID = toUserID
+				err = tx.Save(from).Error
+			case err == nil:
+				if from.Role.Rank() > to.Role.Rank() {
+					to.Role = from.Role
+					if err := tx.Save(to).Error; err != nil {
+						return err
+					}
+				}
+				err = tx.Delete(from).Error
+			}
+			if err != nil {
+				return err
+			}
+			reassigned++
+		}
+		return nil
+	})
+	return reassigned, err
+}
+
+// Delete deletes a team assignment on one of the tenant's companies
 func (r *TeamAssignmentRepository) Delete(id uint) error {
-	return r.db.Delete(&models.TeamAssignment{}, id).Error
+	return r.tenant.companyRows(r.db).Delete(&models.TeamAssignment{}, id).Error
 }
 
 // DeleteByUserAndCompany deletes a team assignment by user and company
 func (r *TeamAssignmentRepository) DeleteByUserAndCompany(userID, companyID uint) error {
-	return r.db.Where("user_id = ? AND company_id = ?", userID, companyID).Delete(&models.TeamAssignment{}).Error
+	return r.tenant.companyRows(r.db).Where("user_id = ? AND company_id = ?", userID, companyID).Delete(&models.TeamAssignment{}).Error
 }
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNoTenant is returned by queries on organization data through a repository that was
+	// not confined with Within
+	ErrNoTenant = errors.New("query is not scoped to an organization")
+	// ErrOtherTenant is returned when creating a record for another organization
+	ErrOtherTenant = errors.New("record belongs to another organization")
+)
+
+// OrganizationScope returns the scope of every company of an organization
+func OrganizationScope(orgID uint) CompanyScope {
+	return CompanyScope{OrganizationID: orgID, AllCompanies: true}
+}
+
+// tenant is what the queries of a repository over organization data are confined to: the
+// companies of a scope. Repositories start out with the zero tenant, whose queries fail with
+// ErrNoTenant, so each caller has to choose. Within also binds the repository's db to the
+// tenant, so PostgreSQL's row-level security holds its queries to the same organization.
+type tenant struct {
+	scope CompanyScope
+}
+
+// check fails db's query when no tenant was chosen
+func (t tenant) check(db *gorm.DB) *gorm.DB {
+	if t.scope.OrganizationID != 0 {
+		return db
+	}
+	db = db.Session(&gorm.Session{})
+	db.AddError(ErrNoTenant)
+	return db
+}
+
+// companies returns a query over the tenant's portfolio companies
+func (t tenant) companies(db *gorm.DB) *gorm.DB {
+	return visibleCompanies(t.check(db), t.scope)
+}
+
+// companyRows confines a query on a table with a company_id column to the tenant's companies
+func (t tenant) companyRows(db *gorm.DB) *gorm.DB {
+	return t.check(db).Where("company_id IN (?)", visibleCompanyIDs(db, t.scope))
+}
+
+// organizationRows confines a query to the rows whose column holds the tenant's organization
+func (t tenant) organizationRows(db *gorm.DB, column string) *gorm.DB {
+	return t.check(db).Where(column+" = ?", t.scope.OrganizationID)
+}
+
+// hasCompany returns gorm.ErrRecordNotFound unless a company is one of the tenant's
+func (t tenant) hasCompany(db *gorm.DB, companyID uint) error {
+	return exists(t.companies(db).Where("portfolio_companies.id = ?", companyID))
+}
+
+// claim assigns a new record to the tenant's organization, refusing one that names another
+func (t tenant) claim(orgID *uint) error {
+	switch {
+	case t.scope.OrganizationID == 0:
+		return ErrNoTenant
+	case *orgID == 0:
+		*orgID = t.scope.OrganizationID
+	case *orgID != t.scope.OrganizationID:
+		return ErrOtherTenant
+	}
+	return nil
+}
+
+// exists returns gorm.ErrRecordNotFound unless query matches a row
+func exists(query *gorm.DB) error {
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
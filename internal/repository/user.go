@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+	"time"
 	"ventura/internal/models"
 
 	"gorm.io/gorm"
@@ -14,9 +16,23 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-// Create creates a new user
+// WithContext returns the repository bound to ctx
+func (r *UserRepository) WithContext(ctx context.Context) *UserRepository {
+	return &UserRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create creates a new user as a member of user.OrganizationID with user.Role
 func (r *UserRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.Membership{
+			UserID:         user.ID,
+			OrganizationID: user.OrganizationID,
+			Role:           user.Role,
+		}).Error
+	})
 }
 
 // FindByEmail finds a user by email
@@ -39,14 +55,11 @@ func (r *UserRepository) FindByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
-// Update updates a user
+// Update updates a user. The default organization and its role are left alone: users are
+// usually loaded as a member of some organization, and those only change through
+// memberships and SetDefaultOrganization.
 func (r *UserRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
-}
-
-// Delete deletes a user
-func (r *UserRepository) Delete(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+	return r.db.Omit("OrganizationID", "Role", "Organization").Save(user).Error
 }
 
 // GetAll returns all users
@@ -56,29 +69,135 @@ func (r *UserRepository) GetAll() ([]models.User, error) {
 	return users, err
 }
 
-// GetAllByOrganization returns all users in an organization
+// GetAllByOrganization returns the members of an organization with their role in it,
+// deactivated ones included
 func (r *UserRepository) GetAllByOrganization(orgID uint) ([]models.User, error) {
-	var users []models.User
-	err := r.db.Where("organization_id = ?", orgID).Find(&users).Error
-	return users, err
+	var memberships []models.Membership
+	err := r.db.Preload("User").Where("organization_id = ?", orgID).Order("created_at").Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]models.User, 0, len(memberships))
+	for i := range memberships {
+		if memberships[i].User != nil {
+			users = append(users, *memberships[i].AsUser())
+		}
+	}
+	return users, nil
 }
 
-// FindByEmailWithOrganization finds a user by email and preloads organization
-func (r *UserRepository) FindByEmailWithOrganization(email string) (*models.User, error) {
-	var user models.User
-	err := r.db.Preload("Organization").Where("email = ?", email).First(&user).Error
+// FindMember loads a user as a member of an organization: OrganizationID, Role and
+// Organization describe that membership. It fails if the user doesn't belong to it or
+// was deactivated there.
+func (r *UserRepository) FindMember(userID, orgID uint) (*models.User, error) {
+	return r.findMember(r.db.Where("deactivated_at IS NULL"), userID, orgID)
+}
+
+// FindMemberIncludingDeactivated is FindMember for administering members, who may be deactivated
+func (r *UserRepository) FindMemberIncludingDeactivated(userID, orgID uint) (*models.User, error) {
+	return r.findMember(r.db, userID, orgID)
+}
+
+func (r *UserRepository) findMember(query *gorm.DB, userID, orgID uint) (*models.User, error) {
+	var membership models.Membership
+	err := query.Preload("User").Preload("Organization").
+		Where("user_id = ? AND organization_id = ?", userID, orgID).First(&membership).Error
 	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	if membership.User == nil || membership.Organization == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return membership.AsUser(), nil
 }
 
-// FindByIDWithOrganization finds a user by ID and preloads organization
-func (r *UserRepository) FindByIDWithOrganization(id uint) (*models.User, error) {
-	var user models.User
-	err := r.db.Preload("Organization").First(&user, id).Error
+// FindDefaultMember loads a user as a member of their default organization, falling
+// back to their oldest active membership if they have left it or been deactivated there
+func (r *UserRepository) FindDefaultMember(user *models.User) (*models.User, error) {
+	member, err := r.FindMember(user.ID, user.OrganizationID)
+	if err == nil {
+		return member, nil
+	}
+
+	var membership models.Membership
+	if err := r.db.Joins("JOIN organizations ON organizations.id = memberships.organization_id AND organizations.deleted_at IS NULL").
+		Where("memberships.user_id = ? AND memberships.deactivated_at IS NULL", user.ID).Order("memberships.created_at").First(&membership).Error; err != nil {
+		return nil, err
+	}
+	return r.FindMember(user.ID, membership.OrganizationID)
+}
+
+// FindMemberByEmail finds a user by email as a member of their default organization
+func (r *UserRepository) FindMemberByEmail(email string) (*models.User, error) {
+	user, err := r.FindByEmail(email)
 	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	return r.FindDefaultMember(user)
+}
+
+// SetDefaultOrganization makes orgID the organization the user lands in at login
+func (r *UserRepository) SetDefaultOrganization(userID, orgID uint) error {
+	return r.db.Exec(
+		"UPDATE users SET organization_id = m.organization_id, role = m.role, updated_at = NOW() FROM memberships m WHERE users.id = ? AND m.user_id = users.id AND m.organization_id = ?",
+		userID, orgID,
+	).Error
+}
+
+// GetServiceAccountsByOrganization returns all service accounts in an organization
+func (r *UserRepository) GetServiceAccountsByOrganization(orgID uint) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("organization_id = ? AND is_service_account = ?", orgID, true).Find(&users).Error
+	return users, err
+}
+
+// AdvanceTOTPStep records the time step of an accepted TOTP code. It reports false if
+// that step (or a later one) was already used, which rejects replayed codes.
+func (r *UserRepository) AdvanceTOTPStep(id uint, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_used_step < ?", id, step).
+		Update("totp_last_used_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RecordFailedLogin atomically increments the failed login counter and returns the new count
+func (r *UserRepository) RecordFailedLogin(id uint) (int, error) {
+	var attempts int
+	err := r.db.Raw(
+		"UPDATE users SET failed_login_attempts = failed_login_attempts + 1 WHERE id = ? RETURNING failed_login_attempts", id,
+	).Scan(&attempts).Error
+	return attempts, err
+}
+
+// SetLockedUntil blocks logins for a user until the given time
+func (r *UserRepository) SetLockedUntil(id uint, until time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("locked_until", until).Error
+}
+
+// ResetFailedLogins clears the failed login counter and any lockout
+func (r *UserRepository) ResetFailedLogins(id uint) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	}).Error
+}
+
+// CountByRole counts an organization's members holding a role
+func (r *UserRepository) CountByRole(orgID uint, role models.UserRole) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Membership{}).Where("organization_id = ? AND role = ?", orgID, role).Count(&count).Error
+	return count, err
+}
+
+// CountActiveByRole counts the members of an organization holding a role who can still
+// sign in to it: deactivated members and service accounts are left out
+func (r *UserRepository) CountActiveByRole(orgID uint, role models.UserRole) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Membership{}).
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.organization_id = ? AND memberships.role = ? AND memberships.deactivated_at IS NULL", orgID, role).
+		Where("users.is_service_account = ?", false).
+		Count(&count).Error
+	return count, err
 }
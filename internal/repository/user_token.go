@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"time"
+	"ventura/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type UserTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) *UserTokenRepository {
+	return &UserTokenRepository{db: db}
+}
+
+// Create creates a new user token
+func (r *UserTokenRepository) Create(token *models.UserToken) error {
+	return r.db.Create(token).Error
+}
+
+// Consume marks an unused, unexpired token as used. It reports false if the token
+// is unknown, expired or was already used, so concurrent redemptions can't both win.
+func (r *UserTokenRepository) Consume(tokenID string, userID uint, purpose string) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&models.UserToken{}).
+		Where("token_id = ? AND user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenID, userID, purpose, now).
+		Update("used_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// InvalidateForUser marks every outstanding token of a purpose as used
+func (r *UserTokenRepository) InvalidateForUser(userID uint, purpose string) error {
+	return r.db.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
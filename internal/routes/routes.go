@@ -1,10 +1,15 @@
 package routes
 
 import (
+	"log"
+	"os"
+	"strings"
+	"time"
 	"ventura/internal/config"
 	"ventura/internal/di"
 	"ventura/internal/handler"
 	"ventura/internal/middleware"
+	"ventura/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,12 +18,25 @@ import (
 func Setup(container *di.Container) *gin.Engine {
 	r := gin.Default()
 
+	// Client IPs drive rate limiting and audit logs; only trust forwarding headers from known proxies
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		if err := r.SetTrustedProxies(strings.Split(proxies, ",")); err != nil {
+			log.Fatal("Invalid TRUSTED_PROXIES:", err)
+		}
+	}
+
 	// CORS Configuration
 	r.Use(config.SetupCORS())
 
+	// Database changes are audited as the client until authentication identifies the user
+	r.Use(middleware.AuditActor())
+
 	// Health Check (public)
 	r.GET("/health", handler.HealthCheck)
 
+	// Public signing keys for token verification by other services
+	r.GET("/.well-known/jwks.json", handler.JWKS)
+
 	// Register route groups
 	registerAuthRoutes(r, container)
 	registerLegacyRoutes(r, container)
@@ -27,41 +45,93 @@ func Setup(container *di.Container) *gin.Engine {
 	return r
 }
 
+// authRateLimit is the number of /auth requests allowed per client IP per minute
+const authRateLimit = 60
+
 // registerAuthRoutes sets up authentication routes (public)
 func registerAuthRoutes(r *gin.Engine, c *di.Container) {
+	requireAuth := middleware.AuthMiddleware(c.SessionService, c.APITokenService, c.PermissionService)
+	requireSession := middleware.RequireSession()
+
+	// Per-IP throttle against password guessing and credential stuffing
 	auth := r.Group("/auth")
+	auth.Use(middleware.RateLimit(authRateLimit, time.Minute))
 	{
 		auth.POST("/register", c.AuthHandler.Register)
 		auth.POST("/login", c.AuthHandler.Login)
 		auth.POST("/refresh", c.AuthHandler.Refresh)
 		auth.POST("/logout", c.AuthHandler.Logout)
-		auth.GET("/me", middleware.AuthMiddleware(), c.AuthHandler.Me)
+
+		// Account recovery and verification (public)
+		auth.POST("/password/forgot", c.AuthHandler.ForgotPassword)
+		auth.POST("/password/reset", c.AuthHandler.ResetPassword)
+		auth.POST("/password/initial", c.AuthHandler.SetInitialPassword)
+		auth.POST("/verify-email", c.AuthHandler.VerifyEmail)
+		auth.POST("/verify-email/resend", c.AuthHandler.ResendVerification)
+		auth.POST("/organizations/restore", c.OrganizationHandler.RestoreOrganization)
+		auth.GET("/me", requireAuth, c.AuthHandler.Me)
 
 		// Profile management (protected)
-		auth.PUT("/profile", middleware.AuthMiddleware(), c.AuthHandler.UpdateProfile)
-		auth.PUT("/password", middleware.AuthMiddleware(), c.AuthHandler.ChangePassword)
-		auth.GET("/organization", middleware.AuthMiddleware(), c.AuthHandler.GetOrganization)
-		auth.POST("/invites", middleware.AuthMiddleware(), c.AuthHandler.CreateInviteCode)
-		auth.GET("/invites", middleware.AuthMiddleware(), c.AuthHandler.GetInviteCodes)
+		auth.PUT("/profile", requireAuth, c.AuthHandler.UpdateProfile)
+		auth.PUT("/password", requireAuth, requireSession, c.AuthHandler.ChangePassword)
+		auth.GET("/organization", requireAuth, c.AuthHandler.GetOrganization)
+
+		// Organization memberships (protected)
+		auth.GET("/organizations", requireAuth, requireSession, c.MembershipHandler.GetOrganizations)
+		auth.POST("/organizations/switch", requireAuth, requireSession, c.MembershipHandler.SwitchOrganization)
+
+		// Invitations: look up a code before registering, or join with an existing account
+		auth.GET("/invitations/:code", c.InvitationHandler.GetInvitation)
+		auth.POST("/invitations/accept", requireAuth, requireSession, c.InvitationHandler.AcceptInvitation)
+
+		// Session/device management (protected)
+		auth.GET("/sessions", requireAuth, requireSession, c.AuthHandler.GetSessions)
+		auth.DELETE("/sessions", requireAuth, requireSession, c.AuthHandler.RevokeOtherSessions)
+		auth.DELETE("/sessions/:id", requireAuth, requireSession, c.AuthHandler.RevokeSession)
+
+		// OpenID Connect single sign-on (public, browser redirects)
+		auth.GET("/sso/callback", c.SSOHandler.SSOCallback)
+		auth.GET("/sso/:slug/start", c.SSOHandler.StartSSO)
+
+		// Two-factor login steps (public, authorized by the challenge token from /login)
+		auth.POST("/mfa/verify", c.MFAHandler.VerifyMFA)
+		auth.POST("/mfa/setup", c.MFAHandler.SetupMFA)
+		auth.POST("/mfa/setup/confirm", c.MFAHandler.ConfirmSetupMFA)
+
+		// Two-factor management (protected)
+		auth.GET("/mfa", requireAuth, requireSession, c.MFAHandler.GetMFAStatus)
+		auth.POST("/mfa/enroll", requireAuth, requireSession, c.MFAHandler.EnrollMFA)
+		auth.POST("/mfa/enroll/confirm", requireAuth, requireSession, c.MFAHandler.ConfirmEnrollMFA)
+		auth.POST("/mfa/recovery-codes", requireAuth, requireSession, c.MFAHandler.RegenerateRecoveryCodes)
+		auth.DELETE("/mfa", requireAuth, requireSession, c.MFAHandler.DisableMFA)
 	}
 }
 
 // registerLegacyRoutes sets up legacy routes for backward compatibility
 func registerLegacyRoutes(r *gin.Engine, c *di.Container) {
-	r.POST("/investments", c.InvestmentHandler.CreateInvestment)
+	r.POST("/investments",
+		middleware.AuthMiddleware(c.SessionService, c.APITokenService, c.PermissionService),
+		middleware.RequireScope(models.ScopeResourcePortfolio),
+		middleware.RequirePermission(models.PermInvestmentWrite),
+		c.InvestmentHandler.CreateInvestment)
 }
 
 // registerAPIRoutes sets up all protected API routes
 func registerAPIRoutes(r *gin.Engine, c *di.Container) {
 	api := r.Group("/api")
-	api.Use(middleware.AuthMiddleware())
+	api.Use(middleware.AuthMiddleware(c.SessionService, c.APITokenService, c.PermissionService))
 	{
 		// Search endpoint
 		api.GET("/search", c.SearchHandler.GlobalSearch)
 
+		// Organization settings every member's views depend on
+		api.GET("/organization/settings", c.OrganizationHandler.GetSettings)
+
 		registerDashboardRoutes(api, c)
 		registerPortfolioRoutes(api, c)
 		registerDealRoutes(api, c)
+		registerAnalyticsRoutes(api, c)
+		registerNotificationRoutes(api, c)
 		registerFounderRoutes(api, c)
 		registerMonthlyUpdateRoutes(api, c)
 		registerTeamRoutes(api, c)
@@ -72,6 +142,7 @@ func registerAPIRoutes(r *gin.Engine, c *di.Container) {
 // registerDashboardRoutes sets up dashboard routes
 func registerDashboardRoutes(api *gin.RouterGroup, c *di.Container) {
 	dashboard := api.Group("/dashboard")
+	dashboard.Use(middleware.RequireScope(models.ScopeResourceDashboard))
 	{
 		dashboard.GET("", c.DashboardHandler.GetDashboard)
 		dashboard.GET("/aum", c.DashboardHandler.GetAUM)
@@ -86,81 +157,196 @@ func registerDashboardRoutes(api *gin.RouterGroup, c *di.Container) {
 
 // registerPortfolioRoutes sets up portfolio routes
 func registerPortfolioRoutes(api *gin.RouterGroup, c *di.Container) {
+	canWrite := middleware.RequirePermission(models.PermCompanyWrite)
+
 	portfolio := api.Group("/portfolio")
+	portfolio.Use(middleware.RequireScope(models.ScopeResourcePortfolio))
 	{
 		portfolio.GET("/companies", c.PortfolioHandler.GetCompanies)
 		portfolio.GET("/companies/:id", c.PortfolioHandler.GetCompany)
-		portfolio.POST("/companies", c.PortfolioHandler.CreateCompany)
-		portfolio.PUT("/companies/:id", c.PortfolioHandler.UpdateCompany)
-		portfolio.DELETE("/companies/:id", c.PortfolioHandler.DeleteCompany)
-		portfolio.PATCH("/companies/:id/notifications", c.PortfolioHandler.ToggleNotifications)
+		portfolio.POST("/companies", canWrite, c.PortfolioHandler.CreateCompany)
+		portfolio.PUT("/companies/:id", canWrite, c.PortfolioHandler.UpdateCompany)
+		portfolio.DELETE("/companies/:id", middleware.RequirePermission(models.PermCompanyDelete), c.PortfolioHandler.DeleteCompany)
+		portfolio.PATCH("/companies/:id/notifications", canWrite, c.PortfolioHandler.ToggleNotifications)
+		portfolio.PATCH("/companies/:id/restriction", canWrite, c.PortfolioHandler.SetRestriction)
 	}
+
+	// Change history, alongside the company's founders and updates
+	api.GET("/companies/:id/history", middleware.RequireScope(models.ScopeResourcePortfolio), c.HistoryHandler.GetCompanyHistory)
 }
 
 // registerDealRoutes sets up deal flow routes
 func registerDealRoutes(api *gin.RouterGroup, c *di.Container) {
+	canWrite := middleware.RequirePermission(models.PermDealWrite)
+
 	deals := api.Group("/deals")
+	deals.Use(middleware.RequireScope(models.ScopeResourceDeals))
 	{
 		deals.GET("", c.DealHandler.GetDeals)
-		deals.POST("", c.DealHandler.CreateDeal)
-		deals.PATCH("/:id/stage", c.DealHandler.UpdateDealStage)
-		deals.PATCH("/:id/close", c.DealHandler.CloseDeal)
-		deals.PATCH("/:id/lose", c.DealHandler.LoseDeal)
-		deals.POST("/:id/ai-score", c.DealHandler.AIScoreDeal)
+		deals.GET("/stage-transitions", c.DealHandler.GetStageTransitions)
+		deals.GET("/scorecard", c.ScorecardHandler.GetScorecard)
+		deals.GET("/:id/history", c.HistoryHandler.GetDealHistory)
+		deals.GET("/:id/stage-events", c.DealHandler.GetStageEvents)
+		deals.GET("/:id/timeline", c.DealActivityHandler.GetTimeline)
+		deals.GET("/:id/activities/:activityId/revisions", c.DealActivityHandler.GetActivityRevisions)
+		deals.GET("/:id/analyses", c.DealAnalysisHandler.GetAnalyses)
+		deals.GET("/:id/analyses/:analysisId", c.DealAnalysisHandler.GetAnalysis)
+		deals.GET("/:id/scores", c.ScorecardHandler.GetDealScores)
+		deals.POST("", canWrite, c.DealHandler.CreateDeal)
+		deals.PATCH("/:id/stage", canWrite, c.DealHandler.UpdateDealStage)
+		deals.PATCH("/:id/close", canWrite, c.DealHandler.CloseDeal)
+		deals.PATCH("/:id/lose", canWrite, c.DealHandler.LoseDeal)
+		deals.POST("/:id/activities", canWrite, c.DealActivityHandler.CreateActivity)
+		deals.PUT("/:id/activities/:activityId", canWrite, c.DealActivityHandler.UpdateActivity)
+		deals.DELETE("/:id/activities/:activityId", canWrite, c.DealActivityHandler.DeleteActivity)
+		deals.PUT("/:id/scores", canWrite, c.ScorecardHandler.SetDealScores)
+		deals.POST("/:id/analyses/:analysisId/accept", canWrite, c.DealAnalysisHandler.AcceptAnalysis)
+		deals.POST("/:id/analyses/:analysisId/reject", canWrite, c.DealAnalysisHandler.RejectAnalysis)
+		deals.POST("/:id/ai-score", middleware.RequirePermission(models.PermDealScore), c.DealAnalysisHandler.AnalyzeDeal)
+	}
+}
+
+// registerNotificationRoutes sets up the caller's notification routes
+func registerNotificationRoutes(api *gin.RouterGroup, c *di.Container) {
+	notifications := api.Group("/notifications")
+	notifications.Use(middleware.RequireScope(models.ScopeResourceDeals))
+	{
+		notifications.GET("", c.NotificationHandler.GetNotifications)
+		notifications.POST("/read-all", c.NotificationHandler.MarkAllNotificationsRead)
+		notifications.POST("/:id/read", c.NotificationHandler.MarkNotificationRead)
+	}
+}
+
+// registerAnalyticsRoutes sets up pipeline analytics routes
+func registerAnalyticsRoutes(api *gin.RouterGroup, c *di.Container) {
+	analytics := api.Group("/analytics")
+	analytics.Use(middleware.RequireScope(models.ScopeResourceDeals))
+	{
+		analytics.GET("/pipeline", c.AnalyticsHandler.GetPipeline)
 	}
 }
 
 // registerFounderRoutes sets up founder routes
 func registerFounderRoutes(api *gin.RouterGroup, c *di.Container) {
+	founderScope := middleware.RequireScope(models.ScopeResourceFounders)
+	canWrite := middleware.RequirePermission(models.PermFounderWrite)
+
 	founders := api.Group("/founders")
+	founders.Use(founderScope)
 	{
 		founders.GET("", c.FounderHandler.GetFounders)
 		founders.GET("/:id", c.FounderHandler.GetFounder)
-		founders.PUT("/:id", c.FounderHandler.UpdateFounder)
-		founders.DELETE("/:id", c.FounderHandler.DeleteFounder)
+		founders.GET("/:id/history", c.HistoryHandler.GetFounderHistory)
+		founders.PUT("/:id", canWrite, c.FounderHandler.UpdateFounder)
+		founders.DELETE("/:id", middleware.RequirePermission(models.PermFounderDelete), c.FounderHandler.DeleteFounder)
 	}
 
 	// Company-specific founder routes
-	api.GET("/companies/:id/founders", c.FounderHandler.GetFoundersByCompany)
-	api.POST("/companies/:id/founders", c.FounderHandler.CreateFounder)
+	api.GET("/companies/:id/founders", founderScope, c.FounderHandler.GetFoundersByCompany)
+	api.POST("/companies/:id/founders", founderScope, canWrite, c.FounderHandler.CreateFounder)
 }
 
 // registerMonthlyUpdateRoutes sets up monthly update routes
 func registerMonthlyUpdateRoutes(api *gin.RouterGroup, c *di.Container) {
+	updateScope := middleware.RequireScope(models.ScopeResourceUpdates)
+	canWrite := middleware.RequirePermission(models.PermUpdateWrite)
+
 	updates := api.Group("/monthly-updates")
+	updates.Use(updateScope)
 	{
 		updates.GET("", c.MonthlyUpdateHandler.GetMonthlyUpdates)
 		updates.GET("/:id", c.MonthlyUpdateHandler.GetMonthlyUpdate)
-		updates.PUT("/:id", c.MonthlyUpdateHandler.UpdateMonthlyUpdate)
-		updates.DELETE("/:id", c.MonthlyUpdateHandler.DeleteMonthlyUpdate)
+		updates.GET("/:id/history", c.HistoryHandler.GetMonthlyUpdateHistory)
+		updates.PUT("/:id", canWrite, c.MonthlyUpdateHandler.UpdateMonthlyUpdate)
+		updates.DELETE("/:id", middleware.RequirePermission(models.PermUpdateDelete), c.MonthlyUpdateHandler.DeleteMonthlyUpdate)
 	}
 
 	// Company-specific monthly update routes
-	api.GET("/companies/:id/updates", c.MonthlyUpdateHandler.GetMonthlyUpdatesByCompany)
-	api.POST("/companies/:id/updates", c.MonthlyUpdateHandler.CreateMonthlyUpdate)
+	api.GET("/companies/:id/updates", updateScope, c.MonthlyUpdateHandler.GetMonthlyUpdatesByCompany)
+	api.POST("/companies/:id/updates", updateScope, canWrite, c.MonthlyUpdateHandler.CreateMonthlyUpdate)
 }
 
 // registerTeamRoutes sets up team assignment routes
 func registerTeamRoutes(api *gin.RouterGroup, c *di.Container) {
+	teamScope := middleware.RequireScope(models.ScopeResourceTeam)
+	canManage := middleware.RequirePermission(models.PermTeamManage)
+
 	// Team routes are nested under companies
-	api.GET("/companies/:id/team", c.TeamHandler.GetCompanyTeam)
-	api.POST("/companies/:id/team", c.TeamHandler.AddTeamMember)
-	api.DELETE("/companies/:id/team/:userId", c.TeamHandler.RemoveTeamMember)
+	api.GET("/companies/:id/team", teamScope, c.TeamHandler.GetCompanyTeam)
+	api.POST("/companies/:id/team", teamScope, canManage, c.TeamHandler.AddTeamMember)
+	api.DELETE("/companies/:id/team/:userId", teamScope, canManage, c.TeamHandler.RemoveTeamMember)
 }
 
-// registerAdminRoutes sets up admin-only routes
+// registerAdminRoutes sets up administration routes; each is gated by its own permission
 func registerAdminRoutes(api *gin.RouterGroup, c *di.Container) {
+	canManageUsers := middleware.RequirePermission(models.PermUserManage)
+	canManageRoles := middleware.RequirePermission(models.PermRoleManage)
+	canManageOrg := middleware.RequirePermission(models.PermOrgManage)
+	requireSession := middleware.RequireSession()
+
 	admin := api.Group("/admin")
-	admin.Use(middleware.RequireRole("admin"))
+	admin.Use(middleware.RequireScope(models.ScopeResourceAdmin))
 	{
 		// User management
-		admin.GET("/users", c.UserHandler.GetUsers)
-		admin.GET("/users/:id", c.UserHandler.GetUser)
-		admin.PUT("/users/:id", c.UserHandler.UpdateUser)
-		admin.DELETE("/users/:id", c.UserHandler.DeleteUser)
-		admin.POST("/users/invite", c.UserHandler.InviteUser)
+		admin.GET("/users", canManageUsers, c.UserHandler.GetUsers)
+		admin.GET("/users/:id", canManageUsers, c.UserHandler.GetUser)
+		admin.PUT("/users/:id", canManageUsers, c.UserHandler.UpdateUser)
+		admin.DELETE("/users/:id", canManageUsers, c.UserHandler.DeactivateUser)
+		admin.POST("/users/:id/deactivate", canManageUsers, c.UserHandler.DeactivateUser)
+		admin.POST("/users/:id/reactivate", canManageUsers, c.UserHandler.ReactivateUser)
+		admin.POST("/users/:id/handover", canManageUsers, c.UserHandler.HandoverUser)
+		admin.POST("/users/:id/unlock", canManageUsers, c.UserHandler.UnlockUser)
+
+		// Invitations
+		admin.GET("/invitations", canManageUsers, c.InvitationHandler.GetInvitations)
+		admin.POST("/invitations", canManageUsers, c.InvitationHandler.CreateInvitation)
+		admin.DELETE("/invitations/:id", canManageUsers, c.InvitationHandler.RevokeInvitation)
+		admin.POST("/invitations/:id/resend", canManageUsers, c.InvitationHandler.ResendInvitation)
+
+		// Roles and permissions
+		admin.GET("/permissions", canManageRoles, c.RoleHandler.GetPermissions)
+		admin.GET("/roles", c.RoleHandler.GetRoles)
+		admin.POST("/roles", canManageRoles, c.RoleHandler.CreateRole)
+		admin.PUT("/roles/:id", canManageRoles, c.RoleHandler.UpdateRole)
+		admin.DELETE("/roles/:id", canManageRoles, c.RoleHandler.DeleteRole)
 
 		// Audit logs
-		admin.GET("/audit-logs", c.AuditHandler.GetAuditLogs)
+		canReadAudit := middleware.RequirePermission(models.PermAuditRead)
+		admin.GET("/audit-logs", canReadAudit, c.AuditHandler.GetAuditLogs)
+		admin.GET("/audit-logs/export", canReadAudit, c.AuditHandler.ExportAuditLogs)
+		admin.GET("/audit-logs/verify", canReadAudit, c.AuditHandler.VerifyAuditLogs)
+		admin.GET("/audit-logs/retention", canReadAudit, c.AuditHandler.GetAuditRetention)
+		admin.PUT("/audit-logs/retention", canManageOrg, c.AuditHandler.UpdateAuditRetention)
+		admin.GET("/audit-logs/archives/:id", canReadAudit, c.AuditHandler.DownloadAuditArchive)
+
+		// Two-factor administration
+		admin.DELETE("/users/:id/mfa", canManageUsers, requireSession, c.MFAHandler.ResetUserMFA)
+		admin.PUT("/organization/mfa-policy", canManageOrg, requireSession, c.MFAHandler.UpdateMFAPolicy)
+		admin.PUT("/organization/settings", canManageOrg, c.OrganizationHandler.UpdateSettings)
+		admin.PUT("/organization/deal-stage-transitions", canManageOrg, c.DealHandler.SetStageTransitions)
+		admin.PUT("/organization/scorecard", canManageOrg, c.ScorecardHandler.SetScorecard)
+
+		// Data export and deletion of the whole organization
+		admin.GET("/organization/exports", canManageOrg, c.OrganizationHandler.GetExports)
+		admin.POST("/organization/exports", canManageOrg, c.OrganizationHandler.RequestExport)
+		admin.GET("/organization/exports/:id", canManageOrg, c.OrganizationHandler.GetExport)
+		admin.GET("/organization/exports/:id/download", canManageOrg, c.OrganizationHandler.DownloadExport)
+		admin.DELETE("/organization", canManageOrg, requireSession, c.OrganizationHandler.DeleteOrganization)
+
+		// Single sign-on configuration
+		admin.GET("/sso", canManageOrg, c.SSOHandler.GetSSOConfig)
+		admin.PUT("/sso", canManageOrg, requireSession, c.SSOHandler.UpdateSSOConfig)
+		admin.DELETE("/sso", canManageOrg, requireSession, c.SSOHandler.DeleteSSOConfig)
+
+		// API tokens and service accounts (not manageable with an API token)
+		tokens := admin.Group("")
+		tokens.Use(middleware.RequirePermission(models.PermAPITokenManage), requireSession)
+		{
+			tokens.GET("/api-tokens", c.APITokenHandler.GetAPITokens)
+			tokens.POST("/api-tokens", c.APITokenHandler.CreateAPIToken)
+			tokens.DELETE("/api-tokens/:id", c.APITokenHandler.RevokeAPIToken)
+			tokens.GET("/service-accounts", c.APITokenHandler.GetServiceAccounts)
+			tokens.POST("/service-accounts", c.APITokenHandler.CreateServiceAccount)
+		}
 	}
 }
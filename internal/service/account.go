@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"ventura/internal/auth"
+	"ventura/internal/mailer"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+var (
+	ErrInvalidActionToken = errors.New("this link is invalid, expired or has already been used")
+	ErrPasswordUnchanged  = errors.New("choose a password different from the temporary one")
+)
+
+// AccountService handles the emailed account flows: verification, password reset, invitations
+// and organization restores
+type AccountService struct {
+	userRepo      *repository.UserRepository
+	userTokenRepo *repository.UserTokenRepository
+	sessionRepo   *repository.SessionRepository
+	mailer        mailer.Mailer
+	appURL        string
+}
+
+func NewAccountService(userRepo *repository.UserRepository, userTokenRepo *repository.UserTokenRepository, sessionRepo *repository.SessionRepository, m mailer.Mailer) *AccountService {
+	return &AccountService{
+		userRepo:      userRepo,
+		userTokenRepo: userTokenRepo,
+		sessionRepo:   sessionRepo,
+		mailer:        m,
+		appURL:        appURL(),
+	}
+}
+
+// appURL is the frontend base URL used in emailed links
+func appURL() string {
+	if value := os.Getenv("APP_URL"); value != "" {
+		return strings.TrimRight(value, "/")
+	}
+	if frontendURL := os.Getenv("FRONTEND_URL"); frontendURL != "" {
+		return strings.TrimRight(strings.TrimSpace(strings.Split(frontendURL, ",")[0]), "/")
+	}
+	return "http://localhost:3000"
+}
+
+// SendEmailVerification emails the user a link to confirm their address
+func (s *AccountService) SendEmailVerification(user *models.User) error {
+	token, err := s.issue(user, auth.TokenTypeEmailVerification, models.UserTokenEmailVerification, auth.EmailVerificationExpiry)
+	if err != nil {
+		return err
+	}
+
+	s.deliver(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Text: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address to finish setting up your Ventura account:\n\n%s\n\nThe link expires in %d hours.\n",
+			user.Name, s.link("/verify-email", token), int(auth.EmailVerificationExpiry.Hours())),
+	})
+	return nil
+}
+
+// ResendEmailVerification sends a new verification link if the account exists and is unverified.
+// It reports nothing to the caller so it can't be used to discover accounts.
+func (s *AccountService) ResendEmailVerification(email string) {
+	user, err := s.userRepo.FindByEmail(strings.ToLower(email))
+	if err != nil || user.IsServiceAccount || user.IsEmailVerified() {
+		return
+	}
+	if err := s.SendEmailVerification(user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+}
+
+// VerifyEmail redeems a verification token and marks the address as verified
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	user, err := s.redeem(token, auth.TokenTypeEmailVerification, models.UserTokenEmailVerification)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsEmailVerified() {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := s.userRepo.WithContext(ctx).Update(user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// RequestPasswordReset emails a reset link if the account exists.
+// It reports nothing to the caller so it can't be used to discover accounts.
+func (s *AccountService) RequestPasswordReset(email string) {
+	user, err := s.userRepo.FindByEmail(strings.ToLower(email))
+	if err != nil || user.IsServiceAccount {
+		return
+	}
+
+	token, err := s.issue(user, auth.TokenTypePasswordReset, models.UserTokenPasswordReset, auth.PasswordResetExpiry)
+	if err != nil {
+		log.Printf("Failed to issue password reset token for user %d: %v", user.ID, err)
+		return
+	}
+
+	s.deliver(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Ventura password",
+		Text: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your Ventura account. If it was you, choose a new password here:\n\n%s\n\nThe link expires in %d minutes and can be used once. If you didn't ask for this, you can ignore this email.\n",
+			user.Name, s.link("/reset-password", token), int(auth.PasswordResetExpiry.Minutes())),
+	})
+}
+
+// ResetPassword redeems a reset token and sets a new password. Every session of the
+// user is signed out and any other outstanding reset links stop working.
+func (s *AccountService) ResetPassword(ctx context.Context, token, newPassword string) (*models.User, error) {
+	user, err := s.redeem(token, auth.TokenTypePasswordReset, models.UserTokenPasswordReset)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := user.SetPassword(newPassword); err != nil {
+		return nil, err
+	}
+	user.MustChangePassword = false
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	if !user.IsEmailVerified() {
+		// Receiving the reset link proves ownership of the address
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.userRepo.WithContext(ctx).Update(user); err != nil {
+		return nil, err
+	}
+
+	s.userTokenRepo.InvalidateForUser(user.ID, models.UserTokenPasswordReset)
+	s.sessionRepo.RevokeAllForUser(user.ID, 0, models.SessionRevokedPasswordReset)
+	return user, nil
+}
+
+// SetInitialPassword replaces the temporary password of a user invited before invitation
+// codes existed
+func (s *AccountService) SetInitialPassword(ctx context.Context, user *models.User, newPassword string) error {
+	if user.CheckPassword(newPassword) {
+		return ErrPasswordUnchanged
+	}
+	if err := user.SetPassword(newPassword); err != nil {
+		return err
+	}
+	user.MustChangePassword = false
+	if !user.IsEmailVerified() {
+		// The temporary password was only ever sent to this address
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	return s.userRepo.WithContext(ctx).Update(user)
+}
+
+// SendInvitation emails an invitation link to the address it is meant for
+func (s *AccountService) SendInvitation(invitation *models.Invitation, orgName, inviterName string) {
+	s.deliver(mailer.Message{
+		To:      invitation.Email,
+		Subject: inviterName + " invited you to join " + orgName + " on Ventura",
+		Text: fmt.Sprintf("Hi,\n\n%s invited you to join %s on Ventura. Open the link below to create your account, or to add %s to the account you already have:\n\n%s\n\nThe invitation expires on %s.\n",
+			inviterName, orgName, orgName, s.appURL+"/invite?code="+url.QueryEscape(invitation.Code), invitation.ExpiresAt.Format("January 2, 2006")),
+	})
+}
+
+// SendOrganizationRestore emails the member who deleted an organization a link that
+// restores it until it is purged
+func (s *AccountService) SendOrganizationRestore(member *models.User, org *models.Organization) error {
+	purgeAt := *org.PurgeAt
+	token, err := s.issue(member, auth.TokenTypeOrganizationRestore, models.UserTokenOrganizationRestore, time.Until(purgeAt))
+	if err != nil {
+		return err
+	}
+
+	s.deliver(mailer.Message{
+		To:      member.Email,
+		Subject: org.Name + " has been deleted from Ventura",
+		Text: fmt.Sprintf("Hi %s,\n\nYou deleted %s on Ventura. Its data will be permanently erased on %s. Until then, you can restore it with the link below:\n\n%s\n\nIf you didn't delete it, restore it now and review your organization's administrators.\n",
+			member.Name, org.Name, purgeAt.Format("January 2, 2006"), s.link("/restore-organization", token)),
+	})
+	return nil
+}
+
+// RedeemOrganizationRestore redeems a restore link and returns who it was sent to and the
+// organization it restores
+func (s *AccountService) RedeemOrganizationRestore(token string) (*models.User, uint, error) {
+	user, claims, err := s.redeemClaims(token, auth.TokenTypeOrganizationRestore, models.UserTokenOrganizationRestore)
+	if err != nil {
+		return nil, 0, err
+	}
+	return user, claims.OrganizationID, nil
+}
+
+// issue creates and records a single-use action token
+func (s *AccountService) issue(user *models.User, tokenType, purpose string, expiry time.Duration) (string, error) {
+	record := &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenID:   auth.NewTokenID(),
+		ExpiresAt: time.Now().Add(expiry),
+	}
+	if err := s.userTokenRepo.Create(record); err != nil {
+		return "", err
+	}
+	return auth.GenerateActionToken(user, tokenType, record.TokenID, expiry)
+}
+
+// redeem verifies an action token's signature and type and consumes it
+func (s *AccountService) redeem(token, tokenType, purpose string) (*models.User, error) {
+	user, _, err := s.redeemClaims(token, tokenType, purpose)
+	return user, err
+}
+
+// redeemClaims is redeem for tokens whose claims say more than who they were issued to
+func (s *AccountService) redeemClaims(token, tokenType, purpose string) (*models.User, *auth.Claims, error) {
+	claims, err := auth.ValidateTypedToken(token, tokenType)
+	if err != nil || claims.ID == "" {
+		return nil, nil, ErrInvalidActionToken
+	}
+
+	consumed, err := s.userTokenRepo.Consume(claims.ID, claims.UserID, purpose)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !consumed {
+		return nil, nil, ErrInvalidActionToken
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil || user.Email != claims.Email {
+		return nil, nil, ErrInvalidActionToken
+	}
+	return user, claims, nil
+}
+
+// link builds a frontend URL carrying a token
+func (s *AccountService) link(path, token string) string {
+	if token == "" {
+		return s.appURL + path
+	}
+	return s.appURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// deliver sends mail in the background so response times don't reveal whether an account exists
+func (s *AccountService) deliver(msg mailer.Message) {
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			log.Printf("Failed to send email %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"ventura/internal/models"
 
@@ -25,28 +26,62 @@ func NewAIDealScorerService() *AIDealScorerService {
 	return &AIDealScorerService{apiKey: key}
 }
 
+// AIDealScorePromptVersion names the scoring prompt; change it whenever the prompt changes
+// so analyses made with different prompts can be told apart
+const AIDealScorePromptVersion = "deal-score-v2"
+
+const aiDealScoreModel = "gemini-1.5-pro"
+
+// AIDealInput is the snapshot of a deal and of its organization's scorecard the scoring
+// prompt is built from
+type AIDealInput struct {
+	CompanyName     string                    `json:"companyName"`
+	Sector          string                    `json:"sector"`
+	RequestedAmount string                    `json:"requestedAmount"`
+	Valuation       string                    `json:"valuation"`
+	RoundStage      string                    `json:"roundStage"`
+	Notes           string                    `json:"notes"`
+	Criteria        []models.ScoringCriterion `json:"criteria"`
+}
+
 type AIDealScoreResult struct {
-	TeamScore     int    `json:"teamScore"`
-	ProductScore  int    `json:"productScore"`
-	MarketScore   int    `json:"marketScore"`
-	TractionScore int    `json:"tractionScore"`
-	Summary       string `json:"summary"`
-	KeyStrengths  string `json:"keyStrengths"`
-	KeyRisks      string `json:"keyRisks"`
+	Scores       map[string]int `json:"scores"`
+	Summary      string         `json:"summary"`
+	KeyStrengths string         `json:"keyStrengths"`
+	KeyRisks     string         `json:"keyRisks"`
 }
 
-func (s *AIDealScorerService) ScoreDeal(ctx context.Context, deal *models.Deal) (*AIDealScoreResult, error) {
+// ScoreDeal asks the model to score a deal on the criteria of its organization's scorecard.
+// The analysis it returns records the model, the prompt version, the input and the raw
+// response along with the parsed scores; it is not saved.
+func (s *AIDealScorerService) ScoreDeal(ctx context.Context, deal *models.Deal, criteria []models.ScoringCriterion) (*models.DealAnalysis, error) {
+	input := AIDealInput{
+		CompanyName:     deal.CompanyName,
+		Sector:          deal.Sector,
+		RequestedAmount: deal.RequestedAmount.String(),
+		Valuation:       deal.Valuation.String(),
+		RoundStage:      deal.RoundStage,
+		Notes:           deal.Notes,
+		Criteria:        criteria,
+	}
+	snapshot, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
 	if s.apiKey == "PLACEHOLDER_KEY" {
 		// Mock response if using placeholder
-		return &AIDealScoreResult{
-			TeamScore:     8,
-			ProductScore:  7,
-			MarketScore:   9,
-			TractionScore: 6,
-			Summary:       "This is a placeholder summary. Please set GEMINI_API_KEY to use the real AI integration.",
-			KeyStrengths:  "- Strong market potential\n- Experienced team",
-			KeyRisks:      "- Early stage traction\n- Highly competitive sector",
-		}, nil
+		result := AIDealScoreResult{
+			Scores:       make(map[string]int, len(criteria)),
+			Summary:      "This is a placeholder summary. Please set GEMINI_API_KEY to use the real AI integration.",
+			KeyStrengths: "- Strong market potential\n- Experienced team",
+			KeyRisks:     "- Early stage traction\n- Highly competitive sector",
+		}
+		for i, criterion := range criteria {
+			result.Scores[criterion.Key] = 6 + i%4
+		}
+		raw, _ := json.Marshal(result)
+		return parseDealAnalysis(deal, "placeholder", snapshot, criteria, string(raw))
 	}
 
 	client, err := genai.NewClient(ctx, option.WithAPIKey(s.apiKey))
@@ -55,19 +90,21 @@ func (s *AIDealScorerService) ScoreDeal(ctx context.Context, deal *models.Deal)
 	}
 	defer client.Close()
 
-	model := client.GenerativeModel("gemini-1.5-pro")
+	model := client.GenerativeModel(aiDealScoreModel)
 	model.ResponseMIMEType = "application/json"
 
-	prompt := fmt.Sprintf(`You are an expert Venture Capital analyst. Evaluate the following startup deal and provide scores from 1 to 10 for Team, Product, Market, and Traction. Also provide a 2-sentence summary, key strengths, and key risks. Return the result strictly as JSON with this schema: 
-{"teamScore": int, "productScore": int, "marketScore": int, "tractionScore": int, "summary": "string", "keyStrengths": "string", "keyRisks": "string"}
+	prompt := fmt.Sprintf(`You are an expert Venture Capital analyst. Evaluate the following startup deal and score it from 1 to 10 on each of the criteria below. Also provide a 2-sentence summary, key strengths, and key risks. Return the result strictly as JSON with this schema: 
+{"scores": {%s}, "summary": "string", "keyStrengths": "string", "keyRisks": "string"}
 
+Criteria:
+%s
 Deal Details:
 Company Name: %s
 Sector: %s
 Requested Amount: %s
 Valuation: %s
 Round Stage: %s
-Notes: %s`, deal.CompanyName, deal.Sector, deal.RequestedAmount.String(), deal.Valuation.String(), deal.RoundStage, deal.Notes)
+Notes: %s`, scoreSchema(criteria), describeCriteria(criteria), input.CompanyName, input.Sector, input.RequestedAmount, input.Valuation, input.RoundStage, input.Notes)
 
 	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
 	if err != nil {
@@ -84,10 +121,60 @@ Notes: %s`, deal.CompanyName, deal.Sector, deal.RequestedAmount.String(), deal.V
 		return nil, fmt.Errorf("unexpected response type from AI")
 	}
 
+	return parseDealAnalysis(deal, aiDealScoreModel, snapshot, criteria, string(text))
+}
+
+// scoreSchema lists the criterion keys of the expected scores object: "team": int, ...
+func scoreSchema(criteria []models.ScoringCriterion) string {
+	fields := make([]string, len(criteria))
+	for i, criterion := range criteria {
+		fields[i] = fmt.Sprintf("%q: int", criterion.Key)
+	}
+	return strings.Join(fields, ", ")
+}
+
+// describeCriteria writes out each criterion with its weight and what its scores mean
+func describeCriteria(criteria []models.ScoringCriterion) string {
+	var sb strings.Builder
+	for _, criterion := range criteria {
+		sb.WriteString(fmt.Sprintf("- %s (key %q, weight %d%%)", criterion.Name, criterion.Key, criterion.Weight))
+		if criterion.Description != "" {
+			sb.WriteString(": " + criterion.Description)
+		}
+		sb.WriteString("\n")
+		for _, level := range criterion.Scale {
+			sb.WriteString(fmt.Sprintf("  %d: %s\n", level.Score, level.Description))
+		}
+	}
+	return sb.String()
+}
+
+// parseDealAnalysis builds the analysis of a deal from the model's raw response, keeping the
+// scores from 1 to 10 on the scorecard's criteria
+func parseDealAnalysis(deal *models.Deal, model string, input json.RawMessage, criteria []models.ScoringCriterion, raw string) (*models.DealAnalysis, error) {
 	var result AIDealScoreResult
-	if err := json.Unmarshal([]byte(string(text)), &result); err != nil {
+	if err := json.Unmarshal([]byte(raw), &result); err != nil {
 		return nil, fmt.Errorf("failed to parse AI response: %v", err)
 	}
 
-	return &result, nil
+	scores := make(map[string]int, len(criteria))
+	for _, criterion := range criteria {
+		if score, ok := result.Scores[criterion.Key]; ok && score >= 1 && score <= 10 {
+			scores[criterion.Key] = score
+		}
+	}
+
+	return &models.DealAnalysis{
+		OrganizationID: deal.OrganizationID,
+		DealID:         deal.ID,
+		Model:          model,
+		PromptVersion:  AIDealScorePromptVersion,
+		Input:          input,
+		RawResponse:    raw,
+		Scores:         scores,
+		Summary:        result.Summary,
+		KeyStrengths:   result.KeyStrengths,
+		KeyRisks:       result.KeyRisks,
+		Status:         models.AnalysisPending,
+	}, nil
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"fmt"
 	"math"
 	"time"
 	"ventura/internal/models"
@@ -117,8 +118,8 @@ type PortfolioHealth struct {
 	Red    []models.PortfolioCompany
 }
 
-// GetPortfolioHealth categorizes companies based on financial health
-func (s *AnalyticsService) GetPortfolioHealth(companies []models.PortfolioCompany) PortfolioHealth {
+// GetPortfolioHealth categorizes companies based on financial health, against the organization's thresholds
+func (s *AnalyticsService) GetPortfolioHealth(companies []models.PortfolioCompany, settings models.OrganizationSettings) PortfolioHealth {
 	health := PortfolioHealth{
 		Green:  []models.PortfolioCompany{},
 		Yellow: []models.PortfolioCompany{},
@@ -126,7 +127,7 @@ func (s *AnalyticsService) GetPortfolioHealth(companies []models.PortfolioCompan
 	}
 
 	for _, company := range companies {
-		company.CalculateHealthStatus()
+		company.CalculateHealthStatus(settings)
 
 		switch company.HealthStatus {
 		case "green":
@@ -153,7 +154,7 @@ type DashboardMetrics struct {
 }
 
 // GetDashboardMetrics calculates all dashboard metrics
-func (s *AnalyticsService) GetDashboardMetrics(companies []models.PortfolioCompany) DashboardMetrics {
+func (s *AnalyticsService) GetDashboardMetrics(companies []models.PortfolioCompany, settings models.OrganizationSettings) DashboardMetrics {
 	totalDeployed := decimal.Zero
 	currentValue := decimal.Zero
 	distributions := decimal.Zero // Assuming no distributions for now
@@ -189,7 +190,7 @@ func (s *AnalyticsService) GetDashboardMetrics(companies []models.PortfolioCompa
 		IRR:              irr * 100, // Convert to percentage
 		MOIC:             s.CalculateMOIC(totalDeployed, currentValue, distributions),
 		SectorAllocation: s.GetSectorAllocation(companies),
-		PortfolioHealth:  s.GetPortfolioHealth(companies),
+		PortfolioHealth:  s.GetPortfolioHealth(companies, settings),
 	}
 }
 
@@ -201,8 +202,8 @@ type PortfolioHistoryPoint struct {
 	CompanyCount  int             `json:"companyCount"`
 }
 
-// GetPortfolioHistory generates quarterly portfolio value history
-func (s *AnalyticsService) GetPortfolioHistory(companies []models.PortfolioCompany) []PortfolioHistoryPoint {
+// GetPortfolioHistory generates portfolio value history by the organization's fiscal quarters
+func (s *AnalyticsService) GetPortfolioHistory(companies []models.PortfolioCompany, settings models.OrganizationSettings) []PortfolioHistoryPoint {
 	if len(companies) == 0 {
 		return []PortfolioHistoryPoint{}
 	}
@@ -217,7 +218,7 @@ func (s *AnalyticsService) GetPortfolioHistory(companies []models.PortfolioCompa
 
 	// Generate quarterly points from earliest investment to now
 	var history []PortfolioHistoryPoint
-	current := time.Date(earliest.Year(), ((earliest.Month()-1)/3)*3+1, 1, 0, 0, 0, 0, time.UTC)
+	current := settings.FiscalQuarterStart(settings.CurrentMonth(earliest))
 	now := time.Now()
 
 	for current.Before(now) || current.Equal(now) {
@@ -242,8 +243,9 @@ func (s *AnalyticsService) GetPortfolioHistory(companies []models.PortfolioCompa
 			}
 		}
 
+		year, quarter := settings.FiscalQuarter(current)
 		history = append(history, PortfolioHistoryPoint{
-			Date:          current.Format("2006-Q") + string('1'+byte((current.Month()-1)/3)),
+			Date:          fmt.Sprintf("%d-Q%d", year, quarter),
 			TotalInvested: invested,
 			CurrentValue:  value,
 			CompanyCount:  count,
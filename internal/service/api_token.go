@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+// apiTokenPrefix marks Ventura API tokens so they can be told apart from JWTs
+// in the Authorization header and picked up by secret scanners
+const apiTokenPrefix = "vnt_"
+
+var (
+	ErrInvalidAPIToken    = errors.New("invalid, expired or revoked API token")
+	ErrAPITokenNotFound   = errors.New("API token not found")
+	ErrTokenOwnerNotFound = errors.New("token owner not found in this organization")
+)
+
+// IsAPIToken reports whether a bearer credential is an API token rather than a JWT
+func IsAPIToken(credential string) bool {
+	return strings.HasPrefix(credential, apiTokenPrefix)
+}
+
+// CreateAPITokenInput describes a new API token
+type CreateAPITokenInput struct {
+	OrganizationID uint
+	UserID         uint // Owner: the creator for personal tokens, or a service account
+	CreatedByID    uint
+	Name           string
+	Scopes         []string
+	ExpiresAt      time.Time
+}
+
+type APITokenService struct {
+	tokenRepo    *repository.APITokenRepository
+	userRepo     *repository.UserRepository
+	auditLogRepo *repository.AuditLogRepository
+}
+
+func NewAPITokenService(tokenRepo *repository.APITokenRepository, userRepo *repository.UserRepository, auditLogRepo *repository.AuditLogRepository) *APITokenService {
+	return &APITokenService{tokenRepo: tokenRepo, userRepo: userRepo, auditLogRepo: auditLogRepo}
+}
+
+// Create issues a new token and returns it with its plaintext, which is never stored
+func (s *APITokenService) Create(ctx context.Context, input CreateAPITokenInput) (*models.APIToken, string, error) {
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	owner, err := s.userRepo.FindMember(input.UserID, input.OrganizationID)
+	if err != nil {
+		return nil, "", ErrTokenOwnerNotFound
+	}
+
+	kind := models.APITokenPersonal
+	if owner.IsServiceAccount {
+		kind = models.APITokenServiceAccount
+	}
+
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, "", err
+	}
+	plaintext := apiTokenPrefix + hex.EncodeToString(randomBytes)
+
+	expiresAt := input.ExpiresAt
+	token := &models.APIToken{
+		OrganizationID: input.OrganizationID,
+		UserID:         owner.ID,
+		Kind:           kind,
+		Name:           input.Name,
+		Prefix:         plaintext[:len(apiTokenPrefix)+8],
+		TokenHash:      hashAPIToken(plaintext),
+		Scopes:         strings.Join(scopes, " "),
+		ExpiresAt:      &expiresAt,
+		CreatedByID:    input.CreatedByID,
+	}
+
+	if err := s.tokenRepo.WithContext(ctx).Create(token); err != nil {
+		return nil, "", err
+	}
+	token.User = owner
+
+	return token, plaintext, nil
+}
+
+// Authenticate resolves a plaintext token to an active token and records last use
+func (s *APITokenService) Authenticate(plaintext, ipAddress string) (*models.APIToken, error) {
+	token, err := s.tokenRepo.FindByHash(hashAPIToken(plaintext))
+	if err != nil || !token.IsActive() || token.User == nil {
+		return nil, ErrInvalidAPIToken
+	}
+
+	// A personal token stops working if its owner leaves the organization, and acts
+	// with the owner's role there
+	owner, err := s.userRepo.FindMember(token.UserID, token.OrganizationID)
+	if err != nil {
+		return nil, ErrInvalidAPIToken
+	}
+	token.User = owner
+
+	s.tokenRepo.TouchLastUsed(token.ID, ipAddress)
+	return token, nil
+}
+
+// RecordUse writes an audit entry for a request made with a token
+func (s *APITokenService) RecordUse(token *models.APIToken, method, path string, status int, ipAddress string) {
+	s.auditLogRepo.Create(&models.AuditLog{
+		OrganizationID: token.OrganizationID,
+		UserID:         token.UserID,
+		UserEmail:      token.User.Email,
+		UserName:       token.User.Name,
+		Action:         models.ActionAPITokenUse,
+		Entity:         models.EntityAPIToken,
+		EntityID:       token.ID,
+		Details:        fmt.Sprintf("%s %s -> %d (token %s)", method, path, status, token.Prefix),
+		IPAddress:      ipAddress,
+	})
+}
+
+// List returns all tokens of an organization
+func (s *APITokenService) List(orgID uint) ([]models.APIToken, error) {
+	return s.tokenRepo.GetAllByOrganization(orgID)
+}
+
+// Revoke revokes a token of the organization
+func (s *APITokenService) Revoke(ctx context.Context, id, orgID uint) (*models.APIToken, error) {
+	token, err := s.tokenRepo.GetByIDAndOrganization(id, orgID)
+	if err != nil {
+		return nil, ErrAPITokenNotFound
+	}
+	if err := s.tokenRepo.WithContext(ctx).Revoke(token.ID); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// normalizeScopes validates and de-duplicates requested scopes
+func normalizeScopes(requested []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, scope := range requested {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !models.IsValidScope(scope) {
+			return nil, fmt.Errorf("invalid scope %q", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+func hashAPIToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	"ventura/internal/audit"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+// Audit retention bounds in days; 0 keeps entries forever
+const (
+	MinAuditRetentionDays = 90
+	MaxAuditRetentionDays = 3650
+)
+
+var (
+	ErrInvalidAuditRetention = fmt.Errorf("retention must be 0 (keep forever) or between %d and %d days", MinAuditRetentionDays, MaxAuditRetentionDays)
+	ErrInvalidExportFormat   = errors.New("format must be csv or jsonl")
+)
+
+// Audit export formats
+const (
+	AuditExportCSV   = "csv"
+	AuditExportJSONL = "jsonl"
+)
+
+// errChainBroken stops verification at the first broken link
+var errChainBroken = errors.New("audit chain broken")
+
+// AuditVerification is the result of checking an organization's audit chain
+type AuditVerification struct {
+	Valid         bool      `json:"valid"`
+	Entries       int       `json:"entries"`       // Entries checked
+	FirstSequence uint64    `json:"firstSequence"` // Entries before it have been archived
+	LastSequence  uint64    `json:"lastSequence"`
+	HeadHash      string    `json:"headHash"`
+	BrokenAt      uint64    `json:"brokenAtSequence,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	CheckedAt     time.Time `json:"checkedAt"`
+}
+
+// AuditService verifies, exports and archives organizations' audit logs
+type AuditService struct {
+	auditLogRepo *repository.AuditLogRepository
+	orgRepo      *repository.OrganizationRepository
+	archiveDir   string
+}
+
+func NewAuditService(auditLogRepo *repository.AuditLogRepository, orgRepo *repository.OrganizationRepository) *AuditService {
+	archiveDir := os.Getenv("AUDIT_ARCHIVE_DIR")
+	if archiveDir == "" {
+		archiveDir = filepath.Join("data", "audit-archives")
+	}
+	return &AuditService{auditLogRepo: auditLogRepo, orgRepo: orgRepo, archiveDir: archiveDir}
+}
+
+// Verify walks an organization's audit chain from the last archive and reports the first
+// entry that was modified, removed or reordered
+func (s *AuditService) Verify(orgID uint) (*AuditVerification, error) {
+	head, err := s.auditLogRepo.GetChainHead(orgID)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := s.auditLogRepo.GetLastArchive(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var sequence uint64
+	var hash string
+	if archive != nil {
+		sequence, hash = archive.LastSequence, archive.LastHash
+	}
+	result := &AuditVerification{
+		Valid:         true,
+		FirstSequence: sequence + 1,
+		LastSequence:  head.Sequence,
+		HeadHash:      head.Hash,
+		CheckedAt:     time.Now(),
+	}
+
+	err = s.auditLogRepo.Stream(repository.AuditLogFilter{OrganizationID: orgID}, func(entry *models.AuditLog) error {
+		var reason string
+		switch {
+		case entry.Sequence > sequence+1:
+			reason = missingEntries(sequence+1, entry.Sequence-1)
+		case entry.Sequence <= sequence:
+			reason = fmt.Sprintf("entry %d is out of sequence", entry.Sequence)
+		case entry.PrevHash != hash:
+			reason = fmt.Sprintf("entry %d does not follow entry %d", entry.Sequence, sequence)
+		default:
+			computed, err := audit.Hash(entry)
+			if err != nil {
+				return err
+			}
+			if computed != entry.Hash {
+				reason = fmt.Sprintf("entry %d has been modified", entry.Sequence)
+			}
+		}
+		if reason != "" {
+			result.Valid, result.BrokenAt, result.Reason = false, entry.Sequence, reason
+			return errChainBroken
+		}
+
+		result.Entries++
+		sequence, hash = entry.Sequence, entry.Hash
+		return nil
+	})
+	if err != nil && !errors.Is(err, errChainBroken) {
+		return nil, err
+	}
+
+	if result.Valid && (sequence != head.Sequence || hash != head.Hash) {
+		result.Valid, result.BrokenAt = false, sequence+1
+		if sequence < head.Sequence {
+			result.Reason = missingEntries(sequence+1, head.Sequence)
+		} else {
+			result.Reason = fmt.Sprintf("entry %d is not the end of the chain", sequence)
+		}
+	}
+	return result, nil
+}
+
+func missingEntries(first, last uint64) string {
+	if first == last {
+		return fmt.Sprintf("entry %d is missing", first)
+	}
+	return fmt.Sprintf("entries %d to %d are missing", first, last)
+}
+
+// Export writes an organization's audit logs matching filter to w as CSV or JSON Lines,
+// oldest first, one entry at a time
+func (s *AuditService) Export(filter repository.AuditLogFilter, format string, w io.Writer) error {
+	switch format {
+	case AuditExportJSONL:
+		encoder := json.NewEncoder(w)
+		return s.auditLogRepo.Stream(filter, func(entry *models.AuditLog) error {
+			return encoder.Encode(entry)
+		})
+
+	case AuditExportCSV:
+		writer := csv.NewWriter(w)
+		header := []string{"id", "sequence", "createdAt", "organizationId", "userId", "userEmail", "userName",
+			"ipAddress", "userAgent", "action", "entity", "entityId", "details", "changes", "prevHash", "hash"}
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+		err := s.auditLogRepo.Stream(filter, func(entry *models.AuditLog) error {
+			return writer.Write([]string{
+				strconv.FormatUint(uint64(entry.ID), 10),
+				strconv.FormatUint(entry.Sequence, 10),
+				entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+				strconv.FormatUint(uint64(entry.OrganizationID), 10),
+				strconv.FormatUint(uint64(entry.UserID), 10),
+				entry.UserEmail,
+				entry.UserName,
+				entry.IPAddress,
+				entry.UserAgent,
+				entry.Action,
+				entry.Entity,
+				strconv.FormatUint(uint64(entry.EntityID), 10),
+				entry.Details,
+				string(entry.Changes),
+				entry.PrevHash,
+				entry.Hash,
+			})
+		})
+		if err != nil {
+			return err
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return ErrInvalidExportFormat
+}
+
+// RetentionDays returns how many days an organization keeps audit entries; 0 is forever
+func (s *AuditService) RetentionDays(orgID uint) (int, error) {
+	org, err := s.orgRepo.FindByID(orgID)
+	if err != nil {
+		return 0, err
+	}
+	return org.AuditRetentionDays, nil
+}
+
+// SetRetention sets how many days an organization keeps audit entries before archiving them
+func (s *AuditService) SetRetention(ctx context.Context, orgID uint, days int) (*models.Organization, error) {
+	if days != 0 && (days < MinAuditRetentionDays || days > MaxAuditRetentionDays) {
+		return nil, ErrInvalidAuditRetention
+	}
+
+	org, err := s.orgRepo.FindByID(orgID)
+	if err != nil {
+		return nil, err
+	}
+	org.AuditRetentionDays = days
+	if err := s.orgRepo.WithContext(ctx).Update(org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// Archives returns an organization's audit archives, oldest first
+func (s *AuditService) Archives(orgID uint) ([]models.AuditArchive, error) {
+	return s.auditLogRepo.GetArchives(orgID)
+}
+
+// Archive returns one of an organization's audit archives
+func (s *AuditService) Archive(id, orgID uint) (*models.AuditArchive, error) {
+	return s.auditLogRepo.FindArchive(id, orgID)
+}
+
+// ApplyRetention archives the entries that are older than their organization's retention
+// period and returns how many were archived
+func (s *AuditService) ApplyRetention() (int, error) {
+	orgs, err := s.orgRepo.GetWithAuditRetention()
+	if err != nil {
+		return 0, err
+	}
+
+	archived := 0
+	var errs []error
+	for _, org := range orgs {
+		archive, err := s.archiveBefore(org.ID, time.Now().AddDate(0, 0, -org.AuditRetentionDays))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("organization %d: %w", org.ID, err))
+			continue
+		}
+		if archive != nil {
+			archived += archive.Entries
+		}
+	}
+	return archived, errors.Join(errs...)
+}
+
+// archiveBefore moves an organization's entries written before cutoff into a gzipped JSON
+// Lines file, deletes them from the log and records the archive in the log
+func (s *AuditService) archiveBefore(orgID uint, cutoff time.Time) (*models.AuditArchive, error) {
+	last, err := s.auditLogRepo.LastSequenceBefore(orgID, cutoff)
+	if err != nil || last == 0 {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(s.archiveDir, 0o750); err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(s.archiveDir, "archive-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	archive := &models.AuditArchive{OrganizationID: orgID}
+	checksum := sha256.New()
+	compressed := gzip.NewWriter(io.MultiWriter(file, checksum))
+	encoder := json.NewEncoder(compressed)
+	err = s.auditLogRepo.StreamThrough(orgID, last, func(entry *models.AuditLog) error {
+		if archive.Entries == 0 {
+			archive.FirstSequence, archive.From = entry.Sequence, entry.CreatedAt
+		}
+		archive.Entries++
+		archive.LastSequence, archive.LastHash, archive.To = entry.Sequence, entry.Hash, entry.CreatedAt
+		return encoder.Encode(entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := compressed.Close(); err != nil {
+		return nil, err
+	}
+	if err := file.Sync(); err != nil {
+		return nil, err
+	}
+	if archive.Entries == 0 {
+		return nil, nil
+	}
+
+	archive.SHA256 = hex.EncodeToString(checksum.Sum(nil))
+	archive.Path = filepath.Join(s.archiveDir, fmt.Sprintf("org-%d-%d-%d.jsonl.gz", orgID, archive.FirstSequence, archive.LastSequence))
+	if err := os.Rename(file.Name(), archive.Path); err != nil {
+		return nil, err
+	}
+	if err := s.auditLogRepo.Archive(archive); err != nil {
+		os.Remove(archive.Path)
+		return nil, err
+	}
+
+	s.auditLogRepo.Create(&models.AuditLog{
+		OrganizationID: orgID,
+		UserEmail:      "system",
+		UserName:       "System",
+		Action:         models.ActionArchive,
+		Entity:         models.EntityAuditLog,
+		EntityID:       archive.ID,
+		Details: fmt.Sprintf("Archived %d audit entries (%d to %d) written before %s",
+			archive.Entries, archive.FirstSequence, archive.LastSequence, cutoff.UTC().Format("2006-01-02")),
+	})
+	return archive, nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+var (
+	ErrCompanyNotFound = errors.New("company not found")
+	ErrCompanyReadOnly = errors.New("only the company's lead and analysts can edit a restricted company")
+	ErrCompanyNotLead  = errors.New("only the company's lead can change its restriction")
+)
+
+// CompanyAccessService applies team-based access control to portfolio companies.
+// Restricted companies are visible to their team and to members with company.all;
+// only lead and analyst assignees (or company.all) may edit them.
+type CompanyAccessService struct {
+	portfolioRepo *repository.PortfolioRepository
+	teamRepo      *repository.TeamAssignmentRepository
+}
+
+func NewCompanyAccessService(portfolioRepo *repository.PortfolioRepository, teamRepo *repository.TeamAssignmentRepository) *CompanyAccessService {
+	return &CompanyAccessService{portfolioRepo: portfolioRepo, teamRepo: teamRepo}
+}
+
+// View returns the company if it is visible in scope
+func (s *CompanyAccessService) View(scope repository.CompanyScope, companyID uint) (*models.PortfolioCompany, error) {
+	company, err := s.portfolioRepo.Within(scope).GetByID(companyID)
+	if err != nil {
+		return nil, ErrCompanyNotFound
+	}
+	return company, nil
+}
+
+// Edit returns the company if the scope's user may change it or its founders, updates and team
+func (s *CompanyAccessService) Edit(scope repository.CompanyScope, companyID uint) (*models.PortfolioCompany, error) {
+	company, err := s.View(scope, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if !company.Restricted || scope.AllCompanies {
+		return company, nil
+	}
+
+	role := s.teamRole(scope, company.ID)
+	if role != models.TeamRoleLead && role != models.TeamRoleAnalyst {
+		return nil, ErrCompanyReadOnly
+	}
+	return company, nil
+}
+
+// SetRestricted restricts or opens up a company. Only its lead or a member with company.all may do so.
+func (s *CompanyAccessService) SetRestricted(ctx context.Context, scope repository.CompanyScope, companyID uint, restricted bool) (*models.PortfolioCompany, error) {
+	company, err := s.View(scope, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.AllCompanies && s.teamRole(scope, company.ID) != models.TeamRoleLead {
+		return nil, ErrCompanyNotLead
+	}
+
+	company.Restricted = restricted
+	if err := s.portfolioRepo.Within(scope).WithContext(ctx).Update(company); err != nil {
+		return nil, err
+	}
+	return company, nil
+}
+
+// AssignCreator makes the creator of a restricted company its lead so they can still see it
+func (s *CompanyAccessService) AssignCreator(ctx context.Context, scope repository.CompanyScope, company *models.PortfolioCompany) error {
+	if !company.Restricted || scope.AllCompanies {
+		return nil
+	}
+	// The company is not visible to its creator until the assignment exists
+	teams := s.teamRepo.Within(repository.OrganizationScope(scope.OrganizationID))
+	return teams.WithContext(ctx).Create(&models.TeamAssignment{
+		UserID:    scope.UserID,
+		CompanyID: company.ID,
+		Role:      models.TeamRoleLead,
+	})
+}
+
+func (s *CompanyAccessService) teamRole(scope repository.CompanyScope, companyID uint) models.TeamRole {
+	assignment, err := s.teamRepo.Within(scope).GetByUserAndCompany(scope.UserID, companyID)
+	if err != nil {
+		return ""
+	}
+	return assignment.Role
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidActivityType = errors.New("activity type must be comment, call, meeting or email")
+	ErrEmptyActivity       = errors.New("activity body is required")
+	ErrNotActivityAuthor   = errors.New("only its author can change an activity")
+)
+
+// mentionPattern matches the @mentions clients write into activity bodies: @[Name](userId)
+var mentionPattern = regexp.MustCompile(`@\[([^\]\n]+)\]\((\d+)\)`)
+
+// Timeline entry kinds besides the activity types
+const (
+	TimelineStageChange = "stage_change"
+	TimelineAIAnalysis  = "ai_analysis"
+)
+
+// ActivityInput is what a member writes when logging or editing an activity
+type ActivityInput struct {
+	Type       models.ActivityType
+	Subject    string
+	Body       string
+	OccurredAt *time.Time // Defaults to now
+}
+
+// TimelineEntry is one item of a deal's timeline: an activity, a stage change or an AI analysis
+type TimelineEntry struct {
+	Kind       string                 `json:"kind"` // An activity type, stage_change or ai_analysis
+	Timestamp  time.Time              `json:"timestamp"`
+	Activity   *models.DealActivity   `json:"activity,omitempty"`
+	StageEvent *models.DealStageEvent `json:"stageEvent,omitempty"`
+	Analysis   *models.DealAnalysis   `json:"analysis,omitempty"`
+}
+
+// DealActivityService logs comments, calls, meetings and emails on deals, notifies the
+// members they @mention, and assembles each deal's timeline
+type DealActivityService struct {
+	activityRepo  *repository.DealActivityRepository
+	dealRepo      *repository.DealRepository
+	analysisRepo  *repository.DealAnalysisRepository
+	userRepo      *repository.UserRepository
+	notifications *NotificationService
+}
+
+func NewDealActivityService(activityRepo *repository.DealActivityRepository, dealRepo *repository.DealRepository, analysisRepo *repository.DealAnalysisRepository, userRepo *repository.UserRepository, notifications *NotificationService) *DealActivityService {
+	return &DealActivityService{activityRepo: activityRepo, dealRepo: dealRepo, analysisRepo: analysisRepo, userRepo: userRepo, notifications: notifications}
+}
+
+// Log records an activity a member wrote on a deal and notifies the members it mentions
+func (s *DealActivityService) Log(ctx context.Context, scope repository.CompanyScope, deal *models.Deal, authorID uint, input ActivityInput) (*models.DealActivity, error) {
+	if !input.Type.IsValid() {
+		return nil, ErrInvalidActivityType
+	}
+	if strings.TrimSpace(input.Body) == "" {
+		return nil, ErrEmptyActivity
+	}
+	author, err := s.userRepo.FindMember(authorID, deal.OrganizationID)
+	if err != nil {
+		return nil, ErrNotMember
+	}
+
+	occurredAt := time.Now()
+	if input.OccurredAt != nil {
+		occurredAt = *input.OccurredAt
+	}
+	activity := &models.DealActivity{
+		OrganizationID: deal.OrganizationID,
+		DealID:         deal.ID,
+		Type:           input.Type,
+		AuthorID:       author.ID,
+		AuthorName:     author.Name,
+		Subject:        input.Subject,
+		Body:           input.Body,
+		OccurredAt:     occurredAt,
+		Mentions:       s.mentions(input.Body, deal.OrganizationID),
+	}
+	if err := s.activityRepo.Within(scope).WithContext(ctx).Create(activity); err != nil {
+		return nil, err
+	}
+
+	s.notifyMentions(ctx, deal, activity, author, activity.Mentions)
+	return activity, nil
+}
+
+// Edit changes the subject, body or date of an activity its author wrote, keeping the
+// previous version, and notifies the members newly mentioned in it
+func (s *DealActivityService) Edit(ctx context.Context, scope repository.CompanyScope, deal *models.Deal, activityID, editorID uint, input ActivityInput) (*models.DealActivity, error) {
+	activity, err := s.authored(scope, deal, activityID, editorID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(input.Body) == "" {
+		return nil, ErrEmptyActivity
+	}
+	editor, err := s.userRepo.FindMember(editorID, deal.OrganizationID)
+	if err != nil {
+		return nil, ErrNotMember
+	}
+
+	revision := &models.DealActivityRevision{
+		ActivityID: activity.ID,
+		Subject:    activity.Subject,
+		Body:       activity.Body,
+		EditedByID: editorID,
+	}
+	mentioned := make(map[uint]bool, len(activity.Mentions))
+	for _, mention := range activity.Mentions {
+		mentioned[mention.UserID] = true
+	}
+
+	now := time.Now()
+	activity.Subject = input.Subject
+	activity.Body = input.Body
+	if input.OccurredAt != nil {
+		activity.OccurredAt = *input.OccurredAt
+	}
+	activity.EditedAt = &now
+	activity.Mentions = s.mentions(input.Body, deal.OrganizationID)
+	if err := s.activityRepo.Within(scope).WithContext(ctx).Update(activity, revision); err != nil {
+		return nil, err
+	}
+
+	var added []models.DealActivityMention
+	for _, mention := range activity.Mentions {
+		if !mentioned[mention.UserID] {
+			added = append(added, mention)
+		}
+	}
+	s.notifyMentions(ctx, deal, activity, editor, added)
+	return activity, nil
+}
+
+// Delete deletes an activity its author wrote
+func (s *DealActivityService) Delete(ctx context.Context, scope repository.CompanyScope, deal *models.Deal, activityID, userID uint) error {
+	if _, err := s.authored(scope, deal, activityID, userID); err != nil {
+		return err
+	}
+	return s.activityRepo.Within(scope).WithContext(ctx).Delete(activityID)
+}
+
+// Revisions returns the earlier versions of an activity on a deal, oldest first
+func (s *DealActivityService) Revisions(scope repository.CompanyScope, deal *models.Deal, activityID uint) ([]models.DealActivityRevision, error) {
+	if _, err := s.onDeal(scope, deal, activityID); err != nil {
+		return nil, err
+	}
+	return s.activityRepo.Within(scope).GetRevisions(activityID)
+}
+
+// Timeline returns a deal's activities, stage changes and AI analyses in chronological order
+func (s *DealActivityService) Timeline(scope repository.CompanyScope, dealID uint) ([]TimelineEntry, error) {
+	activities, err := s.activityRepo.Within(scope).GetByDeal(dealID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.dealRepo.Within(scope).GetStageEvents(dealID)
+	if err != nil {
+		return nil, err
+	}
+	analyses, err := s.analysisRepo.Within(scope).GetByDeal(dealID)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := make([]TimelineEntry, 0, len(activities)+len(events)+len(analyses))
+	for i := range activities {
+		timeline = append(timeline, TimelineEntry{Kind: string(activities[i].Type), Timestamp: activities[i].OccurredAt, Activity: &activities[i]})
+	}
+	for i := range events {
+		timeline = append(timeline, TimelineEntry{Kind: TimelineStageChange, Timestamp: events[i].CreatedAt, StageEvent: &events[i]})
+	}
+	for i := range analyses {
+		timeline = append(timeline, TimelineEntry{Kind: TimelineAIAnalysis, Timestamp: analyses[i].CreatedAt, Analysis: &analyses[i]})
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Timestamp.Before(timeline[j].Timestamp)
+	})
+	return timeline, nil
+}
+
+// onDeal returns an activity of a deal, or gorm.ErrRecordNotFound if it has no such activity
+func (s *DealActivityService) onDeal(scope repository.CompanyScope, deal *models.Deal, activityID uint) (*models.DealActivity, error) {
+	activity, err := s.activityRepo.Within(scope).GetByID(activityID)
+	if err != nil {
+		return nil, err
+	}
+	if activity.DealID != deal.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return activity, nil
+}
+
+// authored returns an activity of a deal that userID wrote
+func (s *DealActivityService) authored(scope repository.CompanyScope, deal *models.Deal, activityID, userID uint) (*models.DealActivity, error) {
+	activity, err := s.onDeal(scope, deal, activityID)
+	if err != nil {
+		return nil, err
+	}
+	if activity.AuthorID != userID {
+		return nil, ErrNotActivityAuthor
+	}
+	return activity, nil
+}
+
+// mentions returns the active members of an organization @mentioned in body, once each
+func (s *DealActivityService) mentions(body string, orgID uint) []models.DealActivityMention {
+	var mentions []models.DealActivityMention
+	seen := make(map[uint]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		id, err := strconv.ParseUint(match[2], 10, 32)
+		if err != nil || seen[uint(id)] {
+			continue
+		}
+		seen[uint(id)] = true
+		member, err := s.userRepo.FindMember(uint(id), orgID)
+		if err != nil || member.IsServiceAccount {
+			continue
+		}
+		mentions = append(mentions, models.DealActivityMention{UserID: member.ID, UserName: member.Name})
+	}
+	return mentions
+}
+
+// notifyMentions notifies mentioned members other than the author
+func (s *DealActivityService) notifyMentions(ctx context.Context, deal *models.Deal, activity *models.DealActivity, author *models.User, mentions []models.DealActivityMention) {
+	var notifications []models.Notification
+	for _, mention := range mentions {
+		if mention.UserID == author.ID {
+			continue
+		}
+		notifications = append(notifications, models.Notification{
+			OrganizationID: deal.OrganizationID,
+			UserID:         mention.UserID,
+			Type:           models.NotificationMention,
+			ActorName:      author.Name,
+			Message:        fmt.Sprintf("%s mentioned you in a %s on %s:\n\n%s", author.Name, activity.Type, deal.CompanyName, activity.Body),
+			DealID:         &deal.ID,
+			ActivityID:     &activity.ID,
+		})
+	}
+	if len(notifications) == 0 {
+		return
+	}
+
+	subject := fmt.Sprintf("%s mentioned you on %s", author.Name, deal.CompanyName)
+	if err := s.notifications.Notify(ctx, notifications, subject, fmt.Sprintf("/deals?deal=%d", deal.ID)); err != nil {
+		log.Printf("Failed to notify mentions in activity %d: %v", activity.ID, err)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// DealAnalysisService runs AI analyses of deals on their organization's scorecard, keeps
+// every one of them, and lets analysts accept or reject the scores they suggest
+type DealAnalysisService struct {
+	analysisRepo *repository.DealAnalysisRepository
+	scorer       *AIDealScorerService
+	scorecards   *ScorecardService
+	userRepo     *repository.UserRepository
+}
+
+func NewDealAnalysisService(analysisRepo *repository.DealAnalysisRepository, scorer *AIDealScorerService, scorecards *ScorecardService, userRepo *repository.UserRepository) *DealAnalysisService {
+	return &DealAnalysisService{analysisRepo: analysisRepo, scorer: scorer, scorecards: scorecards, userRepo: userRepo}
+}
+
+// Analyze has the AI score a deal on its organization's scorecard and saves the analysis as
+// pending; the deal's scores are left as they are
+func (s *DealAnalysisService) Analyze(ctx context.Context, scope repository.CompanyScope, deal *models.Deal, userID uint) (*models.DealAnalysis, error) {
+	requester, err := s.userRepo.FindMember(userID, deal.OrganizationID)
+	if err != nil {
+		return nil, ErrNotMember
+	}
+
+	criteria, err := s.scorecards.Criteria(deal.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	analysis, err := s.scorer.ScoreDeal(ctx, deal, criteria)
+	if err != nil {
+		return nil, err
+	}
+	analysis.RequestedByID = requester.ID
+	analysis.RequestedByName = requester.Name
+	if err := s.analysisRepo.Within(scope).WithContext(ctx).Create(analysis); err != nil {
+		return nil, err
+	}
+	return analysis, nil
+}
+
+// History returns the analyses of a deal, newest first
+func (s *DealAnalysisService) History(scope repository.CompanyScope, dealID uint) ([]models.DealAnalysis, error) {
+	return s.analysisRepo.Within(scope).GetByDeal(dealID)
+}
+
+// Get returns an analysis of a deal, or gorm.ErrRecordNotFound if it has no such analysis
+func (s *DealAnalysisService) Get(scope repository.CompanyScope, deal *models.Deal, analysisID uint) (*models.DealAnalysis, error) {
+	analysis, err := s.analysisRepo.Within(scope).GetByID(analysisID)
+	if err != nil {
+		return nil, err
+	}
+	if analysis.DealID != deal.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return analysis, nil
+}
+
+// Accept makes a pending analysis's scores the deal's AI scores, which count as one more
+// reviewer's. They replace the scores of the analysis accepted before it.
+func (s *DealAnalysisService) Accept(ctx context.Context, scope repository.CompanyScope, deal *models.Deal, analysisID, reviewerID uint) (*models.DealAnalysis, error) {
+	return s.review(ctx, scope, deal, analysisID, reviewerID, models.AnalysisAccepted)
+}
+
+// Reject dismisses a pending analysis's scores, leaving the deal's as they are
+func (s *DealAnalysisService) Reject(ctx context.Context, scope repository.CompanyScope, deal *models.Deal, analysisID, reviewerID uint) (*models.DealAnalysis, error) {
+	return s.review(ctx, scope, deal, analysisID, reviewerID, models.AnalysisRejected)
+}
+
+func (s *DealAnalysisService) review(ctx context.Context, scope repository.CompanyScope, deal *models.Deal, analysisID, reviewerID uint, status models.AnalysisStatus) (*models.DealAnalysis, error) {
+	analysis, err := s.Get(scope, deal, analysisID)
+	if err != nil {
+		return nil, err
+	}
+	if analysis.Status != models.AnalysisPending {
+		return nil, repository.ErrAnalysisReviewed
+	}
+	reviewer, err := s.userRepo.FindMember(reviewerID, deal.OrganizationID)
+	if err != nil {
+		return nil, ErrNotMember
+	}
+
+	var scores []models.DealScore
+	if status == models.AnalysisAccepted {
+		criteria, err := s.scorecards.Criteria(deal.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		for _, criterion := range criteria {
+			if score, ok := analysis.Scores[criterion.Key]; ok {
+				scores = append(scores, models.DealScore{
+					OrganizationID: deal.OrganizationID,
+					Criterion:      criterion.Key,
+					ReviewerName:   "AI analysis",
+					AnalysisID:     &analysis.ID,
+					Score:          score,
+				})
+			}
+		}
+	}
+
+	now := time.Now()
+	analysis.Status = status
+	analysis.ReviewedByID = &reviewer.ID
+	analysis.ReviewedByName = reviewer.Name
+	analysis.ReviewedAt = &now
+	if err := s.analysisRepo.Within(scope).WithContext(ctx).Review(analysis, scores); err != nil {
+		return nil, err
+	}
+	return analysis, nil
+}
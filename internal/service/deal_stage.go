@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+var (
+	ErrInvalidStage         = errors.New("stage must be one of incoming, screening, due_diligence, term_sheet, closed, lost")
+	ErrTransitionNotAllowed = errors.New("stage transition not allowed")
+	ErrInvalidTransitions   = errors.New("transitions must join two different stages and can't leave closed or lost")
+)
+
+// StageChange moves a deal to another stage
+type StageChange struct {
+	To      models.DealStage
+	ActorID uint
+	Comment string
+	Fields  map[string]interface{} // Columns set along with the stage, such as archived_at
+}
+
+// DealStageService moves deals through the pipeline along the transitions their
+// organization allows, recording each move in the deal's stage history
+type DealStageService struct {
+	dealRepo       *repository.DealRepository
+	transitionRepo *repository.DealStageTransitionRepository
+	userRepo       *repository.UserRepository
+}
+
+func NewDealStageService(dealRepo *repository.DealRepository, transitionRepo *repository.DealStageTransitionRepository, userRepo *repository.UserRepository) *DealStageService {
+	return &DealStageService{dealRepo: dealRepo, transitionRepo: transitionRepo, userRepo: userRepo}
+}
+
+// Transitions returns the transitions an organization allows: its own, or the defaults
+func (s *DealStageService) Transitions(orgID uint) ([]models.DealStageTransition, error) {
+	transitions, err := s.transitionRepo.GetByOrganization(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if len(transitions) == 0 {
+		return models.DefaultDealStageTransitions(), nil
+	}
+	return transitions, nil
+}
+
+// SetTransitions replaces the transitions an organization allows. An empty list goes
+// back to the defaults.
+func (s *DealStageService) SetTransitions(ctx context.Context, orgID uint, transitions []models.DealStageTransition) ([]models.DealStageTransition, error) {
+	seen := make(map[models.DealStageTransition]bool, len(transitions))
+	unique := make([]models.DealStageTransition, 0, len(transitions))
+	for _, transition := range transitions {
+		if !transition.FromStage.IsValid() || !transition.ToStage.IsValid() {
+			return nil, ErrInvalidStage
+		}
+		if transition.FromStage == transition.ToStage || transition.FromStage.IsTerminal() {
+			return nil, ErrInvalidTransitions
+		}
+		key := models.DealStageTransition{FromStage: transition.FromStage, ToStage: transition.ToStage}
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+
+	if err := s.transitionRepo.WithContext(ctx).Replace(orgID, unique); err != nil {
+		return nil, err
+	}
+	return s.Transitions(orgID)
+}
+
+// CanTransition returns ErrTransitionNotAllowed unless an organization lets deals move
+// from one stage to another
+func (s *DealStageService) CanTransition(orgID uint, from, to models.DealStage) error {
+	if !to.IsValid() {
+		return ErrInvalidStage
+	}
+	transitions, err := s.Transitions(orgID)
+	if err != nil {
+		return err
+	}
+	for _, transition := range transitions {
+		if transition.FromStage == from && transition.ToStage == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s to %s", ErrTransitionNotAllowed, from, to)
+}
+
+// Create adds a deal to the pipeline in its stage and records its entry, from no stage, as
+// the first event of its stage history
+func (s *DealStageService) Create(ctx context.Context, scope repository.CompanyScope, deal *models.Deal, actorID uint) (*models.DealStageEvent, error) {
+	event := &models.DealStageEvent{
+		ToStage: deal.Stage,
+		ActorID: actorID,
+	}
+	if actor, err := s.userRepo.FindByID(actorID); err == nil {
+		event.ActorName = actor.Name
+	}
+	if err := s.dealRepo.Within(scope).WithContext(ctx).Create(deal, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// Transition moves a deal to change.To if its organization allows it, and records the
+// move in the deal's stage history
+func (s *DealStageService) Transition(ctx context.Context, scope repository.CompanyScope, deal *models.Deal, change StageChange) (*models.DealStageEvent, error) {
+	if err := s.CanTransition(deal.OrganizationID, deal.Stage, change.To); err != nil {
+		return nil, err
+	}
+
+	event := &models.DealStageEvent{
+		OrganizationID: deal.OrganizationID,
+		DealID:         deal.ID,
+		FromStage:      deal.Stage,
+		ToStage:        change.To,
+		ActorID:        change.ActorID,
+		Comment:        change.Comment,
+	}
+	if actor, err := s.userRepo.FindByID(change.ActorID); err == nil {
+		event.ActorName = actor.Name
+	}
+	if err := s.dealRepo.Within(scope).WithContext(ctx).Transition(event, change.Fields); err != nil {
+		return nil, err
+	}
+	deal.Stage = change.To
+	return event, nil
+}
+
+// History returns a deal's stage history, oldest first
+func (s *DealStageService) History(scope repository.CompanyScope, dealID uint) ([]models.DealStageEvent, error) {
+	return s.dealRepo.Within(scope).GetStageEvents(dealID)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+	"ventura/internal/audit"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+var (
+	ErrNotYetCreated   = errors.New("did not exist at that time")
+	ErrHistoryArchived = errors.New("changes since that time have been archived; choose a later date")
+)
+
+// FieldChange is one field's value before and after a change
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// HistoryEntry is one logged event of a record, with the fields it changed
+type HistoryEntry struct {
+	Sequence  uint64        `json:"sequence"`
+	Action    string        `json:"action"`
+	Details   string        `json:"details"`
+	UserID    uint          `json:"userId"`
+	UserEmail string        `json:"userEmail"`
+	UserName  string        `json:"userName"`
+	ChangedAt time.Time     `json:"changedAt"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+// HistoryService reads the versions of records from the audit log
+type HistoryService struct {
+	auditLogRepo *repository.AuditLogRepository
+}
+
+func NewHistoryService(auditLogRepo *repository.AuditLogRepository) *HistoryService {
+	return &HistoryService{auditLogRepo: auditLogRepo}
+}
+
+// History returns the logged events of a record, most recent first. model is the record's
+// type and names its fields.
+func (s *HistoryService) History(orgID uint, entity string, id uint, model interface{}) ([]HistoryEntry, error) {
+	names, err := audit.FieldNames(model)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := s.auditLogRepo.GetEntityHistory(orgID, entity, id)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]HistoryEntry, 0, len(logs))
+	for _, log := range logs {
+		entry := HistoryEntry{
+			Sequence:  log.Sequence,
+			Action:    log.Action,
+			Details:   log.Details,
+			UserID:    log.UserID,
+			UserEmail: log.UserEmail,
+			UserName:  log.UserName,
+			ChangedAt: log.CreatedAt,
+			Changes:   []FieldChange{},
+		}
+		if len(log.Changes) > 0 {
+			var columns map[string]audit.Change
+			if err := json.Unmarshal(log.Changes, &columns); err != nil {
+				return nil, err
+			}
+			for column, change := range columns {
+				field, ok := names[column]
+				if !ok {
+					field = column
+				}
+				entry.Changes = append(entry.Changes, FieldChange{Field: field, Old: change.Old, New: change.New})
+			}
+			sort.Slice(entry.Changes, func(i, j int) bool { return entry.Changes[i].Field < entry.Changes[j].Field })
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
+
+// AsOf rewinds record, the current version of a record, to how it was at asOf by undoing
+// every change logged since. Its relations are cleared.
+func (s *HistoryService) AsOf(orgID uint, entity string, id uint, record interface{}, asOf time.Time) error {
+	if err := audit.Detach(record); err != nil {
+		return err
+	}
+	archive, err := s.auditLogRepo.GetLastArchive(orgID)
+	if err != nil {
+		return err
+	}
+	if archive != nil && !asOf.After(archive.To) {
+		return ErrHistoryArchived
+	}
+
+	logs, err := s.auditLogRepo.GetEntityHistory(orgID, entity, id)
+	if err != nil {
+		return err
+	}
+	for _, log := range logs {
+		if !log.CreatedAt.After(asOf) {
+			break
+		}
+		if log.Action == models.ActionCreate {
+			return ErrNotYetCreated
+		}
+		if err := audit.Revert(record, log.Changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
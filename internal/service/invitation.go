@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+const (
+	// DefaultInvitationExpiry is how long an invitation stays valid unless set otherwise
+	DefaultInvitationExpiry = 7 * 24 * time.Hour
+
+	// MaxInvitationExpiry caps how far in the future an invitation can expire
+	MaxInvitationExpiry = 30 * 24 * time.Hour
+
+	// maxInvitationUses caps shareable codes
+	maxInvitationUses = 500
+)
+
+var (
+	ErrInvitationNotFound      = errors.New("invalid invite code")
+	ErrInvitationExpired       = errors.New("invite code has expired")
+	ErrInvitationRevoked       = errors.New("invite code has been revoked")
+	ErrInvitationUsed          = errors.New("invite code has already been used")
+	ErrInvitationEmailMismatch = errors.New("this invitation was sent to a different email address")
+	ErrInvitationNoEmail       = errors.New("invitation has no email address to send to")
+	ErrInvalidInvitation       = errors.New("maxUses must be between 1 and 500, and 1 for invitations sent to an email address")
+	ErrInvalidTeamRole         = errors.New("team role must be lead, analyst or observer")
+)
+
+// InvitationInput describes a new invitation
+type InvitationInput struct {
+	OrganizationID  uint
+	CreatedByID     uint
+	Email           string // Optional: only this address may accept
+	Role            models.UserRole
+	ExpiresIn       time.Duration
+	MaxUses         int
+	TeamAssignments []models.InvitationTeamAssignment
+}
+
+// InvitationService manages invitations and turns accepted ones into memberships
+type InvitationService struct {
+	invitationRepo *repository.InvitationRepository
+	userRepo       *repository.UserRepository
+	membershipRepo *repository.MembershipRepository
+	teamRepo       *repository.TeamAssignmentRepository
+	portfolioRepo  *repository.PortfolioRepository
+	accounts       *AccountService
+}
+
+func NewInvitationService(invitationRepo *repository.InvitationRepository, userRepo *repository.UserRepository, membershipRepo *repository.MembershipRepository, teamRepo *repository.TeamAssignmentRepository, portfolioRepo *repository.PortfolioRepository, accounts *AccountService) *InvitationService {
+	return &InvitationService{
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		membershipRepo: membershipRepo,
+		teamRepo:       teamRepo,
+		portfolioRepo:  portfolioRepo,
+		accounts:       accounts,
+	}
+}
+
+// Create stores an invitation and emails it if it is addressed to someone.
+// The caller checks that the role and team assignments are within its own access.
+func (s *InvitationService) Create(ctx context.Context, input InvitationInput, inviterName string) (*models.Invitation, error) {
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	maxUses := input.MaxUses
+	if maxUses == 0 {
+		maxUses = 1
+	}
+	if maxUses < 1 || maxUses > maxInvitationUses || (email != "" && maxUses != 1) {
+		return nil, ErrInvalidInvitation
+	}
+
+	if email != "" {
+		if existing, err := s.userRepo.FindByEmail(email); err == nil {
+			if _, err := s.membershipRepo.Find(existing.ID, input.OrganizationID); err == nil {
+				return nil, ErrAlreadyMember
+			}
+		}
+	}
+
+	for i := range input.TeamAssignments {
+		if input.TeamAssignments[i].Role == "" {
+			input.TeamAssignments[i].Role = models.TeamRoleObserver
+		}
+		if !isValidTeamRole(input.TeamAssignments[i].Role) {
+			return nil, ErrInvalidTeamRole
+		}
+	}
+
+	role := input.Role
+	if role == "" {
+		role = models.RoleViewer
+	}
+
+	code, err := generateInvitationCode()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &models.Invitation{
+		OrganizationID:  input.OrganizationID,
+		Code:            code,
+		Email:           email,
+		Role:            role,
+		MaxUses:         maxUses,
+		ExpiresAt:       time.Now().Add(invitationExpiry(input.ExpiresIn)),
+		CreatedByID:     input.CreatedByID,
+		TeamAssignments: input.TeamAssignments,
+	}
+	if err := s.invitationRepo.WithContext(ctx).Create(invitation); err != nil {
+		return nil, err
+	}
+	if created, err := s.invitationRepo.FindByIDAndOrganization(invitation.ID, invitation.OrganizationID); err == nil {
+		invitation = created
+	}
+
+	if email != "" {
+		if err := s.send(ctx, invitation, inviterName); err != nil {
+			return nil, err
+		}
+	}
+	return invitation, nil
+}
+
+// List returns an organization's invitations
+func (s *InvitationService) List(orgID uint) ([]models.Invitation, error) {
+	return s.invitationRepo.GetByOrganization(orgID)
+}
+
+// Revoke stops an invitation of the organization from being accepted
+func (s *InvitationService) Revoke(ctx context.Context, orgID, id uint) (*models.Invitation, error) {
+	invitation, err := s.invitationRepo.FindByIDAndOrganization(id, orgID)
+	if err != nil {
+		return nil, ErrInvitationNotFound
+	}
+	if err := s.invitationRepo.WithContext(ctx).Revoke(invitation.ID); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// Resend emails an invitation again and restarts its validity period, which also
+// revives an expired one
+func (s *InvitationService) Resend(ctx context.Context, orgID, id uint, expiresIn time.Duration, inviterName string) (*models.Invitation, error) {
+	invitation, err := s.invitationRepo.FindByIDAndOrganization(id, orgID)
+	if err != nil {
+		return nil, ErrInvitationNotFound
+	}
+	switch invitation.Status() {
+	case models.InvitationRevoked:
+		return nil, ErrInvitationRevoked
+	case models.InvitationUsed:
+		return nil, ErrInvitationUsed
+	}
+	if invitation.Email == "" {
+		return nil, ErrInvitationNoEmail
+	}
+
+	invitation.ExpiresAt = time.Now().Add(invitationExpiry(expiresIn))
+	if err := s.send(ctx, invitation, inviterName); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// Lookup returns an invitation that can still be accepted
+func (s *InvitationService) Lookup(code string) (*models.Invitation, error) {
+	invitation, err := s.invitationRepo.FindByCode(code)
+	if err != nil || invitation.Organization == nil {
+		return nil, ErrInvitationNotFound
+	}
+	switch invitation.Status() {
+	case models.InvitationRevoked:
+		return nil, ErrInvitationRevoked
+	case models.InvitationUsed:
+		return nil, ErrInvitationUsed
+	case models.InvitationExpired:
+		return nil, ErrInvitationExpired
+	}
+	return invitation, nil
+}
+
+// Accept adds an existing user to the invitation's organization with its role and
+// team assignments
+func (s *InvitationService) Accept(ctx context.Context, code string, user *models.User) (*models.Invitation, error) {
+	invitation, err := s.Lookup(code)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkInvitee(invitation, user.Email); err != nil {
+		return nil, err
+	}
+	if user.IsServiceAccount {
+		return nil, ErrNotMember
+	}
+	if _, err := s.membershipRepo.Find(user.ID, invitation.OrganizationID); err == nil {
+		return nil, ErrAlreadyMember
+	}
+
+	if err := s.claim(ctx, invitation); err != nil {
+		return nil, err
+	}
+	if err := s.membershipRepo.WithContext(ctx).Create(&models.Membership{
+		UserID:         user.ID,
+		OrganizationID: invitation.OrganizationID,
+		Role:           invitation.Role,
+	}); err != nil {
+		s.invitationRepo.WithContext(ctx).ReleaseUse(invitation.ID)
+		return nil, err
+	}
+
+	s.applyTeamAssignments(ctx, invitation, user.ID)
+	return invitation, nil
+}
+
+// Register creates the account of someone joining through an invitation. user is
+// filled in with the invitation's organization and role.
+func (s *InvitationService) Register(ctx context.Context, code string, user *models.User) (*models.Invitation, error) {
+	invitation, err := s.Lookup(code)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkInvitee(invitation, user.Email); err != nil {
+		return nil, err
+	}
+
+	if err := s.claim(ctx, invitation); err != nil {
+		return nil, err
+	}
+	user.OrganizationID = invitation.OrganizationID
+	user.Role = invitation.Role
+	if err := s.userRepo.WithContext(ctx).Create(user); err != nil {
+		s.invitationRepo.WithContext(ctx).ReleaseUse(invitation.ID)
+		return nil, err
+	}
+
+	s.applyTeamAssignments(ctx, invitation, user.ID)
+	return invitation, nil
+}
+
+func (s *InvitationService) send(ctx context.Context, invitation *models.Invitation, inviterName string) error {
+	orgName := ""
+	if invitation.Organization != nil {
+		orgName = invitation.Organization.Name
+	}
+	s.accounts.SendInvitation(invitation, orgName, inviterName)
+	return s.invitationRepo.WithContext(ctx).MarkSent(invitation.ID, invitation.ExpiresAt)
+}
+
+// claim counts a use, reporting why it failed if the invitation changed since Lookup
+func (s *InvitationService) claim(ctx context.Context, invitation *models.Invitation) error {
+	claimed, err := s.invitationRepo.WithContext(ctx).ClaimUse(invitation.ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrInvitationUsed
+	}
+	return nil
+}
+
+// applyTeamAssignments assigns the new member to the invitation's companies. Companies
+// deleted since the invitation was created, and existing assignments, are skipped.
+func (s *InvitationService) applyTeamAssignments(ctx context.Context, invitation *models.Invitation, userID uint) {
+	scope := repository.OrganizationScope(invitation.OrganizationID)
+	companies, teams := s.portfolioRepo.Within(scope), s.teamRepo.Within(scope).WithContext(ctx)
+	for _, planned := range invitation.TeamAssignments {
+		if _, err := companies.GetByID(planned.CompanyID); err != nil {
+			continue
+		}
+		if existing, _ := teams.GetByUserAndCompany(userID, planned.CompanyID); existing != nil {
+			continue
+		}
+		if err := teams.Create(&models.TeamAssignment{
+			UserID:    userID,
+			CompanyID: planned.CompanyID,
+			Role:      planned.Role,
+		}); err != nil {
+			log.Printf("Failed to apply team assignment of invitation %d to company %d: %v", invitation.ID, planned.CompanyID, err)
+		}
+	}
+}
+
+func checkInvitee(invitation *models.Invitation, email string) error {
+	if invitation.Email != "" && !strings.EqualFold(invitation.Email, email) {
+		return ErrInvitationEmailMismatch
+	}
+	return nil
+}
+
+func invitationExpiry(requested time.Duration) time.Duration {
+	if requested <= 0 {
+		return DefaultInvitationExpiry
+	}
+	if requested > MaxInvitationExpiry {
+		return MaxInvitationExpiry
+	}
+	return requested
+}
+
+func isValidTeamRole(role models.TeamRole) bool {
+	switch role {
+	case models.TeamRoleLead, models.TeamRoleAnalyst, models.TeamRoleObserver:
+		return true
+	default:
+		return false
+	}
+}
+
+// generateInvitationCode creates a random, unguessable invite code
+func generateInvitationCode() (string, error) {
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(randomBytes), nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"log"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+const (
+	// loginFreeAttempts is how many consecutive failures are allowed before delays kick in
+	loginFreeAttempts = 3
+
+	// loginMaxLockout caps the delay; repeated failures keep the account locked this long
+	loginMaxLockout = 30 * time.Minute
+)
+
+// Login failure reasons recorded in the audit log
+const (
+	LoginFailureUnknownEmail    = "unknown email"
+	LoginFailureInvalidPassword = "invalid password"
+	LoginFailureInvalidMFACode  = "invalid authentication code"
+	LoginFailureLocked          = "account locked"
+	LoginFailureServiceAccount  = "service account"
+	LoginFailureSSORequired     = "password login disabled, SSO required"
+)
+
+// LoginAttempt identifies where a login attempt came from
+type LoginAttempt struct {
+	Email     string
+	IPAddress string
+	UserAgent string
+}
+
+// LoginGuard throttles password guessing per account and audits every login attempt
+type LoginGuard struct {
+	userRepo     *repository.UserRepository
+	auditLogRepo *repository.AuditLogRepository
+}
+
+func NewLoginGuard(userRepo *repository.UserRepository, auditLogRepo *repository.AuditLogRepository) *LoginGuard {
+	return &LoginGuard{userRepo: userRepo, auditLogRepo: auditLogRepo}
+}
+
+// RetryAfter returns how long the user must wait before trying again, or 0 if not locked
+func (g *LoginGuard) RetryAfter(user *models.User) time.Duration {
+	if !user.IsLocked() {
+		return 0
+	}
+	return time.Until(*user.LockedUntil)
+}
+
+// RecordFailure audits a failed attempt and, for a known account, extends its lockout.
+// user is nil when the email doesn't match an account.
+func (g *LoginGuard) RecordFailure(user *models.User, attempt LoginAttempt, reason string) {
+	entry := &models.AuditLog{
+		UserEmail: attempt.Email,
+		Action:    models.ActionLogin,
+		Entity:    models.EntityUser,
+		Details:   "Login failed: " + reason,
+		IPAddress: attempt.IPAddress,
+		UserAgent: attempt.UserAgent,
+	}
+
+	if user != nil {
+		entry.OrganizationID = user.OrganizationID
+		entry.UserID = user.ID
+		entry.UserEmail = user.Email
+		entry.UserName = user.Name
+		entry.EntityID = user.ID
+
+		// Only wrong credentials count; attempts against a locked account don't extend the lock
+		if reason == LoginFailureInvalidPassword || reason == LoginFailureInvalidMFACode {
+			g.registerFailure(user)
+		}
+	}
+
+	g.auditLogRepo.Create(entry)
+}
+
+// RecordSuccess audits a completed login and clears the failure counter
+func (g *LoginGuard) RecordSuccess(user *models.User, attempt LoginAttempt) {
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		g.userRepo.ResetFailedLogins(user.ID)
+	}
+
+	g.auditLogRepo.Create(&models.AuditLog{
+		OrganizationID: user.OrganizationID,
+		UserID:         user.ID,
+		UserEmail:      user.Email,
+		UserName:       user.Name,
+		Action:         models.ActionLogin,
+		Entity:         models.EntityUser,
+		EntityID:       user.ID,
+		Details:        "Login succeeded",
+		IPAddress:      attempt.IPAddress,
+		UserAgent:      attempt.UserAgent,
+	})
+}
+
+// Unlock clears a user's lockout (admin action)
+func (g *LoginGuard) Unlock(userID uint) error {
+	return g.userRepo.ResetFailedLogins(userID)
+}
+
+func (g *LoginGuard) registerFailure(user *models.User) {
+	attempts, err := g.userRepo.RecordFailedLogin(user.ID)
+	if err != nil {
+		log.Printf("Failed to record failed login for user %d: %v", user.ID, err)
+		return
+	}
+
+	if delay := lockoutFor(attempts); delay > 0 {
+		lockedUntil := time.Now().Add(delay)
+		g.userRepo.SetLockedUntil(user.ID, lockedUntil)
+		if delay == loginMaxLockout {
+			log.Printf("User %d locked out after %d failed login attempts", user.ID, attempts)
+		}
+	}
+}
+
+// lockoutFor returns the delay after the given number of consecutive failures:
+// nothing for the first few, then 2s, 4s, 8s... up to loginMaxLockout
+func lockoutFor(attempts int) time.Duration {
+	excess := attempts - loginFreeAttempts
+	if excess <= 0 {
+		return 0
+	}
+	if excess > 10 {
+		return loginMaxLockout
+	}
+	delay := time.Second << excess
+	if delay > loginMaxLockout {
+		return loginMaxLockout
+	}
+	return delay
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+func TestLockoutFor(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{3, 0}, // The last free attempt
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 8 * time.Second},
+		{10, 128 * time.Second},
+		{13, 1024 * time.Second}, // Just over 17 minutes, the last delay under the cap
+		{14, loginMaxLockout},
+		{15, loginMaxLockout},
+		{100, loginMaxLockout}, // Large counts mustn't overflow the shift
+	}
+	for _, tt := range tests {
+		if got := lockoutFor(tt.attempts); got != tt.want {
+			t.Errorf("lockoutFor(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestLoginGuardLocksAfterFreeAttempts(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.AuditLog{})
+	userRepo := repository.NewUserRepository(db)
+	guard := NewLoginGuard(userRepo, repository.NewAuditLogRepository(db))
+
+	user := &models.User{OrganizationID: 1, Email: "analyst@example.com", Password: "-", Name: "Analyst"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	reload := func() *models.User {
+		var stored models.User
+		if err := db.First(&stored, user.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return &stored
+	}
+	attempt := LoginAttempt{Email: user.Email, IPAddress: "192.0.2.1"}
+
+	for i := 1; i <= loginFreeAttempts; i++ {
+		guard.RecordFailure(reload(), attempt, LoginFailureInvalidPassword)
+		if retry := guard.RetryAfter(reload()); retry != 0 {
+			t.Fatalf("locked for %v after %d failures, want none", retry, i)
+		}
+	}
+
+	guard.RecordFailure(reload(), attempt, LoginFailureInvalidPassword)
+	if retry := guard.RetryAfter(reload()); retry <= 0 || retry > 2*time.Second {
+		t.Fatalf("locked for %v after %d failures, want up to 2s", retry, loginFreeAttempts+1)
+	}
+
+	// Attempts refused because of the lock don't extend it
+	guard.RecordFailure(reload(), attempt, LoginFailureLocked)
+	if stored := reload(); stored.FailedLoginAttempts != loginFreeAttempts+1 {
+		t.Errorf("FailedLoginAttempts = %d after a locked attempt, want %d", stored.FailedLoginAttempts, loginFreeAttempts+1)
+	}
+
+	guard.RecordSuccess(reload(), attempt)
+	if stored := reload(); stored.FailedLoginAttempts != 0 || stored.LockedUntil != nil {
+		t.Errorf("after a login, FailedLoginAttempts = %d and LockedUntil = %v, want both cleared", stored.FailedLoginAttempts, stored.LockedUntil)
+	}
+}
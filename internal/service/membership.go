@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+var (
+	ErrNotMember           = errors.New("you are not a member of this organization")
+	ErrAlreadyMember       = errors.New("already a member of this organization")
+	ErrAlreadyDeactivated  = errors.New("member is already deactivated")
+	ErrNotDeactivated      = errors.New("member is not deactivated")
+	ErrInvalidHandoverUser = errors.New("hand over to another active member of the organization")
+	ErrLastAdmin           = errors.New("the organization needs at least one other active admin")
+	ErrMemberElsewhere     = errors.New("this user also belongs to other organizations, so only they can change how they sign in")
+)
+
+// MembershipService manages which organizations a user belongs to
+type MembershipService struct {
+	membershipRepo     *repository.MembershipRepository
+	userRepo           *repository.UserRepository
+	sessionRepo        *repository.SessionRepository
+	teamAssignmentRepo *repository.TeamAssignmentRepository
+	dealRepo           *repository.DealRepository
+}
+
+func NewMembershipService(membershipRepo *repository.MembershipRepository, userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, teamAssignmentRepo *repository.TeamAssignmentRepository, dealRepo *repository.DealRepository) *MembershipService {
+	return &MembershipService{
+		membershipRepo:     membershipRepo,
+		userRepo:           userRepo,
+		sessionRepo:        sessionRepo,
+		teamAssignmentRepo: teamAssignmentRepo,
+		dealRepo:           dealRepo,
+	}
+}
+
+// HandoverResult counts what a handover moved to the new member
+type HandoverResult struct {
+	TeamAssignments int   `json:"teamAssignments"`
+	Deals           int64 `json:"deals"`
+}
+
+// List returns the organizations a user belongs to
+func (s *MembershipService) List(userID uint) ([]models.Membership, error) {
+	return s.membershipRepo.GetByUser(userID)
+}
+
+// Member loads a user as a member of an organization
+func (s *MembershipService) Member(userID, orgID uint) (*models.User, error) {
+	member, err := s.userRepo.FindMember(userID, orgID)
+	if err != nil {
+		return nil, ErrNotMember
+	}
+	return member, nil
+}
+
+// CheckSoleMembership returns ErrMemberElsewhere if the user belongs to more than one
+// organization. Their second factor and login lockout apply to all of them, so one
+// organization's admins mustn't change those for the others.
+func (s *MembershipService) CheckSoleMembership(userID uint) error {
+	count, err := s.membershipRepo.CountByUser(userID)
+	if err != nil {
+		return err
+	}
+	if count > 1 {
+		return ErrMemberElsewhere
+	}
+	return nil
+}
+
+// ChangeRole sets a member's role and signs them out of the organization so their
+// tokens pick it up. The last active admin can't be demoted.
+func (s *MembershipService) ChangeRole(ctx context.Context, userID, orgID uint, role models.UserRole) error {
+	member, err := s.userRepo.FindMemberIncludingDeactivated(userID, orgID)
+	if err != nil {
+		return ErrNotMember
+	}
+	if role != models.RoleAdmin {
+		if err := s.ensureOtherAdmin(member, orgID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.membershipRepo.WithContext(ctx).UpdateRole(userID, orgID, role); err != nil {
+		return err
+	}
+	return s.sessionRepo.RevokeAllForUserInOrganization(userID, orgID, models.SessionRevokedRoleChange)
+}
+
+// Deactivate blocks a member from an organization without deleting anything they did there:
+// they can no longer sign in to it, and their sessions and API tokens there stop working.
+// If handoverTo is set, their team assignments and deals go to that member first. The last
+// active admin can't be deactivated.
+func (s *MembershipService) Deactivate(ctx context.Context, userID, orgID, deactivatedByID uint, handoverTo *uint) (*HandoverResult, error) {
+	member, err := s.userRepo.FindMemberIncludingDeactivated(userID, orgID)
+	if err != nil {
+		return nil, ErrNotMember
+	}
+	if member.DeactivatedAt != nil {
+		return nil, ErrAlreadyDeactivated
+	}
+	if err := s.ensureOtherAdmin(member, orgID); err != nil {
+		return nil, err
+	}
+
+	var result *HandoverResult
+	if handoverTo != nil {
+		if result, err = s.Handover(ctx, userID, *handoverTo, orgID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.membershipRepo.WithContext(ctx).Deactivate(userID, orgID, deactivatedByID); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.RevokeAllForUserInOrganization(userID, orgID, models.SessionRevokedDeactivated); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Reactivate lets a deactivated member sign in to the organization again
+func (s *MembershipService) Reactivate(ctx context.Context, userID, orgID uint) error {
+	member, err := s.userRepo.FindMemberIncludingDeactivated(userID, orgID)
+	if err != nil {
+		return ErrNotMember
+	}
+	if member.DeactivatedAt == nil {
+		return ErrNotDeactivated
+	}
+	return s.membershipRepo.WithContext(ctx).Reactivate(userID, orgID)
+}
+
+// Handover moves a member's team assignments and deal ownership in an organization to
+// another of its active members, so nothing is left without someone responsible for it
+func (s *MembershipService) Handover(ctx context.Context, fromUserID, toUserID, orgID uint) (*HandoverResult, error) {
+	if _, err := s.userRepo.FindMemberIncludingDeactivated(fromUserID, orgID); err != nil {
+		return nil, ErrNotMember
+	}
+	to, err := s.userRepo.FindMember(toUserID, orgID)
+	if err != nil || to.ID == fromUserID || to.IsServiceAccount {
+		return nil, ErrInvalidHandoverUser
+	}
+
+	scope := repository.OrganizationScope(orgID)
+	assignments, err := s.teamAssignmentRepo.WithContext(ctx).Within(scope).Reassign(fromUserID, toUserID)
+	if err != nil {
+		return nil, err
+	}
+	deals, err := s.dealRepo.WithContext(ctx).Within(scope).ReassignOwner(fromUserID, toUserID)
+	if err != nil {
+		return nil, err
+	}
+	return &HandoverResult{TeamAssignments: assignments, Deals: deals}, nil
+}
+
+// ensureOtherAdmin returns ErrLastAdmin if member is the only active admin of the
+// organization, so it isn't left without anyone who can manage it
+func (s *MembershipService) ensureOtherAdmin(member *models.User, orgID uint) error {
+	if member.Role != models.RoleAdmin || member.DeactivatedAt != nil || member.IsServiceAccount {
+		return nil
+	}
+	admins, err := s.userRepo.CountActiveByRole(orgID, models.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
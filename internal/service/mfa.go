@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"ventura/internal/auth"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	// mfaIssuer is the account label shown in authenticator apps
+	mfaIssuer = "Ventura"
+
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrMFAAlreadyEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled         = errors.New("two-factor authentication is not enabled")
+	ErrMFANoPendingSecret    = errors.New("no two-factor enrollment in progress")
+	ErrInvalidMFACode        = errors.New("invalid authentication code")
+	ErrInvalidMFAPolicy      = errors.New("invalid MFA policy")
+	ErrMFARequiredByPolicy   = errors.New("two-factor authentication is required by your organization")
+	ErrMFAEnrollBeforePolicy = errors.New("enable two-factor authentication on your own account before requiring it")
+)
+
+// MFAEnrollment is what the client needs to add the account to an authenticator app
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+	QRCode     string `json:"qrCode"` // PNG data URL encoding OTPAuthURI
+}
+
+// MFAStatus describes a user's two-factor setup
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"`
+	EnrolledAt             *time.Time `json:"enrolledAt,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recoveryCodesRemaining"`
+}
+
+type MFAService struct {
+	userRepo         *repository.UserRepository
+	orgRepo          *repository.OrganizationRepository
+	recoveryCodeRepo *repository.RecoveryCodeRepository
+	sessionRepo      *repository.SessionRepository
+}
+
+func NewMFAService(userRepo *repository.UserRepository, orgRepo *repository.OrganizationRepository, recoveryCodeRepo *repository.RecoveryCodeRepository, sessionRepo *repository.SessionRepository) *MFAService {
+	return &MFAService{userRepo: userRepo, orgRepo: orgRepo, recoveryCodeRepo: recoveryCodeRepo, sessionRepo: sessionRepo}
+}
+
+// IsRequired reports whether the user's organization policy obliges them to use MFA
+func (s *MFAService) IsRequired(user *models.User) bool {
+	if user.IsServiceAccount {
+		return false
+	}
+	org := user.Organization
+	if org == nil {
+		var err error
+		if org, err = s.orgRepo.FindByID(user.OrganizationID); err != nil {
+			return false
+		}
+	}
+	return org.RequiresMFA(user.Role)
+}
+
+// Status returns the user's two-factor setup
+func (s *MFAService) Status(user *models.User) (*MFAStatus, error) {
+	remaining, err := s.recoveryCodeRepo.CountUnused(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &MFAStatus{
+		Enabled:                user.MFAEnabled,
+		Required:               s.IsRequired(user),
+		EnrolledAt:             user.MFAEnrolledAt,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// BeginEnrollment generates a pending secret. MFA is not active until ConfirmEnrollment
+// proves the authenticator app produces valid codes.
+func (s *MFAService) BeginEnrollment(ctx context.Context, user *models.User) (*MFAEnrollment, error) {
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPPendingSecret = secret
+	if err := s.userRepo.WithContext(ctx).Update(user); err != nil {
+		return nil, err
+	}
+
+	uri := auth.TOTPURI(secret, user.Email, mfaIssuer)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollment{
+		Secret:     secret,
+		OTPAuthURI: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// ConfirmEnrollment activates MFA with the pending secret and returns a fresh set of recovery codes
+func (s *MFAService) ConfirmEnrollment(ctx context.Context, user *models.User, code string) ([]string, error) {
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPPendingSecret == "" {
+		return nil, ErrMFANoPendingSecret
+	}
+
+	step, ok := auth.ValidateTOTP(user.TOTPPendingSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	now := time.Now()
+	user.TOTPSecret = user.TOTPPendingSecret
+	user.TOTPPendingSecret = ""
+	user.TOTPLastUsedStep = step
+	user.MFAEnabled = true
+	user.MFAEnrolledAt = &now
+	if err := s.userRepo.WithContext(ctx).Update(user); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(user.ID)
+}
+
+// Verify checks a second factor: a TOTP code, or failing that a single-use recovery code.
+// usedRecoveryCode tells the caller which one matched so it can be audited.
+func (s *MFAService) Verify(user *models.User, code string) (usedRecoveryCode bool, err error) {
+	if !user.MFAEnabled {
+		return false, ErrMFANotEnabled
+	}
+
+	if err := s.verifyTOTP(user, code); err == nil {
+		return false, nil
+	}
+
+	consumed, err := s.recoveryCodeRepo.Consume(user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+	if !consumed {
+		return false, ErrInvalidMFACode
+	}
+	return true, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes; a current TOTP code is required
+func (s *MFAService) RegenerateRecoveryCodes(user *models.User, code string) ([]string, error) {
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(user.ID)
+}
+
+// Disable removes the user's authenticator and recovery codes
+func (s *MFAService) Disable(ctx context.Context, user *models.User) error {
+	if !user.MFAEnabled && user.TOTPPendingSecret == "" {
+		return ErrMFANotEnabled
+	}
+
+	user.MFAEnabled = false
+	user.MFAEnrolledAt = nil
+	user.TOTPSecret = ""
+	user.TOTPPendingSecret = ""
+	user.TOTPLastUsedStep = 0
+	if err := s.userRepo.WithContext(ctx).Update(user); err != nil {
+		return err
+	}
+	return s.recoveryCodeRepo.DeleteForUser(user.ID)
+}
+
+// SetPolicy changes the organization's MFA policy. Members who are now required to use
+// MFA but have not enrolled are signed out of it, so they enroll at their next login.
+// The acting admin must already be enrolled if the new policy covers them.
+func (s *MFAService) SetPolicy(ctx context.Context, orgID uint, policy models.MFAPolicy, actor *models.User) (*models.Organization, error) {
+	switch policy {
+	case models.MFAPolicyOptional, models.MFAPolicyAdmins, models.MFAPolicyAll:
+	default:
+		return nil, ErrInvalidMFAPolicy
+	}
+
+	org, err := s.orgRepo.FindByID(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	org.MFAPolicy = policy
+	if org.RequiresMFA(actor.Role) && !actor.MFAEnabled {
+		return nil, ErrMFAEnrollBeforePolicy
+	}
+
+	if err := s.orgRepo.WithContext(ctx).Update(org); err != nil {
+		return nil, err
+	}
+
+	users, err := s.userRepo.GetAllByOrganization(orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if !user.MFAEnabled && !user.IsServiceAccount && org.RequiresMFA(user.Role) {
+			s.sessionRepo.RevokeAllForUserInOrganization(user.ID, orgID, models.SessionRevokedMFARequired)
+		}
+	}
+
+	return org, nil
+}
+
+// verifyTOTP accepts a code for the confirmed secret at most once
+func (s *MFAService) verifyTOTP(user *models.User, code string) error {
+	step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	fresh, err := s.userRepo.AdvanceTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+	user.TOTPLastUsedStep = step
+	return nil
+}
+
+// issueRecoveryCodes replaces the user's recovery codes and returns the plaintexts once
+func (s *MFAService) issueRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		randomBytes := make([]byte, 5)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return nil, err
+		}
+		encoded := strings.ToLower(base32.StdEncoding.EncodeToString(randomBytes))
+		codes[i] = encoded[:4] + "-" + encoded[4:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceForUser(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode normalizes case and separators so codes can be typed loosely
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+	"ventura/internal/auth"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB returns an in-memory SQLite database with tables for models
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	// Every connection to :memory: is a database of its own
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// totpCode returns the code an authenticator app shows for secret during step
+func totpCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+func TestVerifyRejectsReplayedCodes(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.RecoveryCode{})
+	userRepo := repository.NewUserRepository(db)
+	mfa := NewMFAService(userRepo, nil, repository.NewRecoveryCodeRepository(db), nil)
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{OrganizationID: 1, Email: "analyst@example.com", Password: "-", Name: "Analyst", MFAEnabled: true, TOTPSecret: secret}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	step := time.Now().Unix() / int64(auth.TOTPPeriod.Seconds())
+	steps := []struct {
+		name string
+		step int64
+		ok   bool
+	}{
+		{"current code", step, true},
+		{"same code again", step, false},
+		{"earlier code still within skew", step - 1, false},
+		{"next code", step + 1, true},
+		{"next code again", step + 1, false},
+	}
+	for _, s := range steps {
+		_, err := mfa.Verify(user, totpCode(t, secret, s.step))
+		if ok := err == nil; ok != s.ok {
+			t.Errorf("%s: err = %v, want ok = %v", s.name, err, s.ok)
+		}
+	}
+
+	var stored models.User
+	if err := db.First(&stored, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.TOTPLastUsedStep != step+1 {
+		t.Errorf("TOTPLastUsedStep = %d, want %d", stored.TOTPLastUsedStep, step+1)
+	}
+}
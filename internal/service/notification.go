@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"log"
+	"ventura/internal/mailer"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+// NotificationListLimit caps how many notifications are listed at once
+const NotificationListLimit = 100
+
+// NotificationService tells members about what concerns them, in the app and by email
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+	userRepo         *repository.UserRepository
+	mailer           mailer.Mailer
+	appURL           string
+}
+
+func NewNotificationService(notificationRepo *repository.NotificationRepository, userRepo *repository.UserRepository, m mailer.Mailer) *NotificationService {
+	return &NotificationService{notificationRepo: notificationRepo, userRepo: userRepo, mailer: m, appURL: appURL()}
+}
+
+// Notify records notifications and emails each recipient, with link as the path in the
+// app the email points to
+func (s *NotificationService) Notify(ctx context.Context, notifications []models.Notification, subject, link string) error {
+	if err := s.notificationRepo.WithContext(ctx).Create(notifications); err != nil {
+		return err
+	}
+	for _, notification := range notifications {
+		recipient, err := s.userRepo.FindByID(notification.UserID)
+		if err != nil {
+			continue
+		}
+		msg := mailer.Message{
+			To:      recipient.Email,
+			Subject: subject,
+			Text:    "Hi " + recipient.Name + ",\n\n" + notification.Message + "\n\n" + s.appURL + link + "\n",
+		}
+		go func() {
+			if err := s.mailer.Send(msg); err != nil {
+				log.Printf("Failed to send email %q to %s: %v", msg.Subject, msg.To, err)
+			}
+		}()
+	}
+	return nil
+}
+
+// List returns a member's most recent notifications in an organization
+func (s *NotificationService) List(userID, orgID uint, unreadOnly bool) ([]models.Notification, error) {
+	return s.notificationRepo.GetByUser(userID, orgID, unreadOnly, NotificationListLimit)
+}
+
+// CountUnread counts a member's unread notifications in an organization
+func (s *NotificationService) CountUnread(userID, orgID uint) (int64, error) {
+	return s.notificationRepo.CountUnread(userID, orgID)
+}
+
+// MarkRead marks one of a member's notifications read
+func (s *NotificationService) MarkRead(ctx context.Context, id, userID, orgID uint) error {
+	return s.notificationRepo.WithContext(ctx).MarkRead(id, userID, orgID)
+}
+
+// MarkAllRead marks all of a member's notifications in an organization read
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID, orgID uint) error {
+	return s.notificationRepo.WithContext(ctx).MarkAllRead(userID, orgID)
+}
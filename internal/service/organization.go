@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+
+	// Timezones are checked against the embedded database, so every host accepts the same names
+	_ "time/tzdata"
+)
+
+// MaxHealthRunwayMonths bounds the runway thresholds of the health status
+const MaxHealthRunwayMonths = 60
+
+// SupportedCurrencies are the base currencies the frontend can convert from
+var SupportedCurrencies = []string{"USD", "INR", "EUR", "GBP", "AED", "JPY", "CAD", "AUD", "CHF", "SGD", "CNY"}
+
+var (
+	ErrUnsupportedCurrency     = errors.New("base currency must be one of USD, INR, EUR, GBP, AED, JPY, CAD, AUD, CHF, SGD, CNY")
+	ErrInvalidFiscalYearStart  = errors.New("fiscal year start must be a month from 1 to 12")
+	ErrInvalidTimezone         = errors.New("timezone must be an IANA name such as Europe/London")
+	ErrInvalidUpdateCadence    = errors.New("update cadence must be monthly or quarterly")
+	ErrInvalidHealthThresholds = fmt.Errorf("health thresholds must satisfy 1 <= yellow < green <= %d runway months", MaxHealthRunwayMonths)
+	ErrDeletionNotConfirmed    = errors.New("confirm the deletion with the organization's slug")
+)
+
+// DefaultDeletionGraceDays is how long a deleted organization can be restored before it is
+// purged, unless ORG_DELETION_GRACE_DAYS says otherwise
+const DefaultDeletionGraceDays = 30
+
+// OrganizationService reads and changes an organization's settings, and deletes, restores
+// and purges organizations
+type OrganizationService struct {
+	orgRepo      *repository.OrganizationRepository
+	userRepo     *repository.UserRepository
+	sessionRepo  *repository.SessionRepository
+	auditLogRepo *repository.AuditLogRepository
+	accounts     *AccountService
+	gracePeriod  time.Duration
+}
+
+func NewOrganizationService(orgRepo *repository.OrganizationRepository, userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, auditLogRepo *repository.AuditLogRepository, accounts *AccountService) *OrganizationService {
+	graceDays := DefaultDeletionGraceDays
+	if value := os.Getenv("ORG_DELETION_GRACE_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 1 {
+			log.Fatalf("Invalid ORG_DELETION_GRACE_DAYS %q", value)
+		}
+		graceDays = days
+	}
+	return &OrganizationService{
+		orgRepo:      orgRepo,
+		userRepo:     userRepo,
+		sessionRepo:  sessionRepo,
+		auditLogRepo: auditLogRepo,
+		accounts:     accounts,
+		gracePeriod:  time.Duration(graceDays) * 24 * time.Hour,
+	}
+}
+
+// Settings returns an organization's settings
+func (s *OrganizationService) Settings(orgID uint) (models.OrganizationSettings, error) {
+	org, err := s.orgRepo.FindByID(orgID)
+	if err != nil {
+		return models.OrganizationSettings{}, err
+	}
+	return org.Settings, nil
+}
+
+// UpdateSettings validates and saves an organization's settings
+func (s *OrganizationService) UpdateSettings(ctx context.Context, orgID uint, settings models.OrganizationSettings) (*models.Organization, error) {
+	if err := validateSettings(settings); err != nil {
+		return nil, err
+	}
+
+	org, err := s.orgRepo.FindByID(orgID)
+	if err != nil {
+		return nil, err
+	}
+	org.Settings = settings
+	if err := s.orgRepo.WithContext(ctx).Update(org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// Delete deletes a member's organization once they confirm it with its slug. Its members lose
+// access at once; its data is kept until the grace period ends, and the member is emailed a
+// link that restores it until then.
+func (s *OrganizationService) Delete(ctx context.Context, userID, orgID uint, confirmation string) (*models.Organization, error) {
+	member, err := s.userRepo.FindMember(userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	org := member.Organization
+	if confirmation != org.Slug {
+		return nil, ErrDeletionNotConfirmed
+	}
+
+	if err := s.orgRepo.WithContext(ctx).Delete(org, member.ID, time.Now().Add(s.gracePeriod)); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.RevokeAllInOrganization(org.ID, models.SessionRevokedOrgDeleted); err != nil {
+		return nil, err
+	}
+	if err := s.accounts.SendOrganizationRestore(member, org); err != nil {
+		log.Printf("Failed to send the restore link of organization %d to user %d: %v", org.ID, member.ID, err)
+	}
+	return org, nil
+}
+
+// Restore undeletes the organization of an emailed restore link, if it hasn't been purged
+// yet, and returns it with the member the link was sent to
+func (s *OrganizationService) Restore(ctx context.Context, token string) (*models.Organization, *models.User, error) {
+	user, orgID, err := s.accounts.RedeemOrganizationRestore(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	org, err := s.orgRepo.FindDeleted(orgID)
+	if err != nil {
+		return nil, nil, ErrInvalidActionToken
+	}
+	if err := s.orgRepo.WithContext(ctx).Restore(org); err != nil {
+		return nil, nil, err
+	}
+	return org, user, nil
+}
+
+// PurgeDue permanently deletes the organizations whose grace period is over, with their
+// audit archives and exports, and returns how many were purged. Each purge is recorded in
+// the system audit log, since the organization's own log goes with it.
+func (s *OrganizationService) PurgeDue() (int, error) {
+	orgs, err := s.orgRepo.GetDueForPurge(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	var errs []error
+	for _, org := range orgs {
+		result, err := s.orgRepo.Purge(org.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("organization %d: %w", org.ID, err))
+			continue
+		}
+		purged++
+
+		for _, path := range result.Files {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("organization %d: %w", org.ID, err))
+			}
+		}
+
+		details := fmt.Sprintf("Purged organization %q (%s) deleted on %s: %d rows and %d accounts erased",
+			org.Name, org.Slug, org.DeletedAt.Time.UTC().Format("2006-01-02"), result.Rows, result.Users)
+		log.Printf("Organization %d: %s", org.ID, details)
+		s.auditLogRepo.Create(&models.AuditLog{
+			UserEmail: "system",
+			UserName:  "System",
+			Action:    models.ActionPurge,
+			Entity:    models.EntityOrganization,
+			EntityID:  org.ID,
+			Details:   details,
+		})
+	}
+	return purged, errors.Join(errs...)
+}
+
+func validateSettings(settings models.OrganizationSettings) error {
+	supported := false
+	for _, currency := range SupportedCurrencies {
+		supported = supported || settings.BaseCurrency == currency
+	}
+	if !supported {
+		return ErrUnsupportedCurrency
+	}
+	if settings.FiscalYearStartMonth < 1 || settings.FiscalYearStartMonth > 12 {
+		return ErrInvalidFiscalYearStart
+	}
+	if _, err := time.LoadLocation(settings.Timezone); err != nil || settings.Timezone == "" || settings.Timezone == "Local" {
+		return ErrInvalidTimezone
+	}
+	switch settings.UpdateCadence {
+	case models.UpdateCadenceMonthly, models.UpdateCadenceQuarterly:
+	default:
+		return ErrInvalidUpdateCadence
+	}
+	if settings.HealthYellowRunwayMonths < 1 || settings.HealthGreenRunwayMonths <= settings.HealthYellowRunwayMonths ||
+		settings.HealthGreenRunwayMonths > MaxHealthRunwayMonths {
+		return ErrInvalidHealthThresholds
+	}
+	return nil
+}
@@ -0,0 +1,509 @@
+package service
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExportRetention is how long a finished organization export can be downloaded
+const ExportRetention = 7 * 24 * time.Hour
+
+// exportTimeout is how long an export may stay in progress before it is considered
+// interrupted, e.g. by a restart
+const exportTimeout = time.Hour
+
+// ExportManifestVersion is the layout of organization exports, recorded in their manifest
+const ExportManifestVersion = 1
+
+var (
+	ErrExportInProgress = errors.New("an export of this organization is already in progress")
+	ErrExportNotReady   = errors.New("export is not ready for download")
+	ErrExportExpired    = errors.New("export has expired, request a new one")
+)
+
+// ExportManifest describes the files of an organization export
+type ExportManifest struct {
+	Version          int          `json:"version"`
+	ExportID         uint         `json:"exportId"`
+	OrganizationID   uint         `json:"organizationId"`
+	OrganizationName string       `json:"organizationName"`
+	GeneratedAt      time.Time    `json:"generatedAt"`
+	Files            []ExportFile `json:"files"`
+	MissingDocuments []uint       `json:"missingDocuments,omitempty"` // Documents whose file isn't stored on this server
+}
+
+// ExportFile is one file of an organization export
+type ExportFile struct {
+	Name    string `json:"name"`
+	Entity  string `json:"entity"`
+	Format  string `json:"format"` // json, jsonl, csv or file (an uploaded document)
+	Records int    `json:"records"`
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+}
+
+// OrganizationExportService builds zips of everything an organization holds, for
+// offboarding and data access requests
+type OrganizationExportService struct {
+	exportRepo         *repository.OrganizationExportRepository
+	orgRepo            *repository.OrganizationRepository
+	portfolioRepo      *repository.PortfolioRepository
+	dealRepo           *repository.DealRepository
+	dealActivityRepo   *repository.DealActivityRepository
+	dealAnalysisRepo   *repository.DealAnalysisRepository
+	dealScoreRepo      *repository.DealScoreRepository
+	founderRepo        *repository.FounderRepository
+	monthlyUpdateRepo  *repository.MonthlyUpdateRepository
+	documentRepo       *repository.DocumentRepository
+	teamAssignmentRepo *repository.TeamAssignmentRepository
+	audits             *AuditService
+	exportDir          string
+}
+
+func NewOrganizationExportService(
+	exportRepo *repository.OrganizationExportRepository,
+	orgRepo *repository.OrganizationRepository,
+	portfolioRepo *repository.PortfolioRepository,
+	dealRepo *repository.DealRepository,
+	dealActivityRepo *repository.DealActivityRepository,
+	dealAnalysisRepo *repository.DealAnalysisRepository,
+	dealScoreRepo *repository.DealScoreRepository,
+	founderRepo *repository.FounderRepository,
+	monthlyUpdateRepo *repository.MonthlyUpdateRepository,
+	documentRepo *repository.DocumentRepository,
+	teamAssignmentRepo *repository.TeamAssignmentRepository,
+	audits *AuditService,
+) *OrganizationExportService {
+	exportDir := os.Getenv("EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = filepath.Join("data", "exports")
+	}
+	return &OrganizationExportService{
+		exportRepo:         exportRepo,
+		orgRepo:            orgRepo,
+		portfolioRepo:      portfolioRepo,
+		dealRepo:           dealRepo,
+		dealActivityRepo:   dealActivityRepo,
+		dealAnalysisRepo:   dealAnalysisRepo,
+		dealScoreRepo:      dealScoreRepo,
+		founderRepo:        founderRepo,
+		monthlyUpdateRepo:  monthlyUpdateRepo,
+		documentRepo:       documentRepo,
+		teamAssignmentRepo: teamAssignmentRepo,
+		audits:             audits,
+		exportDir:          exportDir,
+	}
+}
+
+// Request records an export of an organization and builds it in the background
+func (s *OrganizationExportService) Request(orgID, userID uint) (*models.OrganizationExport, error) {
+	inProgress, err := s.exportRepo.CountInProgress(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if inProgress > 0 {
+		return nil, ErrExportInProgress
+	}
+
+	export := &models.OrganizationExport{OrganizationID: orgID, RequestedByID: userID, Status: models.ExportPending}
+	if err := s.exportRepo.Create(export); err != nil {
+		return nil, err
+	}
+	go s.build(*export)
+	return export, nil
+}
+
+// List returns an organization's exports, most recent first
+func (s *OrganizationExportService) List(orgID uint) ([]models.OrganizationExport, error) {
+	return s.exportRepo.GetByOrganization(orgID)
+}
+
+// Get returns one of an organization's exports
+func (s *OrganizationExportService) Get(id, orgID uint) (*models.OrganizationExport, error) {
+	return s.exportRepo.FindByID(id, orgID)
+}
+
+// Download returns one of an organization's exports if its file can be downloaded
+func (s *OrganizationExportService) Download(id, orgID uint) (*models.OrganizationExport, error) {
+	export, err := s.exportRepo.FindByID(id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	switch export.Status {
+	case models.ExportCompleted:
+		return export, nil
+	case models.ExportExpired:
+		return nil, ErrExportExpired
+	default:
+		return nil, ErrExportNotReady
+	}
+}
+
+// Expire deletes the files of exports past their retention and fails the exports whose
+// build was interrupted. It returns how many exports expired.
+func (s *OrganizationExportService) Expire() (int, error) {
+	if _, err := s.exportRepo.FailStale(time.Now().Add(-exportTimeout)); err != nil {
+		return 0, err
+	}
+
+	exports, err := s.exportRepo.GetExpired(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	var errs []error
+	for i := range exports {
+		export := &exports[i]
+		if err := os.Remove(export.Path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("export %d: %w", export.ID, err))
+			continue
+		}
+		export.Status, export.Path = models.ExportExpired, ""
+		if err := s.exportRepo.Update(export); err != nil {
+			errs = append(errs, fmt.Errorf("export %d: %w", export.ID, err))
+			continue
+		}
+		expired++
+	}
+	return expired, errors.Join(errs...)
+}
+
+// build writes an export's zip and records the outcome
+func (s *OrganizationExportService) build(export models.OrganizationExport) {
+	export.Status = models.ExportRunning
+	if err := s.exportRepo.Update(&export); err != nil {
+		log.Printf("Organization export %d failed to start: %v", export.ID, err)
+		return
+	}
+
+	if err := s.write(&export); err != nil {
+		log.Printf("Organization export %d failed: %v", export.ID, err)
+		export.Status, export.Error = models.ExportFailed, "the export could not be built, please try again"
+		if err := s.exportRepo.Update(&export); err != nil {
+			log.Printf("Failed to record the failure of organization export %d: %v", export.ID, err)
+		}
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ExportRetention)
+	export.Status, export.CompletedAt, export.ExpiresAt = models.ExportCompleted, &now, &expiresAt
+	if err := s.exportRepo.Update(&export); err != nil {
+		log.Printf("Failed to record organization export %d: %v", export.ID, err)
+		os.Remove(export.Path)
+	}
+}
+
+// write builds the zip of an organization's data in the export directory and sets the
+// export's Path, Size and SHA256
+func (s *OrganizationExportService) write(export *models.OrganizationExport) error {
+	org, err := s.orgRepo.FindByID(export.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.exportDir, 0o750); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(s.exportDir, "export-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	checksum := sha256.New()
+	archive := &exportArchive{
+		zip: zip.NewWriter(io.MultiWriter(file, checksum)),
+		manifest: ExportManifest{
+			Version:          ExportManifestVersion,
+			ExportID:         export.ID,
+			OrganizationID:   org.ID,
+			OrganizationName: org.Name,
+			GeneratedAt:      time.Now().UTC(),
+		},
+	}
+
+	if err := archive.add("organization.json", models.EntityOrganization, "json", func(w io.Writer) (int, error) {
+		return 1, encodeJSON(w, org)
+	}); err != nil {
+		return err
+	}
+
+	scope := repository.OrganizationScope(org.ID)
+	documents, err := s.documentRepo.Within(scope).GetAll()
+	if err != nil {
+		return err
+	}
+	tables := []struct {
+		name   string
+		entity string
+		load   func() (interface{}, error)
+	}{
+		{"companies", models.EntityCompany, func() (interface{}, error) { return s.portfolioRepo.Within(scope).GetAll() }},
+		{"deals", models.EntityDeal, func() (interface{}, error) { return s.dealRepo.Within(scope).GetAll() }},
+		{"deal_stage_events", models.EntityDeal, func() (interface{}, error) { return s.dealRepo.Within(scope).GetAllStageEvents() }},
+		{"deal_activities", models.EntityDeal, func() (interface{}, error) { return s.dealActivityRepo.Within(scope).GetAll() }},
+		{"deal_analyses", models.EntityDeal, func() (interface{}, error) { return s.dealAnalysisRepo.Within(scope).GetAll() }},
+		{"deal_scores", models.EntityDeal, func() (interface{}, error) { return s.dealScoreRepo.Within(scope).GetAll() }},
+		{"founders", models.EntityFounder, func() (interface{}, error) { return s.founderRepo.Within(scope).GetAll() }},
+		{"monthly_updates", models.EntityMonthlyUpdate, func() (interface{}, error) { return s.monthlyUpdateRepo.Within(scope).GetAll() }},
+		{"team_assignments", models.EntityTeam, func() (interface{}, error) { return s.teamAssignmentRepo.Within(scope).GetAll() }},
+		{"documents", models.EntityDocument, func() (interface{}, error) { return documents, nil }},
+	}
+	for _, table := range tables {
+		records, err := table.load()
+		if err != nil {
+			return fmt.Errorf("%s: %w", table.name, err)
+		}
+		if err := archive.table(table.name, table.entity, records); err != nil {
+			return fmt.Errorf("%s: %w", table.name, err)
+		}
+	}
+
+	for _, document := range documents {
+		if err := archive.document(document); err != nil {
+			return fmt.Errorf("document %d: %w", document.ID, err)
+		}
+	}
+
+	// The audit log is streamed, as it can outgrow memory
+	filter := repository.AuditLogFilter{OrganizationID: org.ID}
+	entries := 0
+	if err := archive.add("audit_logs.jsonl", models.EntityAuditLog, "jsonl", func(w io.Writer) (int, error) {
+		lines := &lineCounter{}
+		err := s.audits.Export(filter, AuditExportJSONL, io.MultiWriter(w, lines))
+		entries = lines.lines
+		return entries, err
+	}); err != nil {
+		return err
+	}
+	if err := archive.add("audit_logs.csv", models.EntityAuditLog, "csv", func(w io.Writer) (int, error) {
+		return entries, s.audits.Export(filter, AuditExportCSV, w)
+	}); err != nil {
+		return err
+	}
+
+	if err := archive.close(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.exportDir, fmt.Sprintf("org-%d-export-%d.zip", org.ID, export.ID))
+	if err := os.Rename(file.Name(), path); err != nil {
+		return err
+	}
+	export.Path, export.Size, export.SHA256 = path, info.Size(), hex.EncodeToString(checksum.Sum(nil))
+	return nil
+}
+
+// exportArchive writes the files of an export and lists them in its manifest
+type exportArchive struct {
+	zip      *zip.Writer
+	manifest ExportManifest
+}
+
+// add writes a file with the content written by write, which returns how many records it wrote
+func (a *exportArchive) add(name, entity, format string, write func(w io.Writer) (int, error)) error {
+	w, err := a.zip.Create(name)
+	if err != nil {
+		return err
+	}
+	checksum := sha256.New()
+	size := &lineCounter{}
+	records, err := write(io.MultiWriter(w, checksum, size))
+	if err != nil {
+		return err
+	}
+	a.manifest.Files = append(a.manifest.Files, ExportFile{
+		Name:    name,
+		Entity:  entity,
+		Format:  format,
+		Records: records,
+		Size:    size.bytes,
+		SHA256:  hex.EncodeToString(checksum.Sum(nil)),
+	})
+	return nil
+}
+
+// table writes records, a slice of models, as name.json and name.csv
+func (a *exportArchive) table(name, entity string, records interface{}) error {
+	count := reflect.ValueOf(records).Len()
+	if err := a.add(name+".json", entity, "json", func(w io.Writer) (int, error) {
+		return count, encodeJSON(w, records)
+	}); err != nil {
+		return err
+	}
+	return a.add(name+".csv", entity, "csv", func(w io.Writer) (int, error) {
+		return count, encodeCSV(w, records)
+	})
+}
+
+// document copies a document's file, if it is stored on this server, to documents/
+func (a *exportArchive) document(document models.Document) error {
+	file, err := os.Open(document.FilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			a.manifest.MissingDocuments = append(a.manifest.MissingDocuments, document.ID)
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	name := fmt.Sprintf("documents/%d-%s", document.ID, filepath.Base(document.FileName))
+	return a.add(name, models.EntityDocument, "file", func(w io.Writer) (int, error) {
+		_, err := io.Copy(w, file)
+		return 1, err
+	})
+}
+
+// close writes the manifest and finishes the zip
+func (a *exportArchive) close() error {
+	w, err := a.zip.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	if err := encodeJSON(w, a.manifest); err != nil {
+		return err
+	}
+	return a.zip.Close()
+}
+
+// lineCounter counts the bytes and lines written through it
+type lineCounter struct {
+	bytes int64
+	lines int
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	c.bytes += int64(len(p))
+	c.lines += strings.Count(string(p), "\n")
+	return len(p), nil
+}
+
+func encodeJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// encodeCSV writes records, a slice of models, as CSV with a column per field that the API
+// returns, named as in its JSON. Relations are left out; they have files of their own.
+func encodeCSV(w io.Writer, records interface{}) error {
+	slice := reflect.ValueOf(records)
+	columns := csvColumns(slice.Type().Elem())
+
+	writer := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for i := 0; i < slice.Len(); i++ {
+		record := slice.Index(i)
+		row := make([]string, len(columns))
+		for j, column := range columns {
+			row[j] = csvValue(record.FieldByIndex(column.index))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+func csvColumns(t reflect.Type) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || isRelation(field.Type) {
+			continue
+		}
+		name := field.Name
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		columns = append(columns, csvColumn{name: name, index: field.Index})
+	}
+	return columns
+}
+
+// isRelation reports whether a field holds associated models rather than a value
+func isRelation(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return false // Raw JSON and other bytes
+		}
+		t = t.Elem()
+	}
+	switch t {
+	case reflect.TypeOf(time.Time{}), reflect.TypeOf(decimal.Decimal{}):
+		return false
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// csvValue formats a field for CSV: times in RFC 3339, nil as empty, and anything
+// that isn't a scalar as JSON
+func csvValue(value reflect.Value) string {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	switch v := value.Interface().(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339Nano)
+	case decimal.Decimal:
+		return v.String()
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return fmt.Sprint(value.Interface())
+	}
+	data, err := json.Marshal(value.Interface())
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+var (
+	ErrUnknownRole        = errors.New("unknown role")
+	ErrUnknownPermission  = errors.New("unknown permission")
+	ErrInvalidRoleKey     = errors.New("role key must be 2-20 lowercase letters, digits, - or _")
+	ErrRoleKeyTaken       = errors.New("a role with this key already exists")
+	ErrRoleInUse          = errors.New("role is still assigned to users")
+	ErrPermissionEscalate = errors.New("you cannot grant permissions you don't have")
+)
+
+var roleKeyPattern = regexp.MustCompile(`^[a-z0-9_-]{2,20}$`)
+
+// RoleInfo describes a built-in or custom role
+type RoleInfo struct {
+	ID          uint                `json:"id,omitempty"` // Zero for built-in roles
+	Key         models.UserRole     `json:"key"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Permissions []models.Permission `json:"permissions"`
+	BuiltIn     bool                `json:"builtIn"`
+}
+
+// RoleInput describes a custom role to create or update
+type RoleInput struct {
+	Key         models.UserRole // Only used on create
+	Name        string
+	Description string
+	Permissions []models.Permission
+}
+
+var builtInRoles = []RoleInfo{
+	{Key: models.RoleAdmin, Name: "Admin", Description: "Full access, including users and security settings"},
+	{Key: models.RoleEditor, Name: "Editor", Description: "Can change portfolio, deal and founder data"},
+	{Key: models.RoleViewer, Name: "Viewer", Description: "Read-only access"},
+}
+
+// PermissionService resolves roles to permissions and manages custom roles
+type PermissionService struct {
+	roleRepo *repository.RoleRepository
+	userRepo *repository.UserRepository
+}
+
+func NewPermissionService(roleRepo *repository.RoleRepository, userRepo *repository.UserRepository) *PermissionService {
+	return &PermissionService{roleRepo: roleRepo, userRepo: userRepo}
+}
+
+// Permissions returns what a role grants in an organization. Unknown roles grant nothing.
+func (s *PermissionService) Permissions(orgID uint, role models.UserRole) []models.Permission {
+	if permissions, ok := models.BuiltInRolePermissions(role); ok {
+		return permissions
+	}
+	custom, err := s.roleRepo.FindByKey(orgID, role)
+	if err != nil {
+		return []models.Permission{}
+	}
+	return custom.PermissionList()
+}
+
+// ValidateRole checks that role exists in the organization
+func (s *PermissionService) ValidateRole(orgID uint, role models.UserRole) error {
+	if models.IsBuiltInRole(role) {
+		return nil
+	}
+	if _, err := s.roleRepo.FindByKey(orgID, role); err != nil {
+		return ErrUnknownRole
+	}
+	return nil
+}
+
+// CanAssignRole validates role and checks the actor holds every permission it grants,
+// so nobody can hand out more access than they have
+func (s *PermissionService) CanAssignRole(orgID uint, role models.UserRole, actorPermissions []models.Permission) error {
+	if err := s.ValidateRole(orgID, role); err != nil {
+		return err
+	}
+	return checkGrantable(s.Permissions(orgID, role), actorPermissions)
+}
+
+// CanChangeRole checks the actor may move a user from one role to another. Both roles
+// must be within the actor's own access, so a user can't demote someone above them.
+func (s *PermissionService) CanChangeRole(orgID uint, from, to models.UserRole, actorPermissions []models.Permission) error {
+	if err := s.CanAssignRole(orgID, to, actorPermissions); err != nil {
+		return err
+	}
+	return s.CanManage(orgID, from, actorPermissions)
+}
+
+// CanManage checks the actor holds every permission of a member's role, so they can't
+// deactivate, unlock or otherwise act on a member with more access than they have
+func (s *PermissionService) CanManage(orgID uint, role models.UserRole, actorPermissions []models.Permission) error {
+	return checkGrantable(s.Permissions(orgID, role), actorPermissions)
+}
+
+// ListRoles returns the built-in roles followed by the organization's custom roles
+func (s *PermissionService) ListRoles(orgID uint) ([]RoleInfo, error) {
+	custom, err := s.roleRepo.GetByOrganization(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]RoleInfo, 0, len(builtInRoles)+len(custom))
+	for _, role := range builtInRoles {
+		role.Permissions, _ = models.BuiltInRolePermissions(role.Key)
+		role.BuiltIn = true
+		roles = append(roles, role)
+	}
+	for i := range custom {
+		roles = append(roles, toRoleInfo(&custom[i]))
+	}
+	return roles, nil
+}
+
+// CreateRole adds a custom role to the organization
+func (s *PermissionService) CreateRole(ctx context.Context, orgID uint, input RoleInput, actorPermissions []models.Permission) (*RoleInfo, error) {
+	key := models.UserRole(strings.ToLower(strings.TrimSpace(string(input.Key))))
+	if !roleKeyPattern.MatchString(string(key)) {
+		return nil, ErrInvalidRoleKey
+	}
+	if models.IsBuiltInRole(key) {
+		return nil, ErrRoleKeyTaken
+	}
+	if _, err := s.roleRepo.FindByKey(orgID, key); err == nil {
+		return nil, ErrRoleKeyTaken
+	}
+
+	permissions, err := normalizePermissions(input.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrantable(permissions, actorPermissions); err != nil {
+		return nil, err
+	}
+
+	role := &models.CustomRole{
+		OrganizationID: orgID,
+		Key:            key,
+		Name:           strings.TrimSpace(input.Name),
+		Description:    strings.TrimSpace(input.Description),
+		Permissions:    joinPermissions(permissions),
+	}
+	if err := s.roleRepo.WithContext(ctx).Create(role); err != nil {
+		return nil, err
+	}
+
+	info := toRoleInfo(role)
+	return &info, nil
+}
+
+// UpdateRole changes a custom role's name, description and permissions.
+// The new permissions apply to its members on their next request.
+func (s *PermissionService) UpdateRole(ctx context.Context, orgID, id uint, input RoleInput, actorPermissions []models.Permission) (*RoleInfo, error) {
+	role, err := s.roleRepo.FindByIDAndOrganization(id, orgID)
+	if err != nil {
+		return nil, ErrUnknownRole
+	}
+
+	permissions, err := normalizePermissions(input.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	// Both the old and the new grants must be within the actor's own access
+	if err := checkGrantable(append(permissions, role.PermissionList()...), actorPermissions); err != nil {
+		return nil, err
+	}
+
+	role.Name = strings.TrimSpace(input.Name)
+	role.Description = strings.TrimSpace(input.Description)
+	role.Permissions = joinPermissions(permissions)
+	if err := s.roleRepo.WithContext(ctx).Update(role); err != nil {
+		return nil, err
+	}
+
+	info := toRoleInfo(role)
+	return &info, nil
+}
+
+// DeleteRole removes a custom role that is no longer assigned to anyone
+func (s *PermissionService) DeleteRole(ctx context.Context, orgID, id uint) error {
+	role, err := s.roleRepo.FindByIDAndOrganization(id, orgID)
+	if err != nil {
+		return ErrUnknownRole
+	}
+
+	count, err := s.userRepo.CountByRole(orgID, role.Key)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrRoleInUse
+	}
+
+	return s.roleRepo.WithContext(ctx).Delete(role.ID)
+}
+
+func toRoleInfo(role *models.CustomRole) RoleInfo {
+	return RoleInfo{
+		ID:          role.ID,
+		Key:         role.Key,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: role.PermissionList(),
+	}
+}
+
+// normalizePermissions validates and de-duplicates permissions, keeping catalogue order
+func normalizePermissions(requested []models.Permission) ([]models.Permission, error) {
+	for _, p := range requested {
+		if !models.IsValidPermission(p) {
+			return nil, ErrUnknownPermission
+		}
+	}
+
+	permissions := []models.Permission{}
+	for _, info := range models.PermissionCatalogue {
+		if models.HasPermission(requested, info.Key) {
+			permissions = append(permissions, info.Key)
+		}
+	}
+	return permissions, nil
+}
+
+func joinPermissions(permissions []models.Permission) string {
+	parts := make([]string, len(permissions))
+	for i, p := range permissions {
+		parts[i] = string(p)
+	}
+	return strings.Join(parts, " ")
+}
+
+func checkGrantable(granted, actorPermissions []models.Permission) error {
+	for _, p := range granted {
+		if !models.HasPermission(actorPermissions, p) {
+			return ErrPermissionEscalate
+		}
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"time"
+	"ventura/internal/models"
+)
+
+// funnelStages are the stages a deal passes through on its way to closing, in order
+var funnelStages = []models.DealStage{models.StageIncoming, models.StageScreening, models.StageDueDiligence, models.StageTermSheet, models.StageClosed}
+
+// PipelineWindow limits pipeline analytics to deals created in [From, To) and to events in
+// the same window; a zero bound leaves that side open
+type PipelineWindow struct {
+	From time.Time
+	To   time.Time
+}
+
+func (w PipelineWindow) contains(t time.Time) bool {
+	return (w.From.IsZero() || !t.Before(w.From)) && (w.To.IsZero() || t.Before(w.To))
+}
+
+// PipelineAnalytics is the funnel and velocity report of a deal pipeline
+type PipelineAnalytics struct {
+	Deals       int                 `json:"deals"` // Deals created in the window
+	Funnel      []StageConversion   `json:"funnel"`
+	Velocity    []StageVelocity     `json:"velocity"`
+	Throughput  []MonthlyThroughput `json:"throughput"`
+	LossReasons LossReasonBreakdown `json:"lossReasons"`
+}
+
+// StageConversion counts the deals that reached a stage and how many of them went further
+type StageConversion struct {
+	Stage          models.DealStage `json:"stage"`
+	Reached        int              `json:"reached"`        // Deals that reached this stage or a later one
+	Advanced       int              `json:"advanced"`       // Of those, deals that reached the next stage or a later one
+	Lost           int              `json:"lost"`           // Deals lost from this stage
+	ConversionRate float64          `json:"conversionRate"` // Advanced / Reached, as a percentage
+}
+
+// StageVelocity is how long deals stayed in a stage before leaving it
+type StageVelocity struct {
+	Stage      models.DealStage `json:"stage"`
+	Samples    int              `json:"samples"` // Stays in the stage that have ended
+	MedianDays float64          `json:"medianDays"`
+	P90Days    float64          `json:"p90Days"`
+}
+
+// MonthlyThroughput counts what happened in the pipeline during a month
+type MonthlyThroughput struct {
+	Month    string `json:"month"` // 2006-01, in the organization's timezone
+	Created  int    `json:"created"`
+	Advanced int    `json:"advanced"` // Moves to a later open stage
+	Closed   int    `json:"closed"`
+	Lost     int    `json:"lost"`
+}
+
+// LossReasonBreakdown counts the loss reasons of lost deals by the stage they were lost
+// from, their sector and their round
+type LossReasonBreakdown struct {
+	ByStage      []LossReasonGroup `json:"byStage"`
+	BySector     []LossReasonGroup `json:"bySector"`
+	ByRoundStage []LossReasonGroup `json:"byRoundStage"`
+}
+
+// LossReasonGroup counts the loss reasons of the lost deals sharing a stage, sector or round
+type LossReasonGroup struct {
+	Key     string         `json:"key"`
+	Total   int            `json:"total"`
+	Reasons map[string]int `json:"reasons"`
+}
+
+// dealPath is the stages a deal went through, each with the time it entered it
+type dealPath struct {
+	stages  []models.DealStage
+	entered []time.Time
+}
+
+// pathOf rebuilds a deal's path from its stage events, oldest first. Deals that closed or
+// were lost before stage history was recorded start in an unknown stage ("") and enter
+// their final one when archived.
+func pathOf(deal models.Deal, events []models.DealStageEvent) dealPath {
+	path := dealPath{}
+	if len(events) == 0 {
+		if deal.Stage.IsTerminal() && deal.ArchivedAt != nil {
+			path.stages = []models.DealStage{"", deal.Stage}
+			path.entered = []time.Time{deal.CreatedAt, *deal.ArchivedAt}
+			return path
+		}
+		path.stages = []models.DealStage{deal.Stage}
+		path.entered = []time.Time{deal.CreatedAt}
+		return path
+	}
+
+	path.stages = []models.DealStage{events[0].FromStage}
+	path.entered = []time.Time{deal.CreatedAt}
+	if events[0].FromStage == "" {
+		// Deals created since entries were recorded start with the one into their first stage
+		path.stages[0] = events[0].ToStage
+		events = events[1:]
+	}
+	for _, event := range events {
+		path.stages = append(path.stages, event.ToStage)
+		path.entered = append(path.entered, event.CreatedAt)
+	}
+	return path
+}
+
+// furthest returns the index in funnelStages of the furthest stage the path reached
+func (p dealPath) furthest() int {
+	furthest := 0
+	for _, stage := range p.stages {
+		if i := funnelIndex(stage); i > furthest {
+			furthest = i
+		}
+	}
+	return furthest
+}
+
+// lostFrom returns the stage a lost deal was lost from, or "" if unknown
+func (p dealPath) lostFrom() models.DealStage {
+	for i := len(p.stages) - 1; i > 0; i-- {
+		if p.stages[i] == models.StageLost {
+			return p.stages[i-1]
+		}
+	}
+	return ""
+}
+
+func funnelIndex(stage models.DealStage) int {
+	for i, s := range funnelStages {
+		if s == stage {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetPipelineAnalytics reports on deals and their stage events: conversion between stages,
+// time spent in each stage and losses for the deals created in the window, and what
+// happened in the pipeline each month of the window
+func (s *AnalyticsService) GetPipelineAnalytics(deals []models.Deal, events []models.DealStageEvent, window PipelineWindow, settings models.OrganizationSettings) PipelineAnalytics {
+	eventsByDeal := make(map[uint][]models.DealStageEvent)
+	for _, event := range events {
+		eventsByDeal[event.DealID] = append(eventsByDeal[event.DealID], event)
+	}
+
+	reached := make([]int, len(funnelStages))
+	lost := make(map[models.DealStage]int)
+	durations := make(map[models.DealStage][]float64)
+	byStage := make(map[string]map[string]int)
+	bySector := make(map[string]map[string]int)
+	byRound := make(map[string]map[string]int)
+
+	location := settings.Location()
+	throughput := make(map[string]*MonthlyThroughput)
+	month := func(t time.Time) *MonthlyThroughput {
+		key := t.In(location).Format("2006-01")
+		if throughput[key] == nil {
+			throughput[key] = &MonthlyThroughput{Month: key}
+		}
+		return throughput[key]
+	}
+
+	report := PipelineAnalytics{}
+	for _, deal := range deals {
+		path := pathOf(deal, eventsByDeal[deal.ID])
+
+		// Throughput counts what happened in the window, whenever the deal was created
+		for i, stage := range path.stages {
+			if !window.contains(path.entered[i]) {
+				continue
+			}
+			switch {
+			case i == 0:
+				month(path.entered[i]).Created++
+			case stage == models.StageClosed:
+				month(path.entered[i]).Closed++
+			case stage == models.StageLost:
+				month(path.entered[i]).Lost++
+			case funnelIndex(stage) > funnelIndex(path.stages[i-1]):
+				month(path.entered[i]).Advanced++
+			}
+		}
+
+		if !window.contains(deal.CreatedAt) {
+			continue
+		}
+		report.Deals++
+
+		for i := 0; i <= path.furthest(); i++ {
+			reached[i]++
+		}
+		for i := 0; i+1 < len(path.stages); i++ {
+			if stage := path.stages[i]; !stage.IsTerminal() {
+				durations[stage] = append(durations[stage], path.entered[i+1].Sub(path.entered[i]).Hours()/24)
+			}
+		}
+
+		if deal.Stage == models.StageLost {
+			from := path.lostFrom()
+			if from != "" {
+				lost[from]++
+			}
+			countLossReason(byStage, string(from), deal.LossReason)
+			countLossReason(bySector, deal.Sector, deal.LossReason)
+			countLossReason(byRound, deal.RoundStage, deal.LossReason)
+		}
+	}
+
+	for i, stage := range funnelStages {
+		conversion := StageConversion{Stage: stage, Reached: reached[i], Lost: lost[stage]}
+		if i+1 < len(funnelStages) {
+			conversion.Advanced = reached[i+1]
+			if conversion.Reached > 0 {
+				conversion.ConversionRate = roundTo(float64(conversion.Advanced)/float64(conversion.Reached)*100, 1)
+			}
+		}
+		report.Funnel = append(report.Funnel, conversion)
+
+		if stage.IsTerminal() {
+			continue
+		}
+		samples := durations[stage]
+		sort.Float64s(samples)
+		report.Velocity = append(report.Velocity, StageVelocity{
+			Stage:      stage,
+			Samples:    len(samples),
+			MedianDays: roundTo(median(samples), 1),
+			P90Days:    roundTo(percentile(samples, 0.9), 1),
+		})
+	}
+
+	report.Throughput = monthlyThroughput(throughput, window, location)
+	report.LossReasons = LossReasonBreakdown{
+		ByStage:      lossReasonGroups(byStage),
+		BySector:     lossReasonGroups(bySector),
+		ByRoundStage: lossReasonGroups(byRound),
+	}
+	return report
+}
+
+// monthlyThroughput lists every month of the window, or from the first month with
+// activity to the current one when it is open
+func monthlyThroughput(counts map[string]*MonthlyThroughput, window PipelineWindow, location *time.Location) []MonthlyThroughput {
+	months := []MonthlyThroughput{}
+	first, last := window.From, window.To
+	if last.IsZero() {
+		last = time.Now()
+	} else {
+		last = last.Add(-time.Nanosecond)
+	}
+	if first.IsZero() {
+		for key := range counts {
+			start, _ := time.ParseInLocation("2006-01", key, location)
+			if first.IsZero() || start.Before(first) {
+				first = start
+			}
+		}
+		if first.IsZero() {
+			return months
+		}
+	}
+
+	first, last = first.In(location), last.In(location)
+	for m := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, location); !m.After(last); m = m.AddDate(0, 1, 0) {
+		key := m.Format("2006-01")
+		if counts[key] != nil {
+			months = append(months, *counts[key])
+		} else {
+			months = append(months, MonthlyThroughput{Month: key})
+		}
+	}
+	return months
+}
+
+func countLossReason(groups map[string]map[string]int, key, reason string) {
+	if key == "" {
+		key = "unknown"
+	}
+	if reason == "" {
+		reason = models.LossReasonOther
+	}
+	if groups[key] == nil {
+		groups[key] = make(map[string]int)
+	}
+	groups[key][reason]++
+}
+
+func lossReasonGroups(groups map[string]map[string]int) []LossReasonGroup {
+	result := []LossReasonGroup{}
+	for key, reasons := range groups {
+		group := LossReasonGroup{Key: key, Reasons: reasons}
+		for _, count := range reasons {
+			group.Total += count
+		}
+		result = append(result, group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// median returns the median of sorted values, or 0 if there are none
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	switch {
+	case n == 0:
+		return 0
+	case n%2 == 1:
+		return sorted[n/2]
+	default:
+		return (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+}
+
+// percentile returns the nearest-rank p-th percentile (0 < p <= 1) of sorted values, or
+// 0 if there are none
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func roundTo(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+	"ventura/internal/models"
+)
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		sorted []float64
+		want   float64
+	}{
+		{nil, 0},
+		{[]float64{5}, 5},
+		{[]float64{1, 3}, 2},
+		{[]float64{1, 2, 10}, 2},
+		{[]float64{1, 2, 3, 10}, 2.5},
+	}
+	for _, tt := range tests {
+		if got := median(tt.sorted); got != tt.want {
+			t.Errorf("median(%v) = %v, want %v", tt.sorted, got, tt.want)
+		}
+	}
+}
+
+func TestPercentileNearestRank(t *testing.T) {
+	series := func(n int) []float64 {
+		values := make([]float64, n)
+		for i := range values {
+			values[i] = float64(i + 1)
+		}
+		return values
+	}
+	tests := []struct {
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{nil, 0.9, 0},
+		{series(1), 0.9, 1},
+		{series(5), 0.9, 5},   // rank ceil(4.5) = 5
+		{series(10), 0.9, 9},  // rank 9 exactly, no interpolation
+		{series(11), 0.9, 10}, // rank ceil(9.9) = 10
+		{series(20), 0.9, 18},
+		{series(4), 0.5, 2},
+		{series(4), 1, 4},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%v, %v) = %v, want %v", tt.sorted, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestPipelineVelocity(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var deals []models.Deal
+	var events []models.DealStageEvent
+	// Ten deals stay 1 to 10 days in incoming before moving to screening, where they remain
+	for i := 1; i <= 10; i++ {
+		deal := models.Deal{ID: uint(i), Stage: models.StageScreening, CreatedAt: created}
+		deals = append(deals, deal)
+		events = append(events,
+			models.DealStageEvent{DealID: deal.ID, ToStage: models.StageIncoming, CreatedAt: created},
+			models.DealStageEvent{DealID: deal.ID, FromStage: models.StageIncoming, ToStage: models.StageScreening, CreatedAt: created.AddDate(0, 0, i)},
+		)
+	}
+
+	report := NewAnalyticsService().GetPipelineAnalytics(deals, events, PipelineWindow{}, models.DefaultOrganizationSettings())
+
+	velocity := map[models.DealStage]StageVelocity{}
+	for _, v := range report.Velocity {
+		velocity[v.Stage] = v
+	}
+	if got := velocity[models.StageIncoming]; got.Samples != 10 || got.MedianDays != 5.5 || got.P90Days != 9 {
+		t.Errorf("incoming velocity = %+v, want 10 samples, median 5.5 and p90 9 days", got)
+	}
+	// Stays that haven't ended yet aren't samples
+	if got := velocity[models.StageScreening]; got.Samples != 0 {
+		t.Errorf("screening velocity = %+v, want no samples", got)
+	}
+}
+
+func TestPipelineThroughputMonthsInOrganizationTimezone(t *testing.T) {
+	settings := models.DefaultOrganizationSettings()
+	settings.Timezone = "America/New_York"
+	newYork := settings.Location()
+
+	// Each event is in the next UTC month but still the previous month in New York
+	created := time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC)   // Jan 31, 22:00
+	advanced := time.Date(2024, 3, 1, 4, 59, 0, 0, time.UTC) // Feb 29, 23:59
+	closed := time.Date(2024, 3, 1, 5, 0, 0, 0, time.UTC)    // Mar 1, 00:00
+	lost := time.Date(2024, 4, 1, 3, 59, 0, 0, time.UTC)     // Mar 31, 23:59
+	outside := time.Date(2024, 4, 1, 4, 0, 0, 0, time.UTC)   // Apr 1, 00:00, after the window
+
+	deals := []models.Deal{
+		{ID: 1, Stage: models.StageClosed, CreatedAt: created},
+		{ID: 2, Stage: models.StageLost, CreatedAt: created},
+		{ID: 3, Stage: models.StageIncoming, CreatedAt: outside},
+	}
+	events := []models.DealStageEvent{
+		{DealID: 1, ToStage: models.StageIncoming, CreatedAt: created},
+		{DealID: 1, FromStage: models.StageIncoming, ToStage: models.StageScreening, CreatedAt: advanced},
+		{DealID: 1, FromStage: models.StageScreening, ToStage: models.StageClosed, CreatedAt: closed},
+		{DealID: 2, ToStage: models.StageIncoming, CreatedAt: created},
+		{DealID: 2, FromStage: models.StageIncoming, ToStage: models.StageLost, CreatedAt: lost},
+		{DealID: 3, ToStage: models.StageIncoming, CreatedAt: outside},
+	}
+	window := PipelineWindow{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, newYork),
+		To:   time.Date(2024, 4, 1, 0, 0, 0, 0, newYork),
+	}
+
+	report := NewAnalyticsService().GetPipelineAnalytics(deals, events, window, settings)
+
+	want := []MonthlyThroughput{
+		{Month: "2024-01", Created: 2},
+		{Month: "2024-02", Advanced: 1},
+		{Month: "2024-03", Closed: 1, Lost: 1},
+	}
+	if len(report.Throughput) != len(want) {
+		t.Fatalf("throughput = %+v, want %+v", report.Throughput, want)
+	}
+	for i := range want {
+		if report.Throughput[i] != want[i] {
+			t.Errorf("throughput[%d] = %+v, want %+v", i, report.Throughput[i], want[i])
+		}
+	}
+	if report.Deals != 2 {
+		t.Errorf("deals = %d, want the 2 created in the window", report.Deals)
+	}
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+var (
+	ErrInvalidScorecard = errors.New("invalid scorecard")
+	ErrInvalidScore     = errors.New("invalid score")
+)
+
+// criterionKeyPattern is what criterion keys look like: thesis_fit, founder_market_fit
+var criterionKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// CriterionScores is a deal's scores on one criterion of its organization's scorecard
+type CriterionScores struct {
+	models.ScoringCriterion
+	Average *float64           `json:"average"` // Of the reviewers' scores; nil until one scores it
+	Scores  []models.DealScore `json:"scores"`
+}
+
+// DealScorecard is a deal's scores on its organization's scorecard
+type DealScorecard struct {
+	DealID     uint              `json:"dealId"`
+	Criteria   []CriterionScores `json:"criteria"`
+	TotalScore float64           `json:"totalScore"`
+}
+
+// ScorecardService keeps each organization's deal scorecard, the scores reviewers give deals
+// on it, and the weighted total they add up to
+type ScorecardService struct {
+	criterionRepo *repository.ScoringCriterionRepository
+	scoreRepo     *repository.DealScoreRepository
+	userRepo      *repository.UserRepository
+}
+
+func NewScorecardService(criterionRepo *repository.ScoringCriterionRepository, scoreRepo *repository.DealScoreRepository, userRepo *repository.UserRepository) *ScorecardService {
+	return &ScorecardService{criterionRepo: criterionRepo, scoreRepo: scoreRepo, userRepo: userRepo}
+}
+
+// Criteria returns an organization's scorecard, or the default one if it hasn't set its own
+func (s *ScorecardService) Criteria(orgID uint) ([]models.ScoringCriterion, error) {
+	criteria, err := s.criterionRepo.GetByOrganization(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if len(criteria) == 0 {
+		return models.DefaultScoringCriteria(), nil
+	}
+	return criteria, nil
+}
+
+// SetCriteria replaces an organization's scorecard; an empty one restores the default.
+// Scores on criteria that are dropped are kept but no longer count.
+func (s *ScorecardService) SetCriteria(ctx context.Context, orgID uint, criteria []models.ScoringCriterion) ([]models.ScoringCriterion, error) {
+	keys := make(map[string]bool, len(criteria))
+	total := 0
+	for i := range criteria {
+		criterion := &criteria[i]
+		criterion.Name = strings.TrimSpace(criterion.Name)
+		if !criterionKeyPattern.MatchString(criterion.Key) {
+			return nil, fmt.Errorf("%w: key %q must be lowercase letters, digits and underscores", ErrInvalidScorecard, criterion.Key)
+		}
+		if keys[criterion.Key] {
+			return nil, fmt.Errorf("%w: key %q is used twice", ErrInvalidScorecard, criterion.Key)
+		}
+		keys[criterion.Key] = true
+		if criterion.Name == "" {
+			return nil, fmt.Errorf("%w: %s needs a name", ErrInvalidScorecard, criterion.Key)
+		}
+		if criterion.Weight < 1 || criterion.Weight > 100 {
+			return nil, fmt.Errorf("%w: the weight of %s must be 1 to 100", ErrInvalidScorecard, criterion.Key)
+		}
+		total += criterion.Weight
+
+		levels := make(map[int]bool, len(criterion.Scale))
+		for _, level := range criterion.Scale {
+			if level.Score < 1 || level.Score > 10 || levels[level.Score] || strings.TrimSpace(level.Description) == "" {
+				return nil, fmt.Errorf("%w: the scale of %s must describe distinct scores from 1 to 10", ErrInvalidScorecard, criterion.Key)
+			}
+			levels[level.Score] = true
+		}
+		sort.Slice(criterion.Scale, func(a, b int) bool {
+			return criterion.Scale[a].Score < criterion.Scale[b].Score
+		})
+	}
+	if len(criteria) > 0 && total != 100 {
+		return nil, fmt.Errorf("%w: weights add up to %d, not 100", ErrInvalidScorecard, total)
+	}
+
+	if err := s.criterionRepo.WithContext(ctx).Replace(orgID, criteria); err != nil {
+		return nil, err
+	}
+	return s.Criteria(orgID)
+}
+
+// Score replaces the scores a member gives a deal, by criterion key. Criteria left out are
+// unscored by them.
+func (s *ScorecardService) Score(ctx context.Context, scope repository.CompanyScope, deal *models.Deal, reviewerID uint, scores map[string]int) (*DealScorecard, error) {
+	reviewer, err := s.userRepo.FindMember(reviewerID, deal.OrganizationID)
+	if err != nil {
+		return nil, ErrNotMember
+	}
+	criteria, err := s.Criteria(deal.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(criteria))
+	for _, criterion := range criteria {
+		known[criterion.Key] = true
+	}
+	rows := make([]models.DealScore, 0, len(scores))
+	for key, score := range scores {
+		if !known[key] {
+			return nil, fmt.Errorf("%w: %q is not on the scorecard", ErrInvalidScore, key)
+		}
+		if score < 1 || score > 10 {
+			return nil, fmt.Errorf("%w: %s must be scored 1 to 10", ErrInvalidScore, key)
+		}
+		rows = append(rows, models.DealScore{
+			OrganizationID: deal.OrganizationID,
+			Criterion:      key,
+			ReviewerName:   reviewer.Name,
+			Score:          score,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Criterion < rows[j].Criterion })
+
+	if err := s.scoreRepo.Within(scope).WithContext(ctx).ReplaceReviewer(deal.ID, reviewer.ID, rows); err != nil {
+		return nil, err
+	}
+	return s.Scorecard(scope, deal)
+}
+
+// Scorecard returns a deal's scores on each criterion of its organization's scorecard
+func (s *ScorecardService) Scorecard(scope repository.CompanyScope, deal *models.Deal) (*DealScorecard, error) {
+	criteria, err := s.Criteria(deal.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	scores, err := s.scoreRepo.Within(scope).GetByDeal(deal.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	byCriterion := make(map[string][]models.DealScore)
+	for _, score := range scores {
+		byCriterion[score.Criterion] = append(byCriterion[score.Criterion], score)
+	}
+	averages, total := summarizeScores(criteria, scores)
+
+	scorecard := &DealScorecard{DealID: deal.ID, Criteria: make([]CriterionScores, 0, len(criteria)), TotalScore: total}
+	for _, criterion := range criteria {
+		entry := CriterionScores{ScoringCriterion: criterion, Scores: byCriterion[criterion.Key]}
+		if average, ok := averages[criterion.Key]; ok {
+			entry.Average = &average
+		}
+		if entry.Scores == nil {
+			entry.Scores = []models.DealScore{}
+		}
+		scorecard.Criteria = append(scorecard.Criteria, entry)
+	}
+	return scorecard, nil
+}
+
+// Summarize sets the Scores and TotalScore of deals of one organization
+func (s *ScorecardService) Summarize(scope repository.CompanyScope, deals []models.Deal) error {
+	if len(deals) == 0 {
+		return nil
+	}
+	criteria, err := s.Criteria(scope.OrganizationID)
+	if err != nil {
+		return err
+	}
+	ids := make([]uint, len(deals))
+	for i := range deals {
+		ids[i] = deals[i].ID
+	}
+	scores, err := s.scoreRepo.Within(scope).GetByDeals(ids)
+	if err != nil {
+		return err
+	}
+
+	byDeal := make(map[uint][]models.DealScore, len(deals))
+	for _, score := range scores {
+		byDeal[score.DealID] = append(byDeal[score.DealID], score)
+	}
+	for i := range deals {
+		deals[i].Scores, deals[i].TotalScore = summarizeScores(criteria, byDeal[deals[i].ID])
+	}
+	return nil
+}
+
+// summarizeScores averages a deal's scores on each criterion and weights the averages into
+// its total. The total leaves out criteria nobody scored, so it stays on the 1-10 scale;
+// scores on criteria no longer on the scorecard don't count.
+func summarizeScores(criteria []models.ScoringCriterion, scores []models.DealScore) (map[string]float64, float64) {
+	sums := make(map[string]int)
+	counts := make(map[string]int)
+	for _, score := range scores {
+		sums[score.Criterion] += score.Score
+		counts[score.Criterion]++
+	}
+
+	averages := make(map[string]float64)
+	weighted, weights := 0.0, 0
+	for _, criterion := range criteria {
+		if counts[criterion.Key] == 0 {
+			continue
+		}
+		average := float64(sums[criterion.Key]) / float64(counts[criterion.Key])
+		averages[criterion.Key] = roundTo(average, 2)
+		weighted += average * float64(criterion.Weight)
+		weights += criterion.Weight
+	}
+	if weights == 0 {
+		return averages, 0
+	}
+	return averages, roundTo(weighted/float64(weights), 2)
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"maps"
+	"testing"
+	"ventura/internal/models"
+)
+
+func TestSummarizeScores(t *testing.T) {
+	criteria := []models.ScoringCriterion{
+		{Key: "team", Weight: 40},
+		{Key: "market", Weight: 30},
+		{Key: "product", Weight: 30},
+	}
+	scores := func(criterion string, values ...int) []models.DealScore {
+		var out []models.DealScore
+		for _, v := range values {
+			out = append(out, models.DealScore{Criterion: criterion, Score: v})
+		}
+		return out
+	}
+	join := func(groups ...[]models.DealScore) []models.DealScore {
+		var out []models.DealScore
+		for _, g := range groups {
+			out = append(out, g...)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		scores   []models.DealScore
+		averages map[string]float64
+		total    float64
+	}{
+		{
+			name:     "unscored",
+			averages: map[string]float64{},
+			total:    0,
+		},
+		{
+			name:     "every criterion scored",
+			scores:   join(scores("team", 8), scores("market", 6), scores("product", 4)),
+			averages: map[string]float64{"team": 8, "market": 6, "product": 4},
+			total:    6.2, // (8*40 + 6*30 + 4*30) / 100
+		},
+		{
+			// Product has no score, so the total is weighted over the other 70 percent
+			name:     "partially scored",
+			scores:   join(scores("team", 8, 6), scores("market", 4)),
+			averages: map[string]float64{"team": 7, "market": 4},
+			total:    5.71, // (7*40 + 4*30) / 70
+		},
+		{
+			name:     "single criterion",
+			scores:   scores("market", 9, 6),
+			averages: map[string]float64{"market": 7.5},
+			total:    7.5,
+		},
+		{
+			name:     "criterion no longer on the scorecard",
+			scores:   join(scores("team", 5), scores("traction", 10)),
+			averages: map[string]float64{"team": 5},
+			total:    5,
+		},
+		{
+			name:     "averages round to two decimals",
+			scores:   scores("team", 7, 8, 8),
+			averages: map[string]float64{"team": 7.67},
+			total:    7.67,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			averages, total := summarizeScores(criteria, tt.scores)
+			if !maps.Equal(averages, tt.averages) {
+				t.Errorf("averages = %v, want %v", averages, tt.averages)
+			}
+			if total != tt.total {
+				t.Errorf("total = %v, want %v", total, tt.total)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"time"
+	"ventura/internal/auth"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+)
+
+// reuseGracePeriod lets a client that raced two refreshes (e.g. two tabs)
+// present the token it just rotated away without tripping reuse detection
+const reuseGracePeriod = 10 * time.Second
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrInvalidCSRFToken    = errors.New("missing or invalid CSRF token")
+)
+
+// TokenPair is the result of starting or refreshing a session
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	SessionID    uint
+	CSRFToken    string
+}
+
+// SessionAuth describes how the user of a new session signed in
+type SessionAuth struct {
+	Method string // models.SessionAuthPassword or models.SessionAuthSSO
+	MFA    bool   // A second factor was checked too
+}
+
+type SessionService struct {
+	sessionRepo *repository.SessionRepository
+	userRepo    *repository.UserRepository
+}
+
+func NewSessionService(sessionRepo *repository.SessionRepository, userRepo *repository.UserRepository) *SessionService {
+	return &SessionService{sessionRepo: sessionRepo, userRepo: userRepo}
+}
+
+// Start creates a new session for a user who signed in as authn describes and issues
+// its first token pair
+func (s *SessionService) Start(user *models.User, authn SessionAuth, userAgent, ipAddress string) (*TokenPair, error) {
+	now := time.Now()
+	session := &models.Session{
+		UserID:         user.ID,
+		OrganizationID: user.OrganizationID,
+		AuthMethod:     authn.Method,
+		MFAVerified:    authn.MFA,
+		TokenID:        auth.NewTokenID(),
+		CSRFToken:      auth.NewTokenID(),
+		UserAgent:      userAgent,
+		IPAddress:      ipAddress,
+		LastUsedAt:     now,
+		ExpiresAt:      now.Add(auth.RefreshTokenExpiry),
+	}
+
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+
+	return issueTokens(user, session, session.TokenID)
+}
+
+// Refresh rotates a session's refresh token and issues a new token pair. csrfToken must be
+// the session's. The member is reloaded so role changes take effect immediately.
+func (s *SessionService) Refresh(refreshToken, csrfToken, userAgent, ipAddress string) (*TokenPair, *models.User, error) {
+	claims, err := auth.ValidateRefreshToken(refreshToken)
+	if err != nil || claims.SessionID == 0 || claims.ID == "" {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.sessionRepo.FindByID(claims.SessionID)
+	if err != nil || !session.IsActive() || session.UserID != claims.UserID {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if !validCSRFToken(session, csrfToken) {
+		return nil, nil, ErrInvalidCSRFToken
+	}
+
+	user, err := s.userRepo.FindMember(session.UserID, session.OrganizationID)
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	if claims.ID != session.TokenID {
+		// A concurrent refresh just rotated this token: hand back the current one
+		if claims.ID == session.PreviousTokenID && session.RotatedAt != nil && time.Since(*session.RotatedAt) < reuseGracePeriod {
+			pair, err := issueTokens(user, session, session.TokenID)
+			return pair, user, err
+		}
+
+		// Anything older is a replayed token: kill the whole session
+		log.Printf("Refresh token reuse detected for session %d (user %d) from %s", session.ID, session.UserID, ipAddress)
+		s.sessionRepo.Revoke(session.ID, models.SessionRevokedTokenReuse)
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	newTokenID := auth.NewTokenID()
+	rotated, err := s.sessionRepo.Rotate(session.ID, session.TokenID, newTokenID, time.Now().Add(auth.RefreshTokenExpiry), ipAddress, userAgent)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rotated {
+		// Lost the race against another refresh; the client retries within the grace period
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	pair, err := issueTokens(user, session, newTokenID)
+	return pair, user, err
+}
+
+// SwitchOrganization replaces a session with a new one in another of the user's
+// organizations. member must be loaded for the target organization, and authn is how
+// the user just signed in again for it: a session never carries over into an
+// organization it wasn't authenticated for. Tokens of the old session stop working at
+// once, so nothing keeps acting in the previous organization.
+func (s *SessionService) SwitchOrganization(sessionID uint, member *models.User, authn SessionAuth, userAgent, ipAddress string) (*TokenPair, error) {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || !session.IsActive() || session.UserID != member.ID {
+		return nil, ErrSessionNotFound
+	}
+
+	pair, err := s.Start(member, authn, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+	s.sessionRepo.Revoke(session.ID, models.SessionRevokedOrgSwitch)
+	return pair, nil
+}
+
+// IsActive reports whether the session behind an access token is still valid
+func (s *SessionService) IsActive(sessionID uint) bool {
+	active, err := s.sessionRepo.IsActive(sessionID)
+	return err == nil && active
+}
+
+// Logout ends a session and returns its user as a member of the session's organization.
+// csrfToken must be the session's.
+func (s *SessionService) Logout(sessionID uint, csrfToken string) (*models.User, error) {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || !session.IsActive() {
+		return nil, ErrSessionNotFound
+	}
+	if !validCSRFToken(session, csrfToken) {
+		return nil, ErrInvalidCSRFToken
+	}
+	if err := s.sessionRepo.Revoke(session.ID, models.SessionRevokedLogout); err != nil {
+		return nil, err
+	}
+	return s.userRepo.FindMember(session.UserID, session.OrganizationID)
+}
+
+// RevokeForUser ends one of the user's own sessions
+func (s *SessionService) RevokeForUser(userID, sessionID uint) error {
+	revoked, err := s.sessionRepo.RevokeForUser(sessionID, userID, models.SessionRevokedByUser)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllForUser ends every session of a user, optionally keeping one (e.g. the caller's)
+func (s *SessionService) RevokeAllForUser(userID, exceptSessionID uint, reason string) error {
+	return s.sessionRepo.RevokeAllForUser(userID, exceptSessionID, reason)
+}
+
+// RevokeAllInOrganization ends a user's sessions in one organization
+func (s *SessionService) RevokeAllInOrganization(userID, orgID uint, reason string) error {
+	return s.sessionRepo.RevokeAllForUserInOrganization(userID, orgID, reason)
+}
+
+// ListActive returns the user's active sessions
+func (s *SessionService) ListActive(userID uint) ([]models.Session, error) {
+	return s.sessionRepo.GetActiveByUserID(userID)
+}
+
+// validCSRFToken reports whether token is the CSRF token of session. Refresh and logout are
+// authenticated by the refresh cookie alone, which browsers send on cross-site requests too.
+func validCSRFToken(session *models.Session, token string) bool {
+	return session.CSRFToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) == 1
+}
+
+func issueTokens(user *models.User, session *models.Session, tokenID string) (*TokenPair, error) {
+	accessToken, err := auth.GenerateAccessToken(user, session.ID, session.CSRFToken)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := auth.GenerateRefreshToken(user, session.ID, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken, SessionID: session.ID, CSRFToken: session.CSRFToken}, nil
+}
+
+// PruneExpired deletes sessions that expired more than retention ago
+func (s *SessionService) PruneExpired(retention time.Duration) (int64, error) {
+	return s.sessionRepo.DeleteExpired(time.Now().Add(-retention))
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"ventura/internal/auth"
+	"ventura/internal/models"
+	"ventura/internal/repository"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSSONotConfigured   = errors.New("single sign-on is not configured for this organization")
+	ErrSSOInvalidState    = errors.New("single sign-on session expired or was tampered with, please try again")
+	ErrSSOInvalidIssuer   = errors.New("could not load OpenID configuration from the issuer")
+	ErrSSOSecretRequired  = errors.New("clientSecret is required")
+	ErrSSOEmailMissing    = errors.New("the identity provider did not return a verified email address")
+	ErrSSOEmailNotAllowed = errors.New("your email domain is not allowed for this organization")
+	ErrSSOAccountConflict = errors.New("this email address belongs to an account outside this organization")
+)
+
+// SSOConfigInput describes an organization's OIDC provider settings
+type SSOConfigInput struct {
+	Issuer               string
+	ClientID             string
+	ClientSecret         string // Empty keeps the stored secret
+	AllowedDomains       []string
+	DefaultRole          models.UserRole
+	Enabled              bool
+	DisablePasswordLogin bool
+}
+
+// SSOService runs per-organization OpenID Connect logins (authorization code + PKCE)
+type SSOService struct {
+	ssoRepo     *repository.SSORepository
+	orgRepo     *repository.OrganizationRepository
+	userRepo    *repository.UserRepository
+	redirectURL string
+
+	mu        sync.Mutex
+	discovery map[string]*oidc.Provider // Cached discovery documents by issuer
+}
+
+func NewSSOService(ssoRepo *repository.SSORepository, orgRepo *repository.OrganizationRepository, userRepo *repository.UserRepository) *SSOService {
+	redirectURL := os.Getenv("SSO_REDIRECT_URL")
+	if redirectURL == "" {
+		redirectURL = "http://localhost:8080/auth/sso/callback"
+	}
+
+	return &SSOService{
+		ssoRepo:     ssoRepo,
+		orgRepo:     orgRepo,
+		userRepo:    userRepo,
+		redirectURL: redirectURL,
+		discovery:   make(map[string]*oidc.Provider),
+	}
+}
+
+// AppURL is the frontend the browser returns to after an SSO login
+func (s *SSOService) AppURL() string {
+	return appURL()
+}
+
+// GetConfig returns the organization's provider configuration
+func (s *SSOService) GetConfig(orgID uint) (*models.OIDCProvider, error) {
+	provider, err := s.ssoRepo.FindProviderByOrganization(orgID)
+	if err != nil {
+		return nil, ErrSSONotConfigured
+	}
+	return provider, nil
+}
+
+// SaveConfig creates or updates the organization's provider after checking the issuer's discovery document
+func (s *SSOService) SaveConfig(ctx context.Context, orgID uint, input SSOConfigInput) (*models.OIDCProvider, error) {
+	provider, err := s.ssoRepo.FindProviderByOrganization(orgID)
+	if err != nil {
+		provider = &models.OIDCProvider{OrganizationID: orgID}
+	}
+
+	if input.ClientSecret != "" {
+		provider.ClientSecret = input.ClientSecret
+	}
+	if provider.ClientSecret == "" {
+		return nil, ErrSSOSecretRequired
+	}
+
+	issuer := strings.TrimRight(strings.TrimSpace(input.Issuer), "/")
+	s.forgetIssuer(provider.Issuer)
+	if _, err := s.discover(issuer); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSSOInvalidIssuer, err)
+	}
+
+	domains := make([]string, 0, len(input.AllowedDomains))
+	for _, domain := range input.AllowedDomains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+
+	role := input.DefaultRole
+	if role == "" {
+		role = models.RoleViewer
+	}
+
+	provider.Issuer = issuer
+	provider.ClientID = strings.TrimSpace(input.ClientID)
+	provider.AllowedDomains = strings.Join(domains, " ")
+	provider.DefaultRole = role
+	provider.Enabled = input.Enabled
+	provider.DisablePasswordLogin = input.DisablePasswordLogin
+
+	if err := s.ssoRepo.WithContext(ctx).SaveProvider(provider); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// DeleteConfig removes the organization's provider; linked identities are kept for a later re-setup
+func (s *SSOService) DeleteConfig(ctx context.Context, orgID uint) error {
+	provider, err := s.ssoRepo.FindProviderByOrganization(orgID)
+	if err != nil {
+		return ErrSSONotConfigured
+	}
+	s.forgetIssuer(provider.Issuer)
+	return s.ssoRepo.WithContext(ctx).DeleteProvider(orgID)
+}
+
+// PasswordLoginDisabled reports whether the user's organization only allows SSO.
+// Admins keep password login so a broken identity provider can't lock the firm out.
+func (s *SSOService) PasswordLoginDisabled(user *models.User) bool {
+	if user.Role == models.RoleAdmin {
+		return false
+	}
+	provider, err := s.ssoRepo.FindProviderByOrganization(user.OrganizationID)
+	return err == nil && provider.Enabled && provider.DisablePasswordLogin
+}
+
+// BeginLogin prepares a login for the organization with the given slug. It returns the
+// identity provider URL to redirect to and a state token the callback must receive back.
+func (s *SSOService) BeginLogin(slug, redirect string) (authURL, stateToken string, err error) {
+	org, err := s.orgRepo.FindBySlug(slug)
+	if err != nil {
+		return "", "", ErrSSONotConfigured
+	}
+
+	provider, err := s.ssoRepo.FindProviderByOrganization(org.ID)
+	if err != nil || !provider.Enabled {
+		return "", "", ErrSSONotConfigured
+	}
+
+	config, _, err := s.oauthConfig(provider)
+	if err != nil {
+		return "", "", err
+	}
+
+	state := newSSOState(org.ID, redirect)
+	authURL = config.AuthCodeURL(state.State, oidc.Nonce(state.Nonce), oauth2.S256ChallengeOption(state.Verifier))
+
+	stateToken, err = auth.GenerateSSOStateToken(state)
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, stateToken, nil
+}
+
+// CompleteLogin exchanges the authorization code, verifies the ID token and returns the
+// matching user, linking or creating the account if needed, plus the path to return to
+func (s *SSOService) CompleteLogin(ctx context.Context, stateToken, state, code string) (*models.User, string, error) {
+	saved, err := auth.ValidateSSOStateToken(stateToken)
+	if err != nil || subtle.ConstantTimeCompare([]byte(saved.State), []byte(state)) != 1 {
+		return nil, "", ErrSSOInvalidState
+	}
+
+	provider, err := s.ssoRepo.FindProviderByOrganization(saved.OrganizationID)
+	if err != nil || !provider.Enabled {
+		return nil, "", ErrSSONotConfigured
+	}
+
+	config, discovered, err := s.oauthConfig(provider)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(saved.Verifier))
+	if err != nil {
+		return nil, "", fmt.Errorf("code exchange failed: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, "", errors.New("identity provider returned no id_token")
+	}
+
+	idToken, err := discovered.Verifier(&oidc.Config{ClientID: provider.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid id_token: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(saved.Nonce)) != 1 {
+		return nil, "", ErrSSOInvalidState
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified *bool  `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, "", err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" || (claims.EmailVerified != nil && !*claims.EmailVerified) {
+		return nil, "", ErrSSOEmailMissing
+	}
+	if !provider.AllowsEmail(email) {
+		return nil, "", ErrSSOEmailNotAllowed
+	}
+
+	user, err := s.resolveUser(ctx, provider, idToken.Issuer, idToken.Subject, email, claims.Name)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, saved.Redirect, nil
+}
+
+// resolveUser finds the user linked to an identity, links an existing account with the
+// same email, or provisions a new member of the organization
+func (s *SSOService) resolveUser(ctx context.Context, provider *models.OIDCProvider, issuer, subject, email, name string) (*models.User, error) {
+	if identity, err := s.ssoRepo.FindIdentity(issuer, subject); err == nil {
+		user, err := s.userRepo.FindMember(identity.UserID, provider.OrganizationID)
+		if err != nil {
+			return nil, ErrSSOAccountConflict
+		}
+		s.ssoRepo.TouchIdentity(identity.ID, email)
+		return user, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByEmail(email)
+	if err == nil {
+		// Existing accounts must already belong to the organization, e.g. through an invite
+		if _, err := s.userRepo.FindMember(user.ID, provider.OrganizationID); err != nil || user.IsServiceAccount {
+			return nil, ErrSSOAccountConflict
+		}
+		if !user.IsEmailVerified() {
+			// The identity provider vouches for the address
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+			if err := s.userRepo.WithContext(ctx).Update(user); err != nil {
+				return nil, err
+			}
+		}
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		if name == "" {
+			name = email
+		}
+		randomBytes := make([]byte, 24)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		user = &models.User{
+			OrganizationID:  provider.OrganizationID,
+			Email:           email,
+			Password:        hex.EncodeToString(randomBytes), // Unusable; the member signs in through SSO
+			Name:            name,
+			Role:            provider.DefaultRole,
+			EmailVerifiedAt: &now,
+		}
+		if err := s.userRepo.WithContext(ctx).Create(user); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	if err := s.ssoRepo.CreateIdentity(&models.UserIdentity{
+		UserID:      user.ID,
+		Issuer:      issuer,
+		Subject:     subject,
+		Email:       email,
+		LastLoginAt: time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.FindMember(user.ID, provider.OrganizationID)
+}
+
+func (s *SSOService) oauthConfig(provider *models.OIDCProvider) (*oauth2.Config, *oidc.Provider, error) {
+	discovered, err := s.discover(provider.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrSSOInvalidIssuer, err)
+	}
+
+	return &oauth2.Config{
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		Endpoint:     discovered.Endpoint(),
+		RedirectURL:  s.redirectURL,
+		Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+	}, discovered, nil
+}
+
+// discover loads and caches an issuer's OpenID configuration
+func (s *SSOService) discover(issuer string) (*oidc.Provider, error) {
+	s.mu.Lock()
+	cached, ok := s.discovery[issuer]
+	s.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	// The provider fetches signing keys later with this context, so it must outlive the request
+	discovered, err := oidc.NewProvider(context.Background(), issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.discovery[issuer] = discovered
+	s.mu.Unlock()
+	return discovered, nil
+}
+
+func (s *SSOService) forgetIssuer(issuer string) {
+	s.mu.Lock()
+	delete(s.discovery, issuer)
+	s.mu.Unlock()
+}
+
+// newSSOState creates a fresh state, nonce and PKCE verifier for a login
+func newSSOState(orgID uint, redirect string) auth.SSOState {
+	return auth.SSOState{
+		State:          auth.NewTokenID(),
+		Nonce:          auth.NewTokenID(),
+		Verifier:       oauth2.GenerateVerifier(),
+		OrganizationID: orgID,
+		Redirect:       redirect,
+	}
+}
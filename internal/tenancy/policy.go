@@ -0,0 +1,86 @@
+package tenancy
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// currentOrganization is the organization the transaction is admitted to, or NULL
+const currentOrganization = "NULLIF(current_setting('app.current_org', true), '')::bigint"
+
+// policies maps each protected table to the rows a tenant may see and write
+var policies = []struct {
+	table     string
+	predicate string
+}{
+	{"portfolio_companies", "organization_id = " + currentOrganization},
+	{"deals", "organization_id = " + currentOrganization},
+	{"deal_stage_events", "organization_id = " + currentOrganization},
+	{"deal_activities", "organization_id = " + currentOrganization},
+	{"deal_activity_mentions", activityPredicate},
+	{"deal_activity_revisions", activityPredicate},
+	{"deal_analyses", "organization_id = " + currentOrganization},
+	{"deal_scores", "organization_id = " + currentOrganization},
+	{"founders", companyPredicate},
+	{"monthly_updates", companyPredicate},
+	{"documents", companyPredicate},
+	{"team_assignments", companyPredicate},
+	{"audit_logs", "organization_id = " + currentOrganization},
+}
+
+// companyPredicate admits the rows of a table owned by a company to its organization
+const companyPredicate = "company_id IN (SELECT id FROM portfolio_companies WHERE organization_id = " + currentOrganization + ")"
+
+// activityPredicate admits the rows of a table owned by a deal activity to its organization
+const activityPredicate = "activity_id IN (SELECT id FROM deal_activities WHERE organization_id = " + currentOrganization + ")"
+
+// InstallPolicies enables row-level security on the tables holding organization data,
+// forced on their owner too, and (re)creates the policies confining them to the organization
+// chosen with Organization
+func InstallPolicies(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, policy := range policies {
+			statements := []string{
+				fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY", policy.table),
+				fmt.Sprintf("ALTER TABLE %s FORCE ROW LEVEL SECURITY", policy.table),
+				fmt.Sprintf("DROP POLICY IF EXISTS tenant_isolation ON %s", policy.table),
+				fmt.Sprintf("CREATE POLICY tenant_isolation ON %s USING (%s) WITH CHECK (%s)", policy.table, policy.predicate, policy.predicate),
+			}
+			for _, statement := range statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return fmt.Errorf("%s: %w", policy.table, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Migrate runs migrate as the tables' owner with every organization's rows in view. It lifts
+// row-level security off the owner, which only the owner can do, and forces it again with
+// InstallPolicies in the same transaction, so no other session ever sees the tables unforced.
+func Migrate(db *gorm.DB, migrate func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, policy := range policies {
+			if err := tx.Exec(fmt.Sprintf("ALTER TABLE IF EXISTS %s NO FORCE ROW LEVEL SECURITY", policy.table)).Error; err != nil {
+				return fmt.Errorf("%s: %w", policy.table, err)
+			}
+		}
+		if err := migrate(tx); err != nil {
+			return err
+		}
+		return InstallPolicies(tx)
+	})
+}
+
+// CanBypass reports whether db's role is exempt from row-level security: superusers and
+// roles with BYPASSRLS see every row whatever the policies say
+func CanBypass(db *gorm.DB) (bool, error) {
+	var bypass []bool
+	err := db.Raw("SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(&bypass).Error
+	if err != nil || len(bypass) == 0 {
+		return false, err
+	}
+	return bypass[0], nil
+}
@@ -0,0 +1,172 @@
+package tenancy
+
+import (
+	"os"
+	"testing"
+	"time"
+	"ventura/internal/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// apiRole is the role the tests query as: like the API's, it neither owns the tables nor
+// bypasses row-level security
+const apiRole = "ventura_test_api"
+
+// openTestDB migrates the protected tables of the scratch database TEST_DATABASE_URL names,
+// as a superuser, and returns a db whose one connection acts as apiRole with the Plugin
+// registered. Two organizations own a company and a deal each.
+func openTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	// SET ROLE holds for the session, so every statement has to share it
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	err = Migrate(db, func(tx *gorm.DB) error {
+		return tx.AutoMigrate(
+			&models.Organization{},
+			&models.User{},
+			&models.PortfolioCompany{},
+			&models.Deal{},
+			&models.Founder{},
+			&models.MonthlyUpdate{},
+			&models.Document{},
+			&models.AuditLog{},
+			&models.TeamAssignment{},
+			&models.DealStageEvent{},
+			&models.DealActivity{},
+			&models.DealActivityMention{},
+			&models.DealActivityRevision{},
+			&models.DealAnalysis{},
+			&models.DealScore{},
+		)
+	})
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	for _, statement := range []string{
+		"TRUNCATE portfolio_companies, deals CASCADE",
+		"DO $$ BEGIN CREATE ROLE " + apiRole + " NOSUPERUSER NOBYPASSRLS; EXCEPTION WHEN duplicate_object THEN NULL; END $$",
+		"GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO " + apiRole,
+		"GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO " + apiRole,
+		"SET ROLE " + apiRole,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+	t.Cleanup(func() { db.Exec("RESET ROLE") })
+
+	if err := db.Use(Plugin{}); err != nil {
+		t.Fatalf("register plugin: %v", err)
+	}
+
+	for _, orgID := range []uint{1, 2} {
+		company := models.PortfolioCompany{OrganizationID: orgID, Name: "Company", Sector: "SaaS", RoundStage: "Seed", InvestedAt: time.Now()}
+		if err := Organization(db, orgID).Create(&company).Error; err != nil {
+			t.Fatalf("create company: %v", err)
+		}
+		deal := models.Deal{OrganizationID: orgID, CompanyName: "Deal", Sector: "SaaS"}
+		if err := Organization(db, orgID).Create(&deal).Error; err != nil {
+			t.Fatalf("create deal: %v", err)
+		}
+	}
+	return db
+}
+
+func TestOrganizationSeesOnlyItsRows(t *testing.T) {
+	db := openTestDB(t)
+
+	var companies []models.PortfolioCompany
+	if err := Organization(db, 1).Find(&companies).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(companies) != 1 || companies[0].OrganizationID != 1 {
+		t.Fatalf("organization 1 sees %+v, want only its own company", companies)
+	}
+}
+
+func TestQueryWithoutOrganizationSeesNothing(t *testing.T) {
+	db := openTestDB(t)
+
+	for _, model := range []interface{}{&models.PortfolioCompany{}, &models.Deal{}} {
+		var count int64
+		if err := db.Model(model).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%T: query without an organization sees %d rows, want 0", model, count)
+		}
+	}
+}
+
+func TestSystemSettingSeesNothing(t *testing.T) {
+	db := openTestDB(t)
+
+	var count int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET LOCAL app.system = 'on'").Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.PortfolioCompany{}).Count(&count).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("app.system = 'on' sees %d companies, want 0", count)
+	}
+}
+
+func TestOrganizationCannotWriteAnothersRows(t *testing.T) {
+	db := openTestDB(t)
+
+	company := models.PortfolioCompany{OrganizationID: 2, Name: "Intruder", Sector: "SaaS", RoundStage: "Seed", InvestedAt: time.Now()}
+	if err := Organization(db, 1).Create(&company).Error; err == nil {
+		t.Fatal("organization 1 created a company for organization 2")
+	}
+
+	result := Organization(db, 1).Model(&models.Deal{}).Where("organization_id = ?", 2).Update("notes", "changed")
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if result.RowsAffected != 0 {
+		t.Fatalf("organization 1 updated %d of organization 2's deals", result.RowsAffected)
+	}
+}
+
+func TestMigrateForcesRowLevelSecurity(t *testing.T) {
+	db := openTestDB(t)
+
+	var unforced []string
+	err := db.Raw("SELECT relname FROM pg_class WHERE relname IN ? AND NOT (relrowsecurity AND relforcerowsecurity)", protectedTables()).Scan(&unforced).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unforced) != 0 {
+		t.Fatalf("row-level security is not forced on %v", unforced)
+	}
+}
+
+func protectedTables() []string {
+	tables := make([]string, len(policies))
+	for i, policy := range policies {
+		tables[i] = policy.table
+	}
+	return tables
+}
@@ -0,0 +1,122 @@
+package tenancy
+
+import (
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+)
+
+// setting carries a statement's tenant from Organization to the Plugin
+const setting = "tenancy:tenant"
+
+// errNoTransaction is returned by row queries on tenant data made outside a transaction:
+// the rows outlive the statement, so the Plugin cannot open and commit one around them
+var errNoTransaction = errors.New("row queries on tenant data must run in a transaction")
+
+type tenant struct {
+	organizationID uint
+}
+
+// Organization returns db with its statements confined by row-level security to one
+// organization's rows
+func Organization(db *gorm.DB, orgID uint) *gorm.DB {
+	return db.Set(setting, tenant{organizationID: orgID}).Session(&gorm.Session{})
+}
+
+// Plugin runs the statements of a db returned by Organization in a transaction whose
+// app.current_org setting chooses the rows the policies installed by InstallPolicies let it
+// see. Statements of any other db see none of those rows.
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "tenancy"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	// Writes already run in a transaction; the tenant is set before their hooks run
+	if err := db.Callback().Create().After("gorm:begin_transaction").Before("gorm:before_create").Register("tenancy:apply", applyTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:begin_transaction").Before("gorm:before_update").Register("tenancy:apply", applyTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:begin_transaction").Before("gorm:before_delete").Register("tenancy:apply", applyTenant); err != nil {
+		return err
+	}
+
+	// Queries and raw statements get one of their own, unless they run in one already
+	query := db.Callback().Query()
+	if err := query.Before("gorm:query").Register("tenancy:begin_transaction", beginTransaction); err != nil {
+		return err
+	}
+	if err := query.After("gorm:after_query").Register("tenancy:commit_or_rollback_transaction", callbacks.CommitOrRollbackTransaction); err != nil {
+		return err
+	}
+	raw := db.Callback().Raw()
+	if err := raw.Before("gorm:raw").Register("tenancy:begin_transaction", beginTransaction); err != nil {
+		return err
+	}
+	if err := raw.After("gorm:raw").Register("tenancy:commit_or_rollback_transaction", callbacks.CommitOrRollbackTransaction); err != nil {
+		return err
+	}
+	return db.Callback().Row().Before("gorm:row").Register("tenancy:apply", applyTenantInTransaction)
+}
+
+// Apply admits the rest of the transaction db's statement runs in to an organization's rows.
+// It is for writes that know the organization of what they write but not their caller's.
+func Apply(db *gorm.DB, orgID uint) {
+	if db.Error != nil || db.DryRun || db.Dialector.Name() != "postgres" {
+		return
+	}
+	_, err := db.Statement.ConnPool.ExecContext(db.Statement.Context,
+		"SELECT set_config('app.current_org', $1, true)", strconv.FormatUint(uint64(orgID), 10))
+	if err != nil {
+		db.AddError(err)
+	}
+}
+
+func tenantOf(db *gorm.DB) (tenant, bool) {
+	value, ok := db.Get(setting)
+	if !ok {
+		return tenant{}, false
+	}
+	t, ok := value.(tenant)
+	return t, ok
+}
+
+func beginTransaction(db *gorm.DB) {
+	if _, ok := tenantOf(db); !ok || db.Error != nil || db.DryRun {
+		return
+	}
+	callbacks.BeginTransaction(db)
+	applyTenant(db)
+}
+
+func applyTenant(db *gorm.DB) {
+	t, ok := tenantOf(db)
+	if !ok || db.Error != nil || db.DryRun {
+		return
+	}
+	_, err := db.Statement.ConnPool.ExecContext(db.Statement.Context,
+		"SELECT set_config('app.current_org', $1, true)", strconv.FormatUint(uint64(t.organizationID), 10))
+	if err != nil {
+		db.AddError(err)
+	}
+}
+
+func applyTenantInTransaction(db *gorm.DB) {
+	if _, ok := tenantOf(db); !ok || db.DryRun {
+		return
+	}
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); !ok {
+		db.AddError(errNoTransaction)
+		return
+	}
+	applyTenant(db)
+}
@@ -0,0 +1,24 @@
+package worker
+
+import (
+	"log"
+	"time"
+	"ventura/internal/service"
+)
+
+// StartAuditRetention archives audit entries older than their organization's retention
+// period once a day
+func StartAuditRetention(audits *service.AuditService) {
+	go func() {
+		for {
+			archived, err := audits.ApplyRetention()
+			if err != nil {
+				log.Println("Audit retention failed:", err)
+			}
+			if archived > 0 {
+				log.Printf("Audit retention archived %d entries", archived)
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
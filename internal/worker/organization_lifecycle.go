@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"log"
+	"time"
+	"ventura/internal/service"
+)
+
+// StartOrganizationLifecycle purges deleted organizations whose grace period is over and
+// deletes expired exports once an hour
+func StartOrganizationLifecycle(organizations *service.OrganizationService, exports *service.OrganizationExportService) {
+	go func() {
+		for {
+			purged, err := organizations.PurgeDue()
+			if err != nil {
+				log.Println("Organization purge failed:", err)
+			}
+			if purged > 0 {
+				log.Printf("Organization purge erased %d organizations", purged)
+			}
+
+			expired, err := exports.Expire()
+			if err != nil {
+				log.Println("Export cleanup failed:", err)
+			} else if expired > 0 {
+				log.Printf("Export cleanup removed %d expired exports", expired)
+			}
+			time.Sleep(1 * time.Hour)
+		}
+	}()
+}
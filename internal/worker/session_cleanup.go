@@ -0,0 +1,23 @@
+package worker
+
+import (
+	"log"
+	"time"
+	"ventura/internal/service"
+)
+
+// StartSessionCleanup periodically deletes long-expired refresh sessions.
+// Expired sessions are kept for a week so reuse attempts can still be traced.
+func StartSessionCleanup(sessions *service.SessionService) {
+	go func() {
+		for {
+			deleted, err := sessions.PruneExpired(7 * 24 * time.Hour)
+			if err != nil {
+				log.Println("Session cleanup failed:", err)
+			} else if deleted > 0 {
+				log.Printf("Session cleanup removed %d expired sessions", deleted)
+			}
+			time.Sleep(1 * time.Hour)
+		}
+	}()
+}